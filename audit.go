@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bshakr/lsrv/internal/exposure"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// runAudit reports servers other machines can reach and pages that
+// shouldn't be served, such as debug endpoints and admin consoles with
+// default logins. It fails when anything is reachable beyond this machine.
+func runAudit(args []string) int {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	jsonFlag := fs.Bool("json", false, "Print the findings as JSON")
+	bindOnlyFlag := fs.Bool("bind-only", false, "Only check listening addresses and tunnels, without requesting any pages")
+	pageTimeoutFlag := fs.Duration("page-timeout", exposure.DefaultTimeout, "Give up on each page request after this long")
+	timeoutFlag := fs.Duration("timeout", defaultTimeout, "Give up on detection after this long (0 for no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv audit [--json] [--bind-only]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Flag servers and services listening beyond loopback or behind public tunnels,")
+		fmt.Fprintln(os.Stderr, "exposed debug endpoints (Rails info, Django DEBUG, Werkzeug, actuator, ...),")
+		fmt.Fprintln(os.Stderr, "served .env and .git files, and admin consoles with default logins. Exits 1")
+		fmt.Fprintln(os.Stderr, "when anything is high or medium severity.")
+		fs.PrintDefaults()
+	}
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return 2
+	}
+
+	if !commandExists("lsof") {
+		printLsofError()
+		return 1
+	}
+	opts := configuredOptions()
+	opts.Services = true
+	opts.Probe = !*bindOnlyFlag
+	opts.LAN = true
+	opts.Firewall = true
+	opts.Tunnels = true
+	servers, err := findServers(opts, *timeoutFlag)
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	findings := exposure.Audit(context.Background(), servers, *bindOnlyFlag, *pageTimeoutFlag)
+	if *jsonFlag {
+		if findings == nil {
+			findings = []exposure.Finding{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(findings); err != nil {
+			return exitWithError("writing output: %v", err)
+		}
+	} else {
+		printFindings(findings, len(servers))
+	}
+
+	for _, finding := range findings {
+		if finding.Severity != exposure.SeverityLow {
+			return 1
+		}
+	}
+	return 0
+}
+
+// printFindings writes the audit report, one line per finding
+func printFindings(findings []exposure.Finding, audited int) {
+	if len(findings) == 0 {
+		fmt.Printf("No findings in %d server(s)\n", audited)
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tPORT\tSERVER\tFINDING\tURL")
+	counts := make(map[exposure.Severity]int)
+	for _, finding := range findings {
+		counts[finding.Severity]++
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", strings.ToUpper(string(finding.Severity)), finding.Server.Port, auditName(finding.Server), finding.Detail, finding.URL)
+	}
+	tw.Flush()
+
+	var parts []string
+	for _, severity := range []exposure.Severity{exposure.SeverityHigh, exposure.SeverityMedium, exposure.SeverityLow} {
+		if counts[severity] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	fmt.Printf("\n%d finding(s) in %d server(s): %s\n", len(findings), audited, strings.Join(parts, ", "))
+	if counts[exposure.SeverityLow] > 0 {
+		fmt.Println("Low severity pages are only reachable from this machine.")
+	}
+}
+
+// auditName names a server as repo(branch), or a service by its name
+func auditName(server types.Server) string {
+	if server.Service != "" {
+		return server.Service
+	}
+	return fmt.Sprintf("%s(%s)", server.Repo, server.Branch)
+}
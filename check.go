@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/services"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// runCheck verifies that the servers listed under requires in the
+// repository's .lsrv.yml are running, automating the "make sure X, Y and
+// Z are up" step of onboarding docs. It fails listing the missing ones.
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	asciiFlag := fs.Bool("ascii", false, "Mark results with [ok] and [x] instead of symbols")
+	quietFlag := fs.Bool("quiet", false, "Only print the missing servers")
+	timeoutFlag := fs.Duration("timeout", defaultTimeout, "Give up on detection after this long (0 for no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv check [--quiet] [--ascii] [dir]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Check that the servers listed under requires in .lsrv.yml are running, e.g.")
+		fmt.Fprintln(os.Stderr, "requires: [web, webpack, redis, postgres]. Exits 1 naming the missing ones.")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	dir := "."
+	if len(positional) == 1 {
+		dir = positional[0]
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return exitWithError("%v", err)
+	}
+	repoCfg, cfgDir, err := config.FindRepo(dir)
+	if err != nil {
+		return exitWithError("%v", err)
+	}
+	if len(repoCfg.Requires) == 0 {
+		return exitWithError("no requires in %s; list the servers this project needs there", config.RepoFileName)
+	}
+	ctx := context.Background()
+	roots, err := git.Worktrees(ctx, cfgDir)
+	if err != nil {
+		roots = []string{cfgDir}
+	}
+
+	if !commandExists("lsof") {
+		printLsofError()
+		return 1
+	}
+	opts := configuredOptions()
+	opts.Services = true
+	servers, err := findServers(opts, *timeoutFlag)
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	ok, missing := "✓", "✗"
+	if cfg, err := config.Load(); *asciiFlag || (err == nil && cfg.ASCII) {
+		ok, missing = "[ok]", "[x]"
+	}
+
+	var absent []string
+	for _, req := range repoCfg.Requires {
+		req = resolveRequirement(req, repoCfg.Ports)
+		holder, found := satisfy(ctx, req, servers, roots)
+		if !found {
+			absent = append(absent, req.Label())
+			fmt.Printf("%s %-16s %s\n", missing, req.Label(), describeRequirement(req))
+			continue
+		}
+		if !*quietFlag {
+			fmt.Printf("%s %-16s %s\n", ok, req.Label(), holder)
+		}
+	}
+
+	if len(absent) > 0 {
+		fmt.Printf("\n%d of %d required server(s) not running: %s\n", len(absent), len(repoCfg.Requires), strings.Join(absent, ", "))
+		return 1
+	}
+	return 0
+}
+
+// resolveRequirement fills in what a bare name refers to: a port named
+// in the repo's ports, lowest first, or a known service
+func resolveRequirement(req config.Requirement, ports map[int]string) config.Requirement {
+	if req.Port != 0 || req.Service != "" {
+		return req
+	}
+	for _, port := range slices.Sorted(maps.Keys(ports)) {
+		if strings.EqualFold(ports[port], req.Name) {
+			req.Port = port
+			return req
+		}
+	}
+	for _, svc := range services.Known {
+		if strings.EqualFold(svc.Name, req.Name) {
+			req.Service = svc.Name
+			return req
+		}
+	}
+	return req
+}
+
+// satisfy finds what fulfills req and describes it. A port is taken by
+// any listener, even one lsrv doesn't list; a bare name must match the
+// label, process or framework of a server in one of the repo's roots.
+func satisfy(ctx context.Context, req config.Requirement, servers []types.Server, roots []string) (string, bool) {
+	for _, server := range servers {
+		switch {
+		case req.Port != 0:
+			if server.Port != req.Port && !slices.Contains(server.AuxPorts, req.Port) {
+				continue
+			}
+			if req.Service != "" && server.Service != req.Service {
+				continue
+			}
+		case req.Service != "":
+			if server.Service != req.Service {
+				continue
+			}
+		default:
+			if server.Service != "" || !withinAny(roots, server.CWD) || !matchesName(server, req.Name) {
+				continue
+			}
+		}
+		return describeHolder(server), true
+	}
+
+	// Listeners outside any repository, such as a database in a container
+	// lsrv doesn't recognize, still hold the port
+	if req.Port != 0 && req.Service == "" {
+		if listeners, err := detector.Listeners(ctx, req.Port); err == nil && len(listeners) > 0 {
+			return fmt.Sprintf("port %d, %s (pid %d)", req.Port, listeners[0].Command, listeners[0].PID), true
+		}
+		// Another user's or root's process, which lsof can't see
+		if sockets, err := platform.ListeningSockets(); err == nil {
+			for _, sock := range sockets {
+				if sock.Port == req.Port {
+					return fmt.Sprintf("port %d, held by a process of another user", req.Port), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// matchesName reports whether a server is called name by its label,
+// process or framework
+func matchesName(server types.Server, name string) bool {
+	for _, candidate := range []string{server.Label, server.Process, server.Framework} {
+		if candidate != "" && strings.EqualFold(candidate, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeHolder names the server fulfilling a requirement
+func describeHolder(server types.Server) string {
+	if server.Service != "" {
+		return fmt.Sprintf("port %d, %s (pid %d)", server.Port, server.Service, server.PID)
+	}
+	return fmt.Sprintf("port %d, %s (%s), %s pid %d", server.Port, server.Repo, server.Branch, server.Process, server.PID)
+}
+
+// describeRequirement tells what was looked for when nothing matched
+func describeRequirement(req config.Requirement) string {
+	var wanted []string
+	if req.Service != "" {
+		wanted = append(wanted, req.Service)
+	}
+	if req.Port != 0 {
+		wanted = append(wanted, "on port "+strconv.Itoa(req.Port))
+	}
+	if len(wanted) == 0 {
+		return "not running (no server in this repository named so)"
+	}
+	return "not running (" + strings.Join(wanted, " ") + ")"
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// runClean offers to stop servers whose working directory was deleted,
+// which typically still hold ports after their worktree was removed
+func runClean(args []string) int {
+	fs := flag.NewFlagSet("clean", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "Stop every stale server without asking")
+	fs.BoolVar(yes, "y", false, "Stop every stale server without asking (shorthand)")
+	dryRun := fs.Bool("dry-run", false, "List the servers that would be stopped without stopping them")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv clean [--yes] [--dry-run]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Stop servers whose working directory was deleted or moved, asking for each one")
+		fmt.Fprintln(os.Stderr, "")
+		fs.PrintDefaults()
+	}
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return 2
+	}
+
+	servers, err := detectServers()
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	var stale []types.Server
+	for _, server := range servers {
+		if server.Status == types.StatusZombie {
+			stale = append(stale, server)
+		}
+	}
+	if len(stale) == 0 {
+		fmt.Println("No servers running in deleted directories.")
+		return 0
+	}
+
+	if *dryRun || control.SafeMode {
+		for _, server := range stale {
+			fmt.Printf("Would stop %s (pid %d) on port %d, started in %s\n", server.Process, server.PID, server.Port, server.CWD)
+		}
+		if !*dryRun {
+			return exitWithError("%v", control.ErrSafeMode)
+		}
+		return 0
+	}
+
+	input := bufio.NewReader(os.Stdin)
+	for _, server := range stale {
+		if !*yes && !confirm(input, fmt.Sprintf("Stop %s (pid %d) on port %d, started in %s?", server.Process, server.PID, server.Port, server.CWD)) {
+			continue
+		}
+		if err := control.Kill(server); err != nil {
+			return exitWithError("clean %s on port %d: %v", server.Repo, server.Port, err)
+		}
+		fmt.Printf("Stopped %s (pid %d) on port %d\n", server.Process, server.PID, server.Port)
+	}
+	return 0
+}
+
+// confirm asks a yes/no question on stdout, defaulting to no
+func confirm(input *bufio.Reader, question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := input.ReadString('\n')
+	if err != nil {
+		fmt.Println()
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
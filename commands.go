@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/i18n"
+	"github.com/bshakr/lsrv/internal/porthistory"
+	"github.com/bshakr/lsrv/internal/reservation"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// defaultTimeout bounds detection so a hung git command or unresponsive
+// network mount can't freeze lsrv
+const defaultTimeout = 10 * time.Second
+
+// subcommands maps subcommand names to their entry points, which return the
+// process exit code
+var subcommands = map[string]func(args []string) int{
+	"start":     runStart,
+	"logs":      runLogs,
+	"kill":      runKill,
+	"restart":   runRestart,
+	"open":      runOpen,
+	"details":   runDetails,
+	"who":       runWho,
+	"doctor":    runDoctor,
+	"audit":     runAudit,
+	"config":    runConfig,
+	"export":    runExport,
+	"import":    runImport,
+	"run":       runRun,
+	"exec":      runExec,
+	"env":       runEnv,
+	"guard":     runGuard,
+	"check":     runCheck,
+	"clean":     runClean,
+	"ports":     runPorts,
+	"reserve":   runReserve,
+	"graph":     runGraph,
+	"top":       runTop,
+	"whichport": runWhichport,
+	"prompt":    runPrompt,
+	"snapshot":  runSnapshot,
+	"serve":     runServe,
+	"daemon":    runDaemon,
+	"mcp":       runMCP,
+}
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments (e.g., "lsrv logs web -f") and returns the positional arguments.
+// Everything after a "--" terminator is returned verbatim.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		// flag stops at "--" or the first non-flag argument
+		consumed := len(args) - fs.NArg()
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, fs.Args()...), nil
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// detectServers runs server detection after checking that lsof is available
+func detectServers() ([]types.Server, error) {
+	if !commandExists("lsof") {
+		return nil, fmt.Errorf("lsof command not found, please install it")
+	}
+	return findServers(configuredOptions(), defaultTimeout)
+}
+
+// trackedDetectServers returns a detectServers for long-running modes that
+// only inspects processes started since its previous call
+func trackedDetectServers() func() ([]types.Server, error) {
+	tracker := &detector.Tracker{}
+	opts := configuredOptions()
+	return func() ([]types.Server, error) {
+		return refreshServers(tracker, opts, defaultTimeout)
+	}
+}
+
+// configuredOptions returns detection options for the config file's repo
+// name sources and URL templates, so subcommands and servers show the URLs
+// the listing does
+func configuredOptions() detector.Options {
+	opts := detector.Options{RepoNames: configuredRepoNames()}
+	if cfg, err := config.Load(); err == nil {
+		opts.URLTemplates = cfg.URLs
+		opts.Dedup, _ = detector.ParseDedup(cfg.Dedup)
+	}
+	return opts
+}
+
+// configuredRepoNames returns the repo name priority from the global config,
+// so subcommands match servers by the names the listing shows. Invalid
+// settings fall back to the default; the listing warns about them.
+func configuredRepoNames() []git.NameSource {
+	cfg, err := config.Load()
+	if err != nil || len(cfg.RepoName) == 0 {
+		return nil
+	}
+	sources, err := git.ParseNameSources(strings.Join(cfg.RepoName, ","))
+	if err != nil {
+		return nil
+	}
+	return sources
+}
+
+// safeMode reports whether the config asks for read-only operation
+func safeMode() bool {
+	cfg, err := config.Load()
+	return err == nil && cfg.SafeMode
+}
+
+// findServers runs detection bounded by timeout (zero means no limit),
+// reporting an expired deadline as a readable error
+func findServers(opts detector.Options, timeout time.Duration) ([]types.Server, error) {
+	return refreshServers(nil, opts, timeout)
+}
+
+// refreshServers is findServers through a Tracker, which may be nil
+func refreshServers(tracker *detector.Tracker, opts detector.Options, timeout time.Duration) ([]types.Server, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	servers, err := tracker.Refresh(ctx, opts)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, errors.New(i18n.T("timed out after %s, a git command or filesystem may be hung (raise --timeout to wait longer)", timeout))
+	}
+	if err == nil && !opts.NoGit {
+		trackPortHistory(servers)
+		checkReservations(servers)
+	}
+	return servers, err
+}
+
+// checkReservations flags servers on a port reserved for another repo,
+// which matters more than the port being unusual for their own
+func checkReservations(servers []types.Server) {
+	registry, err := reservation.Load()
+	if err != nil {
+		return
+	}
+	for i := range servers {
+		server := &servers[i]
+		if (server.Status == types.StatusHealthy || server.Status == types.StatusUnusual) && registry.Violated(*server) {
+			server.Status = types.StatusReserved
+		}
+	}
+}
+
+// trackPortHistory flags servers running on a port their repo doesn't
+// usually use, then records today's ports. Without git, repos are named
+// after their directory, so callers skip this to keep the history clean.
+func trackPortHistory(servers []types.Server) {
+	history, err := porthistory.Load()
+	if err != nil {
+		return
+	}
+	for i := range servers {
+		server := &servers[i]
+		if server.Service == "" && server.Status == types.StatusHealthy && history.Unusual(server.Repo, server.Port) {
+			server.Status = types.StatusUnusual
+		}
+	}
+	history.Record(servers, time.Now())
+	_ = history.Save()
+}
+
+// exitWithError prints an error in the standard format, translating format
+// when the catalog has it, and returns exit code 1
+func exitWithError(format string, args ...any) int {
+	fmt.Fprintln(os.Stderr, i18n.T("error:"), i18n.T(format, args...))
+	return 1
+}
+
+// printWarning prints a warning in the standard format, translating format
+// when the catalog has it
+func printWarning(format string, args ...any) {
+	fmt.Fprintln(os.Stderr, i18n.T("warning:"), i18n.T(format, args...))
+}
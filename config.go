@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/i18n"
+	"github.com/bshakr/lsrv/internal/platform"
+)
+
+// runConfig validates the config files or writes a starting one
+func runConfig(args []string) int {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	repoFlag := fs.Bool("repo", false, "With init, write .lsrv.yml at the root of the current repository")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv config check")
+		fmt.Fprintln(os.Stderr, "       lsrv config init [--repo]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Check the global config and this repository's .lsrv.yml for unknown settings and")
+		fmt.Fprintln(os.Stderr, "wrong values, or write a commented config to start from.")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 || (positional[0] != "check" && positional[0] != "init") {
+		fs.Usage()
+		return 2
+	}
+
+	if positional[0] == "init" {
+		return configInit(*repoFlag)
+	}
+	return configCheck()
+}
+
+// maxConfigWarnings caps the issues the listing prints; "lsrv config
+// check" shows them all
+const maxConfigWarnings = 3
+
+// warnConfigIssues prints the issues in the global config, so settings
+// that are misspelled or fail to load don't silently fall back to the
+// defaults. loadErr is the error from loading it, if any.
+func warnConfigIssues(loadErr error) {
+	path, err := config.Path()
+	if err != nil {
+		return
+	}
+	issues, _ := config.Check(path)
+	for i, issue := range issues {
+		if i == maxConfigWarnings {
+			printWarning("%d more issue(s) in %s, see \"lsrv config check\"", len(issues)-i, path)
+			break
+		}
+		printWarning("%s", issue)
+	}
+
+	switch {
+	case loadErr != nil && len(issues) > 0:
+		printWarning("ignoring %s until it's fixed, using defaults", path)
+	case loadErr != nil:
+		printWarning("%v, using defaults", loadErr)
+	}
+}
+
+// configCheck prints every issue in the global and repository config
+// files, exiting non-zero if there are any
+func configCheck() int {
+	var paths []string
+	if path, err := config.Path(); err == nil {
+		paths = append(paths, path)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if _, dir, _ := config.FindRepo(wd); dir != "" {
+			paths = append(paths, filepath.Join(dir, config.RepoFileName))
+		}
+	}
+
+	failed := false
+	for _, path := range paths {
+		if !platform.FileExists(path) {
+			continue
+		}
+		issues, err := config.Check(path)
+		if err != nil {
+			return exitWithError("%v", err)
+		}
+		if len(issues) == 0 {
+			fmt.Println(i18n.T("%s: ok", path))
+			continue
+		}
+		failed = true
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// configInit writes the commented global config, or with repo a .lsrv.yml
+// at the work tree root
+func configInit(repo bool) int {
+	path, err := config.Path()
+	if repo {
+		var wd string
+		if wd, err = os.Getwd(); err == nil {
+			if root, ok := git.WorkTreeRoot(wd); ok {
+				wd = root
+			}
+			path = filepath.Join(wd, config.RepoFileName)
+		}
+	}
+	if err != nil {
+		return exitWithError("%v", err)
+	}
+
+	if err := config.Init(path); err != nil {
+		if errors.Is(err, config.ErrExists) {
+			return exitWithError("%s already exists; edit it, and run \"lsrv config check\" to validate it", path)
+		}
+		return exitWithError("%v", err)
+	}
+	fmt.Println(i18n.T("Wrote %s", path))
+	return 0
+}
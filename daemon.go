@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bshakr/lsrv/internal/daemon"
+	"github.com/bshakr/lsrv/internal/platform"
+)
+
+// runDaemon installs, removes or inspects a launchd agent (macOS) or systemd
+// user unit (Linux) that runs "lsrv serve" at login
+func runDaemon(args []string) int {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	addr := fs.String("http", "localhost:7777", "Address the daemon's HTTP API listens on (install)")
+	grpcAddr := fs.String("grpc", "", "Also serve the gRPC API on ADDR (install)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv daemon install [--http=ADDR] [--grpc=ADDR]")
+		fmt.Fprintln(os.Stderr, "       lsrv daemon uninstall")
+		fmt.Fprintln(os.Stderr, "       lsrv daemon status")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Run \"lsrv serve\" at login as a launchd agent (macOS) or systemd user unit (Linux)")
+		fmt.Fprintln(os.Stderr, "")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	switch positional[0] {
+	case "install":
+		binary, err := daemon.CurrentBinary()
+		if err != nil {
+			return exitWithError("%v", err)
+		}
+		service := daemon.Service{
+			Binary: binary,
+			Args:   []string{"serve", "--http=" + *addr},
+			Path:   os.Getenv("PATH"),
+		}
+		if *grpcAddr != "" {
+			service.Args = append(service.Args, "--grpc="+*grpcAddr)
+		}
+		if state, err := platform.StateDir(); err == nil {
+			service.LogPath = filepath.Join(state, "daemon.log")
+		}
+
+		path, err := daemon.Install(service)
+		if err != nil {
+			return exitWithError("installing daemon: %v", err)
+		}
+		fmt.Printf("Installed %s\n", path)
+		fmt.Printf("lsrv serve now runs at login, API on http://%s\n", *addr)
+		return 0
+
+	case "uninstall":
+		path, err := daemon.Uninstall()
+		if err != nil {
+			return exitWithError("uninstalling daemon: %v", err)
+		}
+		fmt.Printf("Stopped the daemon and removed %s\n", path)
+		return 0
+
+	case "status":
+		status, err := daemon.Query()
+		if err != nil {
+			return exitWithError("%v", err)
+		}
+		if status.File == "" {
+			fmt.Println("Not installed (run: lsrv daemon install)")
+			return 1
+		}
+		fmt.Printf("Installed: %s\n", status.File)
+		fmt.Printf("State:     %s\n", status.Detail)
+		if !status.Running {
+			return 1
+		}
+		return 0
+	}
+
+	fs.Usage()
+	return 2
+}
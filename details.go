@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/container"
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/devshell"
+	"github.com/bshakr/lsrv/internal/formatter"
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/health"
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// detailsHealthTimeout bounds the health check of the details view
+const detailsHealthTimeout = 2 * time.Second
+
+// runDetails prints everything lsrv knows about one server, untruncated,
+// one fact per line
+func runDetails(args []string) int {
+	fs := flag.NewFlagSet("details", flag.ContinueOnError)
+	timeoutFlag := fs.Duration("timeout", defaultTimeout, "Give up on detection after this long (0 for no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv details [repo|port] [--timeout=DURATION]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Show everything known about a server, by default the one running in the")
+		fmt.Fprintln(os.Stderr, "current repository: command line, directory, git remote, uptime, memory,")
+		fmt.Fprintln(os.Stderr, "health, environment and URLs.")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	if !commandExists("lsof") {
+		printLsofError()
+		return 1
+	}
+	servers, err := findServers(detailsOptions(), *timeoutFlag)
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	var server types.Server
+	if len(positional) == 0 {
+		server, err = currentServer(servers)
+	} else {
+		server, err = matchDir(preferCurrent(servers, positional[0]), positional[0])
+	}
+	if err != nil {
+		return exitWithError("%v", err)
+	}
+
+	printDetails(server)
+	return 0
+}
+
+// detailsOptions asks detection for everything the details view shows
+func detailsOptions() detector.Options {
+	opts := configuredOptions()
+	opts.Services = true
+	opts.CommandLine = true
+	opts.Runtime = true
+	opts.Env = true
+	opts.LastCommit = true
+	opts.RemoteURL = true
+	opts.Session = true
+	opts.StartedBy = true
+	opts.Backlog = true
+	if cfg, err := config.Load(); err == nil {
+		opts.EnvVars = cfg.EnvVars
+	}
+	if wd, err := os.Getwd(); err == nil {
+		opts.CurrentDir, _ = git.WorkTreeRoot(wd)
+	}
+	return opts
+}
+
+// detailsUnits returns the configured rendering of uptimes and memory in
+// the details layout
+func detailsUnits() formatter.Units {
+	cfg, err := config.Load()
+	warnConfigIssues(err)
+	if err != nil {
+		return formatter.Units{}
+	}
+	return formatter.UnitsFrom(cfg.Units, "details")
+}
+
+// printDetails lays out a server's facts as aligned key/value lines,
+// leaving out the ones that are unknown
+func printDetails(server types.Server) {
+	ctx := context.Background()
+	row := printDetailRow
+	units := detailsUnits()
+
+	if server.Service != "" {
+		row("service", server.Service)
+	} else {
+		row("repo", server.Repo)
+		row("branch", server.Branch)
+		row("package", server.Package)
+	}
+	process := server.Process
+	if server.Framework != "" {
+		process += " · " + server.Framework
+	}
+	row("process", process)
+	printContainer(ctx, server.Process, server.Port)
+	if dc := server.DevContainer; dc != nil {
+		row("dev container", fmt.Sprintf("%s (host :%d → container :%d)", dc.Name, dc.HostPort, dc.ContainerPort))
+	}
+	row("pid", strconv.Itoa(server.PID))
+	row("port", strconv.Itoa(server.Port))
+	if len(server.AuxPorts) > 0 {
+		row("other ports", joinPorts(server.AuxPorts))
+	}
+	row("bind", server.Bind)
+	if server.Backlog != nil {
+		backlog := server.Backlog.String() + " queued"
+		if server.Backlog.Saturated() {
+			backlog += " (full: new connections hang)"
+		}
+		row("backlog", backlog)
+	}
+	row("status", string(server.Status))
+	row("user", server.User)
+	if server.Workers > 0 {
+		row("workers", strconv.Itoa(server.Workers))
+	}
+
+	row("command", server.CommandLine)
+	row("runtime", server.Runtime)
+	row("directory", server.CWD)
+	if root, ok := git.WorkTreeRoot(server.CWD); ok {
+		row("worktree", root)
+	}
+	row("remote", server.RemoteURL)
+	if server.LastCommit != nil {
+		row("last commit", server.LastCommit.Local().Format("2006-01-02 15:04"))
+	}
+
+	printStarted(ctx, server.PID, units)
+	row("started by", strings.Join(server.StartedBy, "→"))
+	row("session", server.Session)
+	if all, err := procinfo.AllUsage(ctx); err == nil {
+		usage := procinfo.TreeUsage(all, server.PID)
+		if usage.RSS > 0 {
+			row("memory", units.Bytes(usage.RSS))
+		}
+		row("cpu time", units.Duration(usage.CPU))
+	}
+	checker := health.NewChecker(1, detailsHealthTimeout)
+	row("health", checker.Check(ctx, server).String())
+
+	row("url", server.URL())
+	if server.DisplayURL() != server.URL() {
+		row("display url", server.DisplayURL())
+	}
+	for _, link := range server.Links() {
+		row("link", link)
+	}
+	row("lan url", server.LANURL)
+
+	if env := server.Env; env != nil {
+		row("env", fmt.Sprintf("%s (%s)", devshell.String(env), env.File))
+		for _, name := range slices.Sorted(maps.Keys(env.Vars)) {
+			row("  "+name, env.Vars[name])
+		}
+	}
+}
+
+// printDetailRow prints one aligned fact of the details view, leaving out
+// unknown values
+func printDetailRow(label, value string) {
+	if value != "" {
+		fmt.Printf("%-14s %s\n", label, value)
+	}
+}
+
+// printStarted prints when a process started and its uptime
+func printStarted(ctx context.Context, pid int, units formatter.Units) {
+	if started, err := procinfo.StartTime(ctx, pid); err == nil {
+		uptime := units.Duration(time.Since(started))
+		printDetailRow("started", fmt.Sprintf("%s (up %s)", started.Local().Format("2006-01-02 15:04:05"), uptime))
+	}
+}
+
+// printContainer names the container behind a port whose listener is a
+// container runtime's port forwarder
+func printContainer(ctx context.Context, process string, port int) {
+	if !container.IsForwarder(process) {
+		return
+	}
+	if c, ok := container.Publishing(ctx, port); ok {
+		printDetailRow("container", fmt.Sprintf("%s (%s, %s %s, %s)", c.Name, c.Image, c.Runtime, c.ID, c.Status))
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/icons"
+	"github.com/bshakr/lsrv/internal/ignore"
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// checkState is the outcome of one doctor check
+type checkState int
+
+const (
+	checkOK checkState = iota
+	checkWarn
+	checkFail
+)
+
+// checkResult is one line of the doctor report, with a fix for anything
+// that isn't OK
+type checkResult struct {
+	state  checkState
+	name   string
+	detail string
+	fix    string
+}
+
+// runDoctor checks the environment lsrv depends on and prints what to fix
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	asciiFlag := fs.Bool("ascii", false, "Mark results with [ok], [!] and [x] instead of symbols")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv doctor [--ascii]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Check lsrv's dependencies, permissions and configuration, and suggest fixes")
+		fs.PrintDefaults()
+	}
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	cfg, cfgResult := checkConfig()
+	results := []checkResult{
+		checkLsof(ctx),
+		checkGit(ctx),
+	}
+	if runtime.GOOS == "linux" {
+		results = append(results, checkProc())
+	}
+	results = append(results,
+		checkPermissions(),
+		cfgResult,
+		checkRepoConfig(),
+		checkIgnoreFile(),
+		checkStateDir(),
+		checkIcons(cfg),
+	)
+
+	symbols := map[checkState]string{checkOK: "✓", checkWarn: "!", checkFail: "✗"}
+	if *asciiFlag || cfg.ASCII {
+		symbols = map[checkState]string{checkOK: "[ok]", checkWarn: "[!]", checkFail: "[x]"}
+	}
+
+	failed := 0
+	for _, result := range results {
+		fmt.Printf("%s %s: %s\n", symbols[result.state], result.name, result.detail)
+		if result.fix != "" {
+			for _, line := range strings.Split(result.fix, "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+		if result.state == checkFail {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d problem(s) found\n", failed)
+		return 1
+	}
+	return 0
+}
+
+// checkLsof looks for lsof, which every listing depends on
+func checkLsof(ctx context.Context) checkResult {
+	path, err := exec.LookPath("lsof")
+	if err != nil {
+		fix := "Install lsof: sudo apt-get install lsof (Debian/Ubuntu) or sudo yum install lsof (RHEL/CentOS)"
+		if platform.IsMacOS() {
+			fix = "lsof ships with macOS; reinstall the Command Line Tools with: xcode-select --install"
+		}
+		return checkResult{checkFail, "lsof", "not found in PATH", fix}
+	}
+
+	// lsof -v prints "revision: 4.95.0" on stderr and exits non-zero
+	output, _ := exec.CommandContext(ctx, "lsof", "-v").CombinedOutput()
+	version := "unknown version"
+	for _, line := range strings.Split(string(output), "\n") {
+		if rev, ok := strings.CutPrefix(strings.TrimSpace(line), "revision:"); ok {
+			version = strings.TrimSpace(rev)
+			break
+		}
+	}
+	return checkResult{checkOK, "lsof", fmt.Sprintf("%s (%s)", version, path), ""}
+}
+
+// checkGit looks for git, which lsrv still needs for --last-commit and
+// repositories its .git file reader can't handle
+func checkGit(ctx context.Context) checkResult {
+	if _, err := exec.LookPath("git"); err != nil {
+		return checkResult{checkWarn, "git", "not found in PATH",
+			"Repo and branch names are read from .git files; install git for --last-commit, bare repos and configs using include"}
+	}
+	output, err := exec.CommandContext(ctx, "git", "--version").Output()
+	if err != nil {
+		return checkResult{checkWarn, "git", fmt.Sprintf("git --version failed: %v", err), "Check that git runs from this shell"}
+	}
+	return checkResult{checkOK, "git", strings.TrimPrefix(strings.TrimSpace(string(output)), "git version "), ""}
+}
+
+// checkProc verifies the /proc files Linux detection reads
+func checkProc() checkResult {
+	if _, err := os.ReadFile("/proc/net/tcp"); err != nil {
+		return checkResult{checkFail, "/proc", fmt.Sprintf("cannot read /proc/net/tcp: %v", err),
+			"lsrv reads listening sockets from /proc; in a container, run it with the host's /proc mounted"}
+	}
+	if _, err := os.Readlink("/proc/self/cwd"); err != nil {
+		return checkResult{checkFail, "/proc", fmt.Sprintf("cannot read process directories: %v", err),
+			"lsrv reads /proc/<pid>/cwd to find each server's repo; check that /proc is mounted"}
+	}
+
+	// hidepid hides other users' processes even from lsof
+	mounts, _ := os.ReadFile("/proc/mounts")
+	for _, line := range strings.Split(string(mounts), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 4 && fields[1] == "/proc" && strings.Contains(fields[3], "hidepid=") {
+			return checkResult{checkWarn, "/proc", "mounted with hidepid, other users' servers are invisible",
+				"Use --sudo to include them"}
+		}
+	}
+	return checkResult{checkOK, "/proc", "readable", ""}
+}
+
+// checkPermissions counts listeners whose process lsof could not inspect
+func checkPermissions() checkResult {
+	if platform.IsRoot() {
+		return checkResult{checkOK, "permissions", "running as root, all servers are visible", ""}
+	}
+
+	report := &detector.Report{}
+	if _, err := findServers(detector.Options{NoGit: true, AllUsers: true, Report: report}, 5*time.Second); err != nil {
+		return checkResult{checkWarn, "permissions", fmt.Sprintf("detection failed: %v", err),
+			"Run lsrv --timings to see which phase fails"}
+	}
+	if report.PermissionDenied > 0 {
+		fix := "Run lsrv --sudo to include them"
+		if _, err := exec.LookPath("sudo"); err != nil {
+			fix = "Run lsrv as root to include them (sudo is not installed)"
+		}
+		return checkResult{checkWarn, "permissions",
+			fmt.Sprintf("%d listener(s) belong to processes you can't inspect", report.PermissionDenied), fix}
+	}
+	return checkResult{checkOK, "permissions", "all listeners are visible to you", ""}
+}
+
+// checkConfig validates the global config file, returning it for the
+// checks that depend on it
+func checkConfig() (*config.Config, checkResult) {
+	path, err := config.Path()
+	if err != nil {
+		return &config.Config{}, checkResult{checkWarn, "config", err.Error(), "Set HOME or XDG_CONFIG_HOME"}
+	}
+
+	issues, err := config.Check(path)
+	if err != nil {
+		return &config.Config{}, checkResult{checkFail, "config", err.Error(), "Make " + path + " readable"}
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	if len(issues) > 0 {
+		return cfg, checkResult{checkFail, "config", configIssues(issues), "Run \"lsrv config check\" and fix " + path}
+	}
+	if !platform.FileExists(path) {
+		return cfg, checkResult{checkOK, "config", fmt.Sprintf("none (%s), using defaults", path), ""}
+	}
+	return cfg, checkResult{checkOK, "config", path, ""}
+}
+
+// checkRepoConfig validates the .lsrv.yml for the current directory, if any
+func checkRepoConfig() checkResult {
+	wd, err := os.Getwd()
+	if err != nil {
+		return checkResult{checkOK, config.RepoFileName, "no working directory", ""}
+	}
+	_, dir, _ := config.FindRepo(wd)
+	path := filepath.Join(dir, config.RepoFileName)
+	if !platform.FileExists(path) {
+		return checkResult{checkOK, config.RepoFileName, "none for this directory", ""}
+	}
+	issues, err := config.Check(path)
+	if err != nil {
+		return checkResult{checkFail, config.RepoFileName, err.Error(), "Make " + path + " readable"}
+	}
+	if len(issues) > 0 {
+		return checkResult{checkFail, config.RepoFileName, configIssues(issues), "Run \"lsrv config check\" and fix " + path}
+	}
+	return checkResult{checkOK, config.RepoFileName, path, ""}
+}
+
+// configIssues summarizes config issues by the first, which carries the
+// file and line
+func configIssues(issues []config.Issue) string {
+	if len(issues) == 1 {
+		return issues[0].String()
+	}
+	return fmt.Sprintf("%s (and %d more)", issues[0], len(issues)-1)
+}
+
+// checkIgnoreFile validates the global ignore file, if any
+func checkIgnoreFile() checkResult {
+	path, err := ignore.Path()
+	if err != nil {
+		return checkResult{checkWarn, "ignore file", err.Error(), "Set HOME or XDG_CONFIG_HOME"}
+	}
+	if _, err := ignore.Load(); err != nil {
+		return checkResult{checkFail, "ignore file", err.Error(), "Fix the pattern in " + path}
+	}
+	if !platform.FileExists(path) {
+		return checkResult{checkOK, "ignore file", "none", ""}
+	}
+	return checkResult{checkOK, "ignore file", path, ""}
+}
+
+// checkStateDir makes sure logs and snapshots can be written
+func checkStateDir() checkResult {
+	dir, err := platform.StateDir()
+	if err != nil {
+		return checkResult{checkWarn, "state directory", err.Error(), "Set HOME or XDG_STATE_HOME"}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return checkResult{checkWarn, "state directory", err.Error(),
+			"lsrv start and lsrv snapshot need a writable " + dir}
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return checkResult{checkWarn, "state directory", err.Error(),
+			"lsrv start and lsrv snapshot need a writable " + dir}
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return checkResult{checkOK, "state directory", dir + " is writable", ""}
+}
+
+// checkIcons shows Nerd Font glyphs for the user to eyeball, as no
+// terminal reports which fonts it renders with
+func checkIcons(cfg *config.Config) checkResult {
+	if cfg.ASCII {
+		return checkResult{checkOK, "icons", "ascii: true, Nerd Font glyphs are not used", ""}
+	}
+	set := icons.NewSet(cfg, false)
+	sample := strings.Join([]string{
+		set.Icon("ruby", types.ProjectTypeUnknown, ""),
+		set.Icon("go", types.ProjectTypeUnknown, ""),
+		set.Icon("java", types.ProjectTypeUnknown, ""),
+		set.Icon("cargo", types.ProjectTypeUnknown, ""),
+	}, " ")
+	return checkResult{checkOK, "icons", "ruby, go, java and rust should look like " + sample,
+		"If they show as boxes or question marks, install a Nerd Font (https://www.nerdfonts.com)\nor use --ascii / set ascii: true in the config"}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/atomicfile"
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/environment"
+	"github.com/bshakr/lsrv/internal/git"
+)
+
+// runExport writes the running servers as a portable JSON file that
+// "lsrv import" can start elsewhere
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	outputFlag := fs.String("output", "", "Write to FILE instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv export [--output=FILE] [DIR]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Describe the running servers (repo, branch, command, port) as JSON for \"lsrv import\".")
+		fmt.Fprintln(os.Stderr, "With DIR, only servers in that repo and its worktrees are exported.")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	if !commandExists("lsof") {
+		printLsofError()
+		return 1
+	}
+
+	opts := detector.Options{RepoNames: configuredRepoNames()}
+	if len(positional) == 1 {
+		if opts.Dirs, err = git.Worktrees(context.Background(), positional[0]); err != nil {
+			return exitWithError("%v", err)
+		}
+	}
+	servers, err := findServers(opts, defaultTimeout)
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	env, err := environment.Export(context.Background(), servers)
+	if err != nil {
+		return exitWithError("%v", err)
+	}
+
+	if *outputFlag == "" {
+		if err := env.Write(os.Stdout); err != nil {
+			return exitWithError("writing output: %v", err)
+		}
+		return 0
+	}
+
+	var buf bytes.Buffer
+	if err := env.Write(&buf); err != nil {
+		return exitWithError("writing output: %v", err)
+	}
+	if err := atomicfile.WriteFile(*outputFlag, buf.Bytes(), 0o644); err != nil {
+		return exitWithError("writing output: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d server(s) to %s\n", len(env.Servers), *outputFlag)
+	return 0
+}
+
+// runImport starts the servers described by an exported file from the
+// matching local checkouts
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	rootFlag := fs.String("root", ".", "Directory holding your checkouts (searched along with its subdirectories)")
+	dryRunFlag := fs.Bool("dry-run", false, "Show what would be started without starting anything")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv import [--root=DIR] [--dry-run] FILE")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Start the servers from an \"lsrv export\" file (- for stdin) in your own checkouts,")
+		fmt.Fprintln(os.Stderr, "found by repo name in DIR or its subdirectories. Servers whose port is taken are skipped.")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	if positional[0] != "-" {
+		f, err := os.Open(positional[0])
+		if err != nil {
+			return exitWithError("%v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	env, err := environment.Read(in)
+	if err != nil {
+		return exitWithError("%v", err)
+	}
+
+	root, err := filepath.Abs(*rootFlag)
+	if err != nil {
+		return exitWithError("resolving directory: %v", err)
+	}
+	checkouts := environment.FindCheckouts(context.Background(), root, configuredRepoNames())
+
+	failed := 0
+	for _, server := range env.Servers {
+		label := fmt.Sprintf("%s :%d", server.Repo, server.Port)
+
+		checkout, ok := checkouts[server.Repo]
+		if !ok {
+			hint := ""
+			if server.Remote != "" {
+				hint = fmt.Sprintf(", clone %s into %s", server.Remote, root)
+			}
+			fmt.Printf("✗ %s: no checkout found%s\n", label, hint)
+			failed++
+			continue
+		}
+		dir := filepath.Join(checkout, filepath.FromSlash(server.Dir))
+
+		if portInUse(server.Port) {
+			fmt.Printf("- %s: port already in use, skipped\n", label)
+			continue
+		}
+
+		var note string
+		if branch := git.GetBranch(context.Background(), checkout); server.Branch != "" && branch != server.Branch {
+			note = fmt.Sprintf(" (on %s, exported from %s)", branch, server.Branch)
+		}
+
+		if *dryRunFlag || control.SafeMode {
+			fmt.Printf("  %s: would run %s in %s%s\n", label, strings.Join(server.Command, " "), dir, note)
+			continue
+		}
+
+		run, err := control.Start(dir, server.Repo, server.Command)
+		if err != nil {
+			fmt.Printf("✗ %s: %v\n", label, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✓ %s: started %s (pid %d)%s\n", label, strings.Join(server.Command, " "), run.PID, note)
+	}
+
+	if failed > 0 {
+		return 1
+	}
+	if control.SafeMode && !*dryRunFlag {
+		return exitWithError("%v", control.ErrSafeMode)
+	}
+	return 0
+}
+
+// portProbeTimeout bounds each dial of portInUse; with nothing listening,
+// loopback connections are refused at once
+const portProbeTimeout = 250 * time.Millisecond
+
+// portInUse reports whether something already listens on port. It dials
+// the loopback addresses rather than binding the port, which succeeds on
+// macOS for the wildcard address even while a server holds 127.0.0.1.
+func portInUse(port int) bool {
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), portProbeTimeout)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/bshakr/lsrv/internal/control"
+)
+
+// runExec runs an arbitrary command in a server's working directory, so
+// "lsrv exec api -- git pull" works without finding the worktree first
+func runExec(args []string) int {
+	fs := flag.NewFlagSet("exec", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv exec <repo|port> -- <command> [args...]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Run a command in the server's working directory, with LSRV_PORT, LSRV_PID")
+		fmt.Fprintln(os.Stderr, "and LSRV_URL set")
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) < 2 {
+		fs.Usage()
+		return 2
+	}
+
+	servers, err := detectServers()
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	server, err := matchDir(servers, positional[0])
+	if err != nil {
+		return exitWithError("%v", err)
+	}
+	if server.CWD == "" {
+		return exitWithError("can't determine the working directory of %s (pid %d)", server.Repo, server.PID)
+	}
+
+	cmd := control.ExecCommand(server, positional[1:])
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		// Pass the command's own exit status through
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return exitWithError("running %s: %v", positional[1], err)
+	}
+	return 0
+}
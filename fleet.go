@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/fleet"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// startFleetScan lists the servers on the configured hosts in the
+// background, so SSH round trips overlap with local detection
+func startFleetScan(hosts []config.Host, args []string, timeout time.Duration) <-chan []fleet.Result {
+	results := make(chan []fleet.Result, 1)
+	go func() {
+		ctx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout+fleet.ConnectTimeout)
+			defer cancel()
+		}
+		results <- fleet.Scan(ctx, hosts, args)
+	}()
+	return results
+}
+
+// mergeFleet appends the remote servers to the local ones, which are named
+// after this machine, and reports the hosts that couldn't be listed
+func mergeFleet(local []types.Server, results []fleet.Result) ([]types.Server, []detector.Warning) {
+	servers := make([]types.Server, 0, len(local))
+	for _, server := range local {
+		server.Host = fleet.LocalName
+		servers = append(servers, server)
+	}
+
+	var failed []string
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result.Host.Name+": "+result.Err.Error())
+			continue
+		}
+		servers = append(servers, result.Servers...)
+	}
+
+	if len(failed) == 0 {
+		return servers, nil
+	}
+	return servers, []detector.Warning{{Code: detector.WarnHostFailed, Count: len(failed), Details: failed}}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/depgraph"
+	"github.com/bshakr/lsrv/internal/formatter"
+)
+
+// runGraph prints which servers are connected to which, such as an app to
+// its database, as a tree or a Graphviz digraph
+func runGraph(args []string) int {
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	formatFlag := fs.String("format", "ascii", "Output format: ascii or dot")
+	asciiFlag := fs.Bool("ascii", false, "Draw the tree with plain ASCII characters")
+	timeoutFlag := fs.Duration("timeout", defaultTimeout, "Give up on detection after this long (0 for no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv graph [--format=ascii|dot] [--timeout=DURATION]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Show which servers and services are connected to each other over local")
+		fmt.Fprintln(os.Stderr, "TCP connections, e.g. \"lsrv graph --format=dot | dot -Tsvg > deps.svg\"")
+		fs.PrintDefaults()
+	}
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return 2
+	}
+	if *formatFlag != "ascii" && *formatFlag != "dot" {
+		return exitWithError("invalid format %q (want ascii or dot)", *formatFlag)
+	}
+
+	if !commandExists("lsof") {
+		printLsofError()
+		return 1
+	}
+
+	// Databases and caches are what most apps connect to
+	opts := configuredOptions()
+	opts.Services = true
+	servers, err := findServers(opts, *timeoutFlag)
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	ctx := context.Background()
+	if *timeoutFlag > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeoutFlag)
+		defer cancel()
+	}
+	edges, err := depgraph.Build(ctx, servers)
+	if err != nil {
+		return exitWithError("reading connections: %v", err)
+	}
+
+	if *formatFlag == "dot" {
+		err = formatter.WriteDOT(os.Stdout, edges)
+	} else {
+		if len(edges) == 0 {
+			fmt.Println("No connections between servers found.")
+			return 0
+		}
+		cfg, loadErr := config.Load()
+		warnConfigIssues(loadErr)
+		err = formatter.WriteGraph(os.Stdout, edges, *asciiFlag || (cfg != nil && cfg.ASCII))
+	}
+	if err != nil {
+		return exitWithError("writing output: %v", err)
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/reservation"
+)
+
+// runGuard checks that a port is free, or held by the expected repo and
+// branch, before a bin/dev script launches a server on it. It fails when
+// another checkout holds the port, which would otherwise go unnoticed
+// while the wrong branch is served.
+func runGuard(args []string) int {
+	fs := flag.NewFlagSet("guard", flag.ContinueOnError)
+	portFlag := fs.Int("port", 0, "Port the server is about to listen on")
+	repoFlag := fs.String("repo", "", "Repo allowed to hold the port (default: the current repository)")
+	branchFlag := fs.String("branch", "", "Branch allowed to hold the port (default: the current branch)")
+	timeoutFlag := fs.Duration("timeout", defaultTimeout, "Give up on detection after this long (0 for no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv guard --port=PORT [--repo=REPO] [--branch=BRANCH]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Exit with an error when PORT is held by another repo or branch than the")
+		fmt.Fprintln(os.Stderr, "current one, e.g. in bin/dev: lsrv guard --port=3000 && bin/rails server")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 0 || *portFlag <= 0 || *portFlag > 65535 {
+		fs.Usage()
+		return 2
+	}
+	port := *portFlag
+
+	// Default to the checkout being launched; a branch only applies to the
+	// current repo unless given explicitly
+	repo, branch := *repoFlag, *branchFlag
+	if wd, err := os.Getwd(); err == nil {
+		if root, ok := git.WorkTreeRoot(wd); ok {
+			ctx := context.Background()
+			current := git.GetRepoName(ctx, root, configuredRepoNames())
+			if repo == "" {
+				repo = current
+			}
+			if branch == "" && strings.EqualFold(repo, current) {
+				branch = git.GetBranch(ctx, root)
+			}
+		}
+	}
+	if repo == "" {
+		return exitWithError("not in a git repository; pass --repo")
+	}
+
+	// A reservation for another repo fails the launch even while the port
+	// is free
+	if registry, err := reservation.Load(); err == nil {
+		if owner, ok := registry.Owner(port); ok && !strings.EqualFold(owner, repo) {
+			return exitWithError("port %d is reserved for %s, not %s (see \"lsrv reserve\")", port, owner, repo)
+		}
+	}
+
+	if !commandExists("lsof") {
+		printLsofError()
+		return 1
+	}
+	opts := configuredOptions()
+	opts.Services = true
+	servers, err := findServers(opts, *timeoutFlag)
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	for _, holder := range control.Match(servers, strconv.Itoa(port)) {
+		if holder.Service != "" {
+			return exitWithError("port %d is held by %s (pid %d), not %s", port, holder.Service, holder.PID, repo)
+		}
+		if !strings.EqualFold(holder.Repo, repo) {
+			return exitWithError("port %d is held by %s (%s), pid %d in %s, not %s; stop it with \"lsrv kill %d\"", port, holder.Repo, holder.Branch, holder.PID, holder.CWD, repo, port)
+		}
+		if branch != "" && holder.Branch != branch {
+			return exitWithError("port %d is held by %s on branch %s, pid %d in %s, not %s; stop it with \"lsrv kill %d\"", port, holder.Repo, holder.Branch, holder.PID, holder.CWD, branch, port)
+		}
+		fmt.Fprintf(os.Stderr, "port %d is already held by %s (%s), pid %d\n", port, holder.Repo, holder.Branch, holder.PID)
+		return 0
+	}
+
+	// Listeners lsrv doesn't list, such as another user's, an ignored
+	// directory's or one outside any repository, still make the launch
+	// fail. Binding the wildcard address succeeds on macOS while a server
+	// holds 127.0.0.1, so the loopback addresses are dialed too.
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err == nil {
+		listener.Close()
+	}
+	if err != nil || portInUse(port) {
+		return exitWithError("port %d is in use by a process lsrv doesn't list (see \"lsof -i :%d\")", port, port)
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/formatter"
+	"github.com/bshakr/lsrv/internal/tui"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// defaultInteractiveInterval is how often interactive mode re-detects
+// servers when --watch doesn't set an interval
+const defaultInteractiveInterval = 2 * time.Second
+
+// runInteractive shows the live, navigable server table. Like watch mode it
+// uses a Tracker so each refresh only resolves newly started processes.
+func runInteractive(interval, timeout time.Duration, detectOpts detector.Options, opts formatter.Options) int {
+	if interval <= 0 {
+		interval = defaultInteractiveInterval
+	}
+
+	tracker := &detector.Tracker{}
+	err := tui.Run(tui.Config{
+		Find:     func() ([]types.Server, error) { return refreshServers(tracker, detectOpts, timeout) },
+		Interval: interval,
+		Format:   opts,
+	})
+	if err != nil {
+		return exitWithError("interactive mode: %v", err)
+	}
+	return 0
+}
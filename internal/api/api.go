@@ -0,0 +1,246 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/health"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// FindFunc returns the currently running servers
+type FindFunc func() ([]types.Server, error)
+
+// Options configures access to the API from web pages
+type Options struct {
+	// AllowOrigins are the origins whose pages may call the API, such as a
+	// browser extension's "chrome-extension://ID"; "*" allows any. Without
+	// them browsers keep pages from reading responses.
+	AllowOrigins []string
+
+	// Token, when set, must come with every request, either as an
+	// "Authorization: Bearer" header or, for images, a token parameter
+	Token string
+
+	// AnyHost accepts requests addressed to any host name rather than only
+	// localhost, for an API served to other machines; the token still
+	// applies
+	AnyHost bool
+
+	// Cached, when set, answers "GET /servers?cached=1" from the latest
+	// background scan, for callers like shell prompts that can't wait for
+	// a fresh one
+	Cached FindFunc
+
+	// Health, when set, serves "GET /health" with the cached health of
+	// every server
+	Health func() []health.Status
+}
+
+// NewHandler returns the HTTP API:
+//
+//	GET    /servers         list all servers (?cached=1 for the latest scan)
+//	GET    /servers/{port}  servers listening on port
+//	DELETE /servers/{port}  kill the servers listening on port
+//	GET    /badge/{port}    SVG badge with what runs on port
+//	GET    /health          cached health of all servers, with Options.Health
+//	GET    /health/{port}   cached health of the servers on port
+func NewHandler(find FindFunc, opts Options) http.Handler {
+	h := &handler{find: find, cached: opts.Cached, health: opts.Health}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /servers", h.listServers)
+	mux.HandleFunc("GET /servers/{port}", h.getServers)
+	mux.HandleFunc("DELETE /servers/{port}", h.killServers)
+	mux.HandleFunc("GET /badge/{port}", h.badge)
+	if h.health != nil {
+		mux.HandleFunc("GET /health", h.listHealth)
+		mux.HandleFunc("GET /health/{port}", h.getHealth)
+	}
+	return withAccess(mux, opts)
+}
+
+type handler struct {
+	find   FindFunc
+	cached FindFunc
+	health func() []health.Status
+}
+
+// withAccess rejects requests addressed to other hosts, answers CORS
+// preflights for allowed origins and rejects requests without the token
+func withAccess(next http.Handler, opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A page whose domain was rebound to 127.0.0.1 still sends its own
+		// name as Host
+		if !opts.AnyHost && !LocalHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not localhost", r.Host))
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" && originAllowed(opts.AllowOrigins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization")
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if opts.Token != "" && !HasToken(r, opts.Token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong API token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// originAllowed reports whether origin is one of allowed, or allowed
+// contains "*"
+func originAllowed(allowed []string, origin string) bool {
+	for _, candidate := range allowed {
+		if candidate == "*" || strings.EqualFold(strings.TrimSuffix(candidate, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalHost reports whether a request's Host names this machine's
+// loopback interface: localhost, 127.0.0.1 or [::1], with any port
+func LocalHost(host string) bool {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	return strings.EqualFold(host, "localhost") || host == "127.0.0.1" || host == "::1"
+}
+
+// HasToken checks the request's bearer token or token parameter in
+// constant time
+func HasToken(r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		given = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// errorResponse is the body returned for failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (h *handler) listServers(w http.ResponseWriter, r *http.Request) {
+	find := h.find
+	if h.cached != nil && r.URL.Query().Get("cached") == "1" {
+		find = h.cached
+	}
+	servers, err := find()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(servers))
+}
+
+func (h *handler) getServers(w http.ResponseWriter, r *http.Request) {
+	servers, status, err := h.serversOnPort(r)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, servers)
+}
+
+func (h *handler) killServers(w http.ResponseWriter, r *http.Request) {
+	servers, status, err := h.serversOnPort(r)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+
+	for _, server := range servers {
+		if err := control.Kill(server); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, control.ErrSafeMode) {
+				status = http.StatusForbidden
+			}
+			writeError(w, status, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, servers)
+}
+
+func (h *handler) listHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.health())
+}
+
+func (h *handler) getHealth(w http.ResponseWriter, r *http.Request) {
+	port, err := strconv.Atoi(r.PathValue("port"))
+	if err != nil || port <= 0 || port > 65535 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid port %q", r.PathValue("port")))
+		return
+	}
+
+	var matched []health.Status
+	for _, status := range h.health() {
+		if status.Port == port {
+			matched = append(matched, status)
+		}
+	}
+	if len(matched) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no server on port %d", port))
+		return
+	}
+	writeJSON(w, http.StatusOK, matched)
+}
+
+// serversOnPort resolves the {port} path value to the servers listening on
+// it, returning the HTTP status to use on failure
+func (h *handler) serversOnPort(r *http.Request) ([]types.Server, int, error) {
+	port, err := strconv.Atoi(r.PathValue("port"))
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid port %q", r.PathValue("port"))
+	}
+
+	servers, err := h.find()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	matched := control.Match(servers, strconv.Itoa(port))
+	if len(matched) == 0 {
+		return nil, http.StatusNotFound, fmt.Errorf("no server on port %d", port)
+	}
+	return matched, http.StatusOK, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// nonNil makes empty results encode as [] rather than null
+func nonNil(servers []types.Server) []types.Server {
+	if servers == nil {
+		return []types.Server{}
+	}
+	return servers
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// Badge colors, matching the usual shields.io palette
+const (
+	badgeUp      = "#4c1"
+	badgeWarning = "#dfb317"
+	badgeStale   = "#fe7d37"
+	badgeDown    = "#9f9f9f"
+	badgeLabel   = "#555"
+)
+
+// badgeCharWidth approximates the width of a character in the badge's
+// 11px sans-serif font; badges only need to look right, not measure exactly
+const badgeCharWidth = 7
+
+// badge draws a status badge for the {port} path value: the repo and branch
+// of the server on it, or "down". Nothing running is still a 200 so the
+// image shows rather than breaking.
+func (h *handler) badge(w http.ResponseWriter, r *http.Request) {
+	port, err := strconv.Atoi(r.PathValue("port"))
+	if err != nil || port <= 0 || port > 65535 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid port %q", r.PathValue("port")))
+		return
+	}
+
+	servers, err := h.find()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	message, color := "down", badgeDown
+	if matched := control.Match(servers, strconv.Itoa(port)); len(matched) > 0 {
+		message, color = badgeMessage(matched[0]), badgeColor(matched[0].Status)
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprint(w, renderBadge(strconv.Itoa(port), message, color))
+}
+
+// badgeMessage names what runs on the port
+func badgeMessage(server types.Server) string {
+	if server.Service != "" {
+		return server.Service
+	}
+	if server.Branch == "" || server.Branch == "-" {
+		return server.Repo
+	}
+	return server.Repo + " · " + server.Branch
+}
+
+// badgeColor is green for healthy servers, orange for stale or zombie ones
+// and yellow for other warnings
+func badgeColor(status types.Status) string {
+	switch status {
+	case "", types.StatusHealthy:
+		return badgeUp
+	case types.StatusStale, types.StatusZombie:
+		return badgeStale
+	}
+	return badgeWarning
+}
+
+// renderBadge draws a two-part flat badge with label on the left
+func renderBadge(label, message, color string) string {
+	labelWidth := utf8.RuneCountInString(label)*badgeCharWidth + 10
+	messageWidth := utf8.RuneCountInString(message)*badgeCharWidth + 10
+	width := labelWidth + messageWidth
+	label, message = html.EscapeString(label), html.EscapeString(message)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">`+
+		`<title>%[4]s: %[5]s</title>`+
+		`<rect width="%[2]d" height="20" fill="%[7]s"/>`+
+		`<rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[8]d" y="14">%[4]s</text>`+
+		`<text x="%[9]d" y="14">%[5]s</text>`+
+		`</g></svg>`,
+		width, labelWidth, messageWidth, label, message, color, badgeLabel, labelWidth/2, labelWidth+messageWidth/2)
+}
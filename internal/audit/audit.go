@@ -0,0 +1,110 @@
+// Package audit appends a record of every server lsrv stops or starts to
+// a log that is only ever added to
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/platform"
+)
+
+// Entry is one action in the audit log, written as a line of JSON
+type Entry struct {
+	Time time.Time `json:"time"`
+
+	// Source is the lsrv entry point that acted, e.g. "kill", "clean",
+	// "serve" or "mcp"
+	Source string `json:"source"`
+
+	// User is the account lsrv ran as
+	User string `json:"user"`
+
+	// Action is "kill", "restart" or "start"
+	Action  string   `json:"action"`
+	Repo    string   `json:"repo,omitempty"`
+	Port    int      `json:"port,omitempty"`
+	PID     int      `json:"pid,omitempty"`
+	Dir     string   `json:"dir,omitempty"`
+	Command []string `json:"command,omitempty"`
+
+	// Error is why the action failed; empty when it succeeded
+	Error string `json:"error,omitempty"`
+}
+
+// Path returns the location of the audit log
+func Path() (string, error) {
+	state, err := platform.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "audit.log"), nil
+}
+
+// Record appends entry to the audit log, filling in the time and user
+func Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.User == "" {
+		if current, err := user.Current(); err == nil {
+			entry.User = current.Username
+		}
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// O_APPEND keeps concurrent writers from overwriting each other's lines
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return f.Close()
+}
+
+// Read returns the entries of the audit log, oldest first. Lines that
+// aren't entries, such as one cut short by a crash, are skipped.
+func Read() ([]Entry, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var entry Entry
+		if len(line) > 0 && json.Unmarshal(line, &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,76 @@
+// Package capability probes what lsrv's privileges let it inspect, so
+// detection skips reads bound to fail and --debug can tell what a listing
+// leaves out
+package capability
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bshakr/lsrv/internal/platform"
+)
+
+// Set is what the current privileges allow
+type Set struct {
+	// Root is set when lsrv runs as root, which allows everything
+	Root bool
+
+	// OtherProcesses reports whether the working directories, command
+	// lines and environments of other users' processes can be read
+	OtherProcesses bool
+
+	// AllSockets reports whether lsof sees which process holds each of
+	// other users' sockets
+	AllSockets bool
+
+	// processReason tells why other users' processes can't be read
+	processReason string
+}
+
+// Current returns the privileges lsrv runs with, probed on first use
+var Current = sync.OnceValue(Probe)
+
+// Probe checks what the current privileges allow. Each supported OS
+// provides probeProcesses in a build-tagged capability_<goos>.go file.
+func Probe() Set {
+	if platform.IsRoot() {
+		return Set{Root: true, OtherProcesses: true, AllSockets: true}
+	}
+	// lsof only sees the processes it can inspect, so other users'
+	// sockets show up without an owner
+	var set Set
+	set.OtherProcesses, set.processReason = probeProcesses()
+	return set
+}
+
+// Inspectable reports whether the details of a process owned by uid can
+// be read
+func (s Set) Inspectable(uid int) bool {
+	return s.OtherProcesses || uid == os.Geteuid()
+}
+
+// Print writes what the privileges allow and, for what they don't, the
+// detection phases that run on the invoking user's servers only
+func (s Set) Print(w io.Writer, phases []string) {
+	if s.Root {
+		fmt.Fprintln(w, "privileges: root, nothing limited")
+		return
+	}
+	fmt.Fprintf(w, "privileges: uid %d, not root\n", os.Geteuid())
+	if s.OtherProcesses {
+		fmt.Fprintln(w, "  other users' processes: readable")
+	} else {
+		fmt.Fprintf(w, "  other users' processes: unreadable (%s)\n", s.processReason)
+		if len(phases) > 0 {
+			fmt.Fprintf(w, "    only for your servers: %s\n", strings.Join(phases, ", "))
+		}
+	}
+	if s.AllSockets {
+		fmt.Fprintln(w, "  other users' sockets: visible to lsof")
+	} else {
+		fmt.Fprintln(w, "  other users' sockets: hidden from lsof, counted as permission denied (add --sudo)")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build darwin
+
+package capability
+
+// probeProcesses reports other users' processes unreadable, as macOS only
+// lets root read their working directories and arguments
+func probeProcesses() (bool, string) {
+	return false, "macOS only lets root read them"
+}
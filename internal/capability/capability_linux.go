@@ -0,0 +1,73 @@
+//go:build linux
+
+package capability
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// probeProcesses tries to read the working directory of a process owned by
+// another user. With none running there is nothing to miss.
+func probeProcesses() (bool, string) {
+	if option := hidepid(); option != "" {
+		return false, "/proc is mounted with " + option
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return false, "/proc can't be read"
+	}
+	euid := os.Geteuid()
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok || int(stat.Uid) == euid {
+			continue
+		}
+		_, err = os.Readlink(filepath.Join("/proc", entry.Name(), "cwd"))
+		if errors.Is(err, fs.ErrPermission) {
+			return false, "reading them needs root"
+		}
+		if err == nil {
+			return true, ""
+		}
+	}
+	return true, ""
+}
+
+// hidepid returns the hidepid option /proc is mounted with, such as
+// "hidepid=2", or "" when other users' processes aren't hidden
+func hidepid() string {
+	f, err := os.Open("/proc/mounts")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[1] != "/proc" {
+			continue
+		}
+		for _, option := range strings.Split(fields[3], ",") {
+			if value, ok := strings.CutPrefix(option, "hidepid="); ok && value != "0" && value != "off" {
+				return option
+			}
+		}
+	}
+	return ""
+}
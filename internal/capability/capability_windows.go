@@ -0,0 +1,9 @@
+//go:build windows
+
+package capability
+
+// probeProcesses reports nothing limited, as Windows detection doesn't
+// read process details yet
+func probeProcesses() (bool, string) {
+	return true, ""
+}
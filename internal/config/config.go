@@ -0,0 +1,416 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/platform"
+	"gopkg.in/yaml.v3"
+)
+
+// RepoFileName is the per-repository configuration file
+const RepoFileName = ".lsrv.yml"
+
+// Display holds icon and label overrides. Keys are process names (e.g.,
+// "puma") or languages (e.g., "ruby"); process names take precedence.
+type Display struct {
+	Icons  map[string]string `yaml:"icons"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+// Config is the global configuration in $XDG_CONFIG_HOME/lsrv/config.yml
+type Config struct {
+	// ASCII replaces Nerd Font glyphs with plain text tags like [ruby]
+	ASCII bool `yaml:"ascii"`
+
+	// RepoName lists where repository names come from, in priority order:
+	// "config", "origin", "upstream" or "toplevel"
+	RepoName []string `yaml:"repo_name" enum:"config,origin,upstream,toplevel"`
+
+	// Dedup is the strategy for merging listeners into rows: "key" (repo,
+	// branch, process and port), "pid", "socket" or "none"
+	Dedup string `yaml:"dedup" enum:"key,pid,socket,none"`
+
+	// EnvVars names the variables shown from each server's environment
+	// with --env and in interactive details; URLs are reduced to their host
+	EnvVars []string `yaml:"env_vars"`
+
+	// URLs maps frameworks (e.g., "storybook") or process names to URL
+	// templates with {host} and {port} placeholders, for servers not
+	// reached at http://localhost:PORT
+	URLs map[string]string `yaml:"urls"`
+
+	// Exports maps variable names to the templates "lsrv env" prints them
+	// with, such as APP_URL: "{url}"; a repo's .lsrv.yml adds to them
+	Exports map[string]string `yaml:"exports"`
+
+	// Hosts are other machines "lsrv --fleet" lists servers on over SSH
+	Hosts []Host `yaml:"hosts"`
+
+	// Serve configures access to "lsrv serve" from web pages
+	Serve Serve `yaml:"serve"`
+
+	// MainBranches are the long-lived branches a server rarely should run
+	// on, such as a worktree left on main; see MainBranchNames
+	MainBranches []string `yaml:"main_branches"`
+
+	// HighlightMainBranches marks the branch of servers on MainBranches
+	HighlightMainBranches bool `yaml:"highlight_main_branches"`
+
+	// Branches color and badge the BRANCH cell of servers on matching
+	// branches, so risky ones stand out; the first match wins
+	Branches []BranchStyle `yaml:"branches"`
+
+	// Runtimes teach lsrv languages and process names it doesn't know, or
+	// change how known ones are shown; see Runtime
+	Runtimes []Runtime `yaml:"runtimes"`
+
+	// Units picks how durations and sizes are shown in tables, details and
+	// top; JSON, CSV and OpenMetrics always carry raw numbers
+	Units Units `yaml:"units"`
+
+	// SafeMode makes lsrv read-only: kill, restart, clean, start and import
+	// only show what they would do, and the APIs refuse to kill
+	SafeMode bool `yaml:"safe_mode"`
+
+	Display `yaml:",inline"`
+}
+
+// DefaultMainBranches are the main branches when the config names none
+var DefaultMainBranches = []string{"main", "master", "production"}
+
+// MainBranchNames returns MainBranches or DefaultMainBranches
+func (c *Config) MainBranchNames() []string {
+	if len(c.MainBranches) == 0 {
+		return DefaultMainBranches
+	}
+	return c.MainBranches
+}
+
+// BranchStyle is how servers on the branches matching Pattern are shown
+type BranchStyle struct {
+	// Pattern is a branch name or glob pattern, such as "feature/*"; as in
+	// paths, * doesn't match a slash
+	Pattern string `yaml:"pattern" required:"true"`
+
+	// Color is the BRANCH cell's color, an ANSI number such as "1" or a hex
+	// value such as "#cc342d"
+	Color string `yaml:"color"`
+
+	// Badge is a short tag shown before the branch, such as "PROD"
+	Badge string `yaml:"badge"`
+}
+
+// Match reports whether branch matches the pattern
+func (b BranchStyle) Match(branch string) bool {
+	ok, _ := path.Match(b.Pattern, branch)
+	return ok
+}
+
+// Host is a machine lsrv reaches over SSH
+type Host struct {
+	// Name is shown in the HOST column, such as "ws1"
+	Name string `yaml:"name" required:"true"`
+
+	// SSH is the destination passed to ssh, such as "me@ws1.example.com"
+	// or an alias from ~/.ssh/config
+	SSH string `yaml:"ssh" required:"true"`
+
+	// Command runs lsrv on the host when it isn't on the PATH of
+	// non-interactive shells there (default "lsrv")
+	Command string `yaml:"command"`
+
+	// Address reaches the host's servers in URLs (default: the host name
+	// from SSH)
+	Address string `yaml:"address"`
+}
+
+// URLHost returns the address for URLs of servers on the host
+func (h Host) URLHost() string {
+	if h.Address != "" {
+		return h.Address
+	}
+	dest := h.SSH
+	if _, after, ok := strings.Cut(dest, "@"); ok {
+		dest = after
+	}
+	return dest
+}
+
+// Runtime describes the servers of one language runtime: which processes
+// run it and how they are shown. An entry replaces the built-in runtime of
+// the same name.
+type Runtime struct {
+	// Name is the language, such as "ruby". It is the ASCII tag and the
+	// key for icons overrides.
+	Name string `yaml:"name" required:"true"`
+
+	// Processes are process names or glob patterns, such as "python3*"
+	Processes []string `yaml:"processes"`
+
+	// Projects are project types detected from the server's directory
+	// ("go", "node", "ruby", ...), used when no process name matches
+	Projects []string `yaml:"projects" enum:"go,rust,node,python,ruby,java,php,dotnet,deno,bun,elixir"`
+
+	// Icon is shown before the process; ASCII mode uses [Name] instead
+	// unless Icon is plain text
+	Icon string `yaml:"icon"`
+
+	// Color is the PROCESS column's color, an ANSI number such as "1" or a
+	// hex value such as "#cc342d"
+	Color string `yaml:"color"`
+
+	// Label replaces the names of matching processes, like labels does
+	Label string `yaml:"label"`
+}
+
+// Unit styles for durations and sizes
+const (
+	UnitsHuman   = "human"
+	UnitsSeconds = "seconds"
+	UnitsBytes   = "bytes"
+)
+
+// UnitStyle is how durations and sizes are written
+type UnitStyle struct {
+	// Time is "human" (12ms, 2h5m0s) or "seconds" (0.012, 7500)
+	Time string `yaml:"time" enum:"human,seconds"`
+
+	// Size is "human" (183.4M) or "bytes" (192305152)
+	Size string `yaml:"size" enum:"human,bytes"`
+}
+
+// Units is the default UnitStyle with overrides per view
+type Units struct {
+	UnitStyle `yaml:",inline"`
+
+	// Formats overrides the style for one view: "table", "plain",
+	// "details" or "top", such as raw numbers in plain output for scripts
+	Formats map[string]UnitStyle `yaml:"formats"`
+}
+
+// For returns the style for view, falling back to the defaults for
+// settings the view doesn't override
+func (u Units) For(view string) UnitStyle {
+	style := u.UnitStyle
+	if override, ok := u.Formats[view]; ok {
+		if override.Time != "" {
+			style.Time = override.Time
+		}
+		if override.Size != "" {
+			style.Size = override.Size
+		}
+	}
+	return style
+}
+
+// Serve configures the HTTP API for browser extensions and new-tab pages
+type Serve struct {
+	// CORS lists the origins whose pages may call the API, such as
+	// "chrome-extension://ID", or "*" for any
+	CORS []string `yaml:"cors"`
+
+	// Token is the API token required from callers; when it isn't set,
+	// lsrv generates one
+	Token string `yaml:"token"`
+}
+
+// RepoConfig is the per-repository configuration in .lsrv.yml
+type RepoConfig struct {
+	// Name overrides the repository name shown for servers in this repo
+	Name string `yaml:"name"`
+
+	// Actions are project commands, such as tests or a console, runnable
+	// in the server's directory with "lsrv run" or a key in interactive mode
+	Actions Actions `yaml:"actions"`
+
+	// Exports are variables for "lsrv env", overriding the global ones of
+	// the same name
+	Exports map[string]string `yaml:"exports"`
+
+	// Ports names ports after what runs on them, such as 5432: db, taking
+	// precedence over names found in docker-compose.yml or a Procfile
+	Ports map[int]string `yaml:"ports"`
+
+	// Requires lists the servers the project needs running, which "lsrv
+	// check" verifies
+	Requires Requirements `yaml:"requires"`
+
+	Display `yaml:",inline"`
+}
+
+// Requirement is a server a project needs running. A bare name refers to
+// a port named in Ports, a known service such as "redis", or a server's
+// label, process or framework; Port and Service pin it down.
+type Requirement struct {
+	Name    string
+	Port    int
+	Service string
+}
+
+// Requirements keeps requirements in file order. Each entry is either a
+// name or a mapping with name, port and service.
+type Requirements []Requirement
+
+// UnmarshalYAML decodes the requires list
+func (r *Requirements) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: requires must be a list of names or {name, port, service} entries", node.Line)
+	}
+
+	for _, item := range node.Content {
+		var req Requirement
+		if item.Kind == yaml.ScalarNode {
+			req.Name = item.Value
+		} else {
+			var long struct {
+				Name    string `yaml:"name"`
+				Port    int    `yaml:"port"`
+				Service string `yaml:"service"`
+			}
+			if err := item.Decode(&long); err != nil {
+				return err
+			}
+			req = Requirement{Name: long.Name, Port: long.Port, Service: long.Service}
+		}
+		if req.Name == "" && req.Port == 0 && req.Service == "" {
+			return fmt.Errorf("line %d: a requirement needs a name, port or service", item.Line)
+		}
+		if req.Port < 0 || req.Port > 65535 {
+			return fmt.Errorf("line %d: invalid port %d", item.Line, req.Port)
+		}
+		*r = append(*r, req)
+	}
+	return nil
+}
+
+// Label returns the requirement's name for reports
+func (r Requirement) Label() string {
+	switch {
+	case r.Name != "":
+		return r.Name
+	case r.Service != "":
+		return r.Service
+	}
+	return fmt.Sprintf("port %d", r.Port)
+}
+
+// Action is a named command run in a server's directory
+type Action struct {
+	Name    string
+	Command string
+
+	// Key is the interactive mode key binding; empty assigns a digit
+	Key string
+}
+
+// Actions keeps actions in file order, which decides their default keys.
+// Each entry is either "name: command" or "name: {command: ..., key: ...}".
+type Actions []Action
+
+// UnmarshalYAML decodes the actions mapping in order
+func (a *Actions) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: actions must be a mapping of name to command", node.Line)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		action := Action{Name: node.Content[i].Value}
+		value := node.Content[i+1]
+		if value.Kind == yaml.ScalarNode {
+			action.Command = value.Value
+		} else {
+			var long struct {
+				Command string `yaml:"command"`
+				Key     string `yaml:"key"`
+			}
+			if err := value.Decode(&long); err != nil {
+				return err
+			}
+			action.Command, action.Key = long.Command, long.Key
+		}
+		if action.Command == "" {
+			return fmt.Errorf("line %d: action %q has no command", value.Line, action.Name)
+		}
+		*a = append(*a, action)
+	}
+	return nil
+}
+
+// Find returns the action with the given name
+func (a Actions) Find(name string) (Action, bool) {
+	for _, action := range a {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+// Path returns the location of the global configuration file
+func Path() (string, error) {
+	dir, err := platform.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.yml"), nil
+}
+
+// Load reads the global configuration; a missing file yields the defaults
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := readYAML(path, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadRepo reads .lsrv.yml from dir; a missing file yields an empty config
+func LoadRepo(dir string) (*RepoConfig, error) {
+	cfg := &RepoConfig{}
+	if err := readYAML(filepath.Join(dir, RepoFileName), cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// FindRepo loads the nearest .lsrv.yml at or above dir, stopping at the top
+// of the git work tree, and returns the directory it was found in. Without
+// a file it returns an empty config and dir.
+func FindRepo(dir string) (*RepoConfig, string, error) {
+	for current := dir; ; {
+		if platform.FileExists(filepath.Join(current, RepoFileName)) {
+			cfg, err := LoadRepo(current)
+			return cfg, current, err
+		}
+		parent := filepath.Dir(current)
+		if platform.FileExists(filepath.Join(current, ".git")) || parent == current {
+			return &RepoConfig{}, dir, nil
+		}
+		current = parent
+	}
+}
+
+// readYAML decodes path into v, treating a missing file as empty
+func readYAML(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
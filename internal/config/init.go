@@ -0,0 +1,48 @@
+package config
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Commented starting points for "lsrv config init"
+var (
+	//go:embed templates/config.yml
+	globalTemplate []byte
+
+	//go:embed templates/lsrv.yml
+	repoTemplate []byte
+)
+
+// ErrExists is returned by Init for a file that is already there
+var ErrExists = errors.New("already exists")
+
+// Init writes a commented config with every setting disabled to path: the
+// global config.yml, or a repository's .lsrv.yml. An existing file is
+// never overwritten.
+func Init(path string) error {
+	template := globalTemplate
+	if filepath.Base(path) == RepoFileName {
+		template = repoTemplate
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s %w", path, ErrExists)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(template); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
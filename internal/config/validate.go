@@ -0,0 +1,263 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue is a problem found in a config file
+type Issue struct {
+	File string
+	Line int
+
+	// Field is the path to the offending setting, such as "hosts[1].ssh";
+	// empty for YAML syntax errors
+	Field string
+
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Field == "" {
+		return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", i.File, i.Line, i.Field, i.Message)
+}
+
+// syntaxLine finds the line in yaml.v3 syntax errors like "yaml: line 3:
+// mapping values are not allowed in this context"
+var syntaxLine = regexp.MustCompile(`^yaml: line (\d+): `)
+
+var unmarshalerType = reflect.TypeFor[yaml.Unmarshaler]()
+
+// Check validates a config file against the settings lsrv knows: Config,
+// or RepoConfig for .lsrv.yml files. Unknown settings, which loading
+// silently ignores, are reported along with values of the wrong type or
+// outside a setting's allowed values. A missing file has no issues; err is
+// only for files that can't be read.
+func Check(path string) ([]Issue, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		issue := Issue{File: path, Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+		if m := syntaxLine.FindStringSubmatch(err.Error()); m != nil {
+			issue.Line, _ = strconv.Atoi(m[1])
+			issue.Message = strings.TrimPrefix(err.Error(), m[0])
+		}
+		return []Issue{issue}, nil
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	t := reflect.TypeFor[Config]()
+	if filepath.Base(path) == RepoFileName {
+		t = reflect.TypeFor[RepoConfig]()
+	}
+	c := checker{file: path}
+	c.check(doc.Content[0], t, "")
+	slices.SortStableFunc(c.issues, func(a, b Issue) int { return a.Line - b.Line })
+	return c.issues, nil
+}
+
+// checker walks a YAML document alongside the Go type it decodes into
+type checker struct {
+	file   string
+	issues []Issue
+}
+
+func (c *checker) report(node *yaml.Node, field, format string, args ...any) {
+	c.issues = append(c.issues, Issue{File: c.file, Line: node.Line, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) check(node *yaml.Node, t reflect.Type, field string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Types decoding themselves, like Actions, know their own rules
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		if err := node.Decode(reflect.New(t).Interface()); err != nil {
+			c.report(node, field, "%s", strings.TrimPrefix(err.Error(), fmt.Sprintf("line %d: ", node.Line)))
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if c.expect(node, yaml.MappingNode, field, "a mapping") {
+			c.checkStruct(node, t, field)
+		}
+	case reflect.Map:
+		if !c.expect(node, yaml.MappingNode, field, "a mapping") {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if t.Key().Kind() == reflect.Int && key.Tag != "!!int" {
+				c.report(key, join(field, key.Value), "expected a number as key, got %q", key.Value)
+				continue
+			}
+			c.check(value, t.Elem(), join(field, key.Value))
+		}
+	case reflect.Slice:
+		if !c.expect(node, yaml.SequenceNode, field, "a list") {
+			return
+		}
+		for i, item := range node.Content {
+			c.check(item, t.Elem(), fmt.Sprintf("%s[%d]", field, i))
+		}
+	case reflect.Bool:
+		if c.expect(node, yaml.ScalarNode, field, "true or false") && node.Tag != "!!bool" {
+			c.report(node, field, "expected true or false, got %q", node.Value)
+		}
+	case reflect.Int:
+		if c.expect(node, yaml.ScalarNode, field, "a number") && node.Tag != "!!int" {
+			c.report(node, field, "expected a number, got %q", node.Value)
+		}
+	case reflect.String:
+		c.expect(node, yaml.ScalarNode, field, "a string")
+	}
+}
+
+// expect reports a node that isn't of the wanted kind
+func (c *checker) expect(node *yaml.Node, kind yaml.Kind, field, want string) bool {
+	if node.Kind == kind {
+		return true
+	}
+	got := fmt.Sprintf("%q", node.Value)
+	switch node.Kind {
+	case yaml.MappingNode:
+		got = "a mapping"
+	case yaml.SequenceNode:
+		got = "a list"
+	}
+	c.report(node, field, "expected %s, got %s", want, got)
+	return false
+}
+
+// checkStruct matches the keys of a mapping to the fields of struct t.
+// Fields tagged enum:"a,b" only accept those values, and fields tagged
+// required:"true" must be set.
+func (c *checker) checkStruct(node *yaml.Node, t reflect.Type, field string) {
+	fields := make(map[string]reflect.StructField)
+	collectFields(t, fields)
+
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		name := join(field, key.Value)
+		f, ok := fields[key.Value]
+		if !ok {
+			c.report(key, name, "unknown setting%s", suggestion(key.Value, fields))
+			continue
+		}
+		seen[key.Value] = true
+		c.check(value, f.Type, name)
+
+		if allowed := f.Tag.Get("enum"); allowed != "" {
+			c.checkEnum(value, name, strings.Split(allowed, ","))
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		if fields[key].Tag.Get("required") == "true" && !seen[key] {
+			c.report(node, join(field, key), "missing, it is required")
+		}
+	}
+}
+
+// checkEnum reports scalar values, or items of a list, not in allowed
+func (c *checker) checkEnum(node *yaml.Node, field string, allowed []string) {
+	values := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		values = node.Content
+	}
+	for _, value := range values {
+		if value.Kind == yaml.ScalarNode && value.Tag != "!!null" && !slices.Contains(allowed, value.Value) {
+			c.report(value, field, "%q isn't one of %s", value.Value, strings.Join(allowed, ", "))
+		}
+	}
+}
+
+// collectFields maps the YAML keys of t's fields, including those of
+// inlined structs, to the fields
+func collectFields(t reflect.Type, fields map[string]reflect.StructField) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if opts == "inline" {
+			collectFields(f.Type, fields)
+			continue
+		}
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		fields[name] = f
+	}
+}
+
+// join appends a key to a field path
+func join(field, key string) string {
+	if field == "" {
+		return key
+	}
+	return field + "." + key
+}
+
+// suggestion proposes the known key closest to a misspelled one
+func suggestion(key string, fields map[string]reflect.StructField) string {
+	best, bestDistance := "", 3
+	for name := range fields {
+		if d := distance(key, name); d < bestDistance || (d == bestDistance && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// distance is the Levenshtein edit distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
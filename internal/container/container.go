@@ -0,0 +1,69 @@
+// Package container finds the Docker or Podman container behind a port
+// whose listener is the container runtime's port forwarder rather than the
+// app itself
+package container
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// forwarders are the processes container runtimes publish ports through
+var forwarders = map[string]bool{
+	"docker-proxy":       true,
+	"com.docker.backend": true,
+	"com.docker.vpnkit":  true,
+	"vpnkit-bridge":      true,
+	"OrbStack Helper":    true,
+	"gvproxy":            true,
+	"rootlessport":       true,
+	"rootlesskit":        true,
+	"conmon":             true,
+}
+
+// runtimes are asked in order which container publishes a port
+var runtimes = []string{"docker", "podman"}
+
+// Container is a running container publishing a port
+type Container struct {
+	ID    string
+	Name  string
+	Image string
+
+	// Status is the runtime's summary, such as "Up 2 hours"
+	Status string
+
+	// Runtime is "docker" or "podman"
+	Runtime string
+}
+
+// IsForwarder reports whether process is a container runtime's port
+// forwarder, so the port belongs to a container
+func IsForwarder(process string) bool {
+	return forwarders[process]
+}
+
+// Publishing returns the container publishing port, asking docker and
+// then podman. ok is false when neither is installed or running, or no
+// container publishes the port.
+func Publishing(ctx context.Context, port int) (Container, bool) {
+	for _, runtime := range runtimes {
+		if _, err := exec.LookPath(runtime); err != nil {
+			continue
+		}
+		output, err := exec.CommandContext(ctx, runtime, "ps", "--filter", "publish="+strconv.Itoa(port),
+			"--format", "{{.ID}}\t{{.Names}}\t{{.Image}}\t{{.Status}}").Output()
+		if err != nil {
+			continue
+		}
+		line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
+		}
+		return Container{ID: fields[0], Name: fields[1], Image: fields[2], Status: fields[3], Runtime: runtime}, true
+	}
+	return Container{}, false
+}
@@ -0,0 +1,251 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/audit"
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/runner"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// shutdownTimeout bounds how long Restart waits for the old process to exit
+const shutdownTimeout = 10 * time.Second
+
+// ErrSafeMode is returned by Kill, Restart and Start while SafeMode is set
+var ErrSafeMode = errors.New("safe_mode is on, nothing was stopped or started (set safe_mode: false in the config to allow it)")
+
+var (
+	// SafeMode refuses to stop or start any process, from the safe_mode
+	// config option
+	SafeMode bool
+
+	// Source names the lsrv entry point in the audit log, e.g. "kill" or
+	// "serve"
+	Source string
+)
+
+// Match returns the servers identified by target, which is either a port
+// number, including a folded auxiliary port, or a repository name
+func Match(servers []types.Server, target string) []types.Server {
+	var matched []types.Server
+
+	if port, err := strconv.Atoi(target); err == nil {
+		for _, server := range servers {
+			if server.Port == port || slices.Contains(server.AuxPorts, port) {
+				matched = append(matched, server)
+			}
+		}
+		return matched
+	}
+
+	for _, server := range servers {
+		if strings.EqualFold(server.Repo, target) {
+			matched = append(matched, server)
+		}
+	}
+	return matched
+}
+
+// Kill asks the server process to shut down gracefully with SIGTERM
+func Kill(server types.Server) error {
+	if SafeMode {
+		return ErrSafeMode
+	}
+	// The command line tells how to start the server again later
+	command, _ := procinfo.CommandLine(context.Background(), server.PID)
+	err := kill(server)
+	record(audit.Entry{Action: "kill", Repo: server.Repo, Port: server.Port, PID: server.PID, Dir: server.CWD, Command: command}, err)
+	return err
+}
+
+// kill sends SIGTERM without the safe mode check and audit record
+func kill(server types.Server) error {
+	if err := platform.ValidatePID(server.PID); err != nil {
+		return err
+	}
+
+	proc, err := os.FindProcess(server.PID)
+	if err != nil {
+		return fmt.Errorf("failed to find process %d: %w", server.PID, err)
+	}
+
+	if err := proc.Signal(syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to signal process %d: %w", server.PID, err)
+	}
+	return nil
+}
+
+// Restart stops the server and starts its command line again in the same
+// directory, capturing output like "lsrv start"
+func Restart(server types.Server) (*runner.Run, error) {
+	if SafeMode {
+		return nil, ErrSafeMode
+	}
+	entry := audit.Entry{Action: "restart", Repo: server.Repo, Port: server.Port, PID: server.PID, Dir: server.CWD}
+	run, err := restart(server, &entry)
+	record(entry, err)
+	return run, err
+}
+
+// restart does the work of Restart, filling in the command it reran
+func restart(server types.Server, entry *audit.Entry) (*runner.Run, error) {
+	command, err := procinfo.CommandLine(context.Background(), server.PID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read command line of pid %d: %w", server.PID, err)
+	}
+	entry.Command = command
+
+	if err := kill(server); err != nil {
+		return nil, err
+	}
+	if err := waitForExit(server.PID, shutdownTimeout); err != nil {
+		return nil, err
+	}
+
+	return runner.Start(server.CWD, server.Repo, command)
+}
+
+// Start launches command in dir in the background like runner.Start,
+// subject to SafeMode and recorded in the audit log
+func Start(dir, repo string, command []string) (*runner.Run, error) {
+	if SafeMode {
+		return nil, ErrSafeMode
+	}
+	run, err := runner.Start(dir, repo, command)
+	entry := audit.Entry{Action: "start", Repo: repo, Dir: dir, Command: command}
+	if run != nil {
+		entry.PID = run.PID
+	}
+	record(entry, err)
+	return run, err
+}
+
+// record appends entry to the audit log with the outcome of the action.
+// Failing to write the log doesn't undo the action, so it only warns.
+func record(entry audit.Entry, err error) {
+	entry.Source = Source
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := audit.Record(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
+
+// Open opens the server's URL in the default browser
+func Open(server types.Server) error {
+	cmd := platform.OpenCommand(server.DisplayURL())
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", cmd.Args[0], err)
+	}
+	return nil
+}
+
+// Actions returns the actions defined in the .lsrv.yml nearest to the
+// server's directory
+func Actions(server types.Server) (config.Actions, error) {
+	if server.CWD == "" {
+		return nil, nil
+	}
+	cfg, _, err := config.FindRepo(server.CWD)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Actions, nil
+}
+
+// DefaultExports are the variables "lsrv env" prints without configured ones
+var DefaultExports = map[string]string{
+	"APP_URL":  "{url}",
+	"APP_PORT": "{port}",
+}
+
+// Exports returns the variables to export for server: the global config's
+// exports, or DefaultExports without any, plus those of the repo's
+// .lsrv.yml, with {url}, {host}, {port}, {pid}, {repo} and {branch} filled in
+func Exports(server types.Server, global map[string]string) (map[string]string, error) {
+	templates := maps.Clone(global)
+	if len(templates) == 0 {
+		templates = maps.Clone(DefaultExports)
+	}
+	if server.CWD != "" {
+		cfg, _, err := config.FindRepo(server.CWD)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(templates, cfg.Exports)
+	}
+
+	fill := strings.NewReplacer(
+		"{url}", server.DisplayURL(),
+		"{pid}", strconv.Itoa(server.PID),
+		"{repo}", server.Repo,
+		"{branch}", server.Branch,
+	)
+	exports := make(map[string]string, len(templates))
+	for name, template := range templates {
+		exports[name] = server.ExpandURL(fill.Replace(template))
+	}
+	return exports, nil
+}
+
+// ActionCommand prepares action to run through the shell in the server's
+// directory, with LSRV_PORT, LSRV_PID and LSRV_URL describing the server.
+// The caller attaches stdio and runs it.
+func ActionCommand(server types.Server, action config.Action) *exec.Cmd {
+	cmd := platform.ShellCommand(action.Command)
+	cmd.Dir = server.CWD
+	cmd.Env = serverEnv(server)
+	return cmd
+}
+
+// ExecCommand prepares argv to run directly, without a shell, in the
+// server's directory with the same environment as ActionCommand. The
+// caller attaches stdio and runs it.
+func ExecCommand(server types.Server, argv []string) *exec.Cmd {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = server.CWD
+	cmd.Env = serverEnv(server)
+	return cmd
+}
+
+// serverEnv returns lsrv's environment plus variables describing server
+func serverEnv(server types.Server) []string {
+	return append(os.Environ(),
+		"LSRV_PORT="+strconv.Itoa(server.Port),
+		"LSRV_PID="+strconv.Itoa(server.PID),
+		"LSRV_URL="+server.DisplayURL(),
+	)
+}
+
+// waitForExit polls until pid is gone or timeout elapses
+func waitForExit(pid int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		proc, err := os.FindProcess(pid)
+		if err != nil {
+			return nil
+		}
+		// Signal 0 checks for existence without affecting the process
+		if err := proc.Signal(syscall.Signal(0)); err != nil {
+			if errors.Is(err, os.ErrProcessDone) || errors.Is(err, syscall.ESRCH) {
+				return nil
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("process %d did not exit within %s", pid, timeout)
+}
@@ -0,0 +1,132 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Label names the service for launchd and systemd
+const Label = "com.github.bshakr.lsrv"
+
+// Service describes how the daemon is started at login
+type Service struct {
+	// Binary is the absolute path of the lsrv executable
+	Binary string
+
+	// Args follow the binary, e.g. ["serve", "--http=localhost:7777"]
+	Args []string
+
+	// Path is the PATH the daemon runs with, so lsof and git resolve as in
+	// the installing shell rather than the service manager's minimal PATH
+	Path string
+
+	// LogPath receives the daemon's output where the service manager has
+	// no journal of its own (launchd)
+	LogPath string
+}
+
+// Status describes an installed service
+type Status struct {
+	// File is the plist or unit file, empty when not installed
+	File string
+
+	// Running reports whether the service manager has the daemon running
+	Running bool
+
+	// Detail is the service manager's own description of the service
+	Detail string
+}
+
+// CurrentBinary returns the absolute path of the running lsrv executable,
+// resolving symlinks such as Homebrew's bin shims so upgrades that replace
+// the link target don't break the service
+func CurrentBinary() (string, error) {
+	binary, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate lsrv executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(binary); err == nil {
+		binary = resolved
+	}
+	return binary, nil
+}
+
+// LaunchdPlist renders a launchd agent that starts the daemon at login and
+// restarts it if it exits
+func LaunchdPlist(s Service) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	fmt.Fprintf(&b, "\t<key>Label</key>\n\t<string>%s</string>\n", xmlEscape(Label))
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range append([]string{s.Binary}, s.Args...) {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", xmlEscape(arg))
+	}
+	b.WriteString("\t</array>\n")
+	if s.Path != "" {
+		fmt.Fprintf(&b, "\t<key>EnvironmentVariables</key>\n\t<dict>\n\t\t<key>PATH</key>\n\t\t<string>%s</string>\n\t</dict>\n", xmlEscape(s.Path))
+	}
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n\t<key>KeepAlive</key>\n\t<true/>\n")
+	if s.LogPath != "" {
+		fmt.Fprintf(&b, "\t<key>StandardOutPath</key>\n\t<string>%s</string>\n", xmlEscape(s.LogPath))
+		fmt.Fprintf(&b, "\t<key>StandardErrorPath</key>\n\t<string>%s</string>\n", xmlEscape(s.LogPath))
+	}
+	b.WriteString("</dict>\n</plist>\n")
+	return b.Bytes()
+}
+
+// SystemdUnit renders a systemd user unit that starts the daemon with the
+// user's session and restarts it on failure
+func SystemdUnit(s Service) []byte {
+	var b bytes.Buffer
+	b.WriteString("[Unit]\nDescription=lsrv development server API\n\n[Service]\n")
+	fmt.Fprintf(&b, "ExecStart=%s\n", systemdCommand(append([]string{s.Binary}, s.Args...)))
+	if s.Path != "" {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote("PATH="+s.Path))
+	}
+	b.WriteString("Restart=on-failure\nRestartSec=5\n\n[Install]\nWantedBy=default.target\n")
+	return b.Bytes()
+}
+
+// run executes a service manager command, folding its output into errors
+func run(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// xmlEscape escapes text for a plist string element
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// systemdCommand joins a command line, quoting words systemd would split
+func systemdCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = systemdQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// systemdQuote double-quotes a word containing spaces, quotes, backslashes
+// or specifiers, escaping as systemd's unit file syntax requires
+func systemdQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\%$") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `%`, `%%`, `$`, `$$`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,102 @@
+//go:build darwin
+
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/bshakr/lsrv/internal/atomicfile"
+)
+
+// pidPattern finds the daemon's PID in "launchctl print" output
+var pidPattern = regexp.MustCompile(`(?m)^\s*pid = (\d+)`)
+
+// file returns the launchd agent location
+func file() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve home directory: %w", err)
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", Label+".plist"), nil
+}
+
+// domain is the launchd domain of the logged-in user's GUI session
+func domain() string {
+	return "gui/" + strconv.Itoa(os.Getuid())
+}
+
+// Install writes the launchd agent and loads it, replacing any earlier
+// installation
+func Install(s Service) (string, error) {
+	path, err := file()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if s.LogPath != "" {
+		if err := os.MkdirAll(filepath.Dir(s.LogPath), 0o755); err != nil {
+			return "", fmt.Errorf("failed to create log directory: %w", err)
+		}
+	}
+
+	// Unload a previous version first; bootstrap refuses loaded labels
+	_ = run("launchctl", "bootout", domain()+"/"+Label)
+
+	if err := atomicfile.WriteFile(path, LaunchdPlist(s), 0o644); err != nil {
+		return "", err
+	}
+	if err := run("launchctl", "bootstrap", domain(), path); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// Uninstall unloads and removes the launchd agent
+func Uninstall() (string, error) {
+	path, err := file()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", fmt.Errorf("not installed (no %s)", path)
+	}
+
+	_ = run("launchctl", "bootout", domain()+"/"+Label)
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// Query reports whether the agent is installed and running
+func Query() (Status, error) {
+	path, err := file()
+	if err != nil {
+		return Status{}, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return Status{}, nil
+	}
+
+	status := Status{File: path}
+	output, err := exec.Command("launchctl", "print", domain()+"/"+Label).CombinedOutput()
+	if err != nil {
+		status.Detail = "not loaded"
+		return status, nil
+	}
+	status.Running = bytes.Contains(output, []byte("state = running"))
+	if m := pidPattern.FindSubmatch(output); status.Running && m != nil {
+		status.Detail = "running, pid " + string(m[1])
+	} else {
+		status.Detail = "loaded, not running"
+	}
+	return status, nil
+}
@@ -0,0 +1,91 @@
+//go:build linux
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/atomicfile"
+	"github.com/bshakr/lsrv/internal/platform"
+)
+
+// unitName is the systemd user unit
+const unitName = "lsrv.service"
+
+// file returns the systemd user unit location
+func file() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" || !filepath.IsAbs(dir) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "systemd", "user", unitName), nil
+}
+
+// Install writes the systemd user unit, then enables and (re)starts it
+func Install(s Service) (string, error) {
+	path, err := file()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+
+	// The journal keeps the output; LogPath is for launchd only
+	s.LogPath = ""
+	if err := atomicfile.WriteFile(path, SystemdUnit(s), 0o644); err != nil {
+		return "", err
+	}
+
+	if err := run("systemctl", "--user", "daemon-reload"); err != nil {
+		return path, err
+	}
+	if err := run("systemctl", "--user", "enable", unitName); err != nil {
+		return path, err
+	}
+	return path, run("systemctl", "--user", "restart", unitName)
+}
+
+// Uninstall stops, disables and removes the systemd user unit
+func Uninstall() (string, error) {
+	path, err := file()
+	if err != nil {
+		return "", err
+	}
+	if !platform.FileExists(path) {
+		return "", fmt.Errorf("not installed (no %s)", path)
+	}
+
+	_ = run("systemctl", "--user", "disable", "--now", unitName)
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return path, run("systemctl", "--user", "daemon-reload")
+}
+
+// Query reports whether the unit is installed and active
+func Query() (Status, error) {
+	path, err := file()
+	if err != nil {
+		return Status{}, err
+	}
+	if !platform.FileExists(path) {
+		return Status{}, nil
+	}
+
+	// is-active exits non-zero for inactive units; its output still says why
+	output, _ := exec.Command("systemctl", "--user", "is-active", unitName).Output()
+	state := strings.TrimSpace(string(output))
+	if state == "" {
+		state = "unknown (is systemd running for this user?)"
+	}
+	return Status{File: path, Running: state == "active", Detail: state}, nil
+}
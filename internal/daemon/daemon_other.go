@@ -0,0 +1,24 @@
+//go:build !darwin && !linux
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// errUnsupported explains that only launchd and systemd are supported
+var errUnsupported = fmt.Errorf("installing the daemon is not supported on %s: %w", runtime.GOOS, errors.ErrUnsupported)
+
+func Install(s Service) (string, error) {
+	return "", errUnsupported
+}
+
+func Uninstall() (string, error) {
+	return "", errUnsupported
+}
+
+func Query() (Status, error) {
+	return Status{}, errUnsupported
+}
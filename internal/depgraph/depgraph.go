@@ -0,0 +1,159 @@
+// Package depgraph maps which detected servers talk to which, such as an
+// app connected to postgres and redis, from established TCP connections
+package depgraph
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// maxAncestors bounds the parent walk attributing a connection to a server,
+// enough for a worker under a master under a package manager
+const maxAncestors = 3
+
+// Edge is a set of connections from one server to another
+type Edge struct {
+	From types.Server
+	To   types.Server
+
+	// Connections counts the established connections, such as the size of
+	// a database pool
+	Connections int
+}
+
+// connection is one established TCP connection from lsof
+type connection struct {
+	pid        int
+	localHost  string
+	remoteHost string
+	remotePort int
+}
+
+// Build returns the edges between servers, found by matching established
+// connections of each server's processes, including its workers and
+// children, against the ports of the others
+func Build(ctx context.Context, servers []types.Server) ([]Edge, error) {
+	output, err := exec.CommandContext(ctx, "lsof", "-iTCP", "-sTCP:ESTABLISHED", "-n", "-P", "-Fpn").Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	// lsof exits 1 when it finds nothing
+	if err != nil && len(output) == 0 {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, fmt.Errorf("failed to run lsof: %w", err)
+		}
+	}
+
+	byPID := make(map[int]int)
+	byPort := make(map[int]int)
+	for i, server := range servers {
+		byPID[server.PID] = i
+		byPort[server.Port] = i
+	}
+
+	// owner resolves a connection's process to the server it belongs to,
+	// remembering misses so each process is walked once
+	owners := make(map[int]int)
+	owner := func(pid int) (int, bool) {
+		if i, ok := owners[pid]; ok {
+			return i, i >= 0
+		}
+		owners[pid] = -1
+		for current, depth := pid, 0; depth <= maxAncestors; depth++ {
+			if i, ok := byPID[current]; ok {
+				owners[pid] = i
+				return i, true
+			}
+			parent, _, err := procinfo.Parent(ctx, current)
+			if err != nil || parent <= 1 {
+				break
+			}
+			current = parent
+		}
+		return -1, false
+	}
+
+	type key struct{ from, to int }
+	counts := make(map[key]int)
+	for _, conn := range parseConnections(output) {
+		to, ok := byPort[conn.remotePort]
+		if !ok || !isLocal(conn) {
+			continue
+		}
+		from, ok := owner(conn.pid)
+		if !ok || from == to {
+			continue
+		}
+		counts[key{from, to}]++
+	}
+
+	edges := make([]Edge, 0, len(counts))
+	for k, n := range counts {
+		edges = append(edges, Edge{From: servers[k.from], To: servers[k.to], Connections: n})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From.Port != edges[j].From.Port {
+			return edges[i].From.Port < edges[j].From.Port
+		}
+		return edges[i].To.Port < edges[j].To.Port
+	})
+	return edges, nil
+}
+
+// parseConnections parses lsof's "p<pid>" and
+// "n<local>-><remote>" field output
+func parseConnections(output []byte) []connection {
+	var conns []connection
+	var pid int
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if pidStr, ok := strings.CutPrefix(line, "p"); ok {
+			pid, _ = strconv.Atoi(pidStr)
+			continue
+		}
+		name, ok := strings.CutPrefix(line, "n")
+		if !ok || pid == 0 {
+			continue
+		}
+		local, remote, ok := strings.Cut(name, "->")
+		if !ok {
+			continue
+		}
+		localHost, _, err := net.SplitHostPort(local)
+		if err != nil {
+			continue
+		}
+		remoteHost, portStr, err := net.SplitHostPort(remote)
+		if err != nil {
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			continue
+		}
+		conns = append(conns, connection{pid: pid, localHost: localHost, remoteHost: remoteHost, remotePort: port})
+	}
+	return conns
+}
+
+// isLocal reports whether a connection stays on this machine: to loopback,
+// or to the address it was made from
+func isLocal(conn connection) bool {
+	if conn.remoteHost == conn.localHost {
+		return true
+	}
+	ip := net.ParseIP(conn.remoteHost)
+	return ip != nil && ip.IsLoopback()
+}
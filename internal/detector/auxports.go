@@ -0,0 +1,101 @@
+package detector
+
+import (
+	"context"
+	"sort"
+
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// auxRuntimes run frontend dev servers that open helper ports besides the
+// app's own, such as Vite's HMR websocket or the Next.js router worker
+var auxRuntimes = map[string]bool{
+	"node": true,
+	"bun":  true,
+	"deno": true,
+}
+
+// foldAuxPorts finds JavaScript dev servers listening on several ports from
+// one process, or from a process and its child, in the same directory. The
+// lowest port is the app; the others are auxiliary. Auxiliary rows are
+// dropped and their ports listed in the app's AuxPorts, unless keep is set,
+// in which case they stay with PrimaryPort pointing at the app.
+func foldAuxPorts(ctx context.Context, servers []types.Server, keep bool) []types.Server {
+	byDir := make(map[string][]int)
+	for i, server := range servers {
+		if server.Service == "" && server.CWD != "" && auxRuntimes[server.Process] {
+			byDir[server.CWD] = append(byDir[server.CWD], i)
+		}
+	}
+
+	drop := make(map[int]bool)
+	for _, group := range byDir {
+		if len(group) < 2 {
+			continue
+		}
+
+		// Join rows of the same process and of a parent and its child
+		family := make(map[int]int)
+		for _, i := range group {
+			family[servers[i].PID] = servers[i].PID
+		}
+		for pid := range family {
+			if ppid, _, err := procinfo.Parent(ctx, pid); err == nil {
+				if _, ok := family[ppid]; ok {
+					family[pid] = ppid
+				}
+			}
+		}
+		root := func(pid int) int {
+			for family[pid] != pid {
+				pid = family[pid]
+			}
+			return pid
+		}
+
+		families := make(map[int][]int)
+		for _, i := range group {
+			families[root(servers[i].PID)] = append(families[root(servers[i].PID)], i)
+		}
+		for _, members := range families {
+			if len(members) < 2 {
+				continue
+			}
+			sort.Slice(members, func(a, b int) bool { return servers[members[a]].Port < servers[members[b]].Port })
+			primary := &servers[members[0]]
+			for _, i := range members[1:] {
+				// Rows for another socket on the same port, kept apart by
+				// the dedup strategy, aren't helper ports
+				if servers[i].Port == primary.Port {
+					continue
+				}
+				if keep {
+					servers[i].PrimaryPort = primary.Port
+					continue
+				}
+				primary.AuxPorts = append(primary.AuxPorts, servers[i].Port)
+				drop[i] = true
+			}
+		}
+	}
+
+	if len(drop) == 0 {
+		return servers
+	}
+	kept := servers[:0]
+	for i, server := range servers {
+		if !drop[i] {
+			kept = append(kept, server)
+		}
+	}
+	return kept
+}
+
+// primaryPort returns the port of the app a row belongs to
+func primaryPort(server types.Server) int {
+	if server.PrimaryPort != 0 {
+		return server.PrimaryPort
+	}
+	return server.Port
+}
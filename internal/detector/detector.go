@@ -1,29 +1,48 @@
 package detector
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
-	"sort"
-	"strconv"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/bshakr/lsrv/internal/container"
+	"github.com/bshakr/lsrv/internal/framework"
 	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/health"
+	"github.com/bshakr/lsrv/internal/ignore"
 	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/portlabel"
+	"github.com/bshakr/lsrv/internal/probe"
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/runconfig"
+	"github.com/bshakr/lsrv/internal/services"
+	"github.com/bshakr/lsrv/internal/timing"
+	"github.com/bshakr/lsrv/internal/toolchain"
 	"github.com/bshakr/lsrv/internal/types"
 )
 
-// Compile regex once at package level for performance
-var portRegex = regexp.MustCompile(`:(\d+)\s+\(LISTEN\)`)
-
 // gitInfo holds the result of parallel git operations
 type gitInfo struct {
 	repo   string
 	branch string
+
+	// root is the top directory of the work tree
+	root string
+
+	// lastCommit is only fetched when Options.LastCommit is set
+	lastCommit *time.Time
+
+	// remoteURL is only fetched when Options.RemoteURL is set
+	remoteURL string
 }
 
 // processInfo holds initial process data before CWD lookup
@@ -31,163 +50,427 @@ type processInfo struct {
 	pid     int
 	command string
 	port    int
+	uid     int
+
+	// bind is the listening address, such as "*" or "127.0.0.1"
+	bind string
+
+	// socket identifies the listening socket (lsof DEVICE column); processes
+	// of a preforking cluster share it
+	socket string
+
+	// workers counts the other processes sharing the socket
+	workers int
+
+	// dir, when set, is the directory the listener serves in place of its
+	// working directory, such as the folder a dev container was opened
+	// from for the runtime's forwarder publishing its port
+	dir string
+
+	// devContainer maps the port of a forwarded dev container listener
+	devContainer *types.DevContainer
 }
 
-// FindServers discovers all running development servers
-func FindServers() ([]types.Server, error) {
-	cmd := exec.Command("lsof", "-iTCP", "-sTCP:LISTEN", "-n", "-P")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to run lsof: %w", err)
-	}
+// Dedup is a strategy for merging listeners into one row
+type Dedup string
 
-	// First pass: collect all PIDs and process info
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-	var processes []processInfo
-	var pids []int
+const (
+	// DedupKey merges listeners with the same repo, branch, process name
+	// and port, such as a server's IPv4 and IPv6 sockets
+	DedupKey Dedup = "key"
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	// DedupPID merges the sockets one process holds on a port
+	DedupPID Dedup = "pid"
 
-		// Skip header
-		if strings.HasPrefix(line, "COMMAND") {
-			continue
-		}
+	// DedupSocket merges only processes sharing one socket, which cluster
+	// grouping already does, so IPv4 and IPv6 sockets get their own rows
+	DedupSocket Dedup = "socket"
 
-		fields := strings.Fields(line)
-		if len(fields) < 9 {
-			continue
+	// DedupNone lists every listener lsof reports
+	DedupNone Dedup = "none"
+)
+
+// ParseDedup validates a dedup strategy name; "" is DedupKey
+func ParseDedup(name string) (Dedup, error) {
+	switch strategy := Dedup(name); strategy {
+	case "":
+		return DedupKey, nil
+	case DedupKey, DedupPID, DedupSocket, DedupNone:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown dedup strategy %q (want key, pid, socket or none)", name)
+}
+
+// dedupKey identifies the row a listener belongs to under strategy, or
+// returns "" when it is never merged
+func dedupKey(strategy Dedup, info gitInfo, proc processInfo) string {
+	switch strategy {
+	case DedupPID:
+		return fmt.Sprintf("%d|%d", proc.pid, proc.port)
+	case DedupSocket:
+		if proc.socket == "" {
+			return ""
 		}
+		return fmt.Sprintf("%s|%d", proc.socket, proc.port)
+	case DedupNone:
+		return ""
+	}
+	return fmt.Sprintf("%s|%s|%s|%d", info.repo, info.branch, proc.command, proc.port)
+}
 
-		pidStr := fields[1]
-		command := fields[0]
+// Options controls optional, more expensive detection work
+type Options struct {
+	// Session resolves the tmux pane or terminal owning each server
+	Session bool
 
-		// Extract port from the line
-		port := extractPort(line)
-		if port == 0 || !isDevPort(port) {
-			continue
+	// StartedBy resolves the chain of ancestors that launched each server
+	StartedBy bool
+
+	// Dedup decides which listeners share a row; "" is DedupKey
+	Dedup Dedup
+
+	// AllUsers includes listeners owned by other accounts; without it only
+	// the invoking user's servers are shown
+	AllUsers bool
+
+	// LastCommit fetches the time of each branch's latest commit
+	LastCommit bool
+
+	// RemoteURL fetches the web URL of each repo's origin remote
+	RemoteURL bool
+
+	// CommandLine fetches each server's full command line
+	CommandLine bool
+
+	// Runtime identifies each server's runtime and version from its
+	// executable
+	Runtime bool
+
+	// Env finds each server's direnv or devenv setup and checks whether
+	// the server started with it applied, copying the EnvVars it names
+	// (nil for devshell.DefaultVars)
+	Env     bool
+	EnvVars []string
+
+	// NoGit skips all git subprocesses: work trees are recognized by their
+	// .git entry alone and servers show their work tree's directory name as
+	// repo and "-" as branch. LastCommit is ignored.
+	NoGit bool
+
+	// MaxDepth limits how many directories above a server's working
+	// directory are searched for the root of its work tree; servers further
+	// down aren't in a repository. 0 searches all the way up.
+	MaxDepth int
+
+	// Ignore drops servers whose working directory it matches, before any
+	// git work is spent on them
+	Ignore *ignore.List
+
+	// SkipBranches drops app servers running on these branches, such as
+	// main for --only-feature-branches
+	SkipBranches []string
+
+	// Dirs, when set, limits detection to servers whose working directory
+	// is within one of these directories, such as a repo's worktrees
+	Dirs []string
+
+	// Since, when positive, keeps only servers whose process started within
+	// this long before detection, such as the ones a launch script just
+	// brought up
+	Since time.Duration
+
+	// CurrentDir is the work tree lsrv was started in; servers running
+	// within it are marked Current, and sorted first with CurrentFirst
+	CurrentDir   string
+	CurrentFirst bool
+
+	// AllPorts keeps rows for auxiliary ports of JavaScript dev servers,
+	// such as HMR websockets, instead of folding them into the app's row
+	AllPorts bool
+
+	// URLTemplates maps frameworks or process names to URL templates for
+	// Server.CustomURL; a framework's template wins over its process's
+	URLTemplates map[string]string
+
+	// RepoNames sets where repository names come from, in priority order;
+	// nil uses git.DefaultNameSources
+	RepoNames []git.NameSource
+
+	// Services also lists databases and other auxiliary services (postgres,
+	// redis, ...) regardless of working directory
+	Services bool
+
+	// Sudo runs the socket enumeration through sudo so listeners of other
+	// users and root are visible
+	Sudo bool
+
+	// Probe connects to each app server port to identify non-HTTP
+	// protocols such as gRPC, websocket-only endpoints or raw TCP
+	Probe bool
+
+	// Latency measures each HTTP server's time to first byte
+	Latency bool
+
+	// Usage reads each server's uptime and the memory its process tree uses
+	Usage bool
+
+	// Backlog reads how full each server's accept queue is, on Linux
+	Backlog bool
+
+	// LAN adds a URL reaching each server from other devices on the
+	// network, using the primary interface address or, with MDNS, the
+	// machine's HOSTNAME.local name
+	LAN  bool
+	MDNS bool
+
+	// Firewall checks whether the local firewall lets other devices reach
+	// servers with a LAN URL
+	Firewall bool
+
+	// Tunnels asks running ngrok, cloudflared and tailscale funnel agents
+	// for the public URLs forwarding to each server
+	Tunnels bool
+
+	// Enrichers add facts to the servers after the built-in enrichment,
+	// such as health checks or resource use
+	Enrichers []Enricher
+
+	// Timings, when non-nil, records the duration of each detection phase
+	Timings *timing.Recorder
+
+	// Report, when non-nil, receives counts of listeners that were skipped
+	Report *Report
+
+	// DumpRaw, when non-nil, receives a copy of lsof's raw output for bug
+	// reports
+	DumpRaw io.Writer
+
+	// cache, set by Tracker, carries per-process results between refreshes
+	cache *pidCache
+}
+
+// Report describes listeners that detection could not fully resolve
+type Report struct {
+	// PermissionDenied counts dev-port listeners known to the kernel whose
+	// owning process lsof could not inspect
+	PermissionDenied int
+
+	// Zombies counts servers whose working directory no longer exists
+	Zombies int
+
+	// Exited counts listeners whose process exited during the scan
+	Exited int
+
+	// ParseErrors lists lsof output lines that could not be parsed
+	ParseErrors []ParseError
+}
+
+// Warning codes, kept stable for machine-readable output
+const (
+	WarnPermissionDenied = "permission_denied"
+	WarnUnparsable       = "unparsable_lsof_output"
+	WarnExited           = "exited_during_scan"
+	WarnDeletedCWD       = "deleted_cwd"
+	WarnHostFailed       = "host_failed"
+)
+
+// Warning tells why a listing may be incomplete
+type Warning struct {
+	Code  string
+	Count int
+
+	// Details lists the affected entries when there is more to say, such
+	// as the lsof lines that could not be parsed
+	Details []string
+}
+
+// Warnings returns the report's problems in a fixed order; a nil report
+// has none
+func (r *Report) Warnings() []Warning {
+	if r == nil {
+		return nil
+	}
+
+	var warnings []Warning
+	if r.PermissionDenied > 0 {
+		warnings = append(warnings, Warning{Code: WarnPermissionDenied, Count: r.PermissionDenied})
+	}
+	if len(r.ParseErrors) > 0 {
+		details := make([]string, len(r.ParseErrors))
+		for i, parseErr := range r.ParseErrors {
+			details[i] = parseErr.Error()
 		}
+		warnings = append(warnings, Warning{Code: WarnUnparsable, Count: len(r.ParseErrors), Details: details})
+	}
+	if r.Exited > 0 {
+		warnings = append(warnings, Warning{Code: WarnExited, Count: r.Exited})
+	}
+	if r.Zombies > 0 {
+		warnings = append(warnings, Warning{Code: WarnDeletedCWD, Count: r.Zombies})
+	}
+	return warnings
+}
 
-		pid, err := strconv.Atoi(pidStr)
-		if err != nil {
+// parseListeners extracts listeners from lsof output, keeping only those
+// owned by ownerUID unless allUsers is set. Lines that could not be parsed
+// are returned as errors rather than silently dropped.
+func parseListeners(output []byte, allUsers bool, ownerUID int) ([]processInfo, []ParseError) {
+	rows, errs := parseLsof(output)
+	var processes []processInfo
+
+	for _, row := range rows {
+		if !allUsers && row.uid != ownerUID {
 			continue
 		}
 
 		// Validate PID before collecting
-		if err := platform.ValidatePID(pid); err != nil {
+		if err := platform.ValidatePID(row.pid); err != nil {
 			continue
 		}
 
 		processes = append(processes, processInfo{
-			pid:     pid,
-			command: command,
-			port:    port,
+			pid:     row.pid,
+			command: row.command,
+			port:    row.port,
+			bind:    row.host,
+			uid:     row.uid,
+			socket:  row.device,
 		})
-		pids = append(pids, pid)
 	}
 
-	// Batch get all CWDs in a single lsof call
-	cwdMap := batchGetProcessCWDs(pids)
+	return processes, errs
+}
 
-	// Collect unique CWDs and check if they're git repos in parallel
-	uniqueCWDs := make(map[string]bool)
-	for _, cwd := range cwdMap {
-		if cwd != "" {
-			uniqueCWDs[cwd] = true
-		}
+// countHiddenListeners counts dev ports with a listening socket that lsof
+// did not report, which happens when the owning process belongs to another
+// user or root. Without allUsers, only sockets owned by ownerUID count.
+func countHiddenListeners(visible []processInfo, allUsers bool, ownerUID int) int {
+	sockets, err := platform.ListeningSockets()
+	if err != nil {
+		return 0
 	}
 
-	// Batch check all unique directories for git repos in parallel
-	gitRepoCache := batchCheckGitRepos(uniqueCWDs)
-
-	// Collect git repos that passed the check for batch git info fetching
-	gitRepoDirs := make(map[string]bool)
-	for dir, isRepo := range gitRepoCache {
-		if isRepo {
-			gitRepoDirs[dir] = true
-		}
+	visibleSockets := make(map[string]bool)
+	visiblePorts := make(map[int]bool)
+	for _, proc := range visible {
+		visibleSockets[proc.socket] = true
+		visiblePorts[proc.port] = true
 	}
 
-	// Batch fetch git info (repo name and branch) for all git repos in parallel
-	gitInfoCache := batchGetGitInfo(gitRepoDirs)
-
-	// Second pass: build server list using cached results
-	seenServers := make(map[string]bool)
-	var servers []types.Server
-
-	for _, proc := range processes {
-		cwd, ok := cwdMap[proc.pid]
-		if !ok || cwd == "" {
+	hiddenPorts := make(map[int]bool)
+	for _, sock := range sockets {
+		if !isDevPort(sock.Port) {
 			continue
 		}
-
-		// Only show servers in git repositories (use cached result)
-		if !gitRepoCache[cwd] {
+		if !allUsers && sock.UID >= 0 && sock.UID != ownerUID {
 			continue
 		}
 
-		// Get repo name and branch from cache
-		info, ok := gitInfoCache[cwd]
-		if !ok {
-			continue
+		// Linux sockets are matched by inode (lsof's DEVICE column); on
+		// macOS only the port is known
+		hidden := !visiblePorts[sock.Port]
+		if sock.Inode != "" {
+			hidden = !visibleSockets[sock.Inode]
 		}
-
-		// Create unique key to deduplicate
-		key := fmt.Sprintf("%s|%s|%s|%d", info.repo, info.branch, proc.command, proc.port)
-		if seenServers[key] {
-			continue
+		if hidden {
+			hiddenPorts[sock.Port] = true
 		}
-		seenServers[key] = true
-
-		servers = append(servers, types.Server{
-			Repo:    info.repo,
-			Branch:  info.branch,
-			Process: proc.command,
-			Port:    proc.port,
-			PID:     proc.pid,
-			CWD:     cwd,
-		})
 	}
+	return len(hiddenPorts)
+}
 
-	// Sort servers by repo, branch, port
-	sort.Slice(servers, func(i, j int) bool {
-		if servers[i].Repo != servers[j].Repo {
-			return servers[i].Repo < servers[j].Repo
+// splitServices separates recognized auxiliary services from candidate app
+// servers, dropping listeners outside the dev port range. Services are only
+// split off when enabled.
+func splitServices(processes []processInfo, enabled bool) ([]processInfo, []types.Server) {
+	var apps []processInfo
+	var found []types.Server
+	seen := make(map[string]bool)
+
+	for _, proc := range processes {
+		if enabled {
+			name, ok := services.MatchProcess(proc.command)
+			if !ok && !isDevPort(proc.port) {
+				name, ok = services.MatchPort(proc.port)
+			}
+			if ok {
+				// IPv4 and IPv6 sockets of one process show up separately
+				key := fmt.Sprintf("%d|%d", proc.pid, proc.port)
+				if !seen[key] {
+					seen[key] = true
+					found = append(found, serviceServer(proc, name))
+				}
+				continue
+			}
 		}
-		if servers[i].Branch != servers[j].Branch {
-			return servers[i].Branch < servers[j].Branch
+
+		if isDevPort(proc.port) {
+			apps = append(apps, proc)
 		}
-		return servers[i].Port < servers[j].Port
-	})
+	}
+	return apps, found
+}
 
-	return servers, nil
+// serviceServer builds the server entry for an auxiliary service
+func serviceServer(proc processInfo, name string) types.Server {
+	return types.Server{
+		Process: proc.command,
+		Port:    proc.port,
+		Bind:    proc.bind,
+		PID:     proc.pid,
+		User:    platform.UserName(proc.uid),
+		UID:     proc.uid,
+		Workers: proc.workers,
+		Service: name,
+	}
 }
 
-func extractPort(line string) int {
-	// Use pre-compiled regex for :PORT (LISTEN) pattern
-	matches := portRegex.FindStringSubmatch(line)
-	if len(matches) > 1 {
-		port, err := strconv.Atoi(matches[1])
-		if err != nil {
-			return 0
+// groupClusters merges processes listening on the same socket, as done by
+// Puma clusters, gunicorn and Node cluster mode, into one entry for the
+// master process with a count of its workers
+func groupClusters(ctx context.Context, processes []processInfo) []processInfo {
+	type socketKey struct {
+		socket string
+		port   int
+	}
+
+	groups := make(map[socketKey][]processInfo)
+	var order []socketKey
+	for _, proc := range processes {
+		key := socketKey{proc.socket, proc.port}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
 		}
-		return port
+		groups[key] = append(groups[key], proc)
 	}
 
-	// Fallback: try to extract from the 9th field
-	fields := strings.Fields(line)
-	if len(fields) >= 9 {
-		parts := strings.Split(fields[8], ":")
-		if len(parts) > 0 {
-			port, err := strconv.Atoi(parts[len(parts)-1])
-			if err != nil {
-				return 0
+	grouped := make([]processInfo, 0, len(order))
+	for _, key := range order {
+		group := groups[key]
+
+		members := make(map[int]bool)
+		for _, proc := range group {
+			members[proc.pid] = true
+		}
+		if len(members) == 1 {
+			grouped = append(grouped, group[0])
+			continue
+		}
+
+		// The master is the member whose parent is outside the group
+		master := group[0]
+		for _, proc := range group {
+			ppid, _, err := procinfo.Parent(ctx, proc.pid)
+			if err == nil && !members[ppid] {
+				master = proc
+				break
 			}
-			return port
 		}
+		master.workers = len(members) - 1
+		grouped = append(grouped, master)
 	}
 
-	return 0
+	return grouped
 }
 
 func isDevPort(port int) bool {
@@ -207,7 +490,7 @@ func isDevPort(port int) bool {
 }
 
 // batchCheckGitRepos checks multiple directories for git repos in parallel
-func batchCheckGitRepos(dirs map[string]bool) map[string]bool {
+func batchCheckGitRepos(ctx context.Context, dirs map[string]bool) map[string]bool {
 	results := make(map[string]bool)
 	var mu sync.Mutex
 	var wg sync.WaitGroup
@@ -216,19 +499,60 @@ func batchCheckGitRepos(dirs map[string]bool) map[string]bool {
 		wg.Add(1)
 		go func(d string) {
 			defer wg.Done()
-			isRepo := git.IsRepo(d)
+			isRepo := git.IsRepo(ctx, d)
 			mu.Lock()
 			results[d] = isRepo
 			mu.Unlock()
 		}(dir)
 	}
 
-	wg.Wait()
+	if !waitContext(ctx, &wg) {
+		return nil
+	}
 	return results
 }
 
+// batchCheckWorkTrees is batchCheckGitRepos without running git, looking
+// for a .git entry in each directory or its parents
+func batchCheckWorkTrees(ctx context.Context, dirs map[string]bool) map[string]bool {
+	results := make(map[string]bool)
+	for dir := range dirs {
+		if ctx.Err() != nil {
+			return nil
+		}
+		results[dir] = git.InWorkTree(dir)
+	}
+	return results
+}
+
+// repoRoot finds the top of the work tree containing dir within maxDepth
+// parents. Repositories found only by git, as with GIT_DIR, have no .git
+// entry to find; dir stands in for their root.
+func repoRoot(dir string, maxDepth int) (string, bool) {
+	if root, ok := git.WorkTreeRootWithin(dir, maxDepth); ok {
+		return root, true
+	}
+	if maxDepth > 0 && git.InWorkTree(dir) {
+		return "", false
+	}
+	return dir, true
+}
+
+// packagePath is the path of a server's directory within its repository,
+// such as "packages/web" in a monorepo, or "" at the root
+func packagePath(root, cwd string) string {
+	if root == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(root, cwd)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
 // batchGetGitInfo fetches git info for multiple directories in parallel
-func batchGetGitInfo(dirs map[string]bool) map[string]gitInfo {
+func batchGetGitInfo(ctx context.Context, dirs map[string]bool, nameSources []git.NameSource, withLastCommit, withRemoteURL bool) map[string]gitInfo {
 	results := make(map[string]gitInfo)
 	var mu sync.Mutex
 	var wg sync.WaitGroup
@@ -237,150 +561,250 @@ func batchGetGitInfo(dirs map[string]bool) map[string]gitInfo {
 		wg.Add(1)
 		go func(d string) {
 			defer wg.Done()
-			info := getGitInfoParallel(d)
+			info := getGitInfoParallel(ctx, d, nameSources, withLastCommit, withRemoteURL)
 			mu.Lock()
 			results[d] = info
 			mu.Unlock()
 		}(dir)
 	}
 
-	wg.Wait()
+	if !waitContext(ctx, &wg) {
+		return nil
+	}
 	return results
 }
 
-// batchGetProcessCWDs gets working directories for multiple PIDs in a single call
-func batchGetProcessCWDs(pids []int) map[int]string {
-	cwdMap := make(map[int]string)
+// waitContext waits for wg, giving up when ctx is done so that a goroutine
+// stuck on an unresponsive filesystem can't block detection forever. It
+// reports whether all goroutines finished.
+func waitContext(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
 
-	if len(pids) == 0 {
-		return cwdMap
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
 	}
+}
 
-	if platform.IsMacOS() {
-		// macOS: use lsof with comma-separated PIDs
-		pidStrs := make([]string, len(pids))
-		for i, pid := range pids {
-			pidStrs[i] = strconv.Itoa(pid)
+// resolveShim names the runtime behind a version manager launcher: the
+// binary it runs if that isn't the manager itself, otherwise the name it
+// was invoked as, since shims are links named after the tool
+func resolveShim(ctx context.Context, pid int, command string) string {
+	if exe, err := procinfo.Executable(ctx, pid); err == nil {
+		if name := filepath.Base(exe); !toolchain.IsShim(name) {
+			return name
+		}
+	}
+	if args, err := procinfo.CommandLine(ctx, pid); err == nil {
+		if name := filepath.Base(args[0]); !toolchain.IsShim(name) {
+			return name
 		}
-		pidList := strings.Join(pidStrs, ",")
+	}
+	return command
+}
 
-		cmd := exec.Command("lsof", "-a", "-p", pidList, "-d", "cwd", "-Fn")
-		output, err := cmd.Output()
-		if err != nil {
-			// If batch fails, fall back to individual lookups
-			return fallbackGetCWDs(pids)
-		}
-
-		// Parse lsof output: format is "p<pid>\nn<path>\np<pid>\nn<path>..."
-		scanner := bufio.NewScanner(strings.NewReader(string(output)))
-		var currentPID int
-		for scanner.Scan() {
-			line := scanner.Text()
-			if strings.HasPrefix(line, "p") {
-				pidStr := strings.TrimPrefix(line, "p")
-				pid, err := strconv.Atoi(pidStr)
-				if err == nil {
-					currentPID = pid
-				}
-			} else if strings.HasPrefix(line, "n") && currentPID != 0 {
-				cwd := strings.TrimPrefix(line, "n")
-				cleaned, err := filepath.Abs(cwd)
-				if err == nil {
-					cwdMap[currentPID] = cleaned
-				}
-				currentPID = 0 // Reset after processing
-			}
+// assignLabels names servers' ports after the services their repo
+// declares. Services such as a Compose-run postgres have no directory of
+// their own, so they take labels from the repos of the listed apps and
+// the one lsrv runs in.
+func assignLabels(servers []types.Server, currentDir string) {
+	byDir := make(map[string]portlabel.Labels)
+	find := func(dir string) portlabel.Labels {
+		if _, ok := byDir[dir]; !ok {
+			byDir[dir] = portlabel.Find(dir)
 		}
-	} else {
-		// Linux: read from /proc/<pid>/cwd for each PID (already fast)
-		for _, pid := range pids {
-			link := fmt.Sprintf("/proc/%d/cwd", pid)
-			info, err := os.Lstat(link)
-			if err != nil {
-				continue
-			}
-			if info.Mode()&os.ModeSymlink == 0 {
-				continue
-			}
+		return byDir[dir]
+	}
 
-			cwd, err := os.Readlink(link)
-			if err != nil {
-				continue
+	shared := make(portlabel.Labels)
+	if currentDir != "" {
+		shared = maps.Clone(find(currentDir))
+	}
+	for i := range servers {
+		cwd, deleted := staleCWD(servers[i].CWD)
+		if servers[i].Service != "" || cwd == "" || deleted {
+			continue
+		}
+		labels := find(cwd)
+		servers[i].Label = labels[servers[i].Port]
+		for port, name := range labels {
+			if _, ok := shared[port]; !ok {
+				shared[port] = name
 			}
+		}
+	}
 
-			cleaned, err := filepath.Abs(cwd)
-			if err != nil {
-				continue
-			}
-			cwdMap[pid] = cleaned
+	for i := range servers {
+		if servers[i].Service != "" {
+			servers[i].Label = shared[servers[i].Port]
 		}
 	}
+}
+
+// assignRunConfigs labels servers an IDE launched, which the repo's files
+// don't name, after the run configuration that started them
+func assignRunConfigs(ctx context.Context, servers []types.Server) {
+	for i := range servers {
+		cwd, deleted := staleCWD(servers[i].CWD)
+		if servers[i].Label != "" || servers[i].Service != "" || cwd == "" || deleted {
+			continue
+		}
+		servers[i].Label = runconfig.Find(ctx, servers[i].PID, servers[i].Port, cwd)
+	}
+}
 
-	return cwdMap
+// resolveProjectDirs replaces the working directory of PHP servers whose
+// command line names the project they serve, which otherwise drops those
+// started from outside the repo. Only newly found directories are
+// resolved; cached ones were resolved when first seen.
+func resolveProjectDirs(ctx context.Context, processes []processInfo, found, cwdMap map[int]string) {
+	for _, proc := range processes {
+		cwd, ok := found[proc.pid]
+		if !ok || !framework.HasProjectDir(proc.command) {
+			continue
+		}
+		if _, stale := staleCWD(cwd); stale {
+			continue
+		}
+		args, err := procinfo.CommandLine(ctx, proc.pid)
+		if err != nil {
+			continue
+		}
+		if dir := framework.ProjectDir(proc.command, cwd, args); dir != "" {
+			cwdMap[proc.pid] = dir
+		}
+	}
 }
 
-// fallbackGetCWDs handles individual CWD lookups if batch fails
-func fallbackGetCWDs(pids []int) map[int]string {
-	cwdMap := make(map[int]string)
-	for _, pid := range pids {
-		cwd, err := getProcessCWD(pid)
-		if err == nil && cwd != "" {
-			cwdMap[pid] = cwd
+// jsRuntimes are the runtimes that stand in for node, named after their
+// executable
+var jsRuntimes = []string{"bun", "deno"}
+
+// resolveJSRuntime names the runtime behind a "node" process. Bun puts a
+// node link to itself on the PATH of scripts it runs when Node isn't
+// installed, so a Vite server started with bun run dev shows as node.
+func resolveJSRuntime(ctx context.Context, pid int, command string) string {
+	if command != "node" {
+		return command
+	}
+	if exe, err := procinfo.Executable(ctx, pid); err == nil {
+		if name := filepath.Base(exe); slices.Contains(jsRuntimes, name) {
+			return name
 		}
 	}
-	return cwdMap
+	return command
 }
 
-func getProcessCWD(pid int) (string, error) {
-	// Validate PID is within reasonable bounds
-	if err := platform.ValidatePID(pid); err != nil {
-		return "", err
+// withoutPIDs drops the processes with one of pids
+func withoutPIDs(processes []processInfo, pids []int) []processInfo {
+	var kept []processInfo
+	for _, proc := range processes {
+		if !slices.Contains(pids, proc.pid) {
+			kept = append(kept, proc)
+		}
 	}
+	return kept
+}
 
-	if platform.IsMacOS() {
-		// macOS
-		cmd := exec.Command("lsof", "-a", "-p", strconv.Itoa(pid), "-d", "cwd", "-Fn")
-		output, err := cmd.Output()
-		if err != nil {
-			return "", err
-		}
-		scanner := bufio.NewScanner(strings.NewReader(string(output)))
-		for scanner.Scan() {
-			line := scanner.Text()
-			if strings.HasPrefix(line, "n") {
-				cwd := strings.TrimPrefix(line, "n")
-				// Clean and validate the path
-				cleaned, err := filepath.Abs(cwd)
-				if err != nil {
-					return "", err
-				}
-				return cleaned, nil
+// measureLatency sets the TTFB of app servers speaking HTTP, measuring
+// them concurrently. Servers that don't answer within the health check
+// timeout, such as ones stuck recompiling, are left without one.
+func measureLatency(ctx context.Context, servers []types.Server) {
+	checker := health.NewChecker(health.DefaultWorkers, health.DefaultTimeout)
+	var wg sync.WaitGroup
+	for i := range servers {
+		server := &servers[i]
+		if server.Service != "" || (server.Protocol != "" && server.Protocol != probe.ProtocolHTTP) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ttfb, err := checker.TTFB(ctx, *server); err == nil {
+				server.TTFB = ttfb
 			}
+		}()
+	}
+	wg.Wait()
+}
+
+// withoutIgnored drops the processes whose working directory, deleted or
+// not, the ignore list matches
+func withoutIgnored(processes []processInfo, cwdOf func(processInfo) string, list *ignore.List) []processInfo {
+	var kept []processInfo
+	for _, proc := range processes {
+		cwd, _ := staleCWD(cwdOf(proc))
+		if !list.Match(cwd) {
+			kept = append(kept, proc)
 		}
-	} else {
-		// Linux - validate the symlink exists first
-		link := fmt.Sprintf("/proc/%d/cwd", pid)
-		info, err := os.Lstat(link)
-		if err != nil {
-			return "", err
+	}
+	return kept
+}
+
+// startedSince keeps the processes that started at or after cutoff.
+// Processes whose start time can't be read are dropped, since they can't
+// be shown to be recent. Container port forwarders are kept: they start
+// with the container, so their start time says nothing about the server
+// behind them, which may have restarted since.
+//
+// The port history can't stand in for start times: it records when a repo
+// was first seen on a port, ever, so a server restarted on its usual port
+// wouldn't count as recent.
+func startedSince(ctx context.Context, processes []processInfo, cutoff time.Time) []processInfo {
+	var kept []processInfo
+	for _, proc := range processes {
+		if container.IsForwarder(proc.command) {
+			kept = append(kept, proc)
+			continue
 		}
-		if info.Mode()&os.ModeSymlink == 0 {
-			return "", fmt.Errorf("not a symlink: %s", link)
+		if started, err := procinfo.StartTime(ctx, proc.pid); err == nil && !started.Before(cutoff) {
+			kept = append(kept, proc)
 		}
+	}
+	return kept
+}
 
-		cwd, err := os.Readlink(link)
-		if err != nil {
-			return "", err
+// withinDirs keeps the processes whose working directory, deleted or not,
+// is one of dirs or below it
+func withinDirs(processes []processInfo, cwdOf func(processInfo) string, dirs []string) []processInfo {
+	var kept []processInfo
+	for _, proc := range processes {
+		if cwdOf(proc) == "" {
+			continue
 		}
-
-		// Clean and validate the resolved path
-		cleaned, err := filepath.Abs(cwd)
-		if err != nil {
-			return "", err
+		cwd, _ := staleCWD(cwdOf(proc))
+		for _, dir := range dirs {
+			if within(dir, cwd) {
+				kept = append(kept, proc)
+				break
+			}
 		}
-		return cleaned, nil
 	}
-	return "", fmt.Errorf("could not determine cwd")
+	return kept
+}
+
+// within reports whether path is dir or inside it
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// staleCWD reports whether a working directory no longer exists, returning
+// the path without the " (deleted)" marker Linux appends to it
+func staleCWD(cwd string) (string, bool) {
+	if dir, ok := strings.CutSuffix(cwd, " (deleted)"); ok {
+		return dir, true
+	}
+	if _, err := os.Stat(cwd); errors.Is(err, fs.ErrNotExist) {
+		return cwd, true
+	}
+	return cwd, false
 }
 
 // DetectProjectType identifies the project type by checking for marker files
@@ -395,6 +819,17 @@ func DetectProjectType(dir string) types.ProjectType {
 		return types.ProjectTypeRust
 	}
 
+	// Check for Deno and Bun projects before Node.js, as they often have a
+	// package.json too
+	if platform.FileExists(filepath.Join(dir, "deno.json")) || platform.FileExists(filepath.Join(dir, "deno.jsonc")) {
+		return types.ProjectTypeDeno
+	}
+	if platform.FileExists(filepath.Join(dir, "bunfig.toml")) ||
+		platform.FileExists(filepath.Join(dir, "bun.lockb")) ||
+		platform.FileExists(filepath.Join(dir, "bun.lock")) {
+		return types.ProjectTypeBun
+	}
+
 	// Check for Node.js project
 	if platform.FileExists(filepath.Join(dir, "package.json")) {
 		return types.ProjectTypeNode
@@ -412,11 +847,24 @@ func DetectProjectType(dir string) types.ProjectType {
 		return types.ProjectTypeRuby
 	}
 
+	// Check for Java project (Maven or Gradle)
+	if platform.FileExists(filepath.Join(dir, "pom.xml")) ||
+		platform.FileExists(filepath.Join(dir, "build.gradle")) ||
+		platform.FileExists(filepath.Join(dir, "build.gradle.kts")) {
+		return types.ProjectTypeJava
+	}
+
+	// Check for Elixir project
+	if platform.FileExists(filepath.Join(dir, "mix.exs")) {
+		return types.ProjectTypeElixir
+	}
+
 	return types.ProjectTypeUnknown
 }
 
-// getGitInfoParallel fetches git repo name and branch in parallel using goroutines
-func getGitInfoParallel(cwd string) gitInfo {
+// getGitInfoParallel fetches git repo name and branch (and optionally the
+// last commit time and remote URL) in parallel using goroutines
+func getGitInfoParallel(ctx context.Context, cwd string, nameSources []git.NameSource, withLastCommit, withRemoteURL bool) gitInfo {
 	var wg sync.WaitGroup
 	info := gitInfo{}
 
@@ -424,17 +872,35 @@ func getGitInfoParallel(cwd string) gitInfo {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		info.repo = git.GetRepoName(cwd)
+		info.repo = git.GetRepoName(ctx, cwd, nameSources)
 	}()
 
 	// Launch goroutine for branch
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		info.branch = git.GetBranch(cwd)
+		info.branch = git.GetBranch(ctx, cwd)
 	}()
 
-	// Wait for both to complete
+	if withLastCommit {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if t, err := git.GetLastCommitTime(ctx, cwd); err == nil {
+				info.lastCommit = &t
+			}
+		}()
+	}
+
+	if withRemoteURL {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info.remoteURL = git.GetRemoteWebURL(ctx, cwd)
+		}()
+	}
+
+	// Wait for all to complete
 	wg.Wait()
 	return info
 }
@@ -0,0 +1,246 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// syntheticLsof builds lsof -l output with n listeners mixing IPv4, IPv6,
+// wildcard binds, system ports and other users' processes
+func syntheticLsof(n int) []byte {
+	var b strings.Builder
+	b.WriteString("COMMAND     PID     USER   FD   TYPE             DEVICE SIZE/OFF NODE NAME\n")
+
+	for i := 0; i < n; i++ {
+		pid := 1000 + i
+		uid := 501
+		if i%10 == 9 {
+			uid = 0
+		}
+
+		var name string
+		switch i % 4 {
+		case 0:
+			name = fmt.Sprintf("127.0.0.1:%d", 3000+i)
+		case 1:
+			name = fmt.Sprintf("[::1]:%d", 3000+i)
+		case 2:
+			name = fmt.Sprintf("*:%d", 3000+i)
+		case 3:
+			name = fmt.Sprintf("*:%d", 22+i%100)
+		}
+
+		fmt.Fprintf(&b, "node      %7d %8d   23u  IPv4 0x%014x      0t0  TCP %s (LISTEN)\n", pid, uid, 0xabc000+i, name)
+	}
+	return []byte(b.String())
+}
+
+func TestParseListeners(t *testing.T) {
+	processes, errs := parseListeners(syntheticLsof(20), false, 501)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+
+	// 20 rows, 2 of them owned by root
+	if len(processes) != 18 {
+		t.Fatalf("got %d listeners, want 18", len(processes))
+	}
+
+	first := processes[0]
+	if first.pid != 1000 || first.port != 3000 || first.command != "node" || first.uid != 501 {
+		t.Errorf("unexpected first listener: %+v", first)
+	}
+	if processes[1].port != 3001 {
+		t.Errorf("IPv6 listener port = %d, want 3001", processes[1].port)
+	}
+
+	if all, _ := parseListeners(syntheticLsof(20), true, 501); len(all) != 20 {
+		t.Errorf("with allUsers got %d listeners, want 20", len(all))
+	}
+
+	apps, _ := splitServices(processes, false)
+	if len(apps) != 14 {
+		t.Errorf("got %d dev-port listeners, want 14", len(apps))
+	}
+}
+
+func TestParseLsofLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		command string
+		host    string
+		port    int
+		device  string
+		wantErr bool
+	}{
+		{"ipv4", "node      4242   501   23u  IPv4 0x1234      0t0  TCP 127.0.0.1:3000 (LISTEN)", "node", "127.0.0.1", 3000, "0x1234", false},
+		{"ipv6 brackets", "ruby      4242   501   12u  IPv6 0x5678      0t0  TCP [::1]:4000 (LISTEN)", "ruby", "[::1]", 4000, "0x5678", false},
+		{"wildcard", "python3   4242   501    3u  IPv4   7762      0t0  TCP *:8000 (LISTEN)", "python3", "*", 8000, "7762", false},
+		{"blank size", "node      4242   501   23u  IPv4 0x1234  TCP *:3001 (LISTEN)", "node", "*", 3001, "0x1234", false},
+		{"no state", "node      4242   501   23u  IPv4 0x1234      0t0  TCP *:3002", "node", "*", 3002, "0x1234", false},
+		{"escaped space", `Google\x20C 4242   501   23u  IPv4 0x1234      0t0  TCP *:3003 (LISTEN)`, "Google C", "*", 3003, "0x1234", false},
+		{"unescaped space", "Code Helper 4242 501 23u IPv4 0x1234 0t0 TCP *:3004 (LISTEN)", "Code Helper", "*", 3004, "0x1234", false},
+		{"connection", "node      4242   501   23u  IPv4 0x1234      0t0  TCP 127.0.0.1:3000->127.0.0.1:5432 (ESTABLISHED)", "", "", 0, "", true},
+		{"no port", "node      4242   501   23u  IPv4 0x1234      0t0  TCP localhost (LISTEN)", "", "", 0, "", true},
+		{"truncated", "node      4242   501", "", "", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row, err := parseLsofLine(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", row)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if row.command != tt.command || row.host != tt.host || row.port != tt.port || row.device != tt.device || row.pid != 4242 || row.uid != 501 {
+				t.Errorf("got %+v, want command %q host %q port %d device %q", row, tt.command, tt.host, tt.port, tt.device)
+			}
+		})
+	}
+}
+
+func TestFilterStage(t *testing.T) {
+	s := &scan{
+		opts:      Options{Dirs: []string{"/src/app"}},
+		processes: []processInfo{{pid: 1, port: 3000}, {pid: 2, port: 3001}},
+		servers:   []types.Server{{Service: "postgres", Port: 5432}},
+		cwds:      map[int]string{1: "/src/app/web", 2: "/src/other"},
+	}
+	if err := (filterStage{}).run(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.processes) != 1 || s.processes[0].pid != 1 {
+		t.Errorf("kept %+v, want only pid 1", s.processes)
+	}
+	if len(s.servers) != 0 {
+		t.Errorf("scoping kept services: %+v", s.servers)
+	}
+}
+
+// fakeStarts answers StartTime from a map, failing for the PIDs missing
+// from it as for processes of other users; other methods aren't used
+type fakeStarts struct {
+	procinfo.Inspector
+	starts map[int]time.Time
+}
+
+func (f fakeStarts) StartTime(ctx context.Context, pid int) (time.Time, error) {
+	if started, ok := f.starts[pid]; ok {
+		return started, nil
+	}
+	return time.Time{}, os.ErrPermission
+}
+
+func TestStartedSince(t *testing.T) {
+	now := time.Now()
+	saved := procinfo.Current
+	procinfo.Current = fakeStarts{starts: map[int]time.Time{
+		1: now.Add(-time.Minute),
+		2: now.Add(-time.Hour),
+		3: now.Add(-10 * time.Minute),
+		5: now.Add(-48 * time.Hour),
+	}}
+	t.Cleanup(func() { procinfo.Current = saved })
+
+	processes := []processInfo{
+		{pid: 1, command: "node"},
+		{pid: 2, command: "node"},
+		{pid: 3, command: "ruby"},   // exactly at the cutoff
+		{pid: 4, command: "python"}, // start time unreadable
+		{pid: 5, command: "docker-proxy"},
+	}
+	var pids []int
+	for _, proc := range startedSince(context.Background(), processes, now.Add(-10*time.Minute)) {
+		pids = append(pids, proc.pid)
+	}
+	if want := []int{1, 3, 5}; !slices.Equal(pids, want) {
+		t.Errorf("kept PIDs %v, want %v", pids, want)
+	}
+}
+
+func TestPipelineRunsEnrichersLast(t *testing.T) {
+	custom := NewEnricher("custom", func(ctx context.Context, servers []types.Server) {
+		for i := range servers {
+			servers[i].Label = "enriched"
+		}
+	})
+	stages := pipeline(Options{Tunnels: true, Enrichers: []Enricher{custom}})
+	if last := stages[len(stages)-1]; last.name() != "custom" {
+		t.Fatalf("last stage is %q, want the custom enricher", last.name())
+	}
+
+	s := &scan{servers: []types.Server{{Port: 3000}}}
+	if err := stages[len(stages)-1].run(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	if s.servers[0].Label != "enriched" {
+		t.Errorf("enricher didn't run on the scan's servers: %+v", s.servers[0])
+	}
+}
+
+func BenchmarkParseListeners(b *testing.B) {
+	for _, size := range []int{10, 100, 1000} {
+		output := syntheticLsof(size)
+		b.Run(fmt.Sprintf("rows=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				parseListeners(output, false, 501)
+			}
+		})
+	}
+}
+
+func BenchmarkGroupClusters(b *testing.B) {
+	for _, size := range []int{10, 100, 1000} {
+		processes, _ := parseListeners(syntheticLsof(size), true, 501)
+		b.Run(fmt.Sprintf("rows=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				groupClusters(context.Background(), processes)
+			}
+		})
+	}
+}
+
+// BenchmarkGitPhases measures the git subprocess phases against real
+// repositories, which typically dominate a run
+func BenchmarkGitPhases(b *testing.B) {
+	if _, err := exec.LookPath("git"); err != nil {
+		b.Skip("git not installed")
+	}
+
+	for _, size := range []int{1, 10, 30} {
+		dirs := make(map[string]bool)
+		root := b.TempDir()
+		for i := 0; i < size; i++ {
+			dir := filepath.Join(root, fmt.Sprintf("repo%d", i))
+			if err := exec.Command("git", "init", "-q", dir).Run(); err != nil {
+				b.Fatalf("git init: %v", err)
+			}
+			dirs[dir] = true
+		}
+
+		b.Run(fmt.Sprintf("repos=%d", size), func(b *testing.B) {
+			for b.Loop() {
+				batchCheckGitRepos(context.Background(), dirs)
+				batchGetGitInfo(context.Background(), dirs, nil, false, false)
+			}
+		})
+	}
+}
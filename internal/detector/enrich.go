@@ -0,0 +1,360 @@
+package detector
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/capability"
+	"github.com/bshakr/lsrv/internal/devcontainer"
+	"github.com/bshakr/lsrv/internal/devshell"
+	"github.com/bshakr/lsrv/internal/firewall"
+	"github.com/bshakr/lsrv/internal/lan"
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/probe"
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/session"
+	"github.com/bshakr/lsrv/internal/toolchain"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// Enricher adds facts to servers once detection has built them, such as
+// their health, resource use or container. Enrichers are the pipeline's
+// middleware: they run in order after the built-in ones, each timed as a
+// phase under its name, and only see the servers, so they can be tested on
+// hand-built ones.
+type Enricher interface {
+	Name() string
+	Enrich(ctx context.Context, servers []types.Server)
+}
+
+// funcEnricher is an Enricher backed by a function
+type funcEnricher struct {
+	name string
+	fn   func(context.Context, []types.Server)
+}
+
+func (e funcEnricher) Name() string { return e.name }
+
+func (e funcEnricher) Enrich(ctx context.Context, servers []types.Server) { e.fn(ctx, servers) }
+
+// NewEnricher returns an Enricher named name that runs fn
+func NewEnricher(name string, fn func(ctx context.Context, servers []types.Server)) Enricher {
+	return funcEnricher{name: name, fn: fn}
+}
+
+// processEnricher is an Enricher reading process details, which only
+// runs on the servers whose processes the current privileges can read
+// rather than failing on each of the others
+type processEnricher struct {
+	funcEnricher
+	caps capability.Set
+}
+
+func (e processEnricher) Enrich(ctx context.Context, servers []types.Server) {
+	if e.caps.OtherProcesses {
+		e.fn(ctx, servers)
+		return
+	}
+	var readable []types.Server
+	var indexes []int
+	for i, server := range servers {
+		if e.caps.Inspectable(server.UID) {
+			readable = append(readable, server)
+			indexes = append(indexes, i)
+		}
+	}
+	if len(readable) == 0 {
+		return
+	}
+	e.fn(ctx, readable)
+	for j, i := range indexes {
+		servers[i] = readable[j]
+	}
+}
+
+// newProcessEnricher returns a processEnricher named name that runs fn
+func newProcessEnricher(name string, fn func(context.Context, []types.Server)) Enricher {
+	return processEnricher{funcEnricher{name: name, fn: fn}, capability.Current()}
+}
+
+// LimitedPhases returns the detection phases opts runs that skip other
+// users' servers because their processes can't be read, for --debug
+func LimitedPhases(opts Options) []string {
+	if capability.Current().OtherProcesses {
+		return nil
+	}
+	phases := []string{cwdStage{}.name()}
+	for _, enricher := range builtinEnrichers(opts) {
+		if _, ok := enricher.(processEnricher); ok {
+			phases = append(phases, enricher.Name())
+		}
+	}
+	return phases
+}
+
+// builtinEnrichers returns the enrichers opts asks for, in the order they
+// run; later ones may use what earlier ones found, as the firewall check
+// uses LAN URLs
+func builtinEnrichers(opts Options) []Enricher {
+	enrichers := []Enricher{
+		NewEnricher("labels", func(ctx context.Context, servers []types.Server) {
+			assignLabels(servers, opts.CurrentDir)
+		}),
+		NewEnricher("run configs", assignRunConfigs),
+
+		// Reading each server's environment is cheap, so Nix shells are
+		// always marked; mixing them with servers started outside breaks
+		// native extensions in ways that are hard to trace otherwise
+		newProcessEnricher("nix", enrichNix),
+	}
+	// Inside a dev container, servers listen on the container's ports,
+	// which the host's browser reaches through the ones it forwards
+	if devcontainer.Inside() {
+		enrichers = append(enrichers, NewEnricher("dev container", enrichDevContainer))
+	}
+	if opts.Session {
+		enrichers = append(enrichers, newProcessEnricher("session", enrichSession))
+	}
+	if opts.StartedBy {
+		enrichers = append(enrichers, newProcessEnricher("started by", enrichStartedBy))
+	}
+	if opts.CommandLine {
+		enrichers = append(enrichers, newProcessEnricher("cmdline", enrichCommandLine))
+	}
+	if opts.Runtime {
+		enrichers = append(enrichers, newProcessEnricher("runtime", enrichRuntime))
+	}
+	if opts.Usage {
+		enrichers = append(enrichers, newProcessEnricher("usage", enrichUsage))
+	}
+	if opts.Env {
+		vars := opts.EnvVars
+		if vars == nil {
+			vars = devshell.DefaultVars
+		}
+		enrichers = append(enrichers, NewEnricher("env", func(ctx context.Context, servers []types.Server) {
+			enrichEnv(ctx, servers, vars)
+		}))
+	}
+	if opts.Probe {
+		enrichers = append(enrichers, NewEnricher("probe", enrichProtocol))
+	}
+	if opts.Latency {
+		enrichers = append(enrichers, NewEnricher("latency", measureLatency))
+	}
+	if opts.Backlog {
+		enrichers = append(enrichers, NewEnricher("backlog", enrichBacklog))
+	}
+	if opts.LAN {
+		enrichers = append(enrichers, NewEnricher("lan", func(ctx context.Context, servers []types.Server) {
+			enrichLAN(servers, opts.MDNS)
+		}))
+	}
+	if opts.Firewall {
+		enrichers = append(enrichers, NewEnricher("firewall", enrichFirewall))
+	}
+	return enrichers
+}
+
+// enrichNix marks servers started inside a Nix development shell
+func enrichNix(ctx context.Context, servers []types.Server) {
+	for i := range servers {
+		servers[i].Nix = devshell.Nix(ctx, servers[i].PID)
+	}
+}
+
+// enrichDevContainer maps the port of each app server whose project has a
+// devcontainer.json forwarding it to the host port it is forwarded to
+func enrichDevContainer(ctx context.Context, servers []types.Server) {
+	type found struct {
+		cfg devcontainer.Config
+		ok  bool
+	}
+	configs := make(map[string]found)
+	for i := range servers {
+		if servers[i].Service != "" || servers[i].CWD == "" {
+			continue
+		}
+		f, seen := configs[servers[i].CWD]
+		if !seen {
+			f.cfg, f.ok = devcontainer.Find(servers[i].CWD)
+			configs[servers[i].CWD] = f
+		}
+		hostPort, forwarded := f.cfg.Ports[servers[i].Port]
+		if !f.ok || !forwarded {
+			continue
+		}
+		name := f.cfg.Name
+		if name == "" {
+			name = servers[i].Repo
+		}
+		servers[i].DevContainer = &types.DevContainer{Name: name, ContainerPort: servers[i].Port, HostPort: hostPort}
+	}
+}
+
+// serverPIDs returns the PID of each server
+func serverPIDs(servers []types.Server) []int {
+	pids := make([]int, len(servers))
+	for i, server := range servers {
+		pids[i] = server.PID
+	}
+	return pids
+}
+
+// enrichSession sets the tmux pane or terminal owning each server
+func enrichSession(ctx context.Context, servers []types.Server) {
+	sessions := session.Resolve(ctx, serverPIDs(servers))
+	for i := range servers {
+		servers[i].Session = sessions[servers[i].PID]
+	}
+}
+
+// enrichStartedBy sets the chain of ancestors that launched each server
+func enrichStartedBy(ctx context.Context, servers []types.Server) {
+	chains := session.StartedBy(ctx, serverPIDs(servers))
+	for i := range servers {
+		servers[i].StartedBy = chains[servers[i].PID]
+	}
+}
+
+// enrichCommandLine sets each server's full command line
+func enrichCommandLine(ctx context.Context, servers []types.Server) {
+	for i := range servers {
+		if args, err := procinfo.CommandLine(ctx, servers[i].PID); err == nil {
+			servers[i].CommandLine = strings.Join(args, " ")
+		}
+	}
+}
+
+// enrichRuntime sets each server's runtime and version, read from its
+// executable
+func enrichRuntime(ctx context.Context, servers []types.Server) {
+	for i := range servers {
+		exe, err := procinfo.Executable(ctx, servers[i].PID)
+		if err != nil {
+			continue
+		}
+		if rt := toolchain.FromExecutable(exe); rt.Version != "" {
+			servers[i].Runtime = rt.String()
+		}
+	}
+}
+
+// enrichUsage sets each server's uptime and the resident memory of its
+// process tree, as lsrv details shows them
+func enrichUsage(ctx context.Context, servers []types.Server) {
+	all, err := procinfo.AllUsage(ctx)
+	if err != nil {
+		return
+	}
+	for i := range servers {
+		if started, err := procinfo.StartTime(ctx, servers[i].PID); err == nil {
+			servers[i].Uptime = time.Since(started)
+		}
+		servers[i].Memory = procinfo.TreeUsage(all, servers[i].PID).RSS
+	}
+}
+
+// enrichEnv sets each app server's direnv or devenv setup, with whether
+// the server started with it applied and the values of vars
+func enrichEnv(ctx context.Context, servers []types.Server, vars []string) {
+	setups := make(map[string]*types.Env)
+	for i := range servers {
+		if servers[i].Service != "" || servers[i].CWD == "" {
+			continue
+		}
+		setup, ok := setups[servers[i].CWD]
+		if !ok {
+			setup = devshell.Find(servers[i].CWD)
+			setups[servers[i].CWD] = setup
+		}
+		if setup == nil {
+			continue
+		}
+		env := *setup
+		// Other users' environments are unreadable; leave Loaded unknown
+		if environ, err := procinfo.Environ(ctx, servers[i].PID); err == nil {
+			devshell.Inspect(&env, environ, vars)
+		}
+		servers[i].Env = &env
+	}
+}
+
+// enrichProtocol identifies the protocol each app server speaks
+func enrichProtocol(ctx context.Context, servers []types.Server) {
+	var ports []int
+	for _, server := range servers {
+		if server.Service == "" {
+			ports = append(ports, server.Port)
+		}
+	}
+	protocols := probe.DetectAll(ctx, ports, probe.DefaultTimeout)
+	for i := range servers {
+		if servers[i].Service == "" {
+			servers[i].Protocol = protocols[servers[i].Port]
+		}
+	}
+}
+
+// enrichLAN sets the URL reaching each app server from other devices
+func enrichLAN(servers []types.Server, mdns bool) {
+	// Without a network there is nothing to reach; list servers anyway
+	resolver, err := lan.NewResolver(mdns)
+	if err != nil {
+		return
+	}
+	for i := range servers {
+		if servers[i].Service == "" {
+			servers[i].LANURL = resolver.URL(servers[i])
+		}
+	}
+}
+
+// enrichFirewall checks whether the firewall lets other devices reach the
+// servers with a LAN URL
+func enrichFirewall(ctx context.Context, servers []types.Server) {
+	checker := firewall.NewChecker(ctx)
+	for i := range servers {
+		if servers[i].LANURL != "" {
+			verdict := checker.Check(ctx, servers[i])
+			servers[i].Firewall = &verdict
+		}
+	}
+}
+
+// enrichBacklog sets the accept queue of each server from the kernel's
+// socket table. Of a server's IPv4 and IPv6 sockets, the fuller one counts.
+// Other systems don't report the queue, leaving it unset.
+func enrichBacklog(ctx context.Context, servers []types.Server) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+	sockets, err := platform.ListeningSockets()
+	if err != nil {
+		return
+	}
+
+	byPort := make(map[int]types.Backlog)
+	for _, sock := range sockets {
+		backlog := types.Backlog{Queued: sock.RxQueue, Limit: sock.Backlog}
+		if current, ok := byPort[sock.Port]; !ok || fuller(backlog, current) {
+			byPort[sock.Port] = backlog
+		}
+	}
+	for i := range servers {
+		if backlog, ok := byPort[servers[i].Port]; ok {
+			servers[i].Backlog = &backlog
+		}
+	}
+}
+
+// fuller reports whether queue a is closer to its limit than b, or holds
+// more connections when a limit is unknown
+func fuller(a, b types.Backlog) bool {
+	if a.Limit > 0 && b.Limit > 0 {
+		return a.Queued*b.Limit > b.Queued*a.Limit
+	}
+	return a.Queued > b.Queued
+}
@@ -0,0 +1,203 @@
+package detector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// lsofRow is one listening socket from lsof's table output
+type lsofRow struct {
+	command string
+	pid     int
+
+	// uid is -1 when lsof printed a login name instead of a UID
+	uid int
+
+	// device is the DEVICE column, identifying the socket
+	device string
+
+	// host is the listening address from NAME, such as "*" or "127.0.0.1"
+	host string
+	port int
+}
+
+// Listener is a process listening on a port, whether or not detection
+// lists it as a server
+type Listener struct {
+	PID     int
+	Command string
+
+	// Bind is the listening address, such as "*" or "127.0.0.1"
+	Bind string
+}
+
+// Listeners returns every process listening on port, including ones
+// outside any repository that detection leaves out. Each process is
+// returned once, even when it listens on several addresses.
+func Listeners(ctx context.Context, port int) ([]Listener, error) {
+	output, err := exec.CommandContext(ctx, "lsof", "-iTCP:"+strconv.Itoa(port), "-sTCP:LISTEN", "-n", "-P", "-l").Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	// lsof exits 1 without output when nothing listens
+	var exitErr *exec.ExitError
+	if err != nil && (!errors.As(err, &exitErr) || len(output) > 0) {
+		return nil, fmt.Errorf("failed to run lsof: %w", err)
+	}
+
+	rows, _ := parseLsof(output)
+	var listeners []Listener
+	seen := make(map[int]bool)
+	for _, row := range rows {
+		if row.port != port || seen[row.pid] {
+			continue
+		}
+		seen[row.pid] = true
+		listeners = append(listeners, Listener{PID: row.pid, Command: row.command, Bind: row.host})
+	}
+	return listeners, nil
+}
+
+// ParseError describes an lsof output line that could not be parsed
+type ParseError struct {
+	// Line is the 1-based line number in lsof's output
+	Line int
+
+	Text   string
+	Reason string
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("lsof line %d: %s: %q", e.Line, e.Reason, e.Text)
+}
+
+// parseLsof parses "lsof -iTCP -sTCP:LISTEN -n -P -l" output, returning the
+// rows it understood and an error for each line it could not
+func parseLsof(output []byte) ([]lsofRow, []ParseError) {
+	var rows []lsofRow
+	var errs []ParseError
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "COMMAND") {
+			continue
+		}
+
+		row, err := parseLsofLine(line)
+		if err != nil {
+			errs = append(errs, ParseError{Line: lineNum, Text: line, Reason: err.Error()})
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, errs
+}
+
+// parseLsofLine parses one row of the form
+//
+//	COMMAND PID USER FD TYPE DEVICE SIZE/OFF NODE NAME
+//
+// Columns are located relative to PID and the "TCP" NODE column rather than
+// by fixed index: SIZE/OFF is blank on some systems, NAME may contain a
+// state suffix, and unescaped spaces may appear in COMMAND.
+func parseLsofLine(line string) (lsofRow, error) {
+	fields := strings.Fields(line)
+
+	// COMMAND ends at the first numeric field, which is the PID
+	pidIndex := -1
+	for i := 1; i < len(fields); i++ {
+		if _, err := strconv.Atoi(fields[i]); err == nil {
+			pidIndex = i
+			break
+		}
+	}
+	if pidIndex < 0 {
+		return lsofRow{}, fmt.Errorf("no PID column")
+	}
+
+	nodeIndex := -1
+	for i := pidIndex + 1; i < len(fields); i++ {
+		if fields[i] == "TCP" {
+			nodeIndex = i
+			break
+		}
+	}
+	if nodeIndex < 0 {
+		return lsofRow{}, fmt.Errorf("no TCP node column")
+	}
+	if nodeIndex-pidIndex < 5 || nodeIndex == len(fields)-1 {
+		return lsofRow{}, fmt.Errorf("missing columns")
+	}
+
+	host, port, err := listenAddress(strings.Join(fields[nodeIndex+1:], " "))
+	if err != nil {
+		return lsofRow{}, err
+	}
+
+	pid, _ := strconv.Atoi(fields[pidIndex])
+	uid, err := strconv.Atoi(fields[pidIndex+1])
+	if err != nil {
+		uid = -1
+	}
+
+	return lsofRow{
+		command: unescapeLsof(strings.Join(fields[:pidIndex], " ")),
+		pid:     pid,
+		uid:     uid,
+		device:  fields[pidIndex+4],
+		host:    host,
+		port:    port,
+	}, nil
+}
+
+// listenAddress extracts the host and port from a NAME column such as
+// "*:3000", "127.0.0.1:3000 (LISTEN)" or "[::1]:3000"
+func listenAddress(name string) (string, int, error) {
+	address, _, _ := strings.Cut(name, " ")
+	if strings.Contains(address, "->") {
+		return "", 0, fmt.Errorf("connected socket, not a listener")
+	}
+
+	colon := strings.LastIndexByte(address, ':')
+	if colon < 0 {
+		return "", 0, fmt.Errorf("no port in NAME %q", name)
+	}
+	host := address[:colon]
+	if strings.HasPrefix(host, "[") != strings.HasSuffix(host, "]") {
+		return "", 0, fmt.Errorf("unbalanced IPv6 brackets in NAME %q", name)
+	}
+
+	port, err := strconv.Atoi(address[colon+1:])
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port in NAME %q", name)
+	}
+	return host, port, nil
+}
+
+// unescapeLsof decodes the \xNN escapes lsof uses for non-printable and
+// space characters in command names
+func unescapeLsof(s string) string {
+	if !strings.Contains(s, `\x`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if c, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
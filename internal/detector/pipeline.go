@@ -0,0 +1,573 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/capability"
+	"github.com/bshakr/lsrv/internal/container"
+	"github.com/bshakr/lsrv/internal/devcontainer"
+	"github.com/bshakr/lsrv/internal/devdns"
+	"github.com/bshakr/lsrv/internal/framework"
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/services"
+	"github.com/bshakr/lsrv/internal/toolchain"
+	"github.com/bshakr/lsrv/internal/tunnel"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// scan is the state detection builds up as it passes through the
+// pipeline's stages
+type scan struct {
+	opts        Options
+	invokingUID int
+
+	// output is lsof's raw listing
+	output []byte
+
+	// processes are the listeners still to be resolved into app servers
+	processes []processInfo
+
+	// servers holds the auxiliary services split off early, then every
+	// server once built
+	servers []types.Server
+
+	// agents are the tunnel agents among the listeners, with --tunnels
+	agents []tunnel.Agent
+
+	// cwds, isRepo and infos are the working directory of each process,
+	// whether each directory is in a work tree and its git details,
+	// partly carried over from an earlier Tracker refresh
+	cwds   map[int]string
+	isRepo map[string]bool
+	infos  map[string]gitInfo
+
+	// heads are the modification times of the HEAD files behind infos,
+	// read before them, for the Tracker to notice checkouts
+	heads map[string]time.Time
+
+	// roots maps directories found to be in a work tree to its root
+	roots map[string]string
+}
+
+// cwd returns the directory a listener serves: the one detection assigned
+// it, else its process's working directory
+func (s *scan) cwd(proc processInfo) string {
+	if proc.dir != "" {
+		return proc.dir
+	}
+	return s.cwds[proc.pid]
+}
+
+// stage is one step of detection, reading what earlier stages resolved
+// and leaving its results in the scan. Each is timed as a phase under its
+// name.
+type stage interface {
+	name() string
+	run(ctx context.Context, s *scan) error
+}
+
+// pipeline returns the stages detection runs for opts, in order: socket
+// enumeration, process resolution, working directories, scoping, git,
+// building and deduplicating servers, then the enrichers
+func pipeline(opts Options) []stage {
+	stages := []stage{
+		lsofStage{},
+		parseStage{},
+		cwdStage{},
+		devContainerStage{},
+		filterStage{},
+		gitCheckStage{},
+		gitInfoStage{},
+		buildStage{},
+		auxPortsStage{},
+	}
+	for _, enricher := range builtinEnrichers(opts) {
+		stages = append(stages, enricherStage{enricher})
+	}
+	if opts.Tunnels {
+		stages = append(stages, tunnelStage{})
+	}
+	for _, enricher := range opts.Enrichers {
+		stages = append(stages, enricherStage{enricher})
+	}
+	return stages
+}
+
+// FindServers discovers all running development servers
+func FindServers(opts Options) ([]types.Server, error) {
+	return FindServersContext(context.Background(), opts)
+}
+
+// FindServersContext discovers all running development servers, abandoning
+// detection and killing outstanding subprocesses when ctx is done
+func FindServersContext(ctx context.Context, opts Options) ([]types.Server, error) {
+	s := &scan{opts: opts, invokingUID: platform.InvokingUID()}
+	for _, st := range pipeline(opts) {
+		endPhase := opts.Timings.Start(st.name())
+		err := st.run(ctx, s)
+		endPhase()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.finish()
+	return s.servers, nil
+}
+
+// lsofStage enumerates the listening TCP sockets
+type lsofStage struct{}
+
+func (lsofStage) name() string { return "lsof" }
+
+func (lsofStage) run(ctx context.Context, s *scan) error {
+	// -l reports UIDs rather than login names so ownership is unambiguous
+	args := []string{"-iTCP", "-sTCP:LISTEN", "-n", "-P", "-l"}
+	cmd := exec.CommandContext(ctx, "lsof", args...)
+	if s.opts.Sudo {
+		cmd = exec.CommandContext(ctx, "sudo", append([]string{"lsof"}, args...)...)
+		// Let sudo prompt for a password on the terminal
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = os.Stderr
+	}
+	output, err := cmd.Output()
+	if s.opts.DumpRaw != nil {
+		if _, dumpErr := s.opts.DumpRaw.Write(output); dumpErr != nil {
+			return fmt.Errorf("failed to save raw lsof output: %w", dumpErr)
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run lsof: %w", err)
+	}
+	s.output = output
+	return nil
+}
+
+// parseStage turns the listing into processes, folding cluster workers
+// into their master and splitting off auxiliary services
+type parseStage struct{}
+
+func (parseStage) name() string { return "parse" }
+
+func (parseStage) run(ctx context.Context, s *scan) error {
+	processes, parseErrs := parseListeners(s.output, s.opts.AllUsers, s.invokingUID)
+	if s.opts.Report != nil {
+		s.opts.Report.ParseErrors = parseErrs
+		visible, _ := parseListeners(s.output, true, s.invokingUID)
+		s.opts.Report.PermissionDenied = countHiddenListeners(visible, s.opts.AllUsers, s.invokingUID)
+	}
+
+	// Collapse cluster workers sharing a socket into their master process
+	processes = groupClusters(ctx, processes)
+
+	// Tunnel agents aren't dev servers, but their APIs know the public URLs
+	// forwarding to the ones that are
+	if s.opts.Tunnels {
+		for _, proc := range processes {
+			if tunnel.IsAgent(proc.command) {
+				s.agents = append(s.agents, tunnel.Agent{PID: proc.pid, Command: proc.command, Port: proc.port})
+			}
+		}
+	}
+
+	// Split off auxiliary services before the expensive CWD and git work
+	s.processes, s.servers = splitServices(processes, s.opts.Services)
+	return nil
+}
+
+// cwdStage finds the working directory of each process in one batch,
+// dropping processes that exited meanwhile
+type cwdStage struct{}
+
+func (cwdStage) name() string { return "cwd batch" }
+
+func (cwdStage) run(ctx context.Context, s *scan) error {
+	// Reuse results for processes seen in an earlier Tracker refresh
+	s.cwds, s.isRepo, s.infos, s.heads = s.opts.cache.lookup(s.processes, s.opts.LastCommit)
+
+	// Other users' directories are left unknown when they can't be read,
+	// rather than asked for one process at a time
+	caps := capability.Current()
+	var pids []int
+	for _, proc := range s.processes {
+		if _, ok := s.cwds[proc.pid]; !ok && caps.Inspectable(proc.uid) {
+			pids = append(pids, proc.pid)
+		}
+	}
+
+	found, exited := procinfo.CWDs(ctx, pids)
+	for pid, cwd := range found {
+		s.cwds[pid] = cwd
+	}
+	resolveProjectDirs(ctx, s.processes, found, s.cwds)
+
+	// Processes that exited mid-scan are gone, not servers to resolve
+	if len(exited) > 0 {
+		s.processes = withoutPIDs(s.processes, exited)
+		if s.opts.Report != nil {
+			s.opts.Report.Exited = len(exited)
+		}
+	}
+	return nil
+}
+
+// devContainerStage assigns the ports a container runtime's forwarder
+// publishes for a running dev container to the folder it was opened from,
+// so they list under that repository with the container port they reach
+type devContainerStage struct{}
+
+func (devContainerStage) name() string { return "dev containers" }
+
+func (devContainerStage) run(ctx context.Context, s *scan) error {
+	// Only ask the container runtime when something forwards a port
+	if !slices.ContainsFunc(s.processes, func(proc processInfo) bool { return container.IsForwarder(proc.command) }) {
+		return nil
+	}
+	running := devcontainer.Running(ctx)
+	for i, proc := range s.processes {
+		// Databases and caches the container publishes stay services
+		if _, isService := services.MatchPort(proc.port); isService || !container.IsForwarder(proc.command) {
+			continue
+		}
+		for _, c := range running {
+			if containerPort, ok := c.Ports[proc.port]; ok {
+				s.processes[i].dir = c.Folder
+				s.processes[i].devContainer = &types.DevContainer{Name: c.Name, ContainerPort: containerPort, HostPort: proc.port}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// filterStage drops ignored processes, the ones that started before the
+// Since window and, when scoped to directories, the ones elsewhere, before
+// any git work is spent on them
+type filterStage struct{}
+
+func (filterStage) name() string { return "filter" }
+
+func (filterStage) run(ctx context.Context, s *scan) error {
+	if s.opts.Ignore != nil {
+		s.processes = withoutIgnored(s.processes, s.cwd, s.opts.Ignore)
+	}
+	if s.opts.Since > 0 {
+		cutoff := time.Now().Add(-s.opts.Since)
+		s.processes = startedSince(ctx, s.processes, cutoff)
+		s.servers = slices.DeleteFunc(s.servers, func(server types.Server) bool {
+			started, err := procinfo.StartTime(ctx, server.PID)
+			return err != nil || started.Before(cutoff)
+		})
+	}
+
+	// Services recognized by process name belong to the machine rather than
+	// a repo, so scoping drops them along with listeners elsewhere
+	if s.opts.Dirs != nil {
+		s.processes = withinDirs(s.processes, s.cwd, s.opts.Dirs)
+		s.servers = nil
+	}
+	return nil
+}
+
+// gitCheckStage finds which working directories are in a work tree, and
+// its root
+type gitCheckStage struct{}
+
+func (gitCheckStage) name() string { return "git checks" }
+
+func (gitCheckStage) run(ctx context.Context, s *scan) error {
+	// Collect unique CWDs not already checked
+	uniqueCWDs := make(map[string]bool)
+	for _, proc := range s.processes {
+		cwd := s.cwd(proc)
+		if _, known := s.isRepo[cwd]; cwd != "" && !known {
+			uniqueCWDs[cwd] = true
+		}
+	}
+
+	checkRepos := batchCheckGitRepos
+	if s.opts.NoGit {
+		checkRepos = batchCheckWorkTrees
+	}
+	s.roots = make(map[string]string)
+	for dir, isRepo := range checkRepos(ctx, uniqueCWDs) {
+		if isRepo {
+			s.roots[dir], isRepo = repoRoot(dir, s.opts.MaxDepth)
+		}
+		s.isRepo[dir] = isRepo
+	}
+	return nil
+}
+
+// gitInfoStage reads the repo name and branch of each new work tree
+type gitInfoStage struct{}
+
+func (gitInfoStage) name() string { return "git info" }
+
+func (gitInfoStage) run(ctx context.Context, s *scan) error {
+	// Collect git repos that passed the check for batch git info fetching
+	gitRepoDirs := make(map[string]bool)
+	for dir, isRepo := range s.isRepo {
+		if _, known := s.infos[dir]; isRepo && !known {
+			gitRepoDirs[dir] = true
+		}
+		// Repos carried over from an earlier refresh, read again after a
+		// checkout, weren't checked in this one
+		if _, found := s.roots[dir]; isRepo && !found {
+			s.roots[dir], _ = repoRoot(dir, s.opts.MaxDepth)
+		}
+	}
+
+	// Noted before reading, so a checkout in between isn't missed
+	if s.opts.cache != nil {
+		for dir := range gitRepoDirs {
+			s.heads[dir], _ = git.HeadModTime(dir)
+		}
+	}
+
+	if s.opts.NoGit {
+		for dir := range gitRepoDirs {
+			s.infos[dir] = gitInfo{repo: filepath.Base(s.roots[dir]), branch: "-", root: s.roots[dir]}
+		}
+	} else {
+		// Packages of a monorepo share their work tree's git info
+		rootDirs := make(map[string]bool)
+		for dir := range gitRepoDirs {
+			rootDirs[s.roots[dir]] = true
+		}
+		infos := batchGetGitInfo(ctx, rootDirs, s.opts.RepoNames, s.opts.LastCommit, s.opts.RemoteURL)
+		for dir := range gitRepoDirs {
+			if info, ok := infos[s.roots[dir]]; ok {
+				info.root = s.roots[dir]
+				s.infos[dir] = info
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	s.opts.cache.store(s.processes, s.cwds, s.isRepo, s.infos, s.heads, s.opts.LastCommit)
+	return nil
+}
+
+// buildStage resolves each remaining process into a server row: the
+// runtime behind shims, its repo and branch, framework and URLs, merging
+// listeners by the dedup strategy
+type buildStage struct{}
+
+func (buildStage) name() string { return "build" }
+
+func (buildStage) run(ctx context.Context, s *scan) error {
+	opts := s.opts
+
+	// Load local DNS/proxy configuration once for friendly URLs
+	resolver := devdns.Load()
+
+	seenServers := make(map[string]bool)
+	pathsCache := make(map[string][]string)
+
+	for _, proc := range s.processes {
+		cwd := s.cwd(proc)
+
+		// A server whose directory is gone still holds its port; report it
+		// rather than dropping it for not being in a git repo
+		if cwd != "" {
+			if dir, stale := staleCWD(cwd); stale {
+				s.servers = append(s.servers, types.Server{
+					Repo:    filepath.Base(dir),
+					Branch:  "-",
+					Process: proc.command,
+					Port:    proc.port,
+					Bind:    proc.bind,
+					PID:     proc.pid,
+					CWD:     dir,
+					User:    platform.UserName(proc.uid),
+					UID:     proc.uid,
+					Workers: proc.workers,
+					Status:  types.StatusZombie,
+				})
+				if opts.Report != nil {
+					opts.Report.Zombies++
+				}
+				continue
+			}
+		}
+
+		// Outside a git repo, a listener on a standard service port is most
+		// likely that service (e.g., a Docker-forwarded database)
+		if opts.Services && (cwd == "" || !s.isRepo[cwd]) {
+			if name, ok := services.MatchPort(proc.port); ok {
+				s.servers = append(s.servers, serviceServer(proc, name))
+				continue
+			}
+		}
+
+		if cwd == "" {
+			// Other users' working directories are often unreadable; still
+			// report the listener rather than hiding it
+			if opts.AllUsers && proc.uid != s.invokingUID {
+				s.servers = append(s.servers, types.Server{
+					Repo:    "?",
+					Branch:  "-",
+					Process: proc.command,
+					Port:    proc.port,
+					Bind:    proc.bind,
+					PID:     proc.pid,
+					User:    platform.UserName(proc.uid),
+					UID:     proc.uid,
+					Workers: proc.workers,
+				})
+			}
+			continue
+		}
+
+		// Only show servers in git repositories (use cached result)
+		if !s.isRepo[cwd] {
+			continue
+		}
+
+		// Get repo name and branch from cache
+		info, ok := s.infos[cwd]
+		if !ok {
+			continue
+		}
+		if slices.Contains(opts.SkipBranches, info.branch) {
+			continue
+		}
+
+		// A version manager launcher still holding the socket names nothing
+		// useful; show the runtime it started instead
+		if toolchain.IsShim(proc.command) {
+			proc.command = resolveShim(ctx, proc.pid, proc.command)
+		}
+		proc.command = resolveJSRuntime(ctx, proc.pid, proc.command)
+
+		if key := dedupKey(opts.Dedup, info, proc); key != "" {
+			if seenServers[key] {
+				continue
+			}
+			seenServers[key] = true
+		}
+
+		server := types.Server{
+			Repo:    info.repo,
+			Branch:  info.branch,
+			Package: packagePath(info.root, cwd),
+			Process: proc.command,
+			Port:    proc.port,
+			Bind:    proc.bind,
+			PID:     proc.pid,
+			CWD:     cwd,
+			User:    platform.UserName(proc.uid),
+			UID:     proc.uid,
+			Workers: proc.workers,
+
+			LastCommit: info.lastCommit,
+			RemoteURL:  info.remoteURL,
+
+			DevContainer: proc.devContainer,
+		}
+		server.FriendlyURL = resolver.FriendlyURL(server)
+		server.Framework = framework.Name(proc.command, cwd, func() []string {
+			args, _ := procinfo.CommandLine(ctx, proc.pid)
+			return args
+		})
+		if template, ok := opts.URLTemplates[server.Framework]; ok && server.Framework != "" {
+			server.CustomURL = server.ExpandURL(template)
+		} else if template, ok := opts.URLTemplates[server.Process]; ok {
+			server.CustomURL = server.ExpandURL(template)
+		}
+		if _, ok := pathsCache[cwd]; !ok {
+			pathsCache[cwd] = framework.Paths(cwd)
+		}
+		server.Paths = pathsCache[cwd]
+		s.servers = append(s.servers, server)
+	}
+	return nil
+}
+
+// auxPortsStage folds the extra ports of JavaScript dev servers into the
+// app's row
+type auxPortsStage struct{}
+
+func (auxPortsStage) name() string { return "aux ports" }
+
+func (auxPortsStage) run(ctx context.Context, s *scan) error {
+	s.servers = foldAuxPorts(ctx, s.servers, s.opts.AllPorts)
+	return nil
+}
+
+// tunnelStage asks the tunnel agents found among the listeners for the
+// public URLs forwarding to each server
+type tunnelStage struct{}
+
+func (tunnelStage) name() string { return "tunnels" }
+
+func (tunnelStage) run(ctx context.Context, s *scan) error {
+	tunnels := tunnel.Find(ctx, s.agents, tunnel.DefaultTimeout)
+	for i := range s.servers {
+		s.servers[i].Tunnels = tunnels[s.servers[i].Port]
+	}
+	return nil
+}
+
+// enricherStage runs an Enricher as a pipeline stage
+type enricherStage struct {
+	enricher Enricher
+}
+
+func (e enricherStage) name() string { return e.enricher.Name() }
+
+func (e enricherStage) run(ctx context.Context, s *scan) error {
+	e.enricher.Enrich(ctx, s.servers)
+	return nil
+}
+
+// finish marks statuses and the servers in the current directory, and
+// sorts the servers
+func (s *scan) finish() {
+	servers, opts := s.servers, s.opts
+	assignStatus(servers, time.Now())
+
+	if opts.CurrentDir != "" {
+		for i := range servers {
+			cwd, _ := staleCWD(servers[i].CWD)
+			servers[i].Current = servers[i].Service == "" && cwd != "" && within(opts.CurrentDir, cwd)
+		}
+	}
+
+	// Sort app servers by repo, branch, port, followed by services by name
+	sort.Slice(servers, func(i, j int) bool {
+		if opts.CurrentFirst && servers[i].Current != servers[j].Current {
+			return servers[i].Current
+		}
+		if servers[i].Service != servers[j].Service {
+			return servers[i].Service < servers[j].Service
+		}
+		if servers[i].Repo != servers[j].Repo {
+			return servers[i].Repo < servers[j].Repo
+		}
+		if servers[i].Branch != servers[j].Branch {
+			return servers[i].Branch < servers[j].Branch
+		}
+		// Auxiliary ports follow the app they belong to
+		if pi, pj := primaryPort(servers[i]), primaryPort(servers[j]); pi != pj {
+			return pi < pj
+		}
+		return servers[i].Port < servers[j].Port
+	})
+}
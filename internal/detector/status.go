@@ -0,0 +1,38 @@
+package detector
+
+import (
+	"time"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// staleAfter is how long a branch may go without commits before its server
+// is considered stale
+const staleAfter = 30 * 24 * time.Hour
+
+// assignStatus sets the status of each server that doesn't have one yet:
+// conflict when another process listens on the same port, stale when the
+// branch's last commit (if fetched) is older than staleAfter, and healthy
+// otherwise
+func assignStatus(servers []types.Server, now time.Time) {
+	pidsByPort := make(map[int]map[int]bool)
+	for _, server := range servers {
+		if pidsByPort[server.Port] == nil {
+			pidsByPort[server.Port] = make(map[int]bool)
+		}
+		pidsByPort[server.Port][server.PID] = true
+	}
+
+	for i := range servers {
+		server := &servers[i]
+		switch {
+		case server.Status != "":
+		case len(pidsByPort[server.Port]) > 1:
+			server.Status = types.StatusConflict
+		case server.LastCommit != nil && now.Sub(*server.LastCommit) > staleAfter:
+			server.Status = types.StatusStale
+		default:
+			server.Status = types.StatusHealthy
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package detector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// Tracker runs detection repeatedly, as in watch mode or behind the HTTP
+// API. Each refresh still enumerates sockets, but working directory and git
+// lookups only run for processes that weren't listening in the previous
+// refresh; processes that exit drop out. Git details are read again when
+// the repository's HEAD changes, as on a checkout.
+type Tracker struct {
+	mu    sync.Mutex
+	cache pidCache
+}
+
+// Refresh discovers running servers, reusing results from earlier refreshes.
+// A nil Tracker runs a plain FindServersContext.
+func (t *Tracker) Refresh(ctx context.Context, opts Options) ([]types.Server, error) {
+	if t == nil {
+		return FindServersContext(ctx, opts)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	opts.cache = &t.cache
+	return FindServersContext(ctx, opts)
+}
+
+// pidCache remembers the working directory and git details of each
+// listening process between refreshes
+type pidCache struct {
+	entries map[int]cacheEntry
+}
+
+type cacheEntry struct {
+	// command and socket guard against a recycled PID
+	command string
+	socket  string
+
+	cwd    string
+	isRepo bool
+	info   gitInfo
+
+	// head is the modification time of the repository's HEAD file when
+	// info was read; a checkout changes it
+	head time.Time
+
+	// withLastCommit records whether info includes the last commit time
+	withLastCommit bool
+}
+
+// lookup returns the cached CWDs, git checks and git info for processes seen
+// in the previous refresh, with the HEAD modification times the git info
+// was read at. A nil cache returns empty maps.
+func (c *pidCache) lookup(processes []processInfo, withLastCommit bool) (map[int]string, map[string]bool, map[string]gitInfo, map[string]time.Time) {
+	cwds := make(map[int]string)
+	isRepo := make(map[string]bool)
+	infos := make(map[string]gitInfo)
+	heads := make(map[string]time.Time)
+	if c == nil {
+		return cwds, isRepo, infos, heads
+	}
+
+	for _, proc := range processes {
+		entry, ok := c.entries[proc.pid]
+		if !ok || entry.command != proc.command || entry.socket != proc.socket {
+			continue
+		}
+		if withLastCommit && entry.isRepo && !entry.withLastCommit {
+			continue
+		}
+
+		cwds[proc.pid] = entry.cwd
+		if entry.cwd == "" {
+			continue
+		}
+		isRepo[entry.cwd] = entry.isRepo
+		if !entry.isRepo {
+			continue
+		}
+		// Leave the git info to be read again after a checkout
+		head, seen := heads[entry.cwd]
+		if !seen {
+			head, _ = git.HeadModTime(entry.cwd)
+			heads[entry.cwd] = head
+		}
+		if head.Equal(entry.head) {
+			infos[entry.cwd] = entry.info
+		}
+	}
+	return cwds, isRepo, infos, heads
+}
+
+// store replaces the cache with the results for the current processes, so
+// entries for exited processes are dropped. heads holds the HEAD
+// modification times read before the git info, so a checkout racing the
+// scan shows on the next refresh.
+func (c *pidCache) store(processes []processInfo, cwds map[int]string, isRepo map[string]bool, infos map[string]gitInfo, heads map[string]time.Time, withLastCommit bool) {
+	if c == nil {
+		return
+	}
+
+	entries := make(map[int]cacheEntry, len(processes))
+	for _, proc := range processes {
+		cwd, ok := cwds[proc.pid]
+		if !ok {
+			// Lookup failed; retry on the next refresh
+			continue
+		}
+		entries[proc.pid] = cacheEntry{
+			command:        proc.command,
+			socket:         proc.socket,
+			cwd:            cwd,
+			isRepo:         isRepo[cwd],
+			info:           infos[cwd],
+			head:           heads[cwd],
+			withLastCommit: withLastCommit,
+		}
+	}
+	c.entries = entries
+}
@@ -0,0 +1,169 @@
+package detector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/procinfo"
+)
+
+// fakeCWDs answers CWDs from a map, reporting the PIDs missing from it as
+// exited, and records which PIDs were asked for; other methods aren't used
+type fakeCWDs struct {
+	procinfo.Inspector
+	cwds  map[int]string
+	asked []int
+}
+
+func (f *fakeCWDs) CWDs(ctx context.Context, pids []int) (map[int]string, []int) {
+	found := make(map[int]string)
+	var exited []int
+	for _, pid := range pids {
+		f.asked = append(f.asked, pid)
+		if cwd, ok := f.cwds[pid]; ok {
+			found[pid] = cwd
+		} else {
+			exited = append(exited, pid)
+		}
+	}
+	return found, exited
+}
+
+// refresh runs the stages that read and fill the cache over processes,
+// as a Tracker refresh would after lsof, and returns the scan and the PIDs
+// whose working directory had to be looked up
+func refresh(t *testing.T, cache *pidCache, processes []processInfo, cwds map[int]string) (*scan, []int) {
+	t.Helper()
+	fake := &fakeCWDs{cwds: cwds}
+	saved := procinfo.Current
+	procinfo.Current = fake
+	t.Cleanup(func() { procinfo.Current = saved })
+
+	s := &scan{opts: Options{NoGit: true, cache: cache}, processes: processes}
+	for _, st := range []stage{cwdStage{}, gitCheckStage{}, gitInfoStage{}} {
+		if err := st.run(context.Background(), s); err != nil {
+			t.Fatalf("%s: %v", st.name(), err)
+		}
+	}
+	slices.Sort(fake.asked)
+	return s, fake.asked
+}
+
+// tempRepo creates a work tree and returns its directory
+func tempRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestPIDCacheRecycledPID(t *testing.T) {
+	repo := tempRepo(t)
+	uid := os.Geteuid()
+	cwds := map[int]string{1: repo, 2: repo, 3: repo}
+	cache := &pidCache{}
+
+	_, asked := refresh(t, cache, []processInfo{
+		{pid: 1, command: "server", socket: "0xa", uid: uid},
+		{pid: 2, command: "server", socket: "0xb", uid: uid},
+		{pid: 3, command: "server", socket: "0xc", uid: uid},
+	}, cwds)
+	if want := []int{1, 2, 3}; !slices.Equal(asked, want) {
+		t.Fatalf("first refresh looked up %v, want %v", asked, want)
+	}
+
+	// PID 2 now runs another command and PID 3 holds another socket, as
+	// when the PIDs were reused by new processes
+	_, asked = refresh(t, cache, []processInfo{
+		{pid: 1, command: "server", socket: "0xa", uid: uid},
+		{pid: 2, command: "worker", socket: "0xb", uid: uid},
+		{pid: 3, command: "server", socket: "0xd", uid: uid},
+	}, cwds)
+	if want := []int{2, 3}; !slices.Equal(asked, want) {
+		t.Errorf("second refresh looked up %v, want %v", asked, want)
+	}
+	if entry := cache.entries[2]; entry.command != "worker" {
+		t.Errorf("cache kept command %q for the recycled PID, want worker", entry.command)
+	}
+}
+
+func TestPIDCacheHeadChange(t *testing.T) {
+	repo := tempRepo(t)
+	processes := []processInfo{{pid: 1, command: "server", socket: "0xa", uid: os.Geteuid()}}
+	cwds := map[int]string{1: repo}
+	cache := &pidCache{}
+
+	refresh(t, cache, processes, cwds)
+
+	// Mark the cached git info to tell reuse from a fresh read, which
+	// reports branch "-" without git
+	entry := cache.entries[1]
+	entry.info.branch = "main"
+	cache.entries[1] = entry
+
+	s, asked := refresh(t, cache, processes, cwds)
+	if len(asked) != 0 {
+		t.Errorf("unchanged refresh looked up %v, want none", asked)
+	}
+	if got := s.infos[repo].branch; got != "main" {
+		t.Errorf("unchanged refresh read branch %q, want the cached main", got)
+	}
+
+	// A checkout rewrites HEAD
+	head := filepath.Join(repo, ".git", "HEAD")
+	checkout := entry.head.Add(time.Minute)
+	if err := os.Chtimes(head, checkout, checkout); err != nil {
+		t.Fatal(err)
+	}
+
+	s, asked = refresh(t, cache, processes, cwds)
+	if len(asked) != 0 {
+		t.Errorf("refresh after checkout looked up %v, want the cached directory", asked)
+	}
+	if got := s.infos[repo].branch; got != "-" {
+		t.Errorf("refresh after checkout kept branch %q, want it read again", got)
+	}
+	if got := cache.entries[1].head; !got.Equal(checkout) {
+		t.Errorf("cached HEAD time %v, want %v", got, checkout)
+	}
+}
+
+func TestPIDCacheDropsExited(t *testing.T) {
+	repo := tempRepo(t)
+	uid := os.Geteuid()
+	cache := &pidCache{}
+
+	refresh(t, cache, []processInfo{
+		{pid: 1, command: "server", socket: "0xa", uid: uid},
+		{pid: 2, command: "server", socket: "0xb", uid: uid},
+		{pid: 3, command: "server", socket: "0xc", uid: uid},
+	}, map[int]string{1: repo, 2: repo, 3: repo})
+
+	// PID 3 stopped listening, and PID 4 exited after lsof listed it
+	s, _ := refresh(t, cache, []processInfo{
+		{pid: 1, command: "server", socket: "0xa", uid: uid},
+		{pid: 2, command: "server", socket: "0xb", uid: uid},
+		{pid: 4, command: "server", socket: "0xd", uid: uid},
+	}, map[int]string{1: repo, 2: repo})
+
+	var pids []int
+	for pid := range cache.entries {
+		pids = append(pids, pid)
+	}
+	slices.Sort(pids)
+	if want := []int{1, 2}; !slices.Equal(pids, want) {
+		t.Errorf("cached PIDs %v, want %v", pids, want)
+	}
+	if len(s.processes) != 2 {
+		t.Errorf("scan kept %d processes, want the 2 still running", len(s.processes))
+	}
+}
@@ -0,0 +1,244 @@
+// Package devcontainer maps the ports of servers running in a dev container
+// between the container and the host, from devcontainer.json and the
+// running containers' labels
+package devcontainer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/jsonc"
+)
+
+// configFiles are where a project keeps its dev container configuration,
+// relative to its root; the first one found is used
+var configFiles = []string{
+	".devcontainer/devcontainer.json",
+	".devcontainer.json",
+	".devcontainer/*/devcontainer.json",
+}
+
+// folderLabel is the label the Dev Containers extension and CLI put on a
+// container, naming the host directory it was opened from
+const folderLabel = "devcontainer.local_folder"
+
+// runtimes are asked in order for running dev containers
+var runtimes = []string{"docker", "podman"}
+
+// Config is what a devcontainer.json says about the container's ports
+type Config struct {
+	// Name is the container's display name, if the file gives one
+	Name string
+
+	// File is the devcontainer.json read
+	File string
+
+	// Ports maps container ports to the host ports reaching them: appPort
+	// publishes "3001:3000" as given, forwardPorts forward to the same port
+	Ports map[int]int
+}
+
+// file is the part of devcontainer.json lsrv reads
+type file struct {
+	Name         string            `json:"name"`
+	ForwardPorts []json.RawMessage `json:"forwardPorts"`
+	AppPort      json.RawMessage   `json:"appPort"`
+}
+
+// Inside reports whether lsrv runs inside a dev container or codespace,
+// where the ports servers listen on are the container's
+func Inside() bool {
+	return os.Getenv("REMOTE_CONTAINERS") == "true" || os.Getenv("CODESPACES") == "true"
+}
+
+// Find returns the dev container configuration of the project containing
+// dir, looking in dir and its parents up to the work tree root. It reports
+// false when there is none or it can't be read.
+func Find(dir string) (Config, bool) {
+	for {
+		if cfg, ok := read(dir); ok {
+			return cfg, true
+		}
+		_, err := os.Stat(filepath.Join(dir, ".git"))
+		parent := filepath.Dir(dir)
+		if err == nil || parent == dir {
+			return Config{}, false
+		}
+		dir = parent
+	}
+}
+
+// read returns the dev container configuration kept in dir
+func read(dir string) (Config, bool) {
+	for _, pattern := range configFiles {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		for _, path := range matches {
+			var f file
+			if !jsonc.ReadFile(path, &f) {
+				continue
+			}
+			cfg := Config{Name: f.Name, File: path, Ports: make(map[int]int)}
+			for _, raw := range f.ForwardPorts {
+				if port := forwardedPort(raw); port > 0 {
+					cfg.Ports[port] = port
+				}
+			}
+			for _, mapping := range appPorts(f.AppPort) {
+				if host, container, ok := parseMapping(mapping); ok {
+					cfg.Ports[container] = host
+				}
+			}
+			return cfg, true
+		}
+	}
+	return Config{}, false
+}
+
+// forwardedPort reads a forwardPorts entry: a port, or "service:port" for
+// a port of another service in a Docker Compose setup
+func forwardedPort(raw json.RawMessage) int {
+	var port int
+	if json.Unmarshal(raw, &port) == nil {
+		return port
+	}
+	var entry string
+	if json.Unmarshal(raw, &entry) != nil {
+		return 0
+	}
+	_, after, _ := strings.Cut(entry, ":")
+	if after == "" {
+		after = entry
+	}
+	port, _ = strconv.Atoi(after)
+	return port
+}
+
+// appPorts reads appPort, which is a port, a docker run -p mapping or a
+// list of either, as strings
+func appPorts(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) != nil {
+		list = []json.RawMessage{raw}
+	}
+	var mappings []string
+	for _, item := range list {
+		var port int
+		var mapping string
+		if json.Unmarshal(item, &port) == nil {
+			mappings = append(mappings, strconv.Itoa(port))
+		} else if json.Unmarshal(item, &mapping) == nil {
+			mappings = append(mappings, mapping)
+		}
+	}
+	return mappings
+}
+
+// parseMapping reads a docker run -p mapping, "3000", "3001:3000" or
+// "127.0.0.1:3001:3000/tcp", into the host and container ports
+func parseMapping(mapping string) (host, container int, ok bool) {
+	mapping, _, _ = strings.Cut(mapping, "/")
+	fields := strings.Split(mapping, ":")
+	container, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return 0, 0, false
+	}
+	if len(fields) == 1 {
+		return container, container, true
+	}
+	if host, err = strconv.Atoi(fields[len(fields)-2]); err != nil {
+		return 0, 0, false
+	}
+	return host, container, true
+}
+
+// Container is a running dev container, seen from the host
+type Container struct {
+	// Name is the devcontainer.json name, else the container's
+	Name string
+
+	// Folder is the host directory the container was opened from
+	Folder string
+
+	// Ports maps the host ports the container publishes to its own ports
+	Ports map[int]int
+}
+
+// Running returns the dev containers running on this machine, asking
+// docker and then podman. It returns none when neither is installed or
+// running.
+func Running(ctx context.Context) []Container {
+	for _, runtime := range runtimes {
+		if _, err := exec.LookPath(runtime); err != nil {
+			continue
+		}
+		output, err := exec.CommandContext(ctx, runtime, "ps", "--filter", "label="+folderLabel,
+			"--format", "{{.Names}}\t{{.Label \""+folderLabel+"\"}}\t{{.Ports}}").Output()
+		if err != nil {
+			continue
+		}
+		var containers []Container
+		for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			fields := strings.Split(line, "\t")
+			if len(fields) != 3 || fields[1] == "" {
+				continue
+			}
+			c := Container{Name: fields[0], Folder: fields[1], Ports: parsePublished(fields[2])}
+			if cfg, ok := read(c.Folder); ok && cfg.Name != "" {
+				c.Name = cfg.Name
+			}
+			containers = append(containers, c)
+		}
+		return containers
+	}
+	return nil
+}
+
+// parsePublished reads the PORTS column of docker ps, such as
+// "0.0.0.0:3001->3000/tcp, :::3001->3000/tcp, 5432/tcp", into host ports
+// and the container ports they reach. Unpublished ports are left out.
+func parsePublished(ports string) map[int]int {
+	published := make(map[int]int)
+	for _, entry := range strings.Split(ports, ", ") {
+		hostPart, containerPart, ok := strings.Cut(entry, "->")
+		if !ok {
+			continue
+		}
+		hostPart = hostPart[strings.LastIndex(hostPart, ":")+1:]
+		containerPart, _, _ = strings.Cut(containerPart, "/")
+
+		// Ranges such as 8000-8002->8000-8002 map port by port
+		hostFirst, hostLast := parseRange(hostPart)
+		containerFirst, _ := parseRange(containerPart)
+		if hostFirst == 0 || containerFirst == 0 {
+			continue
+		}
+		for port := hostFirst; port <= hostLast; port++ {
+			published[port] = containerFirst + port - hostFirst
+		}
+	}
+	return published
+}
+
+// parseRange reads "3000" or "8000-8002", returning 0 when it can't
+func parseRange(ports string) (first, last int) {
+	from, to, isRange := strings.Cut(ports, "-")
+	first, err := strconv.Atoi(from)
+	if err != nil {
+		return 0, 0
+	}
+	if !isRange {
+		return first, first
+	}
+	if last, err = strconv.Atoi(to); err != nil || last < first {
+		return 0, 0
+	}
+	return first, last
+}
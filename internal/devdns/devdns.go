@@ -0,0 +1,258 @@
+package devdns
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// pumaDevTLD is the domain puma-dev serves apps under by default
+const pumaDevTLD = "test"
+
+// dnsmasqConfigs are the usual locations of dnsmasq configuration
+var dnsmasqConfigs = []string{
+	"/etc/dnsmasq.conf",
+	"/usr/local/etc/dnsmasq.conf",
+	"/opt/homebrew/etc/dnsmasq.conf",
+}
+
+// dnsmasqConfigDirs hold drop-in dnsmasq configuration files
+var dnsmasqConfigDirs = []string{
+	"/etc/dnsmasq.d",
+	"/usr/local/etc/dnsmasq.d",
+	"/opt/homebrew/etc/dnsmasq.d",
+}
+
+// Resolver maps servers to friendly hostnames configured on this machine
+type Resolver struct {
+	// pumaDevPorts maps puma-dev proxy ports to app names
+	pumaDevPorts map[int]string
+
+	// pumaDevDirs maps app directories linked into puma-dev to app names
+	pumaDevDirs map[string]string
+
+	// valetSites maps directories Laravel Valet serves to their URL
+	valetSites map[string]string
+
+	// loopbackHosts are /etc/hosts names pointing at this machine
+	loopbackHosts map[string]bool
+
+	// wildcardTLDs are domains dnsmasq resolves to loopback (e.g., "test")
+	wildcardTLDs []string
+}
+
+// Load reads the local DNS and proxy configuration once; missing or
+// unreadable sources are skipped
+func Load() *Resolver {
+	r := &Resolver{
+		pumaDevPorts:  make(map[int]string),
+		pumaDevDirs:   make(map[string]string),
+		valetSites:    make(map[string]string),
+		loopbackHosts: make(map[string]bool),
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		r.loadPumaDev(filepath.Join(home, ".puma-dev"))
+		r.loadValet(filepath.Join(home, ".config", "valet"))
+	}
+	r.loadHosts("/etc/hosts")
+	r.loadDnsmasq()
+
+	return r
+}
+
+// FriendlyURL returns the hostname-based URL for server, or "" if no local
+// DNS setup maps to it
+func (r *Resolver) FriendlyURL(server types.Server) string {
+	// puma-dev proxies on the standard port, so no port in the URL
+	if name, ok := r.pumaDevPorts[server.Port]; ok {
+		return fmt.Sprintf("http://%s.%s", name, pumaDevTLD)
+	}
+	if name, ok := r.pumaDevDirs[server.CWD]; ok {
+		return fmt.Sprintf("http://%s.%s", name, pumaDevTLD)
+	}
+
+	// Valet serves the project itself through its nginx, which is the URL
+	// to share even when a development server runs alongside
+	if url, ok := r.valetSites[server.CWD]; ok {
+		return url
+	}
+
+	name := hostLabel(server.Repo)
+	if name == "" {
+		return ""
+	}
+
+	if len(r.wildcardTLDs) > 0 {
+		return fmt.Sprintf("http://%s.%s:%d", name, r.wildcardTLDs[0], server.Port)
+	}
+
+	for _, tld := range []string{"test", "localhost", "local"} {
+		host := name + "." + tld
+		if r.loopbackHosts[host] {
+			return fmt.Sprintf("http://%s:%d", host, server.Port)
+		}
+	}
+
+	return ""
+}
+
+// loadPumaDev reads puma-dev app entries: files containing a port (or
+// host:port) proxy to that port, symlinks point at an app directory
+func (r *Resolver) loadPumaDev(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+
+		if entry.Type()&os.ModeSymlink != 0 {
+			if target, err := filepath.EvalSymlinks(path); err == nil {
+				r.pumaDevDirs[target] = name
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		spec := strings.TrimSpace(string(data))
+		if _, port, err := net.SplitHostPort(spec); err == nil {
+			spec = port
+		}
+		if port, err := strconv.Atoi(spec); err == nil {
+			r.pumaDevPorts[port] = name
+		}
+	}
+}
+
+// loadValet reads Laravel Valet's config.json: each directory in a parked
+// path, and each link in its Sites directory, is served as NAME.TLD, over
+// HTTPS when Valet holds a certificate for it
+func (r *Resolver) loadValet(dir string) {
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return
+	}
+	var config struct {
+		TLD   string   `json:"tld"`
+		Paths []string `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return
+	}
+	if config.TLD == "" {
+		config.TLD = pumaDevTLD
+	}
+
+	for _, parked := range config.Paths {
+		entries, err := os.ReadDir(parked)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			target, err := filepath.EvalSymlinks(filepath.Join(parked, entry.Name()))
+			if err != nil {
+				continue
+			}
+			if info, err := os.Stat(target); err != nil || !info.IsDir() {
+				continue
+			}
+
+			host := strings.ToLower(entry.Name()) + "." + config.TLD
+			scheme := "http"
+			if _, err := os.Stat(filepath.Join(dir, "Certificates", host+".crt")); err == nil {
+				scheme = "https"
+			}
+			if _, ok := r.valetSites[target]; !ok {
+				r.valetSites[target] = scheme + "://" + host
+			}
+		}
+	}
+}
+
+// loadHosts collects names that /etc/hosts (as managed by hostess or by
+// hand) resolves to a loopback address
+func (r *Resolver) loadHosts(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		if ip == nil || !ip.IsLoopback() {
+			continue
+		}
+		for _, host := range fields[1:] {
+			r.loopbackHosts[strings.ToLower(host)] = true
+		}
+	}
+}
+
+// loadDnsmasq finds wildcard "address=/tld/127.0.0.1" rules
+func (r *Resolver) loadDnsmasq() {
+	files := append([]string(nil), dnsmasqConfigs...)
+	for _, dir := range dnsmasqConfigDirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*"))
+		files = append(files, matches...)
+	}
+
+	seen := make(map[string]bool)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			value, ok := strings.CutPrefix(strings.TrimSpace(line), "address=/")
+			if !ok {
+				continue
+			}
+			domain, ip, ok := strings.Cut(value, "/")
+			parsed := net.ParseIP(ip)
+			if !ok || parsed == nil || !parsed.IsLoopback() {
+				continue
+			}
+			domain = strings.Trim(domain, ".")
+			if domain != "" && !seen[domain] {
+				seen[domain] = true
+				r.wildcardTLDs = append(r.wildcardTLDs, domain)
+			}
+		}
+	}
+}
+
+// hostLabel turns a repo name into a valid DNS label
+func hostLabel(repo string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(repo) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-':
+			b.WriteRune(c)
+		case c == '_' || c == '.':
+			b.WriteRune('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
@@ -0,0 +1,146 @@
+// Package devshell finds the direnv or devenv setup of a server's directory
+// and tells from the server's environment whether it was applied, or
+// whether the server runs inside a Nix development shell
+package devshell
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// DefaultVars are the variables shown when the config doesn't choose any
+var DefaultVars = []string{"PORT", "DATABASE_URL"}
+
+// setupFiles are looked for in each directory, in order of precedence.
+// devenv projects are usually loaded through "use devenv" in an .envrc, so
+// devenv.nix wins when both exist.
+var setupFiles = []struct {
+	tool string
+	name string
+}{
+	{"devenv", "devenv.nix"},
+	{"direnv", ".envrc"},
+}
+
+// Find looks for a setup file in dir and its parents, stopping at the work
+// tree root (the first directory with a .git entry). It returns nil when
+// there is none.
+func Find(dir string) *types.Env {
+	for dir != "" {
+		for _, setup := range setupFiles {
+			file := filepath.Join(dir, setup.name)
+			if _, err := os.Stat(file); err == nil {
+				return &types.Env{Tool: setup.tool, File: file}
+			}
+		}
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+	return nil
+}
+
+// Inspect fills in whether env was applied to a process with the given
+// environment, and copies the variables named in vars
+func Inspect(env *types.Env, environ map[string]string, vars []string) {
+	dir := filepath.Dir(env.File)
+
+	// direnv exports DIRENV_DIR as "-" followed by the .envrc directory;
+	// devenv exports DEVENV_ROOT, whether entered through direnv or not
+	loaded := strings.TrimPrefix(environ["DIRENV_DIR"], "-") == dir
+	if env.Tool == "devenv" {
+		loaded = loaded || environ["DEVENV_ROOT"] == dir
+	}
+	env.Loaded = &loaded
+
+	for _, name := range vars {
+		value, ok := environ[name]
+		if !ok {
+			continue
+		}
+		if env.Vars == nil {
+			env.Vars = make(map[string]string)
+		}
+		env.Vars[name] = redact(value)
+	}
+}
+
+// Nix shells, as reported in Server.Nix
+const (
+	NixShell    = "nix"
+	DevenvShell = "devenv"
+)
+
+// maxAncestors bounds the parent chain walk in Nix
+const maxAncestors = 32
+
+// nixLaunchers maps the processes that start Nix shells to the shell
+// their children run in; "nix" covers nix develop and nix shell
+var nixLaunchers = map[string]string{
+	"nix":       NixShell,
+	"nix-shell": NixShell,
+	"devenv":    DevenvShell,
+}
+
+// Nix tells whether pid was started inside a Nix development shell. Its
+// environment is the reliable sign: devenv exports DEVENV_ROOT and every
+// Nix shell, including nix-direnv's, sets IN_NIX_SHELL. When another
+// user's environment can't be read, the parent chain is searched for a
+// nix, nix-shell or devenv process instead.
+func Nix(ctx context.Context, pid int) string {
+	if environ, err := procinfo.Environ(ctx, pid); err == nil {
+		switch {
+		case environ["DEVENV_ROOT"] != "":
+			return DevenvShell
+		case environ["IN_NIX_SHELL"] != "":
+			return NixShell
+		}
+		return ""
+	}
+
+	current := pid
+	for i := 0; i < maxAncestors && current > 1; i++ {
+		ppid, name, err := procinfo.Parent(ctx, current)
+		if err != nil {
+			return ""
+		}
+		if shell, ok := nixLaunchers[name]; ok {
+			return shell
+		}
+		current = ppid
+	}
+	return ""
+}
+
+// redact reduces URLs to their host so credentials in DATABASE_URL and the
+// like are never shown
+func redact(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return value
+	}
+	return u.Host
+}
+
+// String summarizes env for a table cell: "direnv", or "direnv (not
+// loaded)" when the server started without it
+func String(env *types.Env) string {
+	if env == nil {
+		return ""
+	}
+	if env.Loaded != nil && !*env.Loaded {
+		return env.Tool + " (not loaded)"
+	}
+	return env.Tool
+}
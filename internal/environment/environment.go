@@ -0,0 +1,146 @@
+// Package environment describes running servers portably, so a teammate
+// can start the same set from their own checkouts
+package environment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// formatVersion is bumped on incompatible changes to the file layout
+const formatVersion = 1
+
+// Environment is the exported set of servers
+type Environment struct {
+	Version  int       `json:"version"`
+	Exported time.Time `json:"exported"`
+	Servers  []Server  `json:"servers"`
+}
+
+// Server is a server described without machine-specific paths
+type Server struct {
+	Repo   string `json:"repo"`
+	Branch string `json:"branch"`
+
+	// Remote is the repository's web URL, for cloning a missing checkout
+	Remote string `json:"remote,omitempty"`
+
+	// Dir is the directory the server ran in, relative to the work tree
+	// root; empty for the root itself
+	Dir string `json:"dir,omitempty"`
+
+	// Command is the argument vector, with paths inside the checkout made
+	// relative to Dir and absolute executables reduced to their name
+	Command []string `json:"command"`
+
+	Port int `json:"port"`
+}
+
+// Export describes the app servers among servers. Services are left out, as
+// they belong to the machine rather than a repository.
+func Export(ctx context.Context, servers []types.Server) (*Environment, error) {
+	env := &Environment{Version: formatVersion, Exported: time.Now().UTC()}
+	for _, server := range servers {
+		if server.Service != "" || server.CWD == "" {
+			continue
+		}
+		args, err := procinfo.CommandLine(ctx, server.PID)
+		if err != nil {
+			return nil, fmt.Errorf("reading command line of %s (pid %d): %w", server.Repo, server.PID, err)
+		}
+
+		var dir string
+		if root, ok := git.WorkTreeRoot(server.CWD); ok {
+			if rel, err := filepath.Rel(root, server.CWD); err == nil && rel != "." {
+				dir = filepath.ToSlash(rel)
+			}
+		}
+
+		env.Servers = append(env.Servers, Server{
+			Repo:    server.Repo,
+			Branch:  server.Branch,
+			Remote:  git.GetRemoteWebURL(ctx, server.CWD),
+			Dir:     dir,
+			Command: portableCommand(args, server.CWD),
+			Port:    server.Port,
+		})
+	}
+	return env, nil
+}
+
+// portableCommand drops this machine's paths from a command line: the
+// executable is found through PATH, and arguments inside cwd become
+// relative to it
+func portableCommand(args []string, cwd string) []string {
+	command := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case i == 0 && filepath.IsAbs(arg):
+			command[i] = filepath.Base(arg)
+		case strings.HasPrefix(arg, cwd+string(filepath.Separator)):
+			command[i] = "." + string(filepath.Separator) + strings.TrimPrefix(arg, cwd+string(filepath.Separator))
+		default:
+			command[i] = arg
+		}
+	}
+	return command
+}
+
+// Write encodes env as indented JSON
+func (env *Environment) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(env)
+}
+
+// Read decodes an exported environment
+func Read(r io.Reader) (*Environment, error) {
+	var env Environment
+	if err := json.NewDecoder(r).Decode(&env); err != nil {
+		return nil, fmt.Errorf("invalid environment file: %w", err)
+	}
+	if env.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported environment file version %d (want %d)", env.Version, formatVersion)
+	}
+	for i, server := range env.Servers {
+		if server.Repo == "" || len(server.Command) == 0 {
+			return nil, fmt.Errorf("server %d: repo and command are required", i+1)
+		}
+	}
+	return &env, nil
+}
+
+// FindCheckouts maps repository names to work tree roots among root and
+// its immediate subdirectories, naming each the way detection does
+func FindCheckouts(ctx context.Context, root string, sources []git.NameSource) map[string]string {
+	candidates := []string{root}
+	entries, _ := os.ReadDir(root)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			candidates = append(candidates, filepath.Join(root, entry.Name()))
+		}
+	}
+
+	checkouts := make(map[string]string)
+	for _, dir := range candidates {
+		if !platform.FileExists(filepath.Join(dir, ".git")) {
+			continue
+		}
+		name := git.GetRepoName(ctx, dir, sources)
+		if _, ok := checkouts[name]; !ok {
+			checkouts[name] = dir
+		}
+	}
+	return checkouts
+}
@@ -0,0 +1,282 @@
+// Package exposure audits local servers for accidental exposure: listening
+// beyond loopback, public tunnels, and pages that shouldn't be reachable,
+// such as framework debug endpoints and admin consoles with default logins
+package exposure
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/probe"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// DefaultTimeout bounds each page request
+const DefaultTimeout = 2 * time.Second
+
+// workers is how many servers are fingerprinted at once
+const workers = 8
+
+// maxBody is how much of a page is read for markers
+const maxBody = 64 << 10
+
+// Severity ranks findings
+type Severity string
+
+const (
+	// SeverityHigh means others can likely reach the server or page now
+	SeverityHigh Severity = "high"
+
+	// SeverityMedium means the server is reachable from the local network
+	SeverityMedium Severity = "medium"
+
+	// SeverityLow marks pages that would be a problem if the server were
+	// exposed, but that only this machine can reach
+	SeverityLow Severity = "low"
+)
+
+// Checks reported in Finding.Check
+const (
+	CheckBind        = "bind"
+	CheckTunnel      = "tunnel"
+	CheckDebug       = "debug-endpoint"
+	CheckFile        = "exposed-file"
+	CheckCredentials = "default-credentials"
+)
+
+// Finding is one problem found on a server
+type Finding struct {
+	Severity Severity `json:"severity"`
+
+	// Check is one of the Check constants
+	Check string `json:"check"`
+
+	// URL is the page or address the finding is about, if any
+	URL string `json:"url,omitempty"`
+
+	Detail string       `json:"detail"`
+	Server types.Server `json:"server"`
+}
+
+// fingerprint recognizes a page that shouldn't be reachable
+type fingerprint struct {
+	check string
+	path  string
+
+	// status is the expected response code
+	status int
+
+	// match tells whether the body is the page in question
+	match  func(body string) bool
+	detail string
+}
+
+// contains matches bodies containing marker
+func contains(marker string) func(string) bool {
+	return func(body string) bool { return strings.Contains(body, marker) }
+}
+
+// fingerprints are best-effort: each matches the page of one framework or
+// tool, and misses customized ones
+var fingerprints = []fingerprint{
+	{CheckDebug, "/rails/info/properties", 200, contains("Rails version"), "Rails info page lists versions, paths and middleware"},
+	{CheckDebug, "/lsrv-audit-not-found", 404, contains("DEBUG = True"), "Django runs with DEBUG = True: error pages show settings, SQL and source code"},
+	{CheckDebug, "/console", 200, contains("Werkzeug"), "Werkzeug debugger console runs any Python code"},
+	{CheckDebug, "/_ignition/health-check", 200, contains("can_execute_commands"), "Laravel Ignition is enabled (APP_DEBUG=true)"},
+	{CheckDebug, "/_profiler/empty/search/results?limit=10", 200, contains("Symfony Profiler"), "Symfony profiler shows requests, configuration and environment"},
+	{CheckDebug, "/actuator/env", 200, contains("propertySources"), "Spring Boot actuator exposes the environment, including secrets"},
+	{CheckDebug, "/phpinfo.php", 200, contains("phpinfo()"), "phpinfo() page shows configuration and environment"},
+	{CheckFile, "/.env", 200, isDotenv, ".env file is served, with whatever secrets it holds"},
+	{CheckFile, "/.git/config", 200, contains("[core]"), "git metadata is served: the repository can be downloaded"},
+	{CheckCredentials, "/login", 200, contains("Grafana"), "Grafana login accepts admin/admin until it's changed"},
+	{CheckCredentials, "/", 200, contains("RabbitMQ Management"), "RabbitMQ management UI; guest/guest is the default login"},
+	{CheckCredentials, "/", 200, contains("MinIO Console"), "MinIO console; minioadmin/minioadmin is the default login"},
+	{CheckCredentials, "/manager/html", 401, contains("Tomcat"), "Tomcat Manager; the sample tomcat/tomcat users are often left in place"},
+	{CheckCredentials, "/phpmyadmin/", 200, contains("phpMyAdmin"), "phpMyAdmin; root with an empty password is a common default"},
+	{CheckCredentials, "/api/status", 200, contains(`"kernels"`), "Jupyter server runs code without a token or password"},
+	{CheckCredentials, "/", 200, contains("You Know, for Search"), "Elasticsearch answers without authentication"},
+	{CheckCredentials, "/", 200, contains("MailHog"), "MailHog shows every captured email without a login"},
+}
+
+// isDotenv matches a body of VAR=value lines rather than an HTML page that
+// single-page apps serve for any path
+func isDotenv(body string) bool {
+	if strings.Contains(strings.ToLower(body), "<html") {
+		return false
+	}
+	for _, line := range strings.Split(body, "\n") {
+		name, _, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), "export "), "=")
+		if ok && name != "" && strings.Trim(name, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// cgnat is the shared address space Tailscale and carrier NAT use, private
+// to a tailnet rather than public
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// Audit checks servers and returns the findings, most severe first. Unless
+// bindOnly is set, HTTP servers are fingerprinted with a few requests each.
+// Servers should come with LAN URLs, firewall verdicts, tunnels and probed
+// protocols, so exposure and HTTP servers can be told apart.
+func Audit(ctx context.Context, servers []types.Server, bindOnly bool, timeout time.Duration) []Finding {
+	var findings []Finding
+	for _, server := range servers {
+		findings = append(findings, checkExposure(server)...)
+	}
+
+	if !bindOnly {
+		client := &http.Client{
+			Timeout: timeout,
+			// A login redirect is an answer; following it could leave localhost
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, workers)
+		for _, server := range servers {
+			if server.Protocol != "" && server.Protocol != probe.ProtocolHTTP {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+				found := checkPages(ctx, client, server)
+				mu.Lock()
+				findings = append(findings, found...)
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+	}
+
+	rank := map[Severity]int{SeverityHigh: 0, SeverityMedium: 1, SeverityLow: 2}
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		if rank[a.Severity] != rank[b.Severity] {
+			return rank[a.Severity] - rank[b.Severity]
+		}
+		return a.Server.Port - b.Server.Port
+	})
+	return findings
+}
+
+// Exposed reports whether other machines can likely reach server: it
+// listens beyond loopback without a firewall blocking it, or a tunnel
+// forwards to it
+func Exposed(server types.Server) bool {
+	if len(server.Tunnels) > 0 {
+		return true
+	}
+	if server.Firewall != nil && server.Firewall.State == types.FirewallBlocked {
+		return false
+	}
+	addr, err := netip.ParseAddr(strings.Trim(server.Bind, "[]"))
+	return server.Bind == "*" || (err == nil && !addr.IsLoopback())
+}
+
+// checkExposure flags listening addresses and tunnels that let other
+// machines in
+func checkExposure(server types.Server) []Finding {
+	var findings []Finding
+	for _, tunnel := range server.Tunnels {
+		findings = append(findings, Finding{
+			Severity: SeverityHigh,
+			Check:    CheckTunnel,
+			URL:      tunnel.URL,
+			Detail:   fmt.Sprintf("reachable from the internet through %s", tunnel.Provider),
+			Server:   server,
+		})
+	}
+
+	finding := Finding{Check: CheckBind, URL: server.LANURL, Server: server}
+	addr, err := netip.ParseAddr(strings.Trim(server.Bind, "[]"))
+	switch {
+	case server.Bind == "*" || (err == nil && addr.IsUnspecified()):
+		finding.Severity = SeverityHigh
+		finding.Detail = "listens on all interfaces, including public networks you join"
+	case err != nil || addr.IsLoopback():
+		return findings
+	case addr.IsPrivate() || addr.IsLinkLocalUnicast() || cgnat.Contains(addr.Unmap()):
+		finding.Severity = SeverityMedium
+		finding.Detail = fmt.Sprintf("listens on local network address %s", addr)
+	default:
+		finding.Severity = SeverityHigh
+		finding.Detail = fmt.Sprintf("listens on public address %s", addr)
+	}
+
+	if verdict := server.Firewall; verdict != nil && verdict.State == types.FirewallBlocked {
+		finding.Severity = SeverityLow
+		finding.Detail += fmt.Sprintf(", but %s blocks it", verdict.By)
+	}
+	return append(findings, finding)
+}
+
+// checkPages requests each fingerprinted path once and matches the answers.
+// Pages on servers only this machine can reach are low severity.
+func checkPages(ctx context.Context, client *http.Client, server types.Server) []Finding {
+	severity := SeverityLow
+	if Exposed(server) {
+		severity = SeverityHigh
+	}
+
+	type page struct {
+		status int
+		body   string
+	}
+	pages := make(map[string]*page)
+
+	var findings []Finding
+	for _, fp := range fingerprints {
+		if ctx.Err() != nil {
+			break
+		}
+		got, seen := pages[fp.path]
+		if !seen {
+			if status, body, err := fetch(ctx, client, server.URL()+fp.path); err == nil {
+				got = &page{status, body}
+			}
+			pages[fp.path] = got
+		}
+		if got == nil || got.status != fp.status || !fp.match(got.body) {
+			continue
+		}
+		findings = append(findings, Finding{
+			Severity: severity,
+			Check:    fp.check,
+			URL:      server.URL() + fp.path,
+			Detail:   fp.detail,
+			Server:   server,
+		})
+	}
+	return findings
+}
+
+// fetch GETs url and returns the status and the start of the body
+func fetch(ctx context.Context, client *http.Client, url string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("User-Agent", "lsrv")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	return resp.StatusCode, string(body), err
+}
@@ -0,0 +1,51 @@
+// Package firewall checks whether local firewall rules would block other
+// devices from reaching a server bound to all interfaces, the usual reason
+// a phone can't open a server that "binds to 0.0.0.0"
+package firewall
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// Checker decides per server; each OS provides NewChecker in a
+// build-tagged file, reading the firewall configuration once
+type Checker interface {
+	Check(ctx context.Context, server types.Server) types.Firewall
+}
+
+// open, blocked and unknown build verdicts
+func open(by, detail string) types.Firewall {
+	return types.Firewall{State: types.FirewallOpen, By: by, Detail: detail}
+}
+
+func blocked(by, detail string) types.Firewall {
+	return types.Firewall{State: types.FirewallBlocked, By: by, Detail: detail}
+}
+
+func unknown(by, detail string) types.Firewall {
+	return types.Firewall{State: types.FirewallUnknown, By: by, Detail: detail}
+}
+
+// portInSpec reports whether port is in a spec like "3000", "3000:3010",
+// "3000-3010" or a comma-separated list of those
+func portInSpec(port int, spec string) bool {
+	for _, part := range strings.Split(spec, ",") {
+		low, high, isRange := strings.Cut(part, ":")
+		if !isRange {
+			low, high, isRange = strings.Cut(part, "-")
+		}
+		if !isRange {
+			high = low
+		}
+		from, err1 := strconv.Atoi(strings.TrimSpace(low))
+		to, err2 := strconv.Atoi(strings.TrimSpace(high))
+		if err1 == nil && err2 == nil && port >= from && port <= to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package firewall
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// socketfilterfw controls the macOS application firewall
+const socketfilterfw = "/usr/libexec/ApplicationFirewall/socketfilterfw"
+
+// darwinChecker asks the application firewall about each server's
+// executable and, as root, checks whether pf blocks incoming connections
+type darwinChecker struct {
+	appFirewall bool
+	blockAll    bool
+
+	pfBlocks []string
+	pfPasses []string
+}
+
+// NewChecker reads the application firewall's global state and pf's rules
+func NewChecker(ctx context.Context) Checker {
+	c := &darwinChecker{}
+	if output, err := exec.CommandContext(ctx, socketfilterfw, "--getglobalstate").Output(); err == nil {
+		c.appFirewall = strings.Contains(string(output), "enabled")
+	}
+	if c.appFirewall {
+		if output, err := exec.CommandContext(ctx, socketfilterfw, "--getblockall").Output(); err == nil {
+			c.blockAll = strings.Contains(string(output), "ENABLED") || strings.Contains(string(output), "block all")
+		}
+	}
+
+	// pf's rules are only readable by root; without it only the application
+	// firewall is checked
+	if platform.IsRoot() {
+		info, err := exec.CommandContext(ctx, "pfctl", "-s", "info").Output()
+		if err == nil && strings.Contains(string(info), "Status: Enabled") {
+			if rules, err := exec.CommandContext(ctx, "pfctl", "-s", "rules").Output(); err == nil {
+				for _, rule := range strings.Split(string(rules), "\n") {
+					switch {
+					case strings.HasPrefix(rule, "block") && strings.Contains(rule, " in "):
+						c.pfBlocks = append(c.pfBlocks, rule)
+					case strings.HasPrefix(rule, "pass") && strings.Contains(rule, " in "):
+						c.pfPasses = append(c.pfPasses, rule)
+					}
+				}
+			}
+		}
+	}
+	return c
+}
+
+func (c *darwinChecker) Check(ctx context.Context, server types.Server) types.Firewall {
+	if verdict, ok := c.checkPF(server.Port); ok {
+		return verdict
+	}
+	if !c.appFirewall {
+		return open("application firewall", "off")
+	}
+	if c.blockAll {
+		return blocked("application firewall", "blocks all incoming connections")
+	}
+
+	exe, err := procinfo.Executable(ctx, server.PID)
+	if err != nil {
+		return unknown("application firewall", "can't resolve the server's executable")
+	}
+	output, err := exec.CommandContext(ctx, socketfilterfw, "--getappblocked", exe).Output()
+	if err != nil {
+		return unknown("application firewall", err.Error())
+	}
+	switch text := string(output); {
+	case strings.Contains(text, "permitted"):
+		return open("application firewall", exe+" is permitted")
+	case strings.Contains(text, "blocked"):
+		return blocked("application firewall", exe+" is blocked")
+	}
+	return unknown("application firewall", exe+" isn't listed; macOS asks on the first incoming connection")
+}
+
+// checkPF decides when pf has a blocking rule: a pass rule for the port
+// (or for all traffic) opens it, otherwise the block wins. ok is false
+// when pf isn't blocking anything.
+func (c *darwinChecker) checkPF(port int) (types.Firewall, bool) {
+	if len(c.pfBlocks) == 0 {
+		return types.Firewall{}, false
+	}
+	for _, rule := range c.pfPasses {
+		spec, hasPort := pfPort(rule)
+		if !hasPort || portInSpec(port, spec) {
+			return open("pf", rule), true
+		}
+	}
+	for _, rule := range c.pfBlocks {
+		if spec, hasPort := pfPort(rule); !hasPort || portInSpec(port, spec) {
+			return blocked("pf", rule), true
+		}
+	}
+	return types.Firewall{}, false
+}
+
+// pfPort extracts the destination port of a rule like
+// "pass in proto tcp from any to any port = 3000" or "port 3000:3010"
+func pfPort(rule string) (string, bool) {
+	fields := strings.Fields(rule)
+	for i, field := range fields {
+		if field != "port" || i+1 >= len(fields) {
+			continue
+		}
+		spec := fields[i+1]
+		if spec == "=" && i+2 < len(fields) {
+			spec = fields[i+2]
+		}
+		return spec, true
+	}
+	return "", false
+}
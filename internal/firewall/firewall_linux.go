@@ -0,0 +1,206 @@
+package firewall
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// linuxChecker applies ufw's rules when ufw is enabled, and otherwise the
+// iptables INPUT chain, which only root can list
+type linuxChecker struct {
+	ufw       bool
+	ufwPolicy string
+	ufwRules  []ufwRule
+	ufwErr    error
+
+	iptables    []string
+	iptablesErr error
+
+	// noIptables is set when iptables isn't installed, and nft when
+	// nftables, whose rules aren't checked, is
+	noIptables bool
+	nft        bool
+}
+
+// ufwRule is an incoming rule from /etc/ufw/user.rules
+type ufwRule struct {
+	action string
+	proto  string
+	ports  string
+}
+
+// NewChecker reads the ufw or iptables configuration
+func NewChecker(ctx context.Context) Checker {
+	c := &linuxChecker{}
+	if readShellVar("/etc/ufw/ufw.conf", "ENABLED") == "yes" {
+		c.ufw = true
+		c.ufwPolicy = readShellVar("/etc/default/ufw", "DEFAULT_INPUT_POLICY")
+		c.ufwRules, c.ufwErr = readUFWRules("/etc/ufw/user.rules")
+		return c
+	}
+
+	output, err := exec.CommandContext(ctx, "iptables", "-S", "INPUT").Output()
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		c.noIptables = true
+		_, nftErr := exec.LookPath("nft")
+		c.nft = nftErr == nil
+	case err != nil && !platform.IsRoot():
+		c.iptablesErr = errors.New("the INPUT chain needs root to read (try sudo)")
+	case err != nil:
+		c.iptablesErr = err
+	default:
+		c.iptables = strings.Split(strings.TrimSpace(string(output)), "\n")
+	}
+	return c
+}
+
+func (c *linuxChecker) Check(ctx context.Context, server types.Server) types.Firewall {
+	if c.ufw {
+		return c.checkUFW(server.Port)
+	}
+	return c.checkIptables(server.Port)
+}
+
+// checkUFW applies the first matching rule, like ufw, then the default
+// policy
+func (c *linuxChecker) checkUFW(port int) types.Firewall {
+	if c.ufwErr != nil {
+		if c.ufwPolicy == "ACCEPT" {
+			return open("ufw", "default incoming policy is ACCEPT")
+		}
+		return unknown("ufw", "enabled, but its rules need root to read (try sudo)")
+	}
+
+	for _, rule := range c.ufwRules {
+		if rule.proto != "any" && rule.proto != "tcp" {
+			continue
+		}
+		if rule.ports != "any" && !portInSpec(port, rule.ports) {
+			continue
+		}
+		if rule.action == "allow" || rule.action == "limit" {
+			return open("ufw", fmt.Sprintf("%s %s", rule.action, rule.ports))
+		}
+		return blocked("ufw", fmt.Sprintf("%s %s", rule.action, rule.ports))
+	}
+
+	if c.ufwPolicy == "ACCEPT" {
+		return open("ufw", "default incoming policy is ACCEPT")
+	}
+	return blocked("ufw", fmt.Sprintf("no rule allows %d (sudo ufw allow %d/tcp)", port, port))
+}
+
+// checkIptables walks the INPUT chain for a rule deciding new TCP
+// connections to port, falling back to the chain's policy. Jumps to other
+// chains aren't followed, so a rule set using them is reported as unknown.
+func (c *linuxChecker) checkIptables(port int) types.Firewall {
+	if c.noIptables {
+		if c.nft {
+			return unknown("nftables", "nftables rules aren't checked")
+		}
+		return open("", "no ufw, iptables or nftables found")
+	}
+	if c.iptablesErr != nil {
+		return unknown("iptables", c.iptablesErr.Error())
+	}
+
+	policy := "ACCEPT"
+	for _, line := range c.iptables {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "-P" {
+			policy = fields[2]
+			continue
+		}
+		if len(fields) < 2 || fields[0] != "-A" {
+			continue
+		}
+
+		var target, dports string
+		skip := false
+		for i := 2; i < len(fields)-1; i++ {
+			switch fields[i] {
+			case "-j":
+				target = fields[i+1]
+			case "--dport", "--dports":
+				dports = fields[i+1]
+			case "-i", "-s", "--state", "--ctstate":
+				// Loopback, source-restricted and conntrack rules don't
+				// decide new connections from the LAN
+				skip = true
+			case "-p":
+				skip = skip || (fields[i+1] != "tcp" && fields[i+1] != "all")
+			}
+		}
+		if skip || (dports != "" && !portInSpec(port, dports)) {
+			continue
+		}
+
+		switch target {
+		case "ACCEPT":
+			return open("iptables", strings.Join(fields[2:], " "))
+		case "DROP", "REJECT":
+			return blocked("iptables", strings.Join(fields[2:], " "))
+		case "":
+		default:
+			return unknown("iptables", "rules jump to chain "+target)
+		}
+	}
+
+	if policy == "ACCEPT" {
+		return open("iptables", "INPUT policy is ACCEPT")
+	}
+	return blocked("iptables", fmt.Sprintf("INPUT policy is %s and no rule accepts %d", policy, port))
+}
+
+// readShellVar returns a VAR=value setting from a shell-style config file
+func readShellVar(path, name string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), name+"="); ok {
+			return strings.Trim(value, `"'`)
+		}
+	}
+	return ""
+}
+
+// readUFWRules parses the "### tuple ###" comments ufw writes above each
+// rule, such as "### tuple ### allow tcp 3000 0.0.0.0/0 any 0.0.0.0/0 in"
+func readUFWRules(path string) ([]ufwRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []ufwRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		tuple, ok := strings.CutPrefix(scanner.Text(), "### tuple ### ")
+		if !ok {
+			continue
+		}
+		// action proto dport dst sport src [direction]
+		fields := strings.Fields(tuple)
+		if len(fields) < 6 || (len(fields) > 6 && fields[len(fields)-1] != "in") {
+			continue
+		}
+		// Rules limited to some source addresses don't open the port to all
+		if fields[5] != "0.0.0.0/0" && fields[5] != "::/0" {
+			continue
+		}
+		rules = append(rules, ufwRule{action: fields[0], proto: fields[1], ports: fields[2]})
+	}
+	return rules, scanner.Err()
+}
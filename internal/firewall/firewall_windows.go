@@ -0,0 +1,19 @@
+package firewall
+
+import (
+	"context"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// windowsChecker doesn't read Windows Defender Firewall rules yet
+type windowsChecker struct{}
+
+// NewChecker returns a checker reporting every server as unknown
+func NewChecker(ctx context.Context) Checker {
+	return windowsChecker{}
+}
+
+func (windowsChecker) Check(ctx context.Context, server types.Server) types.Firewall {
+	return unknown("", "firewall checks aren't supported on Windows")
+}
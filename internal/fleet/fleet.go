@@ -0,0 +1,149 @@
+// Package fleet lists servers on other machines by running lsrv there over
+// SSH, for dev environments spread over a laptop and remote workstations.
+// Each host needs lsrv installed; its JSON output is merged with the local
+// listing.
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/types"
+	"github.com/bshakr/lsrv/pkg/schema"
+)
+
+// LocalName is the HOST of servers on this machine
+const LocalName = "local"
+
+// ConnectTimeout bounds establishing each SSH connection; callers add it
+// to the time they allow lsrv to run on the host
+const ConnectTimeout = 5 * time.Second
+
+// Result is the outcome of scanning one host
+type Result struct {
+	Host    config.Host
+	Servers []types.Server
+	Err     error
+}
+
+// Scan runs lsrv with args on every host at once and returns the results
+// in the hosts' order. A host that fails doesn't affect the others.
+func Scan(ctx context.Context, hosts []config.Host, args []string) []Result {
+	results := make([]Result, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			servers, err := scanHost(ctx, host, args)
+			results[i] = Result{Host: host, Servers: servers, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// scanHost runs "lsrv --format=json" on host. BatchMode makes ssh fail
+// rather than prompt for a password the user can't see.
+func scanHost(ctx context.Context, host config.Host, args []string) ([]types.Server, error) {
+	command := host.Command
+	if command == "" {
+		command = "lsrv"
+	}
+	sshArgs := append([]string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=" + strconv.Itoa(int(ConnectTimeout.Seconds())), host.SSH, "--", command, "--format=json"}, args...)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, errors.New("timed out")
+	}
+	if err != nil {
+		if msg := lastLine(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+
+	var out schema.Output
+	if err := json.Unmarshal(output, &out); err != nil {
+		return nil, fmt.Errorf("unreadable output from %s: %w", command, err)
+	}
+	if out.SchemaVersion != schema.Version {
+		return nil, fmt.Errorf("%s there writes JSON schema version %d, this lsrv reads %d", command, out.SchemaVersion, schema.Version)
+	}
+
+	servers := make([]types.Server, len(out.Servers))
+	for i, server := range out.Servers {
+		servers[i] = fromSchema(server)
+		servers[i].Host = host.Name
+		servers[i].HostAddress = host.URLHost()
+	}
+	return servers, nil
+}
+
+// lastLine returns the last non-empty line of ssh's error output, which
+// names the failure ("Connection refused", "lsrv: command not found")
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// fromSchema turns a server from another lsrv's JSON back into a server.
+// Current is dropped, as it refers to the remote lsrv's directory.
+func fromSchema(s schema.Server) types.Server {
+	server := types.Server{
+		Repo:        s.Repo,
+		Branch:      s.Branch,
+		Process:     s.Process,
+		Port:        s.Port,
+		PID:         s.PID,
+		CWD:         s.CWD,
+		User:        s.User,
+		UID:         s.UID,
+		Package:     s.Package,
+		Workers:     s.Workers,
+		AuxPorts:    s.AuxPorts,
+		PrimaryPort: s.PrimaryPort,
+		LastCommit:  s.LastCommit,
+		Runtime:     s.Runtime,
+		Nix:         s.Nix,
+		RemoteURL:   s.RemoteURL,
+		Service:     s.Service,
+		Bind:        s.Bind,
+		LANURL:      s.LANURL,
+		FriendlyURL: s.FriendlyURL,
+		CustomURL:   s.CustomURL,
+		Framework:   s.Framework,
+		CommandLine: s.CommandLine,
+		Paths:       s.Paths,
+		Status:      types.Status(s.Status),
+		Label:       s.Label,
+		Session:     s.Session,
+		StartedBy:   s.StartedBy,
+		TTFB:        time.Duration(s.TTFBMS * float64(time.Millisecond)),
+		Uptime:      time.Duration(s.UptimeSeconds) * time.Second,
+		Memory:      s.MemoryBytes,
+		Protocol:    s.Protocol,
+	}
+	if s.Env != nil {
+		server.Env = &types.Env{Tool: s.Env.Tool, File: s.Env.File, Loaded: s.Env.Loaded, Vars: s.Env.Vars}
+	}
+	for _, tunnel := range s.Tunnels {
+		server.Tunnels = append(server.Tunnels, types.Tunnel{Provider: tunnel.Provider, URL: tunnel.URL})
+	}
+	if s.Firewall != nil {
+		server.Firewall = &types.Firewall{State: types.FirewallState(s.Firewall.State), By: s.Firewall.By, Detail: s.Firewall.Detail}
+	}
+	return server
+}
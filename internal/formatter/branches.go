@@ -0,0 +1,59 @@
+package formatter
+
+import (
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/types"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// branchStyle returns the first of styles matching branch
+func branchStyle(styles []config.BranchStyle, branch string) (config.BranchStyle, bool) {
+	if branch == "" {
+		return config.BranchStyle{}, false
+	}
+	for _, style := range styles {
+		if style.Match(branch) {
+			return style, true
+		}
+	}
+	return config.BranchStyle{}, false
+}
+
+// badgeText is a badge as plain text, "[PROD]", which is what plain output
+// and terminals without colors show
+func badgeText(badge string) string {
+	return "[" + badge + "]"
+}
+
+// badgeRows turns the plain badges leading the BRANCH cells into colored
+// ones of the same width, " PROD " in reverse video. cellStyle returns the
+// style of a cell, which the rest of the cell keeps. Cells whose badge was
+// truncated or marked are left as they are.
+func badgeRows(rows [][]string, servers []types.Server, columns []column, styles []config.BranchStyle, renderer *lipgloss.Renderer, cellStyle func(row, col int) lipgloss.Style) {
+	if renderer.ColorProfile() == termenv.Ascii {
+		return
+	}
+	for i, server := range servers {
+		style, ok := branchStyle(styles, server.Branch)
+		if !ok || style.Badge == "" {
+			continue
+		}
+		for j, col := range columns {
+			if col.id != colBranch {
+				continue
+			}
+			rest, found := strings.CutPrefix(rows[i][j], badgeText(style.Badge))
+			if !found {
+				continue
+			}
+			// Render both parts in the cell's style; the badge's reset would
+			// otherwise drop it for the rest
+			base := cellStyle(i, j).UnsetPadding()
+			badge := base.Reverse(!base.GetReverse()).Render(" " + style.Badge + " ")
+			rows[i][j] = badge + base.Render(rest)
+		}
+	}
+}
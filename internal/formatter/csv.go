@@ -0,0 +1,116 @@
+package formatter
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/devshell"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// writeCSV renders the servers as CSV with a header row
+func writeCSV(w io.Writer, servers []types.Server) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"repo", "branch", "process", "pid", "port", "url", "cwd", "friendly_url", "session", "user", "uid", "workers", "service", "last_commit", "protocol", "status", "links", "command_line", "framework", "bind", "lan_url", "runtime", "env", "current", "custom_url", "aux_ports", "primary_port", "ttfb_ms", "label", "host", "nix", "tunnels", "package", "started_by", "backlog_queued", "backlog_limit", "dev_container", "container_port", "host_port"}); err != nil {
+		return err
+	}
+
+	for _, server := range servers {
+		var lastCommit string
+		if server.LastCommit != nil {
+			lastCommit = server.LastCommit.UTC().Format(time.RFC3339)
+		}
+
+		// Servers not measured or not answering have no latency rather than 0
+		var ttfb string
+		if server.TTFB > 0 {
+			ttfb = strconv.FormatFloat(types.Milliseconds(server.TTFB), 'f', -1, 64)
+		}
+
+		// Unknown queues and limits are empty rather than 0
+		var queued, limit string
+		if server.Backlog != nil {
+			queued = strconv.Itoa(server.Backlog.Queued)
+			if server.Backlog.Limit > 0 {
+				limit = strconv.Itoa(server.Backlog.Limit)
+			}
+		}
+
+		var devContainer, containerPort, hostPort string
+		if server.DevContainer != nil {
+			devContainer = server.DevContainer.Name
+			containerPort = strconv.Itoa(server.DevContainer.ContainerPort)
+			hostPort = strconv.Itoa(server.DevContainer.HostPort)
+		}
+
+		record := []string{
+			server.Repo,
+			server.Branch,
+			server.Process,
+			strconv.Itoa(server.PID),
+			strconv.Itoa(server.Port),
+			server.LocalURL(),
+			server.CWD,
+			server.FriendlyURL,
+			server.Session,
+			server.User,
+			strconv.Itoa(server.UID),
+			strconv.Itoa(server.Workers),
+			server.Service,
+			lastCommit,
+			server.Protocol,
+			string(server.Status),
+			strings.Join(server.Links(), " "),
+			server.CommandLine,
+			server.Framework,
+			server.Bind,
+			server.LANURL,
+			server.Runtime,
+			devshell.String(server.Env),
+			strconv.FormatBool(server.Current),
+			server.CustomURL,
+			joinPorts(server.AuxPorts),
+			strconv.Itoa(server.PrimaryPort),
+			ttfb,
+			server.Label,
+			server.Host,
+			server.Nix,
+			joinTunnels(server.Tunnels),
+			server.Package,
+			strings.Join(server.StartedBy, "→"),
+			queued,
+			limit,
+			devContainer,
+			containerPort,
+			hostPort,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// joinTunnels renders public URLs space-separated, like the links column
+func joinTunnels(tunnels []types.Tunnel) string {
+	urls := make([]string, len(tunnels))
+	for i, tunnel := range tunnels {
+		urls[i] = tunnel.URL
+	}
+	return strings.Join(urls, " ")
+}
+
+// joinPorts renders ports space-separated, like the links column
+func joinPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,148 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/depgraph"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// diagram is the repo → server → port structure drawn by the dot and
+// mermaid formats, with one server node per process so a process listening
+// on several ports is drawn once
+type diagram struct {
+	repos    []string
+	repoApps map[string][]int
+	services []int
+
+	// processes lists each PID once, in listing order
+	processes []int
+	byPID     map[int]types.Server
+	ports     map[int][]int
+
+	edges []depgraph.Edge
+}
+
+// newDiagram groups servers by repo and process
+func newDiagram(servers []types.Server, edges []depgraph.Edge) diagram {
+	d := diagram{
+		repoApps: make(map[string][]int),
+		byPID:    make(map[int]types.Server),
+		ports:    make(map[int][]int),
+		edges:    edges,
+	}
+	for _, server := range servers {
+		if _, seen := d.byPID[server.PID]; !seen {
+			d.byPID[server.PID] = server
+			d.processes = append(d.processes, server.PID)
+			if server.Service != "" {
+				d.services = append(d.services, server.PID)
+			} else {
+				if _, ok := d.repoApps[server.Repo]; !ok {
+					d.repos = append(d.repos, server.Repo)
+				}
+				d.repoApps[server.Repo] = append(d.repoApps[server.Repo], server.PID)
+			}
+		}
+		d.ports[server.PID] = append(d.ports[server.PID], server.Port)
+		d.ports[server.PID] = append(d.ports[server.PID], server.AuxPorts...)
+	}
+	return d
+}
+
+// processLabel names a server node after its process and, for apps, branch
+func (d diagram) processLabel(pid int) string {
+	server := d.byPID[pid]
+	if server.Service != "" {
+		return fmt.Sprintf("%s (pid %d)", server.Service, pid)
+	}
+	label := server.Process
+	if server.Framework != "" {
+		label += " · " + server.Framework
+	}
+	if server.Branch != "" && server.Branch != "-" {
+		label += " @ " + server.Branch
+	}
+	return fmt.Sprintf("%s (pid %d)", label, pid)
+}
+
+// writeDiagramDOT renders the environment as a Graphviz digraph
+func writeDiagramDOT(w io.Writer, servers []types.Server, edges []depgraph.Edge) error {
+	d := newDiagram(servers, edges)
+
+	var b strings.Builder
+	b.WriteString("digraph lsrv {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, repo := range d.repos {
+		fmt.Fprintf(&b, "  %q [shape=folder];\n", "repo:"+repo)
+		for _, pid := range d.repoApps[repo] {
+			fmt.Fprintf(&b, "  %q -> %q;\n", "repo:"+repo, fmt.Sprint("pid:", pid))
+		}
+	}
+	for _, pid := range d.processes {
+		fmt.Fprintf(&b, "  %q [shape=box, label=%q];\n", fmt.Sprint("pid:", pid), d.processLabel(pid))
+		for _, port := range d.ports[pid] {
+			fmt.Fprintf(&b, "  %q [shape=ellipse, label=%q];\n", fmt.Sprint("port:", port), fmt.Sprint(":", port))
+			fmt.Fprintf(&b, "  %q -> %q;\n", fmt.Sprint("pid:", pid), fmt.Sprint("port:", port))
+		}
+	}
+	for _, edge := range d.edges {
+		fmt.Fprintf(&b, "  %q -> %q [style=dashed", fmt.Sprint("pid:", edge.From.PID), fmt.Sprint("port:", edge.To.Port))
+		if edge.Connections > 1 {
+			fmt.Fprintf(&b, ", label=%q", fmt.Sprint(edge.Connections))
+		}
+		b.WriteString("];\n")
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeDiagramMermaid renders the environment as a Mermaid flowchart, which
+// GitHub and many documentation tools render inline
+func writeDiagramMermaid(w io.Writer, servers []types.Server, edges []depgraph.Edge) error {
+	d := newDiagram(servers, edges)
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, repo := range d.repos {
+		fmt.Fprintf(&b, "  repo%d{{%s}}\n", i, mermaidText(repo))
+		for _, pid := range d.repoApps[repo] {
+			fmt.Fprintf(&b, "  repo%d --> pid%d\n", i, pid)
+		}
+	}
+	for _, pid := range d.processes {
+		fmt.Fprintf(&b, "  pid%d[%s]\n", pid, mermaidText(d.processLabel(pid)))
+		for _, port := range d.ports[pid] {
+			fmt.Fprintf(&b, "  pid%d --> port%d([\":%d\"])\n", pid, port, port)
+		}
+	}
+	for _, edge := range d.edges {
+		if edge.Connections > 1 {
+			fmt.Fprintf(&b, "  pid%d -. %d .-> port%d\n", edge.From.PID, edge.Connections, edge.To.Port)
+		} else {
+			fmt.Fprintf(&b, "  pid%d -.-> port%d\n", edge.From.PID, edge.To.Port)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidEscapes matches characters Mermaid would read as syntax in a label
+var mermaidEscapes = regexp.MustCompile(`["#]`)
+
+// mermaidText quotes a node label, escaping characters as Mermaid entities
+func mermaidText(s string) string {
+	escaped := mermaidEscapes.ReplaceAllStringFunc(s, func(c string) string {
+		if c == `"` {
+			return "#quot;"
+		}
+		return "#35;"
+	})
+	return `"` + escaped + `"`
+}
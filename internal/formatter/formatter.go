@@ -2,107 +2,546 @@ package formatter
 
 import (
 	"fmt"
+	"io"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/depgraph"
 	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/devshell"
+	"github.com/bshakr/lsrv/internal/hyperlink"
+	"github.com/bshakr/lsrv/internal/i18n"
+	"github.com/bshakr/lsrv/internal/icons"
+	"github.com/bshakr/lsrv/internal/platform"
 	"github.com/bshakr/lsrv/internal/types"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/table"
 )
 
-// PrintResults outputs the servers in a formatted table
-func PrintResults(servers []types.Server) {
-	if len(servers) == 0 {
-		fmt.Println("No running web servers found.")
-		return
-	}
-
-	printRoundedTable(servers)
-}
-
-func getProcessIcon(process string, cwd string) string {
-	// First check process name
-	switch process {
-	case "ruby", "rails", "puma":
-		return ""
-	case "node", "npm", "yarn":
-		return "⬢"
-	case "python", "gunicorn", "uvicorn":
-		return "🐍"
-	case "go":
-		return ""
-	case "java":
-		return ""
-	case "php", "php-fpm", "apache2", "httpd":
-		return "🐘"
-	case "cargo":
-		return "" // Nerd Fonts Rust icon
-	case "dotnet", "kestrel":
-		return "" // Nerd Fonts C# icon
-	case "bun":
-		return "🍞"
-	case "elixir", "beam.smp", "mix":
-		return "" // Nerd Fonts Elixir icon
-	}
-
-	// If process name didn't match, check project type from directory
-	projectType := detector.DetectProjectType(cwd)
-	switch projectType {
-	case types.ProjectTypeGo:
-		return ""
-	case types.ProjectTypeRust:
-		return ""
-	case types.ProjectTypeNode:
-		return "⬢"
-	case types.ProjectTypePython:
-		return "🐍"
-	case types.ProjectTypeRuby:
-		return ""
-	}
-
-	// Default fallback
-	return "🌐"
+// slowTTFB and verySlowTTFB grade the LATENCY column: below slowTTFB a
+// page feels instant, above verySlowTTFB the server is likely recompiling
+const (
+	slowTTFB     = 200 * time.Millisecond
+	verySlowTTFB = time.Second
+)
+
+// Format identifies an output format
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatCSV   Format = "csv"
+
+	// FormatPlain is the table as aligned text without borders or colors,
+	// the default when output isn't a terminal
+	FormatPlain Format = "plain"
+
+	// FormatMarkdown is the table as GitHub-flavored Markdown, for issues
+	// and docs
+	FormatMarkdown Format = "markdown"
+
+	// FormatAlfred and FormatRaycast emit launcher items for Alfred Script
+	// Filters and Raycast extensions
+	FormatAlfred  Format = "alfred"
+	FormatRaycast Format = "raycast"
+
+	// FormatDOT and FormatMermaid draw the environment as a repo → server
+	// → port graph, with connections between servers when known
+	FormatDOT     Format = "dot"
+	FormatMermaid Format = "mermaid"
+
+	// FormatOpenMetrics emits server counts and per-server series for
+	// scrapers such as node_exporter's textfile collector
+	FormatOpenMetrics Format = "openmetrics"
+)
+
+// ParseFormat validates a user-supplied format name
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatTable, FormatPlain, FormatMarkdown, FormatJSON, FormatCSV, FormatAlfred, FormatRaycast, FormatDOT, FormatMermaid, FormatOpenMetrics:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q (expected table, plain, markdown, json, csv, alfred, raycast, dot, mermaid or openmetrics)", name)
+}
+
+// Options controls how servers are rendered
+type Options struct {
+	Format Format
+
+	// Width is the terminal width used to fit the table; 0 means unlimited
+	Width int
+
+	// NoTruncate disables shortening of long repo and branch names
+	NoTruncate bool
+
+	// ShowPackage adds the PACKAGE column with each server's directory
+	// within its repository
+	ShowPackage bool
+
+	// ShowSession adds the SESSION column
+	ShowSession bool
+
+	// ShowHost adds the HOST column, which is also shown whenever servers
+	// from other machines are listed
+	ShowHost bool
+
+	// ShowUser adds the USER column, which is also shown whenever a server
+	// belongs to another account than the one running lsrv
+	ShowUser bool
+
+	// ShowLastCommit adds the LAST COMMIT column
+	ShowLastCommit bool
+
+	// ShowStartedBy adds the STARTED BY column with what launched each
+	// server
+	ShowStartedBy bool
+
+	// ShowCommand adds the COMMAND column with each full command line
+	ShowCommand bool
+
+	// ShowRuntime adds the VERSION column with each runtime and version
+	ShowRuntime bool
+
+	// ShowLatency adds the LATENCY column with each server's time to first
+	// byte, colored by how slow it is
+	ShowLatency bool
+
+	// Units picks humanized or raw latencies in the LATENCY column
+	Units Units
+
+	// ShowBacklog adds the BACKLOG column with how full each server's
+	// accept queue is, red when saturated
+	ShowBacklog bool
+
+	// ShowEnv adds the ENV column naming each server's direnv or devenv
+	// setup
+	ShowEnv bool
+
+	// ShowLAN adds the LAN URL column for opening servers from other devices
+	ShowLAN bool
+
+	// ShowTunnels adds the PUBLIC URL column with the ngrok, cloudflared or
+	// tailscale funnel URL forwarding to each server
+	ShowTunnels bool
+
+	// Accessible adds a STATUS column spelling out each server's status
+	// next to its symbol, so no status is conveyed by color alone
+	Accessible bool
+
+	// MainBranches are long-lived branches, such as main, whose servers
+	// get a marked and colored BRANCH cell
+	MainBranches []string
+
+	// BranchStyles color and badge the BRANCH cells of matching branches
+	BranchStyles []config.BranchStyle
+
+	// Icons resolves process icons and labels; nil uses the defaults
+	Icons *icons.Set
+
+	// Columns are extra columns supplied by the caller, placed before the
+	// URL, such as the interactive mode's live HEALTH column
+	Columns []Column
+
+	// Highlight, when non-nil, marks the rows it returns true for, such as
+	// the selected row in interactive mode
+	Highlight func(types.Server) bool
+
+	// Mark, when non-nil, may rewrite the cells of each row after they're
+	// fitted to the width, such as to emphasize the characters matching
+	// interactive mode's filter. Headers are untranslated.
+	Mark func(server types.Server, headers, cells []string)
+
+	// Renderer overrides color detection; nil detects it from the writer
+	Renderer *lipgloss.Renderer
+
+	// Edges are the connections between servers drawn by the dot and
+	// mermaid formats
+	Edges []depgraph.Edge
+
+	// Warnings explain why the listing may be incomplete; the JSON format
+	// includes them
+	Warnings []detector.Warning
+
+	// Hyperlinks makes URLs, and repos with a remote, clickable with OSC 8
+	// escape sequences; only set it for terminals that support them
+	Hyperlinks bool
+}
+
+// Column is a caller-supplied table column
+type Column struct {
+	Header string
+	Value  func(types.Server) string
+
+	// Style, when non-nil, styles the column's cells
+	Style func(types.Server, lipgloss.Style) lipgloss.Style
+}
+
+// Write renders the servers to w according to opts
+func Write(w io.Writer, servers []types.Server, opts Options) error {
+	switch opts.Format {
+	case FormatJSON:
+		return writeJSON(w, servers, opts.Warnings)
+	case FormatCSV:
+		return writeCSV(w, servers)
+	case FormatAlfred:
+		return writeAlfred(w, servers)
+	case FormatRaycast:
+		return writeRaycast(w, servers)
+	case FormatDOT:
+		return writeDiagramDOT(w, servers, opts.Edges)
+	case FormatMermaid:
+		return writeDiagramMermaid(w, servers, opts.Edges)
+	case FormatOpenMetrics:
+		return writeOpenMetrics(w, servers)
+	}
+
+	if opts.NoTruncate {
+		opts.Width = 0
+	}
+
+	// Servers from other machines say which one they run on
+	if !opts.ShowHost {
+		opts.ShowHost = slices.ContainsFunc(servers, func(s types.Server) bool { return s.Host != "" })
+	}
+
+	// On a shared machine, rows owned by other accounts say whose they are
+	if !opts.ShowUser {
+		invoking := platform.InvokingUID()
+		opts.ShowUser = slices.ContainsFunc(servers, func(s types.Server) bool { return s.UID != invoking })
+	}
+
+	// Auxiliary services get their own table below the app servers
+	var apps, svcs []types.Server
+	for _, server := range servers {
+		if server.Service != "" {
+			svcs = append(svcs, server)
+		} else {
+			apps = append(apps, server)
+		}
+	}
+
+	printTable := func(servers []types.Server, columns []column) error {
+		switch opts.Format {
+		case FormatPlain:
+			return printPlainTable(w, servers, columns)
+		case FormatMarkdown:
+			return printMarkdownTable(w, servers, columns)
+		}
+		return printRoundedTable(w, servers, columns, opts)
+	}
+
+	if len(apps) == 0 {
+		if _, err := fmt.Fprintln(w, i18n.T("No running web servers found.")); err != nil {
+			return err
+		}
+	} else if err := printTable(apps, tableColumns(opts)); err != nil {
+		return err
+	}
+
+	if len(svcs) == 0 {
+		return nil
+	}
+	title := "\n" + i18n.T("Services")
+	if opts.Format == FormatMarkdown {
+		// A Markdown table needs a blank line after the text above it
+		title = "\n### " + i18n.T("Services") + "\n"
+	}
+	if _, err := fmt.Fprintln(w, title); err != nil {
+		return err
+	}
+	return printTable(svcs, serviceColumns(opts))
 }
 
 // ============================================================================
 // TABLE RENDERING
 // ============================================================================
 
-// printRoundedTable renders the table with rounded borders
-func printRoundedTable(servers []types.Server) {
-	rows := serversToRows(servers)
+// columnID identifies a table column independent of its position
+type columnID int
+
+const (
+	colRepo columnID = iota
+	colBranch
+	colProcess
+	colPID
+	colRuntime
+	colEnv
+	colLatency
+	colBacklog
+	colUser
+	colLastCommit
+	colSession
+	colStartedBy
+	colCommand
+	colStatus
+	colURL
+	colLAN
+	colTunnel
+	colPackage
+	colService
+	colAddress
+	colHost
+
+	// colExtra is the first caller-supplied column; the i-th has
+	// colExtra+i
+	colExtra
+)
+
+// column describes how a table column is titled and filled
+type column struct {
+	id     columnID
+	header string
+	value  func(types.Server) string
+
+	// truncate allows shortening the column on narrow terminals
+	truncate bool
+}
+
+// tableColumns returns the columns to render, with optional columns placed
+// before the URL
+func tableColumns(opts Options) []column {
+	var columns []column
+	if opts.ShowHost {
+		columns = append(columns, column{colHost, "HOST", func(s types.Server) string { return orDash(s.Host) }, false})
+	}
+	columns = append(columns, []column{
+		{colRepo, "REPO", func(s types.Server) string {
+			repo := s.Repo
+			if !opts.Accessible && s.Status != "" && s.Status != types.StatusHealthy {
+				repo = opts.Icons.StatusSymbol(s.Status) + " " + repo
+			}
+			if s.PrimaryPort != 0 {
+				repo = opts.Icons.AuxMarker() + " " + repo
+			}
+			if s.Current {
+				repo = opts.Icons.CurrentMarker() + " " + repo
+			}
+			return repo
+		}, true},
+		{colBranch, "BRANCH", func(s types.Server) string {
+			branch := s.Branch
+			if slices.Contains(opts.MainBranches, s.Branch) {
+				branch = opts.Icons.MainBranchMarker() + " " + branch
+			}
+			if style, ok := branchStyle(opts.BranchStyles, s.Branch); ok && style.Badge != "" {
+				branch = badgeText(style.Badge) + " " + branch
+			}
+			return branch
+		}, true},
+	}...)
+
+	if opts.ShowPackage {
+		columns = append(columns, column{colPackage, "PACKAGE", func(s types.Server) string { return orDash(s.Package) }, true})
+	}
+
+	columns = append(columns, []column{
+		{colProcess, "PROCESS", func(s types.Server) string {
+			label := nixLabel(auxLabel(processLabel(opts.Icons.Label(s.Process, s.CWD), s.Workers), s.AuxPorts), s.Nix, opts.Icons)
+			if s.Framework != "" {
+				return portLabel(fmt.Sprintf("%s %s · %s", opts.Icons.FrameworkIcon(s.Framework, s.CWD), label, s.Framework), s.Label)
+			}
+			icon := opts.Icons.Icon(s.Process, detector.DetectProjectType(s.CWD), s.CWD)
+			return portLabel(fmt.Sprintf("%s %s", icon, label), s.Label)
+		}, false},
+		{colPID, "PID", func(s types.Server) string { return fmt.Sprintf("%d", s.PID) }, false},
+	}...)
+
+	if opts.ShowRuntime {
+		columns = append(columns, column{colRuntime, "VERSION", func(s types.Server) string { return orDash(s.Runtime) }, false})
+	}
+	if opts.ShowEnv {
+		columns = append(columns, column{colEnv, "ENV", func(s types.Server) string { return orDash(devshell.String(s.Env)) }, false})
+	}
+	if opts.ShowLatency {
+		columns = append(columns, column{colLatency, "LATENCY", func(s types.Server) string {
+			if s.TTFB == 0 {
+				return "-"
+			}
+			return opts.Units.Latency(s.TTFB)
+		}, false})
+	}
+	if opts.ShowBacklog {
+		columns = append(columns, column{colBacklog, "BACKLOG", func(s types.Server) string {
+			if s.Backlog == nil {
+				return "-"
+			}
+			return s.Backlog.String()
+		}, false})
+	}
+	if opts.ShowUser {
+		columns = append(columns, column{colUser, "USER", func(s types.Server) string { return orDash(s.User) }, false})
+	}
+	if opts.ShowLastCommit {
+		columns = append(columns, column{colLastCommit, "LAST COMMIT", func(s types.Server) string {
+			if s.LastCommit == nil {
+				return "-"
+			}
+			return formatAge(time.Since(*s.LastCommit))
+		}, false})
+	}
+	if opts.ShowSession {
+		columns = append(columns, column{colSession, "SESSION", func(s types.Server) string { return orDash(s.Session) }, true})
+	}
+	if opts.ShowStartedBy {
+		columns = append(columns, column{colStartedBy, "STARTED BY", func(s types.Server) string { return orDash(strings.Join(s.StartedBy, "→")) }, true})
+	}
+
+	if opts.ShowCommand {
+		columns = append(columns, column{colCommand, "COMMAND", func(s types.Server) string { return orDash(s.CommandLine) }, true})
+	}
+
+	if opts.ShowLAN {
+		columns = append(columns, column{colLAN, "LAN URL", func(s types.Server) string {
+			if s.Firewall != nil && s.Firewall.State == types.FirewallBlocked {
+				return fmt.Sprintf("%s (%s)", s.LANURL, i18n.T("blocked by %s", s.Firewall.By))
+			}
+			return orDash(s.LANURL)
+		}, false})
+	}
+
+	if opts.ShowTunnels {
+		columns = append(columns, column{colTunnel, "PUBLIC URL", func(s types.Server) string {
+			switch len(s.Tunnels) {
+			case 0:
+				return "-"
+			case 1:
+				return s.Tunnels[0].URL
+			}
+			return fmt.Sprintf("%s (+%d)", s.Tunnels[0].URL, len(s.Tunnels)-1)
+		}, false})
+	}
+
+	if opts.Accessible {
+		columns = append(columns, column{colStatus, "STATUS", func(s types.Server) string {
+			if s.Status == "" {
+				return "-"
+			}
+			return opts.Icons.StatusSymbol(s.Status) + " " + string(s.Status)
+		}, false})
+	}
+
+	for i, extra := range opts.Columns {
+		columns = append(columns, column{colExtra + columnID(i), extra.Header, extra.Value, false})
+	}
+
+	return append(columns, column{colURL, "URL", func(s types.Server) string {
+		return containerPortLabel(s.DisplayURL(), s.DevContainer)
+	}, false})
+}
+
+// serviceColumns returns the columns of the auxiliary services table
+func serviceColumns(opts Options) []column {
+	var columns []column
+	if opts.ShowHost {
+		columns = append(columns, column{colHost, "HOST", func(s types.Server) string { return orDash(s.Host) }, false})
+	}
+	columns = append(columns, []column{
+		{colService, "SERVICE", func(s types.Server) string {
+			return portLabel(fmt.Sprintf("%s %s", opts.Icons.ServiceIcon(s.Service), s.Service), s.Label)
+		}, false},
+		{colProcess, "PROCESS", func(s types.Server) string { return nixLabel(processLabel(s.Process, s.Workers), s.Nix, opts.Icons) }, false},
+		{colPID, "PID", func(s types.Server) string { return fmt.Sprintf("%d", s.PID) }, false},
+	}...)
+
+	if opts.ShowEnv {
+		columns = append(columns, column{colEnv, "ENV", func(s types.Server) string { return orDash(devshell.String(s.Env)) }, false})
+	}
+	if opts.ShowUser {
+		columns = append(columns, column{colUser, "USER", func(s types.Server) string { return orDash(s.User) }, false})
+	}
+
+	return append(columns, column{colAddress, "ADDRESS", func(s types.Server) string {
+		return fmt.Sprintf("localhost:%d", s.Port)
+	}, false})
+}
+
+// printRoundedTable renders the table with rounded borders, fitting it to
+// opts.Width when the width is non-zero by truncating long columns and, if
+// that is not enough, hiding the less useful ones
+func printRoundedTable(w io.Writer, servers []types.Server, columns []column, opts Options) error {
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = i18n.T(col.header)
+	}
+
+	columns, headers, rows := fitTable(columns, headers, serversToRows(servers, columns), opts.Width)
+	if opts.Mark != nil {
+		keys := make([]string, len(columns))
+		for i, col := range columns {
+			keys[i] = col.header
+		}
+		for i, server := range servers {
+			opts.Mark(server, keys, rows[i])
+		}
+	}
+	if opts.Hyperlinks {
+		linkRows(rows, servers, columns)
+	}
+
+	// Render against w so colors are dropped when writing to a file or pipe
+	renderer := opts.Renderer
+	if renderer == nil {
+		renderer = lipgloss.NewRenderer(w)
+	}
 
 	// Header style - bold, white text
-	headerStyle := lipgloss.NewStyle().
+	headerStyle := renderer.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color("15")).
 		Padding(0, 2)
 
 	// Cell style - normal weight
-	cellStyle := lipgloss.NewStyle().
+	cellStyle := renderer.NewStyle().
 		Padding(0, 2)
 
-	// Create table with rounded borders
-	t := table.New().
-		Border(lipgloss.RoundedBorder()).
-		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("8"))).
-		Headers("REPO", "BRANCH", "PROCESS", "PID", "URL").
-		StyleFunc(func(row, col int) lipgloss.Style {
-			// Use table.HeaderRow constant for header detection
-			if row == table.HeaderRow {
-				return headerStyle
-			}
+	styleFor := func(row, col int) lipgloss.Style {
+		// Use table.HeaderRow constant for header detection
+		if row == table.HeaderRow {
+			return headerStyle
+		}
 
-			// Data rows
-			if row < 0 || row >= len(servers) {
-				return cellStyle
+		// Data rows
+		if row < 0 || row >= len(servers) || col >= len(columns) {
+			return cellStyle
+		}
+
+		style := getCellStyle(servers[row], columns[col].id, cellStyle, opts.Icons)
+		if columns[col].id == colBranch {
+			if slices.Contains(opts.MainBranches, servers[row].Branch) {
+				style = style.Foreground(lipgloss.Color("3")) // Yellow
+			}
+			if branch, ok := branchStyle(opts.BranchStyles, servers[row].Branch); ok && branch.Color != "" {
+				style = style.Foreground(lipgloss.Color(branch.Color))
 			}
+		}
+		if id := columns[col].id; id >= colExtra && opts.Columns[id-colExtra].Style != nil {
+			style = opts.Columns[id-colExtra].Style(servers[row], style)
+		}
+		if servers[row].Current {
+			style = style.Bold(true)
+		}
+		if opts.Highlight != nil && opts.Highlight(servers[row]) {
+			style = style.Reverse(true)
+		}
+		return style
+	}
+	if len(opts.BranchStyles) > 0 {
+		badgeRows(rows, servers, columns, opts.BranchStyles, renderer, styleFor)
+	}
 
-			return getCellStyle(servers[row], col, cellStyle)
-		}).
+	// Create table with rounded borders
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(renderer.NewStyle().Foreground(lipgloss.Color("8"))).
+		Headers(headers...).
+		StyleFunc(styleFor).
 		Rows(rows...)
 
-	fmt.Println(t)
+	_, err := fmt.Fprintln(w, t)
+	return err
 }
 
 // ============================================================================
@@ -110,62 +549,185 @@ func printRoundedTable(servers []types.Server) {
 // ============================================================================
 
 // serversToRows converts servers to table row format
-func serversToRows(servers []types.Server) [][]string {
+func serversToRows(servers []types.Server, columns []column) [][]string {
 	rows := make([][]string, len(servers))
 	for i, server := range servers {
-		icon := getProcessIcon(server.Process, server.CWD)
-		url := fmt.Sprintf("http://localhost:%d", server.Port)
-		rows[i] = []string{
-			server.Repo,
-			server.Branch,
-			fmt.Sprintf("%s %s", icon, server.Process),
-			fmt.Sprintf("%d", server.PID),
-			url,
+		rows[i] = make([]string, len(columns))
+		for j, col := range columns {
+			rows[i][j] = col.value(server)
 		}
 	}
 	return rows
 }
 
+// linkRows turns URL and LAN URL cells into links to the full URL, and repo
+// cells into links to the remote, after fitting so truncated text still
+// opens the whole target
+func linkRows(rows [][]string, servers []types.Server, columns []column) {
+	for i, server := range servers {
+		for j, col := range columns {
+			switch col.id {
+			case colURL:
+				rows[i][j] = hyperlink.Wrap(server.DisplayURL(), rows[i][j])
+			case colLAN:
+				rows[i][j] = hyperlink.Wrap(server.LANURL, rows[i][j])
+			case colTunnel:
+				if len(server.Tunnels) > 0 {
+					rows[i][j] = hyperlink.Wrap(server.Tunnels[0].URL, rows[i][j])
+				}
+			case colRepo:
+				rows[i][j] = hyperlink.Wrap(server.RemoteURL, rows[i][j])
+			}
+		}
+	}
+}
+
+// containerPortLabel notes the port a server in a dev container listens on
+// inside it, when the host reaches it on another
+func containerPortLabel(url string, dc *types.DevContainer) string {
+	if dc == nil || dc.ContainerPort == dc.HostPort {
+		return url
+	}
+	return fmt.Sprintf("%s (container :%d)", url, dc.ContainerPort)
+}
+
+// processLabel appends the worker count, if any, to a process name
+func processLabel(name string, workers int) string {
+	switch workers {
+	case 0:
+		return name
+	case 1:
+		return fmt.Sprintf("%s (1 worker)", name)
+	}
+	return fmt.Sprintf("%s (%d workers)", name, workers)
+}
+
+// nixLabel marks processes started inside a Nix development shell, e.g.
+// "puma ❄"
+func nixLabel(label, nix string, set *icons.Set) string {
+	if nix == "" {
+		return label
+	}
+	return label + " " + set.NixMarker()
+}
+
+// portLabel appends the name the repo gives the port, if any, e.g.
+// "puma · web"
+func portLabel(text, label string) string {
+	if label == "" {
+		return text
+	}
+	return text + " · " + label
+}
+
+// auxLabel appends the number of folded auxiliary ports, if any, to a
+// process label
+func auxLabel(label string, ports []int) string {
+	switch len(ports) {
+	case 0:
+		return label
+	case 1:
+		return fmt.Sprintf("%s (+1 port)", label)
+	}
+	return fmt.Sprintf("%s (+%d ports)", label, len(ports))
+}
+
+// formatAge renders a duration as a compact relative age like "3d ago"
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 60*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(d.Hours()/24/30))
+	}
+	return fmt.Sprintf("%dy ago", int(d.Hours()/24/365))
+}
+
+// FormatBytes renders a size such as "512K", "183.4M" or "2.1G"
+func FormatBytes(n uint64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fG", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fM", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%dK", n>>10)
+}
+
+// orDash substitutes a dash for empty optional values
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
 // getCellStyle returns the appropriate lipgloss style for a cell
-func getCellStyle(server types.Server, col int, baseStyle lipgloss.Style) lipgloss.Style {
-	// Define colors for process types (used as fallback)
-	colors := map[string]lipgloss.Color{
-		"ruby":   lipgloss.Color("1"), // Red
-		"node":   lipgloss.Color("2"), // Green
-		"python": lipgloss.Color("3"), // Yellow
-		"cargo":  lipgloss.Color("1"), // Red for Rust
-	}
-
-	// Color the process column based on type
-	if col == 2 {
-		// Detect color based on project type or process name
-		projectType := detector.DetectProjectType(server.CWD)
-		switch projectType {
-		case types.ProjectTypeGo:
-			return baseStyle.Foreground(lipgloss.Color("6")) // Cyan
-		case types.ProjectTypeRust:
+func getCellStyle(server types.Server, col columnID, baseStyle lipgloss.Style, set *icons.Set) lipgloss.Style {
+	// Highlight servers needing attention; the symbol carries the meaning
+	if col == colRepo || col == colStatus {
+		switch server.Status {
+		case types.StatusZombie:
+			return baseStyle.Foreground(lipgloss.Color("3")) // Yellow
+		case types.StatusConflict:
 			return baseStyle.Foreground(lipgloss.Color("1")) // Red
-		case types.ProjectTypeNode:
+		case types.StatusStale:
+			return baseStyle.Foreground(lipgloss.Color("8")) // Gray
+		case types.StatusUnusual:
+			return baseStyle.Foreground(lipgloss.Color("6")) // Cyan
+		case types.StatusReserved:
+			return baseStyle.Foreground(lipgloss.Color("5")) // Magenta
+		}
+	}
+
+	// Grade latency: fast is green, noticeable yellow, slow red
+	if col == colLatency && server.TTFB > 0 {
+		switch {
+		case server.TTFB < slowTTFB:
 			return baseStyle.Foreground(lipgloss.Color("2")) // Green
-		case types.ProjectTypePython:
+		case server.TTFB < verySlowTTFB:
 			return baseStyle.Foreground(lipgloss.Color("3")) // Yellow
-		case types.ProjectTypeRuby:
-			return baseStyle.Foreground(lipgloss.Color("1")) // Red
 		}
+		return baseStyle.Foreground(lipgloss.Color("1")) // Red
+	}
 
-		// Fallback to process name matching using standard library
-		for processType, color := range colors {
-			if strings.Contains(server.Process, processType) {
-				return baseStyle.Foreground(color)
-			}
+	// Queued connections mean the server falls behind; a full queue hangs
+	// new ones
+	if col == colBacklog && server.Backlog != nil {
+		switch {
+		case server.Backlog.Saturated():
+			return baseStyle.Foreground(lipgloss.Color("1")) // Red
+		case server.Backlog.Queued > 0:
+			return baseStyle.Foreground(lipgloss.Color("3")) // Yellow
 		}
+	}
+
+	// Color services consistently; their process names vary
+	if col == colService {
+		return baseStyle.Foreground(lipgloss.Color("5")) // Magenta
+	}
 
-		// Default for unknown processes
+	// Color the process column after its framework or runtime; an unknown
+	// CWD must not be resolved relative to lsrv's own directory
+	if col == colProcess {
+		var projectType types.ProjectType
+		if server.CWD != "" {
+			projectType = detector.DetectProjectType(server.CWD)
+		}
+		if color := set.Color(server.Process, server.Framework, projectType); color != "" {
+			return baseStyle.Foreground(lipgloss.Color(color))
+		}
 		return baseStyle.Foreground(lipgloss.Color("7")) // White
 	}
 
-	// Color URLs blue
-	if col == 4 {
+	// Color URLs and addresses blue
+	if col == colURL || col == colLAN || col == colTunnel || col == colAddress {
 		return baseStyle.Foreground(lipgloss.Color("4")) // Blue
 	}
 
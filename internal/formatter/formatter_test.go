@@ -0,0 +1,131 @@
+package formatter
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/icons"
+	"github.com/bshakr/lsrv/internal/types"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// update rewrites the golden files with the current output, for reviewing
+// formatter changes as a diff of testdata:
+//
+//	go test ./internal/formatter -update
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// fixtureTime keeps timestamps in the output fixed
+var fixtureTime = time.Date(2025, 3, 14, 9, 26, 53, 0, time.UTC)
+
+// fixtures are the server listings rendered in every format. Directories
+// don't exist, so no project type or .lsrv.yml is picked up from the
+// machine running the tests.
+func fixtures() map[string][]types.Server {
+	return map[string][]types.Server{
+		"empty": nil,
+		"basic": {
+			{Repo: "shop", Branch: "main", Process: "node", PID: 4242, Port: 3000, CWD: "/nonexistent/shop", User: "dev", UID: 501, Framework: "storybook", Status: types.StatusHealthy, Current: true, TTFB: 12345 * time.Microsecond, Uptime: 2*time.Hour + 5*time.Minute, Memory: 183 << 20},
+			{Repo: "api", Branch: "feature/payments", Process: "ruby", PID: 4243, Port: 3001, CWD: "/nonexistent/api", User: "dev", UID: 501, Workers: 4, Status: types.StatusZombie, LastCommit: &fixtureTime},
+			{Repo: "shop", Branch: "main", Process: "node", PID: 4244, Port: 3035, CWD: "/nonexistent/shop", User: "dev", UID: 501, PrimaryPort: 3000, Label: "webpack"},
+			{Repo: "release", Branch: "release/2.4", Process: "python3", PID: 4245, Port: 8000, CWD: "/nonexistent/release", User: "dev", UID: 501, Protocol: "grpc"},
+			{Process: "postgres", PID: 812, Port: 5432, User: "dev", UID: 501, Service: "postgres"},
+		},
+		"unicode": {
+			{Repo: "café", Branch: "fix/ünïcödé-ブランチ", Process: "beam.smp", PID: 5100, Port: 4000, CWD: "/nonexistent/café", User: "dev", UID: 501, Framework: "phoenix"},
+			{Repo: "日本語サイト", Branch: "機能/検索", Process: "bun", PID: 5101, Port: 5174, CWD: "/nonexistent/日本語サイト", User: "dev", UID: 501},
+			{Repo: "emoji", Branch: "🚀-launch", Process: "deno", PID: 5102, Port: 8080, CWD: "/nonexistent/emoji", User: "dev", UID: 501},
+		},
+		"long_names": {
+			{Repo: "an-exceedingly-long-repository-name-from-a-monorepo-split", Branch: "feature/JIRA-12345-rework-the-checkout-flow-for-guest-users", Process: "node", PID: 6100, Port: 3000, CWD: "/nonexistent/long", User: "dev", UID: 501},
+			{Repo: "short", Branch: "a-branch-name-that-is-also-much-longer-than-any-terminal-column", Process: "ruby", PID: 6101, Port: 3001, CWD: "/nonexistent/short", User: "dev", UID: 501, CustomURL: "https://short.localhost/with/a/long/path"},
+		},
+	}
+}
+
+// widths are the terminal widths tables are fitted to; narrow forces
+// truncation, 0 renders them in full
+var widths = map[string]int{
+	"wide":   0,
+	"narrow": 60,
+}
+
+// renderOptions returns options rendering the same on any machine: no
+// colors, ASCII icons and the built-in defaults. The USER column is always
+// shown, as it otherwise depends on who runs the tests.
+func renderOptions(format Format, width int) Options {
+	renderer := lipgloss.NewRenderer(&bytes.Buffer{})
+	renderer.SetColorProfile(termenv.Ascii)
+	return Options{
+		Format:       format,
+		Width:        width,
+		ShowUser:     true,
+		MainBranches: []string{"main"},
+		BranchStyles: []config.BranchStyle{{Pattern: "release/*", Badge: "REL"}},
+		Icons:        icons.NewSet(&config.Config{}, true),
+		Renderer:     renderer,
+	}
+}
+
+// assertGolden compares got with testdata/name, rewriting it with -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run go test -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run go test -update and review the diff)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestWriteGolden(t *testing.T) {
+	for fixture, servers := range fixtures() {
+		// Only the bordered table is fitted to the terminal
+		for size, width := range widths {
+			name := fixture + ".table." + size + ".golden"
+			t.Run(name, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := Write(&buf, servers, renderOptions(FormatTable, width)); err != nil {
+					t.Fatal(err)
+				}
+				assertGolden(t, name, buf.Bytes())
+				if width == 0 {
+					return
+				}
+				for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+					if got := lipgloss.Width(line); got > width {
+						t.Errorf("line is %d cells wide, wider than %d: %s", got, width, line)
+					}
+				}
+			})
+		}
+		for _, format := range []Format{FormatPlain, FormatMarkdown, FormatJSON, FormatCSV, FormatAlfred, FormatRaycast, FormatDOT, FormatMermaid, FormatOpenMetrics} {
+			name := fixture + "." + string(format) + ".golden"
+			t.Run(name, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := Write(&buf, servers, renderOptions(format, 0)); err != nil {
+					t.Fatal(err)
+				}
+				assertGolden(t, name, buf.Bytes())
+			})
+		}
+	}
+}
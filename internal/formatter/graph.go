@@ -0,0 +1,81 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/depgraph"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// WriteGraph renders edges as a tree per connecting server:
+//
+//	webapp:3000 (node)
+//	├─→ postgres:5432 (4 connections)
+//	└─→ redis:6379
+func WriteGraph(w io.Writer, edges []depgraph.Edge, ascii bool) error {
+	branch, last := "├─→ ", "└─→ "
+	if ascii {
+		branch, last = "|-> ", "`-> "
+	}
+
+	for i, edge := range edges {
+		if i == 0 || edges[i-1].From.PID != edge.From.PID {
+			if i > 0 {
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(w, "%s (%s)\n", graphNode(edge.From), edge.From.Process); err != nil {
+				return err
+			}
+		}
+
+		prefix := branch
+		if i == len(edges)-1 || edges[i+1].From.PID != edge.From.PID {
+			prefix = last
+		}
+		if _, err := fmt.Fprintf(w, "%s%s%s\n", prefix, graphNode(edge.To), connectionCount(edge.Connections)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteDOT renders edges as a Graphviz digraph, for "dot -Tsvg"
+func WriteDOT(w io.Writer, edges []depgraph.Edge) error {
+	var b strings.Builder
+	b.WriteString("digraph lsrv {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, edge := range edges {
+		fmt.Fprintf(&b, "  %q -> %q", graphNode(edge.From), graphNode(edge.To))
+		if edge.Connections > 1 {
+			fmt.Fprintf(&b, " [label=%q]", fmt.Sprint(edge.Connections))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// graphNode names a server in the graph by its repo or service and port
+func graphNode(server types.Server) string {
+	name := server.Repo
+	if server.Service != "" {
+		name = server.Service
+	}
+	return fmt.Sprintf("%s:%d", name, server.Port)
+}
+
+// connectionCount describes a pool of connections; a single one needs no
+// note
+func connectionCount(n int) string {
+	if n <= 1 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d connections)", n)
+}
@@ -0,0 +1,90 @@
+package formatter
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/types"
+	"github.com/bshakr/lsrv/pkg/schema"
+)
+
+// writeJSON renders the servers and warnings as an indented schema.Output
+// object
+func writeJSON(w io.Writer, servers []types.Server, warnings []detector.Warning) error {
+	out := schema.Output{
+		SchemaVersion: schema.Version,
+		Servers:       make([]schema.Server, len(servers)),
+		Warnings:      make([]schema.Warning, len(warnings)),
+	}
+	for i, server := range servers {
+		out.Servers[i] = schemaServer(server)
+	}
+	for i, warning := range warnings {
+		out.Warnings[i] = schema.Warning{Code: warning.Code, Count: warning.Count, Details: warning.Details}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// schemaServer copies a server into the published schema, which keeps the
+// output stable as the internal type changes
+func schemaServer(s types.Server) schema.Server {
+	server := schema.Server{
+		Repo:          s.Repo,
+		Branch:        s.Branch,
+		Process:       s.Process,
+		Port:          s.Port,
+		PID:           s.PID,
+		CWD:           s.CWD,
+		User:          s.User,
+		UID:           s.UID,
+		Package:       s.Package,
+		Host:          s.Host,
+		URL:           s.LocalURL(),
+		Workers:       s.Workers,
+		AuxPorts:      s.AuxPorts,
+		PrimaryPort:   s.PrimaryPort,
+		LastCommit:    s.LastCommit,
+		Runtime:       s.Runtime,
+		Nix:           s.Nix,
+		RemoteURL:     s.RemoteURL,
+		Service:       s.Service,
+		Bind:          s.Bind,
+		LANURL:        s.LANURL,
+		FriendlyURL:   s.FriendlyURL,
+		CustomURL:     s.CustomURL,
+		Framework:     s.Framework,
+		CommandLine:   s.CommandLine,
+		Paths:         s.Paths,
+		Links:         s.Links(),
+		Current:       s.Current,
+		Status:        string(s.Status),
+		Label:         s.Label,
+		Session:       s.Session,
+		StartedBy:     s.StartedBy,
+		TTFBMS:        types.Milliseconds(s.TTFB),
+		UptimeSeconds: int64(s.Uptime / time.Second),
+		MemoryBytes:   s.Memory,
+		Protocol:      s.Protocol,
+	}
+	if s.Env != nil {
+		server.Env = &schema.Env{Tool: s.Env.Tool, File: s.Env.File, Loaded: s.Env.Loaded, Vars: s.Env.Vars}
+	}
+	for _, tunnel := range s.Tunnels {
+		server.Tunnels = append(server.Tunnels, schema.Tunnel{Provider: tunnel.Provider, URL: tunnel.URL})
+	}
+	if s.Firewall != nil {
+		server.Firewall = &schema.Firewall{State: string(s.Firewall.State), By: s.Firewall.By, Detail: s.Firewall.Detail}
+	}
+	if s.Backlog != nil {
+		server.Backlog = &schema.Backlog{Queued: s.Backlog.Queued, Limit: s.Backlog.Limit}
+	}
+	if s.DevContainer != nil {
+		server.DevContainer = &schema.DevContainer{Name: s.DevContainer.Name, ContainerPort: s.DevContainer.ContainerPort, HostPort: s.DevContainer.HostPort}
+	}
+	return server
+}
@@ -0,0 +1,134 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// launcherItem is the launcher-neutral view of a server that the Alfred and
+// Raycast schemas are projected from
+type launcherItem struct {
+	id       string
+	title    string
+	subtitle string
+	url      string
+	server   types.Server
+}
+
+// launcherItems maps servers to launcher items titled by repo (or service)
+// with branch, process and port as the subtitle
+func launcherItems(servers []types.Server) []launcherItem {
+	items := make([]launcherItem, 0, len(servers))
+	for _, server := range servers {
+		item := launcherItem{
+			id:     strconv.Itoa(server.Port),
+			title:  server.Repo,
+			url:    server.DisplayURL(),
+			server: server,
+		}
+		if server.Service != "" {
+			item.title = server.Service
+			item.subtitle = fmt.Sprintf("%s · :%d", server.Process, server.Port)
+		} else {
+			item.subtitle = fmt.Sprintf("%s · %s · :%d", server.Branch, processLabel(server.Process, server.Workers), server.Port)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// alfredItem follows Alfred's Script Filter JSON format
+type alfredItem struct {
+	UID          string            `json:"uid"`
+	Title        string            `json:"title"`
+	Subtitle     string            `json:"subtitle"`
+	Arg          string            `json:"arg"`
+	Autocomplete string            `json:"autocomplete,omitempty"`
+	Valid        *bool             `json:"valid,omitempty"`
+	Text         *alfredText       `json:"text,omitempty"`
+	Variables    map[string]string `json:"variables,omitempty"`
+}
+
+type alfredText struct {
+	Copy      string `json:"copy"`
+	LargeType string `json:"largetype"`
+}
+
+// writeAlfred renders the servers as an Alfred Script Filter result. The
+// port, PID and directory are exposed as workflow variables.
+func writeAlfred(w io.Writer, servers []types.Server) error {
+	items := []alfredItem{}
+	for _, item := range launcherItems(servers) {
+		items = append(items, alfredItem{
+			UID:          item.id,
+			Title:        item.title,
+			Subtitle:     item.subtitle,
+			Arg:          item.url,
+			Autocomplete: item.title,
+			Text:         &alfredText{Copy: item.url, LargeType: item.url},
+			Variables: map[string]string{
+				"port": strconv.Itoa(item.server.Port),
+				"pid":  strconv.Itoa(item.server.PID),
+				"cwd":  item.server.CWD,
+			},
+		})
+	}
+
+	// Alfred shows nothing for an empty list, so say why
+	if len(items) == 0 {
+		valid := false
+		items = append(items, alfredItem{Title: "No running web servers found", Valid: &valid})
+	}
+
+	return encodeLauncher(w, struct {
+		Items []alfredItem `json:"items"`
+	}{items})
+}
+
+// raycastItem mirrors the props of a Raycast List.Item so an extension can
+// spread it directly
+type raycastItem struct {
+	ID          string             `json:"id"`
+	Title       string             `json:"title"`
+	Subtitle    string             `json:"subtitle"`
+	Arg         string             `json:"arg"`
+	Accessories []raycastAccessory `json:"accessories,omitempty"`
+	Keywords    []string           `json:"keywords,omitempty"`
+}
+
+type raycastAccessory struct {
+	Text string `json:"text"`
+}
+
+// writeRaycast renders the servers as a list of Raycast items
+func writeRaycast(w io.Writer, servers []types.Server) error {
+	items := []raycastItem{}
+	for _, item := range launcherItems(servers) {
+		keywords := []string{strconv.Itoa(item.server.Port), item.server.Process}
+		if item.server.Branch != "" {
+			keywords = append(keywords, item.server.Branch)
+		}
+		items = append(items, raycastItem{
+			ID:          item.id,
+			Title:       item.title,
+			Subtitle:    item.subtitle,
+			Arg:         item.url,
+			Accessories: []raycastAccessory{{Text: "PID " + strconv.Itoa(item.server.PID)}},
+			Keywords:    keywords,
+		})
+	}
+
+	return encodeLauncher(w, struct {
+		Items []raycastItem `json:"items"`
+	}{items})
+}
+
+func encodeLauncher(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,38 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/i18n"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// printMarkdownTable renders the table as a GitHub-flavored Markdown
+// table, for pasting into issues, pull requests and docs
+func printMarkdownTable(w io.Writer, servers []types.Server, columns []column) error {
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for _, cell := range cells {
+			b.WriteString(" " + markdownEscaper.Replace(cell) + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = i18n.T(col.header)
+	}
+	writeRow(headers)
+	b.WriteString("|" + strings.Repeat(" --- |", len(columns)) + "\n")
+	for _, row := range serversToRows(servers, columns) {
+		writeRow(row)
+	}
+	_, err := fmt.Fprint(w, b.String())
+	return err
+}
+
+// markdownEscaper keeps pipes in cell text from ending the cell
+var markdownEscaper = strings.NewReplacer("|", `\|`)
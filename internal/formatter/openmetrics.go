@@ -0,0 +1,109 @@
+package formatter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// metricStatuses are reported in lsrv_servers even when no server has them,
+// so alerts on a status don't depend on the series existing
+var metricStatuses = []types.Status{
+	types.StatusHealthy,
+	types.StatusStale,
+	types.StatusZombie,
+	types.StatusConflict,
+	types.StatusUnusual,
+	types.StatusReserved,
+}
+
+// writeOpenMetrics renders a snapshot in the OpenMetrics text format, which
+// node_exporter's textfile collector also reads: server counts by status,
+// the number of services, and an up series per server with its latency when
+// measured
+func writeOpenMetrics(w io.Writer, servers []types.Server) error {
+	bw := bufio.NewWriter(w)
+
+	counts := make(map[types.Status]int)
+	services := 0
+	for _, server := range servers {
+		if server.Service != "" {
+			services++
+		} else {
+			counts[server.Status]++
+		}
+	}
+
+	fmt.Fprintln(bw, "# HELP lsrv_servers Running development servers by status.")
+	fmt.Fprintln(bw, "# TYPE lsrv_servers gauge")
+	for _, status := range metricStatuses {
+		fmt.Fprintf(bw, "lsrv_servers{status=\"%s\"} %d\n", status, counts[status])
+	}
+
+	fmt.Fprintln(bw, "# HELP lsrv_services Running databases and auxiliary services.")
+	fmt.Fprintln(bw, "# TYPE lsrv_services gauge")
+	fmt.Fprintf(bw, "lsrv_services %d\n", services)
+
+	// Listeners only told apart by their PID, such as two processes in a
+	// port conflict, would repeat a series, which parsers reject
+	seen := make(map[string]bool)
+	var labeled []types.Server
+	var labels []string
+	for _, server := range servers {
+		if l := metricLabels(server); !seen[l] {
+			seen[l] = true
+			labeled = append(labeled, server)
+			labels = append(labels, l)
+		}
+	}
+
+	fmt.Fprintln(bw, "# HELP lsrv_server_up Whether a server is listening; servers that stopped have no series.")
+	fmt.Fprintln(bw, "# TYPE lsrv_server_up gauge")
+	for i := range labeled {
+		fmt.Fprintf(bw, "lsrv_server_up%s 1\n", labels[i])
+	}
+
+	fmt.Fprintln(bw, "# HELP lsrv_server_ttfb_seconds Time to the first byte of a server's root page, with --latency.")
+	fmt.Fprintln(bw, "# TYPE lsrv_server_ttfb_seconds gauge")
+	fmt.Fprintln(bw, "# UNIT lsrv_server_ttfb_seconds seconds")
+	for i, server := range labeled {
+		if server.TTFB > 0 {
+			fmt.Fprintf(bw, "lsrv_server_ttfb_seconds%s %s\n", labels[i], strconv.FormatFloat(server.TTFB.Seconds(), 'f', -1, 64))
+		}
+	}
+
+	fmt.Fprintln(bw, "# EOF")
+	return bw.Flush()
+}
+
+// metricLabels identifies a server in a metric's label set. Services are
+// labeled with their name instead of repo and branch.
+func metricLabels(server types.Server) string {
+	var labels []string
+	add := func(name, value string) {
+		labels = append(labels, fmt.Sprintf("%s=\"%s\"", name, escapeLabel(value)))
+	}
+	if server.Host != "" {
+		add("host", server.Host)
+	}
+	if server.Service != "" {
+		add("service", server.Service)
+	} else {
+		add("repo", server.Repo)
+		add("branch", server.Branch)
+	}
+	add("process", server.Process)
+	add("port", strconv.Itoa(server.Port))
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// labelEscaper escapes label values as the text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
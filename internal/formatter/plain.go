@@ -0,0 +1,46 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/i18n"
+	"github.com/bshakr/lsrv/internal/types"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// plainGap separates the columns of a plain table
+const plainGap = "  "
+
+// printPlainTable renders the table as aligned text without borders or
+// escape sequences, for pipes and files where grep and cut should see
+// only the values
+func printPlainTable(w io.Writer, servers []types.Server, columns []column) error {
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = i18n.T(col.header)
+	}
+	rows := append([][]string{headers}, serversToRows(servers, columns)...)
+
+	// Icons are often two cells wide, so align by display width
+	widths := make([]int, len(columns))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], lipgloss.Width(cell))
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range rows {
+		for i, cell := range row {
+			b.WriteString(cell)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-lipgloss.Width(cell)) + plainGap)
+			}
+		}
+		b.WriteString("\n")
+	}
+	_, err := fmt.Fprint(w, b.String())
+	return err
+}
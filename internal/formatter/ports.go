@@ -0,0 +1,30 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// WritePorts renders one compact line per server, ordered by port, e.g.
+// "3000 myapp(main) node". Auxiliary services show the service name instead
+// of repo and branch.
+func WritePorts(w io.Writer, servers []types.Server) error {
+	sorted := append([]types.Server(nil), servers...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Port < sorted[j].Port
+	})
+
+	for _, server := range sorted {
+		owner := fmt.Sprintf("%s(%s)", server.Repo, server.Branch)
+		if server.Service != "" {
+			owner = server.Service
+		}
+		if _, err := fmt.Fprintf(w, "%d %s %s\n", server.Port, owner, server.Process); err != nil {
+			return err
+		}
+	}
+	return nil
+}
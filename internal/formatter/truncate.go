@@ -0,0 +1,150 @@
+package formatter
+
+import (
+	"slices"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/rivo/uniseg"
+)
+
+const (
+	// minTruncatedWidth is the narrowest a truncated column is allowed to get
+	minTruncatedWidth = 10
+
+	// cellPadding is the horizontal padding applied to each table cell
+	cellPadding = 4
+)
+
+// dropOrder lists the columns hidden, first to last, when a table can't fit
+// the terminal even with its long columns truncated. Repo, branch, service
+// and URL columns are never hidden.
+var dropOrder = []columnID{
+	colUser, colPID, colLastCommit, colBacklog, colLatency, colEnv, colRuntime,
+	colSession, colStartedBy, colCommand, colPackage, colStatus, colHost,
+	colTunnel, colLAN, colProcess,
+}
+
+// fitTable makes the rendered table fit within width. Long columns are
+// shortened with a middle ellipsis first; when that isn't enough the
+// columns in dropOrder are hidden one at a time, and as a last resort the
+// remaining columns are shortened too. A width of 0 disables fitting.
+func fitTable(columns []column, headers []string, rows [][]string, width int) ([]column, []string, [][]string) {
+	if width <= 0 || len(rows) == 0 {
+		return columns, headers, rows
+	}
+
+	// Measure the natural width of every column, and how narrow it may get
+	colWidths := make([]int, len(headers))
+	floors := make([]int, len(headers))
+	for col, header := range headers {
+		colWidths[col] = lipgloss.Width(header)
+		floors[col] = max(minTruncatedWidth, colWidths[col])
+	}
+	for _, row := range rows {
+		for col, cell := range row {
+			colWidths[col] = max(colWidths[col], lipgloss.Width(cell))
+		}
+	}
+
+	var truncCols []int
+	for i, col := range columns {
+		if col.truncate {
+			truncCols = append(truncCols, i)
+		}
+	}
+
+	// Hide columns while even the truncated table is too wide
+	hidden := make([]bool, len(columns))
+	for _, id := range dropOrder {
+		if tableWidth(colWidths, hidden, truncCols, floors) <= width {
+			break
+		}
+		if i := slices.IndexFunc(columns, func(c column) bool { return c.id == id }); i >= 0 {
+			hidden[i] = true
+		}
+	}
+
+	total := tableWidth(colWidths, hidden, nil, nil)
+	total = shave(colWidths, floors, truncCols, hidden, total, width)
+	var rest []int
+	for i := range columns {
+		if !hidden[i] && !columns[i].truncate {
+			rest = append(rest, i)
+		}
+	}
+	shave(colWidths, floors, rest, hidden, total, width)
+
+	var keptColumns []column
+	var keptHeaders []string
+	for i := range columns {
+		if !hidden[i] {
+			keptColumns = append(keptColumns, columns[i])
+			keptHeaders = append(keptHeaders, headers[i])
+		}
+	}
+	fitted := make([][]string, len(rows))
+	for i, row := range rows {
+		for col, cell := range row {
+			if !hidden[col] {
+				fitted[i] = append(fitted[i], truncateMiddle(cell, colWidths[col]))
+			}
+		}
+	}
+	return keptColumns, keptHeaders, fitted
+}
+
+// tableWidth returns the rendered width of the table's visible columns,
+// with the cols columns shortened to their floors
+func tableWidth(colWidths []int, hidden []bool, cols []int, floors []int) int {
+	// Borders: one per column plus the trailing edge
+	total := 1
+	for col, w := range colWidths {
+		if hidden[col] {
+			continue
+		}
+		if slices.Contains(cols, col) {
+			w = min(w, floors[col])
+		}
+		total += w + cellPadding + 1
+	}
+	return total
+}
+
+// shave narrows the widest of the cols columns one cell at a time, down to
+// its floor, until the table's total width fits, and returns the new total
+func shave(colWidths, floors, cols []int, hidden []bool, total, width int) int {
+	for total > width {
+		widest := -1
+		for _, col := range cols {
+			if !hidden[col] && colWidths[col] > floors[col] && (widest == -1 || colWidths[col] > colWidths[widest]) {
+				widest = col
+			}
+		}
+		if widest == -1 {
+			break
+		}
+		colWidths[widest]--
+		total--
+	}
+	return total
+}
+
+// truncateMiddle shortens s to at most limit cells of display width, as
+// lipgloss.Width measures it, by replacing its middle with an ellipsis,
+// keeping both the prefix and the distinguishing suffix. A wide character
+// that would straddle the cut is dropped, leaving the result a cell short.
+func truncateMiddle(s string, limit int) string {
+	width := ansi.StringWidth(s)
+	if width <= limit || limit < 2 {
+		return s
+	}
+
+	keep := limit - 1
+	head := ansi.Truncate(s, keep-keep/2, "")
+	tail := s
+	for ansi.StringWidth(tail) > keep/2 {
+		_, tail, _, _ = uniseg.FirstGraphemeClusterInString(tail, -1)
+	}
+	return head + "…" + tail
+}
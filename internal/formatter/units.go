@@ -0,0 +1,55 @@
+package formatter
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/health"
+)
+
+// Units picks how durations and sizes are rendered in human-facing
+// output; the zero value humanizes both
+type Units struct {
+	// RawTime writes durations as seconds, such as "0.012" or "7500"
+	RawTime bool
+
+	// RawSize writes sizes as bytes
+	RawSize bool
+}
+
+// UnitsFrom returns the units configured for view: "table", "plain",
+// "details" or "top"
+func UnitsFrom(units config.Units, view string) Units {
+	style := units.For(view)
+	return Units{
+		RawTime: style.Time == config.UnitsSeconds,
+		RawSize: style.Size == config.UnitsBytes,
+	}
+}
+
+// Latency renders a latency such as "12ms", or "0.012" in seconds
+func (u Units) Latency(d time.Duration) string {
+	if u.RawTime {
+		return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+	}
+	return health.FormatLatency(d)
+}
+
+// Duration renders a longer span such as an uptime, rounded to the
+// second: "2h5m0s", or "7500" in seconds
+func (u Units) Duration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if u.RawTime {
+		return strconv.FormatInt(int64(d/time.Second), 10)
+	}
+	return d.String()
+}
+
+// Bytes renders a size such as "183.4M", or the count of bytes
+func (u Units) Bytes(n uint64) string {
+	if u.RawSize {
+		return strconv.FormatUint(n, 10)
+	}
+	return FormatBytes(n)
+}
@@ -0,0 +1,241 @@
+package framework
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// marker adds paths when a project file mentions a dependency
+type marker struct {
+	files    []string
+	contains []string
+	paths    []string
+}
+
+// markers lists frameworks whose useful default paths can be inferred from
+// their dependency manifests
+var markers = []marker{
+	{[]string{"package.json"}, []string{`"@apollo/server"`, `"apollo-server`, `"graphql-yoga"`, `"express-graphql"`}, []string{"/graphql"}},
+	{[]string{"requirements.txt", "pyproject.toml", "Pipfile"}, []string{"fastapi"}, []string{"/docs", "/redoc"}},
+	{[]string{"requirements.txt", "pyproject.toml", "Pipfile"}, []string{"django"}, []string{"/admin/"}},
+	{[]string{"pom.xml", "build.gradle", "build.gradle.kts"}, []string{"spring-boot-starter-actuator"}, []string{"/actuator/health"}},
+	{[]string{"mix.exs"}, []string{"phoenix_live_dashboard"}, []string{"/dev/dashboard"}},
+}
+
+// railsMount matches engines mounted in config/routes.rb, e.g.
+// "mount Sidekiq::Web => '/sidekiq'" or "mount GraphiQL::Rails::Engine, at: '/graphiql'"
+var railsMount = regexp.MustCompile(`mount\s+(Sidekiq::Web|GraphiQL::Rails::Engine)\s*(?:=>|,\s*at:)\s*['"]([^'"]+)['"]`)
+
+// Paths returns useful URL paths (e.g., "/graphql" or "/docs") for the
+// frameworks used by the project in dir, in a stable order
+func Paths(dir string) []string {
+	if dir == "" {
+		return nil
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	files := make(map[string]string)
+	read := func(name string) string {
+		if content, ok := files[name]; ok {
+			return content
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		files[name] = strings.ToLower(string(data))
+		return files[name]
+	}
+
+	for _, m := range markers {
+		if matches(m, read) {
+			for _, path := range m.paths {
+				add(path)
+			}
+		}
+	}
+
+	if routes, err := os.ReadFile(filepath.Join(dir, "config", "routes.rb")); err == nil {
+		for _, match := range railsMount.FindAllStringSubmatch(string(routes), -1) {
+			add(match[2])
+		}
+	}
+
+	return paths
+}
+
+// matches reports whether any of the marker's files mentions one of its
+// dependencies
+func matches(m marker, read func(string) string) bool {
+	for _, file := range m.files {
+		content := read(file)
+		for _, needle := range m.contains {
+			if strings.Contains(content, needle) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Server frameworks reported by Name
+const (
+	Phoenix    = "phoenix"
+	SpringBoot = "spring-boot"
+	Storybook  = "storybook"
+	Webpack    = "webpack"
+	Laravel    = "laravel"
+	Symfony    = "symfony"
+)
+
+// Name identifies the server framework of a process running in dir, such
+// as Phoenix for beam.smp in a Mix project depending on phoenix, or Spring
+// Boot for java with Spring Boot on its command line or in its build file.
+// Storybook and webpack dev servers are node, bun or deno processes
+// running their command-line tools. PHP servers are Laravel when started by
+// artisan serve or in a directory with artisan, and Symfony when depending
+// on its framework bundle or run by the symfony CLI. It returns "" when the
+// framework is unknown. args is only called for processes that may need it.
+func Name(process, dir string, args func() []string) string {
+	if isPHP(process) {
+		for _, arg := range args() {
+			if strings.HasSuffix(arg, laravelServer) {
+				return Laravel
+			}
+		}
+		switch {
+		case dir == "":
+			return ""
+		case fileExists(filepath.Join(dir, "artisan")):
+			return Laravel
+		case fileContains(filepath.Join(dir, "composer.json"), `"symfony/framework-bundle"`):
+			return Symfony
+		}
+		return ""
+	}
+
+	switch process {
+	case "symfony":
+		return Symfony
+	case "node", "bun", "deno":
+		// Storybook builds with webpack, so it is checked first
+		args := args()
+		for _, arg := range args {
+			if strings.Contains(filepath.Base(arg), "storybook") {
+				return Storybook
+			}
+		}
+		for _, arg := range args {
+			if strings.HasPrefix(filepath.Base(arg), "webpack") {
+				return Webpack
+			}
+		}
+	case "beam.smp", "beam", "elixir", "mix":
+		if dir != "" && fileContains(filepath.Join(dir, "mix.exs"), ":phoenix") {
+			return Phoenix
+		}
+	case "java":
+		for _, arg := range args() {
+			if strings.Contains(arg, "spring-boot") || strings.Contains(arg, "org.springframework.boot") || arg == "bootRun" {
+				return SpringBoot
+			}
+		}
+		if dir == "" {
+			return ""
+		}
+		for _, build := range []string{"pom.xml", "build.gradle", "build.gradle.kts"} {
+			if fileContains(filepath.Join(dir, build), "spring-boot") {
+				return SpringBoot
+			}
+		}
+	}
+	return ""
+}
+
+// laravelServer is the router script artisan serve runs php -S with, from
+// the project's vendor directory
+const laravelServer = "/vendor/laravel/framework/src/Illuminate/Foundation/resources/server.php"
+
+// isPHP reports whether process is a PHP interpreter, including versioned
+// names like php8.3 and php-fpm
+func isPHP(process string) bool {
+	return strings.HasPrefix(process, "php")
+}
+
+// HasProjectDir reports whether ProjectDir applies to process, sparing
+// the command line lookup for others
+func HasProjectDir(process string) bool {
+	return isPHP(process) || process == "symfony"
+}
+
+// ProjectDir returns the project directory a PHP server serves when it
+// differs from the process's working directory, or "" to keep that one.
+// Such servers are often started from elsewhere: php -S with -t DOCROOT,
+// artisan serve, whose php -S child runs in public/ with the project's
+// router script, or the symfony CLI with --dir.
+func ProjectDir(process, cwd string, args []string) string {
+	var dir string
+	switch {
+	case isPHP(process):
+		for i, arg := range args {
+			if root, ok := strings.CutSuffix(arg, laravelServer); ok {
+				return root
+			}
+			if arg == "-t" && i+1 < len(args) {
+				dir = args[i+1]
+			} else if root, ok := strings.CutPrefix(arg, "-t"); ok && root != "" {
+				dir = root
+			}
+		}
+	case process == "symfony":
+		for i, arg := range args {
+			if arg == "--dir" && i+1 < len(args) {
+				dir = args[i+1]
+			} else if root, ok := strings.CutPrefix(arg, "--dir="); ok {
+				dir = root
+			}
+		}
+	}
+
+	if dir == "" {
+		dir = cwd
+	} else if !filepath.IsAbs(dir) && cwd != "" {
+		dir = filepath.Join(cwd, dir)
+	}
+	if !filepath.IsAbs(dir) {
+		return ""
+	}
+	dir = filepath.Clean(dir)
+
+	// Serving the public/ document root of a Composer project is serving
+	// the project
+	if parent := filepath.Dir(dir); slices.Contains(documentRoots, filepath.Base(dir)) && fileExists(filepath.Join(parent, "composer.json")) {
+		return parent
+	}
+	if dir == cwd {
+		return ""
+	}
+	return dir
+}
+
+// documentRoots are the web roots of Laravel and Symfony (public) and of
+// older Symfony and Drupal (web) projects
+var documentRoots = []string{"public", "web"}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func fileContains(path, needle string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.Contains(string(data), needle)
+}
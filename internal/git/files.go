@@ -0,0 +1,336 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// slots bounds the git processes running at once, so listing many repos
+// doesn't fork dozens of them in parallel
+var slots = make(chan struct{}, runtime.NumCPU())
+
+// runGit runs git with args in dir, waiting for a free slot first
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-slots }()
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	return cmd.Output()
+}
+
+// gitDirs returns the git directory of the work tree containing dir and
+// the common directory shared by all its worktrees, which holds the config.
+// They're the same except in linked worktrees.
+func gitDirs(dir string) (gitDir, commonDir string, ok bool) {
+	root, ok := workTreeRoot(dir)
+	if !ok {
+		return "", "", false
+	}
+
+	gitDir = filepath.Join(root, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return "", "", false
+	}
+	if !info.IsDir() {
+		// Linked worktrees and submodules have a "gitdir: <path>" file
+		data, err := os.ReadFile(gitDir)
+		if err != nil {
+			return "", "", false
+		}
+		path, found := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+		if !found {
+			return "", "", false
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		gitDir = filepath.Clean(path)
+	}
+
+	commonDir = gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		path := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(gitDir, path)
+		}
+		commonDir = filepath.Clean(path)
+	}
+	return gitDir, commonDir, true
+}
+
+// HeadModTime returns when the HEAD file of dir's work tree last changed,
+// as it does on every checkout, so callers keeping the branch between
+// scans know when to read it again
+func HeadModTime(dir string) (time.Time, bool) {
+	gitDir, _, ok := gitDirs(dir)
+	if !ok {
+		return time.Time{}, false
+	}
+	info, err := os.Stat(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// readBranch returns the branch checked out in dir's work tree from its
+// HEAD file. A rebase or bisect in progress is named after the branch it
+// started from, like "rebasing feature/x", and a detached HEAD after its
+// commit, like "detached@a1b2c3d".
+func readBranch(dir string) (string, bool) {
+	gitDir, _, ok := gitDirs(dir)
+	if !ok {
+		return "", false
+	}
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", false
+	}
+
+	if branch, ok := rebaseBranch(gitDir); ok {
+		return "rebasing " + branch, true
+	}
+	if branch, ok := bisectBranch(gitDir); ok {
+		return "bisecting " + branch, true
+	}
+
+	head := strings.TrimSpace(string(data))
+	if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+		branch, ok := strings.CutPrefix(ref, "refs/heads/")
+		return branch, ok
+	}
+	return detached(head), true
+}
+
+// rebaseBranch returns the branch being rebased, from the state directory
+// of the merge backend (interactive rebases, and the default since git
+// 2.26) or the apply backend. A "git am" also uses rebase-apply but has no
+// head-name.
+func rebaseBranch(gitDir string) (string, bool) {
+	for _, state := range []string{"rebase-merge", "rebase-apply"} {
+		data, err := os.ReadFile(filepath.Join(gitDir, state, "head-name"))
+		if err != nil {
+			continue
+		}
+		if branch, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "refs/heads/"); ok {
+			return branch, true
+		}
+	}
+	return "", false
+}
+
+// bisectBranch returns the branch a bisect started from, which
+// BISECT_START records without its refs/heads/ prefix. A bisect started
+// from a detached HEAD records a commit instead.
+func bisectBranch(gitDir string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "BISECT_START"))
+	if err != nil {
+		return "", false
+	}
+	start := strings.TrimSpace(string(data))
+	if start == "" || isCommitID(start) {
+		return "", false
+	}
+	return start, true
+}
+
+// detached names a detached HEAD after its abbreviated commit
+func detached(commit string) string {
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	return "detached@" + commit
+}
+
+// isCommitID reports whether s is a full SHA-1 or SHA-256 object name
+func isCommitID(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	return strings.Trim(s, "0123456789abcdef") == ""
+}
+
+// readWorktrees returns the root directories of every work tree of the
+// repository containing dir, main work tree first, from the pointer files
+// under .git/worktrees. ok is false for layouts like bare repositories and
+// submodules, which git has to list.
+func readWorktrees(dir string) (roots []string, ok bool) {
+	_, commonDir, found := gitDirs(dir)
+	if !found {
+		return nil, false
+	}
+	// A bare repository, as in the bare clone + worktrees workflow, has
+	// only linked worktrees
+	switch {
+	case filepath.Base(commonDir) == ".git":
+		roots = []string{filepath.Dir(commonDir)}
+	case !isBare(commonDir):
+		return nil, false
+	}
+
+	entries, err := os.ReadDir(filepath.Join(commonDir, "worktrees"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, false
+	}
+	for _, entry := range entries {
+		// gitdir holds the path of the linked work tree's .git file
+		data, err := os.ReadFile(filepath.Join(commonDir, "worktrees", entry.Name(), "gitdir"))
+		if err != nil {
+			continue
+		}
+		roots = append(roots, filepath.Dir(strings.TrimSpace(string(data))))
+	}
+	return roots, true
+}
+
+// remoteConfig is the remote URLs parsed from a repository's config file
+type remoteConfig struct {
+	modTime time.Time
+	size    int64
+	urls    map[string]string
+
+	// bare is core.bare, set in repositories without a main work tree
+	bare bool
+
+	// complete is false when the file includes other files, which may set
+	// remotes this parser doesn't see
+	complete bool
+}
+
+var (
+	remoteConfigsMu sync.Mutex
+	remoteConfigs   = make(map[string]remoteConfig)
+)
+
+// readRemoteURL returns a remote's URL from the config file of the
+// repository containing dir. ok is false when git has to be asked instead.
+func readRemoteURL(dir, remote string) (url string, ok bool) {
+	_, commonDir, found := gitDirs(dir)
+	if !found {
+		return "", false
+	}
+	cfg, ok := readRepoConfig(commonDir)
+	if !ok || !cfg.complete {
+		return "", false
+	}
+	return cfg.urls[remote], true
+}
+
+// isBare reports whether the repository in commonDir is bare
+func isBare(commonDir string) bool {
+	cfg, ok := readRepoConfig(commonDir)
+	return ok && cfg.bare
+}
+
+// readRepoConfig parses the config file in a repository's common
+// directory, once per change of the file
+func readRepoConfig(commonDir string) (remoteConfig, bool) {
+	path := filepath.Join(commonDir, "config")
+	info, err := os.Stat(path)
+	if err != nil {
+		return remoteConfig{}, false
+	}
+
+	remoteConfigsMu.Lock()
+	defer remoteConfigsMu.Unlock()
+
+	// Worktrees of one repository share the file, so parse it once per change
+	cfg, cached := remoteConfigs[path]
+	if !cached || !cfg.modTime.Equal(info.ModTime()) || cfg.size != info.Size() {
+		cfg, err = parseRemoteConfig(path)
+		if err != nil {
+			return remoteConfig{}, false
+		}
+		cfg.modTime, cfg.size = info.ModTime(), info.Size()
+		remoteConfigs[path] = cfg
+	}
+	return cfg, true
+}
+
+// parseRemoteConfig reads the remote.<name>.url and core.bare settings of
+// a git config file. Like "git config --get", the last value of a repeated
+// key wins.
+func parseRemoteConfig(path string) (remoteConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return remoteConfig{}, err
+	}
+	defer file.Close()
+
+	cfg := remoteConfig{urls: make(map[string]string), complete: true}
+	var section, subsection string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			header, _, _ := strings.Cut(line[1:], "]")
+			name, sub, quoted := strings.Cut(header, " ")
+			section = strings.ToLower(strings.TrimSpace(name))
+			subsection = ""
+			if quoted {
+				subsection = strings.Trim(strings.TrimSpace(sub), `"`)
+			} else if name, sub, dotted := strings.Cut(section, "."); dotted {
+				// Deprecated [remote.origin] syntax
+				section, subsection = name, sub
+			}
+			if section == "include" || section == "includeif" {
+				cfg.complete = false
+			}
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(line, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if section == "core" && subsection == "" && key == "bare" {
+			// A key without "=" is true
+			switch strings.ToLower(configValue(value)) {
+			case "true", "yes", "on", "1":
+				cfg.bare = true
+			default:
+				cfg.bare = !hasValue
+			}
+			continue
+		}
+		if section != "remote" || subsection == "" {
+			continue
+		}
+		if key == "url" {
+			cfg.urls[subsection] = configValue(value)
+		}
+	}
+	return cfg, scanner.Err()
+}
+
+// configValue strips quotes and a trailing comment from a config value
+func configValue(value string) string {
+	var b strings.Builder
+	quoted := false
+	for _, c := range strings.TrimSpace(value) {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case (c == '#' || c == ';') && !quoted:
+			return strings.TrimSpace(b.String())
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
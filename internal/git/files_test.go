@@ -0,0 +1,406 @@
+package git
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const commit = "a1b2c3d4e5f60718293a4b5c6d7e8f9012345678"
+
+// writeTree creates files under root, with their parent directories
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGitDirs(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"repo/.git/HEAD":                   "ref: refs/heads/main\n",
+		"repo/.git/worktrees/wt/commondir": "../..\n",
+		"repo/src/main.go":                 "package main\n",
+		"wt/.git":                          "gitdir: ../repo/.git/worktrees/wt\n",
+		"abs/.git":                         "gitdir: " + filepath.Join(root, "repo/.git/worktrees/wt") + "\n",
+		"broken/.git":                      "not a pointer\n",
+		"plain/readme":                     "",
+		"sub/.git":                         "gitdir: ../repo/.git/modules/sub\n",
+		"repo/.git/modules/sub/HEAD":       "ref: refs/heads/main\n",
+		"repo/.git/worktrees/wt/HEAD":      "ref: refs/heads/feature\n",
+		"repo/.git/worktrees/wt/gitdir":    filepath.Join(root, "wt/.git") + "\n",
+		"repo/.git/modules/sub/config":     "[core]\n\tbare = false\n",
+	})
+
+	tests := []struct {
+		name          string
+		dir           string
+		wantGitDir    string
+		wantCommonDir string
+		wantOK        bool
+	}{
+		{"main work tree", "repo", "repo/.git", "repo/.git", true},
+		{"subdirectory", "repo/src", "repo/.git", "repo/.git", true},
+		{"relative gitdir and commondir", "wt", "repo/.git/worktrees/wt", "repo/.git", true},
+		{"absolute gitdir", "abs", "repo/.git/worktrees/wt", "repo/.git", true},
+		{"submodule", "sub", "repo/.git/modules/sub", "repo/.git/modules/sub", true},
+		{"malformed .git file", "broken", "", "", false},
+		{"not a repository", "plain", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitDir, commonDir, ok := gitDirs(filepath.Join(root, tt.dir))
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if want := filepath.Join(root, tt.wantGitDir); gitDir != want {
+				t.Errorf("gitDir = %q, want %q", gitDir, want)
+			}
+			if want := filepath.Join(root, tt.wantCommonDir); commonDir != want {
+				t.Errorf("commonDir = %q, want %q", commonDir, want)
+			}
+		})
+	}
+}
+
+func TestReadBranch(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "branch",
+			files:  map[string]string{"HEAD": "ref: refs/heads/feature/x\n"},
+			want:   "feature/x",
+			wantOK: true,
+		},
+		{
+			name:   "detached",
+			files:  map[string]string{"HEAD": commit + "\n"},
+			want:   "detached@a1b2c3d",
+			wantOK: true,
+		},
+		{
+			name:   "non-branch ref",
+			files:  map[string]string{"HEAD": "ref: refs/remotes/origin/main\n"},
+			wantOK: false,
+		},
+		{
+			name: "rebase with merge backend",
+			files: map[string]string{
+				"HEAD":                   commit + "\n",
+				"rebase-merge/head-name": "refs/heads/feature/x\n",
+			},
+			want:   "rebasing feature/x",
+			wantOK: true,
+		},
+		{
+			name: "rebase with apply backend",
+			files: map[string]string{
+				"HEAD":                   commit + "\n",
+				"rebase-apply/head-name": "refs/heads/fix\n",
+			},
+			want:   "rebasing fix",
+			wantOK: true,
+		},
+		{
+			name: "git am",
+			files: map[string]string{
+				"HEAD":                  "ref: refs/heads/main\n",
+				"rebase-apply/applying": "",
+			},
+			want:   "main",
+			wantOK: true,
+		},
+		{
+			name: "rebase of a detached HEAD",
+			files: map[string]string{
+				"HEAD":                   commit + "\n",
+				"rebase-merge/head-name": "detached HEAD\n",
+			},
+			want:   "detached@a1b2c3d",
+			wantOK: true,
+		},
+		{
+			name: "bisect",
+			files: map[string]string{
+				"HEAD":         commit + "\n",
+				"BISECT_START": "main\n",
+			},
+			want:   "bisecting main",
+			wantOK: true,
+		},
+		{
+			name: "bisect from a detached HEAD",
+			files: map[string]string{
+				"HEAD":         commit + "\n",
+				"BISECT_START": commit + "\n",
+			},
+			want:   "detached@a1b2c3d",
+			wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			files := make(map[string]string)
+			for name, content := range tt.files {
+				files[filepath.Join(".git", name)] = content
+			}
+			writeTree(t, root, files)
+
+			got, ok := readBranch(root)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Errorf("branch = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadWorktrees(t *testing.T) {
+	tests := []struct {
+		name string
+		// files are relative to the temp dir; "ROOT" in their content is
+		// replaced by its path
+		files  map[string]string
+		dir    string
+		want   []string
+		wantOK bool
+	}{
+		{
+			name:   "single work tree",
+			files:  map[string]string{"repo/.git/HEAD": "ref: refs/heads/main\n"},
+			dir:    "repo",
+			want:   []string{"repo"},
+			wantOK: true,
+		},
+		{
+			name: "linked worktrees",
+			files: map[string]string{
+				"repo/.git/HEAD":                  "ref: refs/heads/main\n",
+				"repo/.git/worktrees/a/gitdir":    "ROOT/a/.git\n",
+				"repo/.git/worktrees/a/commondir": "../..\n",
+				"repo/.git/worktrees/b/gitdir":    "ROOT/b/.git\n",
+				"repo/.git/worktrees/b/commondir": "../..\n",
+				"a/.git":                          "gitdir: ../repo/.git/worktrees/a\n",
+				"b/.git":                          "gitdir: ../repo/.git/worktrees/b\n",
+			},
+			dir:    "b",
+			want:   []string{"repo", "a", "b"},
+			wantOK: true,
+		},
+		{
+			name: "bare clone with worktrees",
+			files: map[string]string{
+				"repo.git/HEAD":                      "ref: refs/heads/main\n",
+				"repo.git/config":                    "[core]\n\tbare = true\n",
+				"repo.git/worktrees/main/gitdir":     "ROOT/main/.git\n",
+				"repo.git/worktrees/main/commondir":  "../..\n",
+				"repo.git/worktrees/topic/gitdir":    "ROOT/topic/.git\n",
+				"repo.git/worktrees/topic/commondir": "../..\n",
+				"main/.git":                          "gitdir: ../repo.git/worktrees/main\n",
+				"topic/.git":                         "gitdir: ../repo.git/worktrees/topic\n",
+			},
+			dir:    "main",
+			want:   []string{"main", "topic"},
+			wantOK: true,
+		},
+		{
+			name: "bare repository in .bare",
+			files: map[string]string{
+				"proj/.git":                            "gitdir: ./.bare\n",
+				"proj/.bare/HEAD":                      "ref: refs/heads/main\n",
+				"proj/.bare/config":                    "[core]\n\tbare\n",
+				"proj/.bare/worktrees/main/gitdir":     "ROOT/proj/main/.git\n",
+				"proj/.bare/worktrees/main/commondir":  "../..\n",
+				"proj/main/.git":                       "gitdir: ../.bare/worktrees/main\n",
+				"proj/.bare/worktrees/stale/commondir": "../..\n",
+				"proj/.bare/worktrees/stale/locked":    "",
+			},
+			dir:    "proj/main",
+			want:   []string{"proj/main"},
+			wantOK: true,
+		},
+		{
+			name: "submodule",
+			files: map[string]string{
+				"repo/.git/HEAD":               "ref: refs/heads/main\n",
+				"repo/.git/modules/sub/HEAD":   "ref: refs/heads/main\n",
+				"repo/.git/modules/sub/config": "[core]\n\tbare = false\n",
+				"repo/sub/.git":                "gitdir: ../.git/modules/sub\n",
+			},
+			dir:    "repo/sub",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			files := make(map[string]string)
+			for name, content := range tt.files {
+				files[name] = strings.ReplaceAll(content, "ROOT", root)
+			}
+			writeTree(t, root, files)
+
+			got, ok := readWorktrees(filepath.Join(root, tt.dir))
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			var want []string
+			for _, dir := range tt.want {
+				want = append(want, filepath.Join(root, dir))
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("worktrees = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestParseRemoteConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		wantURLs     map[string]string
+		wantBare     bool
+		wantComplete bool
+	}{
+		{
+			name:         "remotes",
+			config:       "[core]\n\tbare = false\n[remote \"origin\"]\n\turl = git@github.com:a/b.git\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n[remote \"upstream\"]\n\turl = https://github.com/c/b.git\n",
+			wantURLs:     map[string]string{"origin": "git@github.com:a/b.git", "upstream": "https://github.com/c/b.git"},
+			wantComplete: true,
+		},
+		{
+			name:         "comments and blank lines",
+			config:       "# header\n\n; another\n[remote \"origin\"]\n\t# url = wrong\n\turl = https://example.com/a.git ; trailing\n",
+			wantURLs:     map[string]string{"origin": "https://example.com/a.git"},
+			wantComplete: true,
+		},
+		{
+			name:         "quoted value",
+			config:       "[remote \"origin\"]\n\turl = \"https://example.com/a#b;c.git\" # comment\n",
+			wantURLs:     map[string]string{"origin": "https://example.com/a#b;c.git"},
+			wantComplete: true,
+		},
+		{
+			name:         "last value wins",
+			config:       "[remote \"origin\"]\n\turl = https://example.com/old.git\n[remote \"origin\"]\n\turl = https://example.com/new.git\n",
+			wantURLs:     map[string]string{"origin": "https://example.com/new.git"},
+			wantComplete: true,
+		},
+		{
+			name:         "case-insensitive section and key",
+			config:       "[Remote \"origin\"]\n\tURL = https://example.com/a.git\n",
+			wantURLs:     map[string]string{"origin": "https://example.com/a.git"},
+			wantComplete: true,
+		},
+		{
+			name:         "deprecated dotted section",
+			config:       "[remote.origin]\n\turl = https://example.com/a.git\n",
+			wantURLs:     map[string]string{"origin": "https://example.com/a.git"},
+			wantComplete: true,
+		},
+		{
+			name:         "url outside a remote",
+			config:       "[remote]\n\turl = https://example.com/a.git\n[branch \"main\"]\n\turl = https://example.com/b.git\n",
+			wantURLs:     map[string]string{},
+			wantComplete: true,
+		},
+		{
+			name:         "bare",
+			config:       "[core]\n\tbare = true\n",
+			wantURLs:     map[string]string{},
+			wantBare:     true,
+			wantComplete: true,
+		},
+		{
+			name:         "bare without a value",
+			config:       "[core]\n\tbare\n",
+			wantURLs:     map[string]string{},
+			wantBare:     true,
+			wantComplete: true,
+		},
+		{
+			name:         "bare in another section",
+			config:       "[core \"x\"]\n\tbare = true\n",
+			wantURLs:     map[string]string{},
+			wantComplete: true,
+		},
+		{
+			name:     "include",
+			config:   "[include]\n\tpath = remotes.inc\n[remote \"origin\"]\n\turl = https://example.com/a.git\n",
+			wantURLs: map[string]string{"origin": "https://example.com/a.git"},
+		},
+		{
+			name:     "conditional include",
+			config:   "[includeIf \"gitdir:~/work/\"]\n\tpath = work.inc\n",
+			wantURLs: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config")
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := parseRemoteConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(cfg.urls, tt.wantURLs) {
+				t.Errorf("urls = %v, want %v", cfg.urls, tt.wantURLs)
+			}
+			if cfg.bare != tt.wantBare {
+				t.Errorf("bare = %v, want %v", cfg.bare, tt.wantBare)
+			}
+			if cfg.complete != tt.wantComplete {
+				t.Errorf("complete = %v, want %v", cfg.complete, tt.wantComplete)
+			}
+		})
+	}
+}
+
+func TestConfigValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{" https://example.com/a.git ", "https://example.com/a.git"},
+		{"https://example.com/a.git # comment", "https://example.com/a.git"},
+		{"https://example.com/a.git;comment", "https://example.com/a.git"},
+		{`"https://example.com/a.git"`, "https://example.com/a.git"},
+		{`"a # b" ; c`, "a # b"},
+		{`pre"fix ; x"post`, "prefix ; xpost"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := configValue(tt.value); got != tt.want {
+			t.Errorf("configValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
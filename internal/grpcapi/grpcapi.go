@@ -0,0 +1,200 @@
+package grpcapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/api"
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// FindFunc returns the currently running servers
+type FindFunc func() ([]types.Server, error)
+
+// gRPC status codes returned by the API
+const (
+	codeOK               = 0
+	codeInvalidArgument  = 3
+	codeNotFound         = 5
+	codePermissionDenied = 7
+	codeUnimplemented    = 12
+	codeInternal         = 13
+	codeUnauthenticated  = 16
+)
+
+// Watch polling bounds for WatchServers
+const (
+	defaultWatchInterval = 2 * time.Second
+	minWatchInterval     = 250 * time.Millisecond
+)
+
+// NewServer returns an HTTP server for the lsrv.v1.Lsrv service defined in
+// proto/lsrv/v1/lsrv.proto, speaking gRPC over cleartext HTTP/2 (h2c).
+// Calls must carry access.Token, when set, as "authorization: Bearer"
+// metadata, like requests to the HTTP API.
+func NewServer(addr string, find FindFunc, access api.Options) *http.Server {
+	// HTTP/1 stays enabled so stray browser and curl requests get an
+	// explanation instead of a protocol error
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Server{
+		Addr:      addr,
+		Handler:   &handler{find: find, token: access.Token},
+		Protocols: &protocols,
+	}
+}
+
+type handler struct {
+	find  FindFunc
+	token string
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "lsrv serves gRPC on this address", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+
+	if h.token != "" && !api.HasToken(r, h.token) {
+		finish(w, codeUnauthenticated, "missing or wrong API token")
+		return
+	}
+
+	request, err := readMessage(r.Body)
+	if err != nil {
+		finish(w, codeInvalidArgument, fmt.Sprintf("reading request: %v", err))
+		return
+	}
+
+	switch r.URL.Path {
+	case "/lsrv.v1.Lsrv/ListServers":
+		h.listServers(w)
+	case "/lsrv.v1.Lsrv/WatchServers":
+		h.watchServers(w, r, request)
+	case "/lsrv.v1.Lsrv/KillServer":
+		h.killServer(w, request)
+	default:
+		finish(w, codeUnimplemented, "unknown method "+r.URL.Path)
+	}
+}
+
+func (h *handler) listServers(w http.ResponseWriter) {
+	servers, err := h.find()
+	if err != nil {
+		finish(w, codeInternal, err.Error())
+		return
+	}
+	if err := writeMessage(w, encodeServerList(servers)); err != nil {
+		return
+	}
+	finish(w, codeOK, "")
+}
+
+// watchServers streams the server list whenever it changes until the
+// client goes away
+func (h *handler) watchServers(w http.ResponseWriter, r *http.Request, request []byte) {
+	intervalMS, err := decodeUint(request, 1)
+	if err != nil {
+		finish(w, codeInvalidArgument, err.Error())
+		return
+	}
+	interval := time.Duration(intervalMS) * time.Millisecond
+	if interval == 0 {
+		interval = defaultWatchInterval
+	}
+	interval = max(interval, minWatchInterval)
+
+	rc := http.NewResponseController(w)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last []byte
+	for {
+		servers, err := h.find()
+		if err != nil {
+			finish(w, codeInternal, err.Error())
+			return
+		}
+
+		if msg := encodeServerList(servers); last == nil || !bytes.Equal(msg, last) {
+			if writeMessage(w, msg) != nil || rc.Flush() != nil {
+				return
+			}
+			last = msg
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *handler) killServer(w http.ResponseWriter, request []byte) {
+	port, err := decodeUint(request, 1)
+	if err != nil || port == 0 || port > 65535 {
+		finish(w, codeInvalidArgument, "port must be between 1 and 65535")
+		return
+	}
+
+	servers, err := h.find()
+	if err != nil {
+		finish(w, codeInternal, err.Error())
+		return
+	}
+
+	matched := control.Match(servers, strconv.FormatUint(port, 10))
+	if len(matched) == 0 {
+		finish(w, codeNotFound, fmt.Sprintf("no server on port %d", port))
+		return
+	}
+	for _, server := range matched {
+		if err := control.Kill(server); err != nil {
+			code := codeInternal
+			if errors.Is(err, control.ErrSafeMode) {
+				code = codePermissionDenied
+			}
+			finish(w, code, err.Error())
+			return
+		}
+	}
+
+	if err := writeMessage(w, encodeServerList(matched)); err != nil {
+		return
+	}
+	finish(w, codeOK, "")
+}
+
+// finish sets the gRPC status trailers
+func finish(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set("Grpc-Message", percentEncode(message))
+	}
+}
+
+// percentEncode escapes a grpc-message value as the gRPC spec requires
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package grpcapi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// Protobuf wire types used by lsrv.proto
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// Field numbers of the Server message in proto/lsrv/v1/lsrv.proto
+const (
+	fieldRepo = iota + 1
+	fieldBranch
+	fieldProcess
+	fieldPort
+	fieldPID
+	fieldCWD
+	fieldUser
+	fieldURL
+	fieldStatus
+	fieldWorkers
+	fieldService
+)
+
+func appendTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+func appendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendVarint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendMessage(b []byte, field int, msg []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
+
+// encodeServer encodes a Server message
+func encodeServer(s types.Server) []byte {
+	var b []byte
+	b = appendString(b, fieldRepo, s.Repo)
+	b = appendString(b, fieldBranch, s.Branch)
+	b = appendString(b, fieldProcess, s.Process)
+	b = appendVarint(b, fieldPort, uint64(s.Port))
+	// int32 fields are encoded as sign-extended varints
+	b = appendVarint(b, fieldPID, uint64(int64(s.PID)))
+	b = appendString(b, fieldCWD, s.CWD)
+	b = appendString(b, fieldUser, s.User)
+	b = appendString(b, fieldURL, s.DisplayURL())
+	b = appendString(b, fieldStatus, string(s.Status))
+	b = appendVarint(b, fieldWorkers, uint64(s.Workers))
+	b = appendString(b, fieldService, s.Service)
+	return b
+}
+
+// encodeServerList encodes a message whose only field is the repeated
+// Server field 1, as in ListServersResponse and KillServerResponse
+func encodeServerList(servers []types.Server) []byte {
+	var b []byte
+	for _, server := range servers {
+		b = appendMessage(b, 1, encodeServer(server))
+	}
+	return b
+}
+
+// decodeUint reads varint field number field from a message, skipping all
+// other fields; it returns 0 when the field is absent
+func decodeUint(msg []byte, field int) (uint64, error) {
+	var value uint64
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return 0, errors.New("malformed tag")
+		}
+		msg = msg[n:]
+
+		switch wireType := int(tag & 7); wireType {
+		case wireVarint:
+			v, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return 0, errors.New("malformed varint")
+			}
+			msg = msg[n:]
+			if int(tag>>3) == field {
+				value = v
+			}
+		case wireBytes:
+			length, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < length {
+				return 0, errors.New("malformed length-delimited field")
+			}
+			msg = msg[n+int(length):]
+		case 1:
+			if len(msg) < 8 {
+				return 0, errors.New("truncated fixed64 field")
+			}
+			msg = msg[8:]
+		case 5:
+			if len(msg) < 4 {
+				return 0, errors.New("truncated fixed32 field")
+			}
+			msg = msg[4:]
+		default:
+			return 0, fmt.Errorf("unsupported wire type %d", wireType)
+		}
+	}
+	return value, nil
+}
+
+// maxMessageSize bounds request messages; lsrv's requests are tiny
+const maxMessageSize = 1 << 20
+
+// readMessage reads one length-prefixed gRPC message
+func readMessage(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if header[0] != 0 {
+		return nil, errors.New("compressed messages are not supported")
+	}
+
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds limit", length)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// writeMessage writes one uncompressed length-prefixed gRPC message
+func writeMessage(w io.Writer, msg []byte) error {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	_, err := w.Write(append(frame, msg...))
+	return err
+}
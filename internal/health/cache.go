@@ -0,0 +1,212 @@
+package health
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// Defaults for a Cache: servers that are up are re-checked every
+// DefaultTTL, failing ones after a backoff starting at DefaultMinBackoff
+// and doubling with each failure up to DefaultMaxBackoff
+const (
+	DefaultTTL        = 30 * time.Second
+	DefaultMinBackoff = 5 * time.Second
+	DefaultMaxBackoff = 5 * time.Minute
+)
+
+// Status is a server's cached health, as served by the daemon's API
+type Status struct {
+	Port    int    `json:"port"`
+	PID     int    `json:"pid"`
+	Repo    string `json:"repo,omitempty"`
+	Service string `json:"service,omitempty"`
+
+	// State is "up", "error" or "down"; "pending" until the first check
+	// finishes
+	State State `json:"state"`
+
+	// Code is the HTTP status code; 0 for TCP-only checks and failures
+	Code int `json:"code,omitempty"`
+
+	// LatencyMS is how long the check took, in milliseconds
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+
+	// CheckedAt and NextCheck are left out while the first check runs
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+	NextCheck time.Time `json:"next_check,omitzero"`
+
+	// Failures counts consecutive failed checks, which set the backoff
+	Failures int `json:"failures,omitempty"`
+}
+
+// StatePending marks servers whose first check hasn't finished
+const StatePending State = "pending"
+
+// cacheKey identifies a server across refreshes; a restarted server gets
+// a new PID and so a fresh check
+type cacheKey struct {
+	pid  int
+	port int
+}
+
+// entry is the cached health of one server
+type entry struct {
+	server   types.Server
+	result   *Result
+	checked  time.Time
+	next     time.Time
+	failures int
+	checking bool
+
+	// gone marks a server that stopped while its check ran; the entry
+	// stays until the check finishes, so a server coming back meanwhile
+	// doesn't start a second one
+	gone bool
+}
+
+// Cache keeps health results for long-running modes, re-checking each
+// server only when its result expires rather than on every refresh, so
+// slow-to-boot or failing apps aren't hammered
+type Cache struct {
+	checker    *Checker
+	ttl        time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu      sync.Mutex
+	entries map[cacheKey]*entry
+}
+
+// NewCache returns a Cache checking through checker, with results of
+// servers that are up kept for ttl and failures backed off up to
+// maxBackoff
+func NewCache(checker *Checker, ttl, maxBackoff time.Duration) *Cache {
+	return &Cache{
+		checker:    checker,
+		ttl:        ttl,
+		minBackoff: min(DefaultMinBackoff, maxBackoff),
+		maxBackoff: maxBackoff,
+		entries:    make(map[cacheKey]*entry),
+	}
+}
+
+// Refresh starts checks of the servers whose results expired, without
+// waiting for them, and forgets servers that are gone once no check of
+// theirs is running
+func (c *Cache) Refresh(ctx context.Context, servers []types.Server) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	current := make(map[cacheKey]bool, len(servers))
+	for _, server := range servers {
+		key := cacheKey{server.PID, server.Port}
+		current[key] = true
+		e, ok := c.entries[key]
+		if !ok {
+			e = &entry{}
+			c.entries[key] = e
+		}
+		e.server = server
+		e.gone = false
+		if e.checking || now.Before(e.next) {
+			continue
+		}
+		e.checking = true
+		go c.check(ctx, key, server)
+	}
+	for key, e := range c.entries {
+		switch {
+		case current[key]:
+		case e.checking:
+			e.gone = true
+		default:
+			delete(c.entries, key)
+		}
+	}
+}
+
+// check runs one health check and schedules the next
+func (c *Cache) check(ctx context.Context, key cacheKey, server types.Server) {
+	result := c.checker.Check(ctx, server)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	e.checking = false
+	if e.gone {
+		delete(c.entries, key)
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	e.result = &result
+	e.checked = time.Now()
+	if result.State == StateUp {
+		e.failures = 0
+		e.next = e.checked.Add(c.ttl)
+		return
+	}
+	e.failures++
+	e.next = e.checked.Add(c.backoff(e.failures))
+}
+
+// backoff doubles the wait for each consecutive failure
+func (c *Cache) backoff(failures int) time.Duration {
+	wait := c.minBackoff
+	for i := 1; i < failures && wait < c.maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, c.maxBackoff)
+}
+
+// Statuses returns the cached health of every server, ordered by port
+func (c *Cache) Statuses() []Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	statuses := make([]Status, 0, len(c.entries))
+	for _, e := range c.entries {
+		if e.gone {
+			continue
+		}
+		status := Status{
+			Port:      e.server.Port,
+			PID:       e.server.PID,
+			Repo:      e.server.Repo,
+			Service:   e.server.Service,
+			State:     StatePending,
+			CheckedAt: e.checked,
+			NextCheck: e.next,
+			Failures:  e.failures,
+		}
+		if e.server.Service != "" {
+			status.Repo = ""
+		}
+		if r := e.result; r != nil {
+			status.State = r.State
+			status.Code = r.Code
+			status.LatencyMS = types.Milliseconds(r.Latency)
+			if r.Err != nil {
+				status.Error = r.Err.Error()
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	slices.SortFunc(statuses, func(a, b Status) int {
+		if a.Port != b.Port {
+			return a.Port - b.Port
+		}
+		return a.PID - b.PID
+	})
+	return statuses
+}
@@ -0,0 +1,180 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/probe"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// DefaultTimeout bounds a single health check
+const DefaultTimeout = 3 * time.Second
+
+// DefaultWorkers is how many checks a Checker runs at once
+const DefaultWorkers = 8
+
+// healthPaths are framework endpoints that report health better than "/"
+var healthPaths = []string{"/actuator/health", "/health", "/healthz", "/up"}
+
+// State summarizes a health check
+type State string
+
+const (
+	// StateUp means the server answered below HTTP 500
+	StateUp State = "up"
+
+	// StateError means the server answered with a 5xx status
+	StateError State = "error"
+
+	// StateDown means the connection failed or timed out
+	StateDown State = "down"
+)
+
+// Result is the outcome of checking one server
+type Result struct {
+	State State
+
+	// Code is the HTTP status code; 0 for TCP-only checks and failures
+	Code int
+
+	Latency time.Duration
+	Err     error
+}
+
+// String renders the result compactly, e.g. "200 · 12ms" or "down"
+func (r Result) String() string {
+	switch {
+	case r.State == StateDown:
+		return string(StateDown)
+	case r.Code == 0:
+		return fmt.Sprintf("open · %s", formatLatency(r.Latency))
+	}
+	return fmt.Sprintf("%d · %s", r.Code, formatLatency(r.Latency))
+}
+
+// Checker runs health checks with a bounded number of concurrent workers,
+// so refreshing many servers doesn't open a burst of connections
+type Checker struct {
+	client  *http.Client
+	timeout time.Duration
+	slots   chan struct{}
+}
+
+// NewChecker returns a Checker running at most workers checks at a time
+func NewChecker(workers int, timeout time.Duration) *Checker {
+	return &Checker{
+		client: &http.Client{
+			Timeout: timeout,
+			// A redirect is an answer; following it could leave localhost
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		timeout: timeout,
+		slots:   make(chan struct{}, workers),
+	}
+}
+
+// Check probes server, waiting for a free worker first. HTTP servers get a
+// GET of their health endpoint or "/"; gRPC, websocket and TCP servers only
+// a connection attempt.
+func (c *Checker) Check(ctx context.Context, server types.Server) Result {
+	select {
+	case c.slots <- struct{}{}:
+		defer func() { <-c.slots }()
+	case <-ctx.Done():
+		return Result{State: StateDown, Err: ctx.Err()}
+	}
+
+	start := time.Now()
+	if server.Protocol != "" && server.Protocol != probe.ProtocolHTTP {
+		addr := strings.TrimPrefix(server.URL(), "http://")
+		conn, err := (&net.Dialer{Timeout: c.timeout}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return Result{State: StateDown, Err: err}
+		}
+		conn.Close()
+		return Result{State: StateUp, Latency: time.Since(start)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL()+healthPath(server), nil)
+	if err != nil {
+		return Result{State: StateDown, Err: err}
+	}
+	req.Header.Set("User-Agent", "lsrv")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Result{State: StateDown, Err: err}
+	}
+	resp.Body.Close()
+
+	result := Result{State: StateUp, Code: resp.StatusCode, Latency: time.Since(start)}
+	if resp.StatusCode >= 500 {
+		result.State = StateError
+	}
+	return result
+}
+
+// TTFB measures the time to the first byte of the response to a GET of
+// the server's root page, the one a browser would load first, so a dev
+// server busy recompiling shows up slow. It waits for a free worker first.
+func (c *Checker) TTFB(ctx context.Context, server types.Server) (time.Duration, error) {
+	select {
+	case c.slots <- struct{}{}:
+		defer func() { <-c.slots }()
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	var start, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, server.URL()+"/", nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "lsrv")
+
+	start = time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return firstByte.Sub(start), nil
+}
+
+// FormatLatency renders a latency compactly, e.g. "12ms" or "1.8s"
+func FormatLatency(d time.Duration) string {
+	return formatLatency(d)
+}
+
+// healthPath picks the server's framework health endpoint, if it has one
+func healthPath(server types.Server) string {
+	for _, path := range healthPaths {
+		if slices.Contains(server.Paths, path) {
+			return path
+		}
+	}
+	return "/"
+}
+
+// formatLatency rounds a latency for display
+func formatLatency(d time.Duration) string {
+	if d < time.Millisecond {
+		return "<1ms"
+	}
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
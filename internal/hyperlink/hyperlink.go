@@ -0,0 +1,52 @@
+// Package hyperlink emits OSC 8 terminal hyperlinks, which make text
+// clickable without showing the link target
+package hyperlink
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Wrap makes text a hyperlink to url
+func Wrap(url, text string) string {
+	if url == "" {
+		return text
+	}
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// Supported reports whether the terminal is known to render OSC 8 links.
+// Others may print the escape sequence as garbage, so only recognized
+// terminals qualify. LSRV_HYPERLINKS=1 or 0 overrides detection.
+func Supported() bool {
+	switch os.Getenv("LSRV_HYPERLINKS") {
+	case "1":
+		return true
+	case "0":
+		return false
+	}
+
+	// Multiplexers hide the outer terminal, and older tmux and screen
+	// versions drop or mangle the sequence
+	if os.Getenv("TMUX") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		return false
+	}
+
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "rio":
+		return true
+	}
+	if os.Getenv("TERM") == "xterm-kitty" || os.Getenv("KITTY_WINDOW_ID") != "" {
+		return true
+	}
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("KONSOLE_VERSION") != "" {
+		return true
+	}
+
+	// VTE-based terminals (GNOME Terminal, Tilix) support it since 0.50
+	if vte, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && vte >= 5000 {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package i18n
+
+// spanish is the Spanish catalog. Placeholders must keep the order and
+// verbs of the English message.
+var spanish = map[string]string{
+	// Table headers
+	"REPO":        "REPO",
+	"BRANCH":      "RAMA",
+	"PROCESS":     "PROCESO",
+	"PID":         "PID",
+	"USER":        "USUARIO",
+	"LAST COMMIT": "ÚLTIMO COMMIT",
+	"PACKAGE":     "PAQUETE",
+	"SESSION":     "SESIÓN",
+	"STARTED BY":  "INICIADO POR",
+	"COMMAND":     "COMANDO",
+	"LAN URL":     "URL LAN",
+	"STATUS":      "ESTADO",
+	"URL":         "URL",
+	"SERVICE":     "SERVICIO",
+	"ADDRESS":     "DIRECCIÓN",
+	"HEALTH":      "SALUD",
+	"VERSION":     "VERSIÓN",
+	"LATENCY":     "LATENCIA",
+	"BACKLOG":     "COLA",
+
+	"No running web servers found.": "No se encontraron servidores web en ejecución.",
+	"Services":                      "Servicios",
+
+	// Help
+	"lsrv version %s": "lsrv versión %s",
+	"Usage:":          "Uso:",
+	"Lists all running web servers across repos and worktrees.":                                       "Lista todos los servidores web en ejecución en tus repositorios y worktrees.",
+	"Supported languages/frameworks:":                                                                 "Lenguajes/frameworks compatibles:",
+	"Commands:":                                                                                       "Comandos:",
+	"Start a server in the background, capturing its output":                                          "Inicia un servidor en segundo plano y guarda su salida",
+	"Show a server's output (-f to follow, -n for line count)":                                        "Muestra la salida de un servidor (-f para seguirla, -n para el número de líneas)",
+	"Stop a server with SIGTERM":                                                                      "Detiene un servidor con SIGTERM",
+	"Pick several servers from a list and stop them after one confirmation":                           "Elige varios servidores de una lista y los detiene tras una sola confirmación",
+	"Restart a server with the same command line":                                                     "Reinicia un servidor con la misma línea de comandos",
+	"Show everything known about a server: command, git remote, uptime, memory, health":               "Muestra todo lo que se sabe de un servidor: comando, remoto de git, tiempo activo, memoria, salud",
+	"Show what listens on PORT, even outside repos, and which repos used it before":                   "Muestra qué escucha en PUERTO, incluso fuera de repos, y qué repos lo usaron antes",
+	"Open a server's URL in the browser":                                                              "Abre la URL de un servidor en el navegador",
+	"Run CMD (after --) in the server's working directory":                                            "Ejecuta CMD (tras --) en el directorio de trabajo del servidor",
+	"can't determine the working directory of %s (pid %d)":                                            "no se puede determinar el directorio de trabajo de %s (pid %d)",
+	"Run action A from the repo's .lsrv.yml in its directory (lists them without A)":                  "Ejecuta la acción A del .lsrv.yml del repositorio en su directorio (sin A, las lista)",
+	"Stop servers whose directory was deleted (asks for each)":                                        "Detiene los servidores cuyo directorio fue borrado (pregunta por cada uno)",
+	"Save the running servers as N, or show what started/stopped since":                               "Guarda los servidores en ejecución como N, o muestra qué arrancó o se detuvo desde entonces",
+	"Print a compact PORT REPO(BRANCH) PROCESS listing":                                               "Imprime un listado compacto PUERTO REPO(RAMA) PROCESO",
+	"Show which servers and services are connected to each other":                                     "Muestra qué servidores y servicios están conectados entre sí",
+	"reading connections: %v":                                                                         "leyendo las conexiones: %v",
+	"Print the current repo's ports (e.g. ●3000) from the daemon, for shell prompts":                  "Imprime los puertos del repo actual (p. ej. ●3000) desde el daemon, para el prompt de la shell",
+	"Show the ports a repo usually runs on and what holds them today":                                 "Muestra los puertos que usa un repo habitualmente y quién los ocupa hoy",
+	"Reserve PORT for the current repo, or list reservations (--suggest prints a free port)":          "Reserva PORT para el repo actual, o lista las reservas (--suggest imprime un puerto libre)",
+	"reading reservations: %v":                                                                        "leyendo las reservas: %v",
+	"saving reservations: %v":                                                                         "guardando las reservas: %v",
+	"encoding reservations: %v":                                                                       "codificando las reservas: %v",
+	"no free port from %d":                                                                            "no hay ningún puerto libre a partir de %d",
+	"invalid port %q":                                                                                 "puerto no válido %q",
+	"port %d is not reserved":                                                                         "el puerto %d no está reservado",
+	"%v; release it first with \"lsrv reserve %d --release\"":                                         "%v; libéralo primero con \"lsrv reserve %d --release\"",
+	"port %d is reserved for %s, not %s (see \"lsrv reserve\")":                                       "el puerto %d está reservado para %s, no para %s (ver \"lsrv reserve\")",
+	"reading port history: %v":                                                                        "leyendo el historial de puertos: %v",
+	"no port history for %s (lsrv records ports each time it lists servers)":                          "no hay historial de puertos para %s (lsrv registra los puertos cada vez que lista servidores)",
+	"Serve a JSON HTTP API for listing and killing servers":                                           "Sirve una API HTTP JSON para listar y detener servidores",
+	"Run \"lsrv serve\" at login via launchd or systemd (also uninstall, status)":                     "Ejecuta \"lsrv serve\" al iniciar sesión con launchd o systemd (también uninstall, status)",
+	"Run a Model Context Protocol server over stdio":                                                  "Ejecuta un servidor Model Context Protocol por stdio",
+	"Report unknown settings and wrong values in config.yml and .lsrv.yml":                            "Informa de ajustes desconocidos y valores erróneos en config.yml y .lsrv.yml",
+	"Write a commented config.yml to start from (--repo for .lsrv.yml)":                               "Escribe un config.yml comentado como punto de partida (--repo para .lsrv.yml)",
+	"Check dependencies, permissions and config, and suggest fixes":                                   "Comprueba dependencias, permisos y configuración, y sugiere soluciones",
+	"Report servers reachable from other machines, exposed debug pages and default logins":            "Informa de los servidores accesibles desde otras máquinas, las páginas de depuración expuestas y los accesos por defecto",
+	"Show a VERSION column with each runtime and version (ruby 3.3.0), resolving asdf/mise/nvm shims": "Muestra una columna VERSIÓN con el runtime y su versión (ruby 3.3.0), resolviendo los shims de asdf/mise/nvm",
+	"Show an ENV column with each direnv/devenv setup, marked when the server started without it":     "Muestra una columna ENV con la configuración de direnv/devenv, marcada si el servidor arrancó sin ella",
+	"Print the running servers as portable JSON (repo, branch, command, port)":                        "Imprime los servidores en ejecución como JSON portable (repo, rama, comando, puerto)",
+	"Start the servers from an export in your own checkouts":                                          "Inicia los servidores de una exportación en tus propias copias de los repositorios",
+	"Options:":                 "Opciones:",
+	"Show this help message":   "Muestra esta ayuda",
+	"Show version information": "Muestra la versión",
+	"Output format: table (default on a terminal), plain (default otherwise), markdown, json, csv, alfred, raycast, dot, mermaid or openmetrics": "Formato de salida: table (por defecto en un terminal), plain (por defecto en otro caso), markdown, json, csv, alfred, raycast, dot, mermaid u openmetrics",
+	"Print export lines (APP_URL, APP_PORT) for a server, for eval in a shell":                                                                   "Muestra líneas export (APP_URL, APP_PORT) de un servidor, para usar con eval en un shell",
+	"no server is running in this repository":                                                             "no hay ningún servidor ejecutándose en este repositorio",
+	"Live view of dev servers sorted by CPU or memory, with keys to re-sort and kill":                     "Vista en vivo de los servidores de desarrollo ordenados por CPU o memoria, con teclas para reordenar y detener",
+	"Fail if PORT is held by another repo or branch, before launching a server":                           "Falla si PORT lo ocupa otro repositorio o rama, antes de lanzar un servidor",
+	"Verify the servers listed under requires in .lsrv.yml are running":                                   "Comprueba que los servidores listados en requires de .lsrv.yml estén en ejecución",
+	"not in a git repository; pass --repo":                                                                "no estás en un repositorio git; indica --repo",
+	"port %d is held by %s (pid %d), not %s":                                                              "el puerto %d lo ocupa %s (pid %d), no %s",
+	"port %d is held by %s (%s), pid %d in %s, not %s; stop it with \"lsrv kill %d\"":                     "el puerto %d lo ocupa %s (%s), pid %d en %s, no %s; detenlo con \"lsrv kill %d\"",
+	"port %d is held by %s on branch %s, pid %d in %s, not %s; stop it with \"lsrv kill %d\"":             "el puerto %d lo ocupa %s en la rama %s, pid %d en %s, no %s; detenlo con \"lsrv kill %d\"",
+	"port %d is in use by a process lsrv doesn't list (see \"lsof -i :%d\")":                              "el puerto %d lo usa un proceso que lsrv no muestra (consulta \"lsof -i :%d\")",
+	"Print the JSON Schema of --format=json output":                                                       "Muestra el JSON Schema de la salida de --format=json",
+	"Write output to FILE atomically, with a summary on stderr":                                           "Escribe la salida en FILE de forma atómica, con un resumen en stderr",
+	"Only show servers in the current repo and its worktrees (same as \"lsrv .\")":                        "Muestra solo los servidores del repositorio actual y sus worktrees (igual que \"lsrv .\")",
+	"Only show servers started within DURATION, e.g. 30m after a launch script":                           "Muestra solo los servidores iniciados en los últimos DURATION, p. ej. 30m tras un script de arranque",
+	"Hide servers on main, master or production (main_branches in the config)":                            "Oculta los servidores en main, master o production (main_branches en la configuración)",
+	"Show servers in directories listed in ~/.config/lsrv/ignore":                                         "Muestra los servidores de directorios listados en ~/.config/lsrv/ignore",
+	"Merge listeners by key (repo, branch, process, port; default), pid, socket or none":                  "Agrupa las escuchas por clave (repo, rama, proceso, puerto; por defecto), pid, socket o ninguna (none)",
+	"List HMR and helper ports of JS dev servers as their own rows":                                       "Lista los puertos de HMR y auxiliares de servidores JS como filas propias",
+	"List the current repo's servers (marked ▸) first":                                                    "Lista primero los servidores del repo actual (marcados con ▸)",
+	"Don't page listings taller than the terminal through $PAGER (default less)":                          "No pagina con $PAGER (por defecto less) los listados más altos que el terminal",
+	"Show full repo and branch names on narrow terminals":                                                 "Muestra los nombres completos de repositorio y rama en terminales estrechas",
+	"Include other users' servers with a USER column (needs sudo)":                                        "Incluye los servidores de otros usuarios con una columna USUARIO (requiere sudo)",
+	"Enumerate sockets via sudo to include other users' and root's servers":                               "Enumera los sockets con sudo para incluir los servidores de otros usuarios y de root",
+	"Also list postgres, mysql, redis, elasticsearch, mailhog, minio, ...":                                "Lista también postgres, mysql, redis, elasticsearch, mailhog, minio, ...",
+	"Show a COMMAND column with each full command line (complete in JSON)":                                "Muestra una columna COMANDO con la línea de comandos completa (entera en JSON)",
+	"Skip git commands for speed; REPO shows the directory name, BRANCH \"-\"":                            "Omite git para ir más rápido; REPO muestra el nombre del directorio y RAMA \"-\"",
+	"Show a LAST COMMIT column with the age of each branch's latest commit":                               "Muestra una columna ÚLTIMO COMMIT con la antigüedad del último commit de cada rama",
+	"Show a STARTED BY column with the processes that launched each server":                               "Muestra una columna INICIADO POR con los procesos que lanzaron cada servidor",
+	"Show a PACKAGE column with each server's directory in its repo (packages/web)":                       "Muestra una columna PACKAGE con el directorio de cada servidor en su repo (packages/web)",
+	"Look at most N directories above a server's directory for its repo (default no limit)":               "Busca el repo como mucho N directorios por encima del directorio de cada servidor (por defecto sin límite)",
+	"--max-depth must be 0 or more":                                                                       "--max-depth debe ser 0 o más",
+	"-n must be 0 or more":                                                                                "-n debe ser 0 o más",
+	"Show a SESSION column with the owning tmux pane, terminal or editor":                                 "Muestra una columna SESIÓN con el panel de tmux, terminal o editor propietario",
+	"Show a LATENCY column with each server's time to first byte, color-graded":                           "Muestra una columna LATENCIA con el tiempo hasta el primer byte de cada servidor, coloreada",
+	"Show a BACKLOG column with queued connections per server, red when the accept queue is full (Linux)": "Muestra una columna COLA con las conexiones en espera de cada servidor, en rojo cuando la cola de aceptación está llena (Linux)",
+	"Probe ports and tag gRPC (grpc://), websocket (ws://) and TCP (tcp://) URLs":                         "Sondea los puertos y marca las URL gRPC (grpc://), websocket (ws://) y TCP (tcp://)",
+	"Take repo names from config, origin, upstream, toplevel (default config,origin)":                     "Toma los nombres de repositorio de config, origin, upstream, toplevel (por defecto config,origin)",
+	"Show a LAN URL column (primary interface IP) for servers not bound to localhost":                     "Muestra una columna URL LAN (IP de la interfaz principal) para servidores no limitados a localhost",
+	"Show a PUBLIC URL column with the ngrok, cloudflared or tailscale funnel URL of each server":         "Muestra una columna PUBLIC URL con la URL de ngrok, cloudflared o tailscale funnel de cada servidor",
+	"Like --lan, and mark LAN URLs the firewall (ufw, iptables, pf, macOS) blocks":                        "Como --lan, y marca las URL LAN que bloquea el cortafuegos (ufw, iptables, pf, macOS)",
+	"blocked by %s": "bloqueada por %s",
+	"Like --lan, but use this machine's HOSTNAME.local name instead of its IP":                                     "Como --lan, pero usa el nombre HOSTNAME.local de esta máquina en lugar de su IP",
+	"Add a STATUS column with symbols and text (healthy, stale, zombie, conflict)":                                 "Añade una columna ESTADO con símbolos y texto (healthy, stale, zombie, conflict)",
+	"Use plain text tags like [ruby] instead of Nerd Font icons":                                                   "Usa etiquetas de texto como [ruby] en lugar de iconos Nerd Font",
+	"Language for messages and table headers: en or es (default from LANG)":                                        "Idioma de los mensajes y encabezados: en o es (por defecto según LANG)",
+	"Browse a live table with per-server health checks (refreshes every --watch, default 2s)":                      "Explora una tabla en vivo con comprobaciones de salud por servidor (se actualiza cada --watch, por defecto 2s)",
+	"Also list servers on the hosts in the config file over SSH, with a HOST column":                               "Lista también los servidores de los hosts del archivo de configuración por SSH, con una columna HOST",
+	"With --watch, redraw as soon as servers start or stop (at most once a second); DURATION becomes the fallback": "Con --watch, redibuja en cuanto un servidor arranca o se detiene (como mucho una vez por segundo); DURATION queda como respaldo",
+	"Redraw the list every DURATION (e.g. 2s), only inspecting new processes":                                      "Redibuja la lista cada DURATION (p. ej. 2s), inspeccionando solo procesos nuevos",
+	"Save lsof's raw output to FILE and list unparsed lines, for bug reports":                                      "Guarda la salida sin procesar de lsof en FILE y lista las líneas no analizadas, para informes de errores",
+	"Give up on detection after DURATION (default 10s, 0 for no limit)":                                            "Abandona la detección tras DURATION (por defecto 10s, 0 sin límite)",
+	"Print per-phase durations (lsof, cwd, git, render) to stderr":                                                 "Imprime la duración de cada fase (lsof, cwd, git, render) en stderr",
+	"Print what runs with less for lack of privileges (other users' processes, sockets) to stderr":                 "Imprime lo que funciona a medias por falta de privilegios (procesos y sockets de otros usuarios) en stderr",
+	"Write performance profile to FILE for analysis":                                                               "Escribe un perfil de rendimiento en FILE para analizarlo",
+	"Output columns:": "Columnas de salida:",
+	"Repository name (from git remote or directory name)": "Nombre del repositorio (del remoto de git o del nombre del directorio)",
+	"Current git branch": "Rama de git actual",
+	"Process running the server with icon (💎 ruby, ⬢ node, 🐹 go, etc.)": "Proceso que ejecuta el servidor, con icono (💎 ruby, ⬢ node, 🐹 go, etc.)",
+	"Process ID": "ID del proceso",
+	"Clickable HTTP URL to access the server": "URL HTTP en la que hacer clic para acceder al servidor",
+
+	// Errors and warnings
+	"error:":   "error:",
+	"warning:": "aviso:",
+	"lsof command not found, please install it":                                         "no se encontró el comando lsof, instálalo",
+	"On macOS, lsof should be pre-installed. If missing, reinstall Command Line Tools:": "En macOS, lsof viene preinstalado. Si falta, reinstala las Command Line Tools:",
+	"On Linux, install lsof:":                                                           "En Linux, instala lsof:",
+	"%d more issue(s) in %s, see \"lsrv config check\"":                                 "%d problema(s) más en %s, consulta \"lsrv config check\"",
+	"ignoring %s until it's fixed, using defaults":                                      "se ignora %s hasta que se corrija, se usan los valores por defecto",
+	"%s: ok":   "%s: correcto",
+	"Wrote %s": "Escrito %s",
+	"%s already exists; edit it, and run \"lsrv config check\" to validate it": "%s ya existe; edítalo y ejecuta \"lsrv config check\" para validarlo",
+	"%v, using defaults":                      "%v, se usan los valores por defecto",
+	"%v, ignoring nothing":                    "%v, no se ignora nada",
+	"repo_name in config: %v, using defaults": "repo_name en la configuración: %v, se usan los valores por defecto",
+	"could not create profile file: %v":       "no se pudo crear el archivo de perfil: %v",
+	"expected at most one directory, got %q":  "se esperaba como máximo un directorio, se recibió %q",
+	"--all-users without root may miss other users' servers; add --sudo for full results": "--all-users sin root puede omitir servidores de otros usuarios; añade --sudo para verlos todos",
+	"--interactive cannot be combined with --output or --format":                          "--interactive no se puede combinar con --output ni --format",
+	"lsrv top needs a terminal":                                            "lsrv top necesita una terminal",
+	"lsrv top isn't supported on this platform yet":                        "lsrv top aún no está disponible en esta plataforma",
+	"invalid sort %q (want cpu or mem)":                                    "orden %q no válido (usa cpu o mem)",
+	"--interval must be positive":                                          "--interval debe ser positivo",
+	"--fleet cannot be combined with --watch or --interactive":             "--fleet no se puede combinar con --watch ni --interactive",
+	"--fleet needs hosts in the config file (see \"hosts\" in the README)": "--fleet necesita hosts en el archivo de configuración (consulta \"hosts\" en el README)",
+	"every host in the config file needs a name and an ssh destination":    "cada host del archivo de configuración necesita un nombre y un destino ssh",
+	"couldn't list servers on %s":                                          "no se pudieron listar los servidores de %s",
+	"--on-change needs --watch":                                            "--on-change necesita --watch",
+	"--on-change isn't supported on this platform":                         "--on-change no está disponible en esta plataforma",
+	"--interactive needs a terminal":                                       "--interactive necesita una terminal",
+	"--watch cannot be combined with --output":                             "--watch no se puede combinar con --output",
+	"finding servers: %v":                                                  "buscando servidores: %v",
+	"writing output: %v":                                                   "escribiendo la salida: %v",
+	"saving raw lsof output: %v":                                           "guardando la salida sin procesar de lsof: %v",
+	"Saved raw lsof output to %s":                                          "Salida sin procesar de lsof guardada en %s",
+	"timed out after %s, a git command or filesystem may be hung (raise --timeout to wait longer)": "se agotó el tiempo tras %s, puede que un comando de git o el sistema de archivos esté bloqueado (sube --timeout para esperar más)",
+	"no server matches %q":                                     "ningún servidor coincide con %q",
+	"not running — start with: %s":                             "no está en ejecución — inícialo con: %s",
+	"%q matches servers in %d directories, use a port instead": "%q coincide con servidores en %d directorios, usa un puerto",
+	"%s has no action %q (run \"lsrv run %s\" to list them)":   "%s no tiene la acción %q (ejecuta \"lsrv run %s\" para verlas)",
+	"reading actions: %v":                                      "leyendo las acciones: %v",
+	"running %s: %v":                                           "ejecutando %s: %v",
+	"reading %s: %v":                                           "leyendo %s: %v",
+	"resolving directory: %v":                                  "resolviendo el directorio: %v",
+	"starting server: %v":                                      "iniciando el servidor: %v",
+	"saving snapshot: %v":                                      "guardando la instantánea: %v",
+	"clean %s on port %d: %v":                                  "limpiando %s en el puerto %d: %v",
+	"installing daemon: %v":                                    "instalando el daemon: %v",
+	"uninstalling daemon: %v":                                  "desinstalando el daemon: %v",
+	"interactive mode: %v":                                     "modo interactivo: %v",
+	"creating API token: %v":                                   "creando el token de la API: %v",
+	"--grpc=%s is reachable from other machines; use localhost:PORT, or add --grpc-remote": "--grpc=%s es accesible desde otras máquinas; usa localhost:PUERTO o añade --grpc-remote",
+	"--http=%s is reachable from other machines; use localhost:PORT, or add --http-remote": "--http=%s es accesible desde otras máquinas; usa localhost:PUERTO o añade --http-remote",
+	"watching sockets: %v":  "vigilando sockets: %v",
+	"Every %s":              "Cada %s",
+	"On change or every %s": "Al cambiar o cada %s",
+	"serving: %v":           "sirviendo: %v",
+
+	// Summaries
+	"Wrote %d server as %s to %s":                                                                          "Se escribió %d servidor como %s en %s",
+	"Wrote %d servers as %s to %s":                                                                         "Se escribieron %d servidores como %s en %s",
+	"%d listener skipped (permission denied), run with --sudo to include":                                  "%d puerto en escucha omitido (permiso denegado), ejecuta con --sudo para incluirlo",
+	"%d listeners skipped (permission denied), run with --sudo to include":                                 "%d puertos en escucha omitidos (permiso denegado), ejecuta con --sudo para incluirlos",
+	"%d lsof output line could not be parsed, rerun with --dump-raw=FILE and attach FILE to a bug report":  "no se pudo analizar %d línea de la salida de lsof; vuelve a ejecutar con --dump-raw=FILE y adjunta FILE a un informe de error",
+	"%d lsof output lines could not be parsed, rerun with --dump-raw=FILE and attach FILE to a bug report": "no se pudieron analizar %d líneas de la salida de lsof; vuelve a ejecutar con --dump-raw=FILE y adjunta FILE a un informe de error",
+	"%d process exited during the scan and was skipped":                                                    "%d proceso terminó durante el escaneo y se omitió",
+	"%d processes exited during the scan and were skipped":                                                 "%d procesos terminaron durante el escaneo y se omitieron",
+	"%d server runs in a deleted directory, run lsrv clean to stop it":                                     "%d servidor se ejecuta en un directorio borrado, ejecuta lsrv clean para detenerlo",
+	"%d servers run in a deleted directory, run lsrv clean to stop them":                                   "%d servidores se ejecutan en un directorio borrado, ejecuta lsrv clean para detenerlos",
+}
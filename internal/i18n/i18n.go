@@ -0,0 +1,94 @@
+// Package i18n translates lsrv's user-facing text. Messages are looked up by
+// their English text, so untranslated messages and the English locale need
+// no catalog entry.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// English is the source language of every message
+const English = "en"
+
+// catalogs maps a language code to its translations, keyed by English text
+var catalogs = map[string]map[string]string{
+	English: {},
+	"es":    spanish,
+}
+
+// current is the language messages are translated into
+var current = English
+
+// Supported returns the available language codes, sorted
+func Supported() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Detect returns the language requested by the environment, checking
+// LC_ALL, LC_MESSAGES and LANG in the order the C library does. Values
+// like "es_ES.UTF-8" are reduced to the language code; "C" and "POSIX"
+// mean English.
+func Detect() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return languageCode(value)
+		}
+	}
+	return English
+}
+
+// languageCode extracts the language from a locale name
+func languageCode(locale string) string {
+	lang, _, _ := strings.Cut(locale, ".")
+	lang, _, _ = strings.Cut(lang, "@")
+	lang, _, _ = strings.Cut(lang, "_")
+	lang = strings.ToLower(lang)
+	if lang == "c" || lang == "posix" {
+		return English
+	}
+	return lang
+}
+
+// Set switches the language, accepting a code or a locale name
+func Set(lang string) error {
+	code := languageCode(lang)
+	if _, ok := catalogs[code]; !ok {
+		return fmt.Errorf("unsupported language %q (available: %s)", lang, strings.Join(Supported(), ", "))
+	}
+	current = code
+	return nil
+}
+
+// Current returns the language code in use
+func Current() string {
+	return current
+}
+
+// T translates msg, then formats it with args like fmt.Sprintf. Messages
+// missing from the catalog are used as is.
+func T(msg string, args ...any) string {
+	if translated, ok := catalogs[current][msg]; ok {
+		msg = translated
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// N translates the singular or plural form of a message depending on n.
+// Both supported languages use the singular for exactly one.
+func N(n int, singular, plural string, args ...any) string {
+	if n == 1 {
+		return T(singular, args...)
+	}
+	return T(plural, args...)
+}
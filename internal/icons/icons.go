@@ -0,0 +1,235 @@
+package icons
+
+import (
+	"sync"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// fallbackLanguage tags servers whose language couldn't be determined
+const fallbackLanguage = "web"
+
+// statusSymbols mark server statuses so they don't rely on color alone
+var statusSymbols = map[types.Status]string{
+	types.StatusHealthy:  "✓",
+	types.StatusStale:    "⏲",
+	types.StatusZombie:   "⚠",
+	types.StatusConflict: "⇄",
+	types.StatusUnusual:  "↷",
+	types.StatusReserved: "⊘",
+}
+
+var asciiStatusSymbols = map[types.Status]string{
+	types.StatusHealthy:  "[+]",
+	types.StatusStale:    "[~]",
+	types.StatusZombie:   "[!]",
+	types.StatusConflict: "[x]",
+	types.StatusUnusual:  "[?]",
+	types.StatusReserved: "[r]",
+}
+
+// Set resolves icons and labels from the defaults, the global config and
+// per-repository .lsrv.yml overrides. A nil *Set uses the defaults only.
+type Set struct {
+	ascii  bool
+	global config.Display
+
+	// runtimes are the user's runtimes followed by the built-in ones
+	runtimes []config.Runtime
+
+	mu    sync.Mutex
+	repos map[string]config.Display
+}
+
+// NewSet returns a Set applying cfg's overrides; ascii forces plain text
+// tags instead of glyphs
+func NewSet(cfg *config.Config, ascii bool) *Set {
+	set := &Set{ascii: ascii, repos: make(map[string]config.Display)}
+	if cfg != nil {
+		set.ascii = set.ascii || cfg.ASCII
+		set.global = cfg.Display
+		set.runtimes = mergeRuntimes(cfg.Runtimes)
+	}
+	return set
+}
+
+// runtimeList returns the runtimes to match processes against
+func (s *Set) runtimeList() []config.Runtime {
+	if s == nil || s.runtimes == nil {
+		return defaults.Runtimes
+	}
+	return s.runtimes
+}
+
+// Color returns the color of a process's PROCESS cell: the framework's,
+// then the runtime's, or "" for the default
+func (s *Set) Color(process, framework string, projectType types.ProjectType) string {
+	if color := frameworkFor(framework).Color; color != "" {
+		return color
+	}
+	return lookup(s.runtimeList(), process, projectType).Color
+}
+
+// Icon returns the icon for a process running in dir. Overrides are looked
+// up by process name, then language, in the repo config before the global
+// config. In ASCII mode only plain-text overrides are used, and the default
+// is a tag like [ruby].
+func (s *Set) Icon(process string, projectType types.ProjectType, dir string) string {
+	runtime := lookup(s.runtimeList(), process, projectType)
+	lang := runtime.Name
+
+	if s != nil {
+		for _, display := range []config.Display{s.repo(dir), s.global} {
+			for _, key := range []string{process, lang} {
+				if icon, ok := display.Icons[key]; ok && (!s.ascii || isASCII(icon)) {
+					return icon
+				}
+			}
+		}
+
+		if s.ascii {
+			if runtime.Icon != "" && isASCII(runtime.Icon) {
+				return runtime.Icon
+			}
+			return "[" + lang + "]"
+		}
+	}
+
+	return runtime.Icon
+}
+
+// FrameworkIcon returns the icon for a server framework such as "phoenix",
+// honoring overrides keyed by the framework name in the repo config before
+// the global config
+func (s *Set) FrameworkIcon(framework string, dir string) string {
+	if s != nil {
+		for _, display := range []config.Display{s.repo(dir), s.global} {
+			if icon, ok := display.Icons[framework]; ok && (!s.ascii || isASCII(icon)) {
+				return icon
+			}
+		}
+		if s.ascii {
+			return "[" + frameworkFor(framework).Language + "]"
+		}
+	}
+	return frameworkFor(framework).Icon
+}
+
+// ServiceIcon returns the icon for an auxiliary service such as "postgres",
+// honoring global overrides keyed by the service name
+func (s *Set) ServiceIcon(service string) string {
+	if s != nil {
+		if icon, ok := s.global.Icons[service]; ok && (!s.ascii || isASCII(icon)) {
+			return icon
+		}
+		if s.ascii {
+			return "[" + service + "]"
+		}
+	}
+
+	if glyph, ok := defaults.Services[service]; ok {
+		return glyph
+	}
+	return lookup(s.runtimeList(), "", "").Icon
+}
+
+// CurrentMarker returns the marker put before the repo of servers running
+// in the current directory's work tree
+func (s *Set) CurrentMarker() string {
+	if s != nil && s.ascii {
+		return ">"
+	}
+	return "▸"
+}
+
+// AuxMarker returns the marker put before the repo of rows for a dev
+// server's auxiliary ports, shown under the app they belong to
+func (s *Set) AuxMarker() string {
+	if s != nil && s.ascii {
+		return "`-"
+	}
+	return "↳"
+}
+
+// MainBranchMarker returns the marker put before the branch of servers on
+// a main branch, with highlight_main_branches
+func (s *Set) MainBranchMarker() string {
+	if s != nil && s.ascii {
+		return "!"
+	}
+	return "⚑"
+}
+
+// NixMarker returns the marker put after the process of servers started
+// inside a Nix development shell
+func (s *Set) NixMarker() string {
+	if s != nil && s.ascii {
+		return "(nix)"
+	}
+	return "❄"
+}
+
+// StatusSymbol returns the symbol for a server status, honoring global
+// overrides keyed by the status name (e.g., "zombie")
+func (s *Set) StatusSymbol(status types.Status) string {
+	if s != nil {
+		if icon, ok := s.global.Icons[string(status)]; ok && (!s.ascii || isASCII(icon)) {
+			return icon
+		}
+		if s.ascii {
+			return asciiStatusSymbols[status]
+		}
+	}
+	return statusSymbols[status]
+}
+
+// Label returns the display name for a process running in dir, which is the
+// process name unless overridden
+func (s *Set) Label(process string, dir string) string {
+	if s == nil {
+		return process
+	}
+
+	for _, display := range []config.Display{s.repo(dir), s.global} {
+		if label, ok := display.Labels[process]; ok {
+			return label
+		}
+	}
+	if runtime, ok := byProcess(s.runtimeList(), process); ok && runtime.Label != "" {
+		return runtime.Label
+	}
+	return process
+}
+
+// repo returns the overrides from dir's .lsrv.yml, loading each file once
+func (s *Set) repo(dir string) config.Display {
+	if dir == "" {
+		return config.Display{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if display, ok := s.repos[dir]; ok {
+		return display
+	}
+
+	// A broken repo config shouldn't break listing; fall back to globals
+	var display config.Display
+	if cfg, err := config.LoadRepo(dir); err == nil {
+		display = cfg.Display
+	}
+	s.repos[dir] = display
+	return display
+}
+
+// isASCII reports whether s contains only printable ASCII characters
+func isASCII(s string) bool {
+	for _, c := range s {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
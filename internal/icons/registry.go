@@ -0,0 +1,100 @@
+package icons
+
+import (
+	_ "embed"
+	"path"
+	"slices"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed registry.yml
+var registryYAML []byte
+
+// registry lists the runtimes, frameworks and services lsrv knows how to
+// show, so supporting a new one is a matter of data rather than code
+type registry struct {
+	Runtimes   []config.Runtime  `yaml:"runtimes"`
+	Frameworks []frameworkEntry  `yaml:"frameworks"`
+	Services   map[string]string `yaml:"services"`
+}
+
+// frameworkEntry describes how servers of a detected framework are shown
+type frameworkEntry struct {
+	Name string `yaml:"name"`
+
+	// Language is the ASCII tag, as the framework name is already part
+	// of the label
+	Language string `yaml:"language"`
+
+	Icon string `yaml:"icon"`
+
+	// Color overrides the runtime's color when set
+	Color string `yaml:"color"`
+}
+
+// defaults is the built-in registry from registry.yml. It is part of the
+// binary, so failing to parse it is a build mistake.
+var defaults = func() registry {
+	var r registry
+	if err := yaml.Unmarshal(registryYAML, &r); err != nil {
+		panic("icons: parsing registry.yml: " + err.Error())
+	}
+	return r
+}()
+
+// mergeRuntimes puts the user's runtimes before the built-in ones, which
+// they replace by name
+func mergeRuntimes(user []config.Runtime) []config.Runtime {
+	runtimes := slices.Clone(user)
+	for _, runtime := range defaults.Runtimes {
+		replaced := slices.ContainsFunc(user, func(u config.Runtime) bool { return u.Name == runtime.Name })
+		if !replaced {
+			runtimes = append(runtimes, runtime)
+		}
+	}
+	return runtimes
+}
+
+// byProcess returns the first runtime with a pattern matching process
+func byProcess(runtimes []config.Runtime, process string) (config.Runtime, bool) {
+	for _, runtime := range runtimes {
+		for _, pattern := range runtime.Processes {
+			if ok, _ := path.Match(pattern, process); ok {
+				return runtime, true
+			}
+		}
+	}
+	return config.Runtime{}, false
+}
+
+// lookup returns the runtime of a process, checking the process name first
+// and the project type second, or the fallback runtime
+func lookup(runtimes []config.Runtime, process string, projectType types.ProjectType) config.Runtime {
+	if runtime, ok := byProcess(runtimes, process); ok {
+		return runtime
+	}
+	for _, runtime := range runtimes {
+		if slices.Contains(runtime.Projects, string(projectType)) {
+			return runtime
+		}
+	}
+	for _, runtime := range runtimes {
+		if runtime.Name == fallbackLanguage {
+			return runtime
+		}
+	}
+	return config.Runtime{Name: fallbackLanguage}
+}
+
+// frameworkFor returns the registry entry of a framework, or a zero entry
+func frameworkFor(name string) frameworkEntry {
+	for _, entry := range defaults.Frameworks {
+		if entry.Name == name {
+			return entry
+		}
+	}
+	return frameworkEntry{}
+}
@@ -0,0 +1,166 @@
+// Package ignore reads the global ignore file listing directories whose
+// servers lsrv never shows, such as vendored checkouts or a dotfiles repo
+// serving its docs
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/platform"
+)
+
+// FileName is the ignore file's name in lsrv's config directory
+const FileName = "ignore"
+
+// List is a set of gitignore-style directory patterns. A nil *List ignores
+// nothing.
+type List struct {
+	rules []rule
+}
+
+// rule is one pattern line; later rules override earlier ones
+type rule struct {
+	pattern *regexp.Regexp
+	negate  bool
+}
+
+// Path returns the location of the global ignore file
+func Path() (string, error) {
+	dir, err := platform.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Load reads the global ignore file; a missing file ignores nothing
+func Load() (*List, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	home, _ := os.UserHomeDir()
+	list := &List{}
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r, err := parseRule(line, home)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		list.rules = append(list.rules, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return list, nil
+}
+
+// parseRule compiles a pattern line. Patterns with a slash, other than a
+// trailing one, are absolute paths (after expanding "~"); others match a
+// directory of that name anywhere, like in a .gitignore.
+func parseRule(line, home string) (rule, error) {
+	var r rule
+	if rest, ok := strings.CutPrefix(line, "!"); ok {
+		r.negate = true
+		line = rest
+	}
+
+	pattern := strings.TrimSuffix(line, "/")
+	if rest, ok := strings.CutPrefix(pattern, "~"); ok && (rest == "" || rest[0] == '/') {
+		if home == "" {
+			return rule{}, fmt.Errorf("can't expand %q without a home directory", line)
+		}
+		pattern = home + rest
+	}
+	if pattern == "" {
+		return rule{}, fmt.Errorf("empty pattern %q", line)
+	}
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	} else if !strings.HasPrefix(pattern, "/") && !strings.HasPrefix(pattern, "**/") {
+		return rule{}, fmt.Errorf("pattern %q must be absolute, start with ~ or have no slash", line)
+	}
+
+	re, err := regexp.Compile(globRegexp(pattern))
+	if err != nil {
+		return rule{}, fmt.Errorf("invalid pattern %q: %w", line, err)
+	}
+	r.pattern = re
+	return r, nil
+}
+
+// globRegexp translates a glob with "**" (any number of directories), "*"
+// and "?" (within one directory) into an anchored regular expression
+func globRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// Match reports whether dir, or a directory above it, is ignored. Like in
+// git, a later "!" pattern re-includes what an earlier one ignored.
+func (l *List) Match(dir string) bool {
+	if l == nil || dir == "" {
+		return false
+	}
+
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	ignored := false
+	for _, r := range l.rules {
+		if r.negate == ignored && matchesOrAbove(r.pattern, dir) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// matchesOrAbove reports whether pattern matches dir or one of its parents
+func matchesOrAbove(pattern *regexp.Regexp, dir string) bool {
+	for current := dir; ; {
+		if pattern.MatchString(current) {
+			return true
+		}
+		i := strings.LastIndexByte(current, '/')
+		if i <= 0 {
+			return false
+		}
+		current = current[:i]
+	}
+}
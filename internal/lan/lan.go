@@ -0,0 +1,110 @@
+package lan
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// Resolver builds URLs that reach local servers from other devices on the
+// network, such as a phone used for mobile testing
+type Resolver struct {
+	// host is the machine's primary IP address or mDNS name
+	host string
+}
+
+// NewResolver looks up the machine's primary interface address, or its
+// HOSTNAME.local mDNS name when mdns is set
+func NewResolver(mdns bool) (*Resolver, error) {
+	if mdns {
+		name, err := MDNSName()
+		if err != nil {
+			return nil, err
+		}
+		return &Resolver{host: name}, nil
+	}
+
+	ip, err := PrimaryIP()
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{host: hostPort(ip, 0)}, nil
+}
+
+// URL returns the LAN URL of server, or "" when it only listens on loopback.
+// Servers bound to one specific address are reached through that address.
+func (r *Resolver) URL(server types.Server) string {
+	if server.Bind == "*" {
+		return fmt.Sprintf("http://%s:%d", r.host, server.Port)
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(server.Bind, "[]"))
+	switch {
+	case err != nil || addr.IsLoopback():
+		return ""
+	case addr.IsUnspecified():
+		return fmt.Sprintf("http://%s:%d", r.host, server.Port)
+	}
+	return "http://" + hostPort(addr, server.Port)
+}
+
+// PrimaryIP returns the address of the interface holding the default route.
+// No packets are sent: connecting a UDP socket only selects a route.
+func PrimaryIP() (netip.Addr, error) {
+	conn, err := net.Dial("udp", "192.0.2.1:9")
+	if err == nil {
+		defer conn.Close()
+		if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && !addr.IP.IsLoopback() {
+			if ip, ok := netip.AddrFromSlice(addr.IP); ok {
+				return ip.Unmap(), nil
+			}
+		}
+	}
+
+	// Without a default route, take the first private IPv4 address
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to list interface addresses: %w", err)
+	}
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip, ok := netip.AddrFromSlice(ipnet.IP); ok && ip.Unmap().Is4() && ip.IsPrivate() {
+			return ip.Unmap(), nil
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("no network interface with a LAN address")
+}
+
+// MDNSName returns the machine's multicast DNS name (e.g., "mbp.local"),
+// which macOS and Linux with Avahi answer on the local network
+func MDNSName() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to read hostname: %w", err)
+	}
+	name, _, _ := strings.Cut(hostname, ".")
+	if name == "" {
+		return "", fmt.Errorf("empty hostname")
+	}
+	return strings.ToLower(name) + ".local", nil
+}
+
+// hostPort formats addr for use in a URL, with brackets around IPv6
+// addresses and the port omitted when zero
+func hostPort(addr netip.Addr, port int) string {
+	if port == 0 {
+		if addr.Is6() {
+			return "[" + addr.String() + "]"
+		}
+		return addr.String()
+	}
+	return net.JoinHostPort(addr.String(), strconv.Itoa(port))
+}
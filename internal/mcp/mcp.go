@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// protocolVersion is the MCP revision this server implements
+const protocolVersion = "2024-11-05"
+
+// JSON-RPC 2.0 error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// FindFunc returns the currently running servers
+type FindFunc func() ([]types.Server, error)
+
+// request is an incoming JSON-RPC message; ID is absent for notifications
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Server answers MCP requests using the same detection and control engine
+// as the CLI
+type Server struct {
+	find    FindFunc
+	version string
+}
+
+// NewServer returns an MCP server reporting the given lsrv version
+func NewServer(find FindFunc, version string) *Server {
+	return &Server{find: find, version: version}
+}
+
+// Serve reads newline-delimited JSON-RPC messages from r and writes
+// responses to w until r is exhausted
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	enc := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			resp := response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, err.Error()}}
+			if err := enc.Encode(resp); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, rpcErr := s.handle(req)
+
+		// Notifications never get a response
+		if len(req.ID) == 0 {
+			continue
+		}
+
+		resp := response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// handle dispatches a single request by method name
+func (s *Server) handle(req request) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": protocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "lsrv", "version": s.version},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": toolDefinitions}, nil
+	case "tools/call":
+		var params struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{codeInvalidParams, err.Error()}
+		}
+		return s.callTool(params.Name, params.Arguments), nil
+	}
+
+	if strings.HasPrefix(req.Method, "notifications/") {
+		return nil, nil
+	}
+	return nil, &rpcError{codeMethodNotFound, fmt.Sprintf("method not found: %s", req.Method)}
+}
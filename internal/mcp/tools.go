@@ -0,0 +1,148 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/starthint"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// tool describes an MCP tool and its JSON Schema input
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// targetSchema is the input shared by tools acting on one server
+var targetSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"target": map[string]any{
+			"type":        "string",
+			"description": "Port number or repository name of the server",
+		},
+	},
+	"required": []string{"target"},
+}
+
+var toolDefinitions = []tool{
+	{
+		Name:        "list_servers",
+		Description: "List running local development servers with their repo, branch, process, PID, port and URL. Optionally filter by port or repo name.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"target": map[string]any{
+					"type":        "string",
+					"description": "Optional port number or repository name to filter by",
+				},
+			},
+		},
+	},
+	{
+		Name:        "kill_server",
+		Description: "Stop a running development server by sending it SIGTERM.",
+		InputSchema: targetSchema,
+	},
+	{
+		Name:        "restart_server",
+		Description: "Restart a running development server with the same command line in the same directory.",
+		InputSchema: targetSchema,
+	},
+	{
+		Name:        "open_server",
+		Description: "Open a running development server's URL in the default browser.",
+		InputSchema: targetSchema,
+	},
+}
+
+// toolResult is the MCP tools/call result payload
+type toolResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func textResult(format string, args ...any) toolResult {
+	return toolResult{Content: []textContent{{Type: "text", Text: fmt.Sprintf(format, args...)}}}
+}
+
+func errorResult(format string, args ...any) toolResult {
+	result := textResult(format, args...)
+	result.IsError = true
+	return result
+}
+
+// callTool runs a tool; failures are reported in the result, not as
+// protocol errors, so the model can see them
+func (s *Server) callTool(name string, args map[string]any) toolResult {
+	servers, err := s.find()
+	if err != nil {
+		return errorResult("finding servers: %v", err)
+	}
+
+	// Clients may send ports as numbers or strings
+	var target string
+	if value, ok := args["target"]; ok && value != nil {
+		target = strings.TrimSpace(fmt.Sprint(value))
+	}
+	if target != "" {
+		servers = control.Match(servers, target)
+	}
+
+	switch name {
+	case "list_servers":
+		if servers == nil {
+			servers = []types.Server{}
+		}
+		data, err := json.MarshalIndent(servers, "", "  ")
+		if err != nil {
+			return errorResult("encoding servers: %v", err)
+		}
+		return textResult("%s", data)
+	case "kill_server", "restart_server", "open_server":
+	default:
+		return errorResult("unknown tool: %s", name)
+	}
+
+	if target == "" {
+		return errorResult("target is required")
+	}
+	if len(servers) == 0 {
+		if hint, ok := starthint.For(target); ok && name != "kill_server" {
+			return errorResult("no server matches %q; not running, start with: %s", target, hint.String())
+		}
+		return errorResult("no server matches %q", target)
+	}
+
+	var lines []string
+	for _, server := range servers {
+		switch name {
+		case "kill_server":
+			if err := control.Kill(server); err != nil {
+				return errorResult("killing %s on port %d: %v", server.Repo, server.Port, err)
+			}
+			lines = append(lines, fmt.Sprintf("Stopped %s (pid %d) on port %d", server.Repo, server.PID, server.Port))
+		case "restart_server":
+			run, err := control.Restart(server)
+			if err != nil {
+				return errorResult("restarting %s on port %d: %v", server.Repo, server.Port, err)
+			}
+			lines = append(lines, fmt.Sprintf("Restarted %s as pid %d, output in %s", server.Repo, run.PID, run.LogPath))
+		case "open_server":
+			if err := control.Open(server); err != nil {
+				return errorResult("opening %s: %v", server.DisplayURL(), err)
+			}
+			lines = append(lines, fmt.Sprintf("Opened %s", server.DisplayURL()))
+		}
+	}
+	return textResult("%s", strings.Join(lines, "\n"))
+}
@@ -3,13 +3,17 @@ package platform
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"os/user"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
 )
 
-// IsMacOS checks if the current operating system is macOS
+// IsMacOS checks if lsrv was built for macOS
 func IsMacOS() bool {
-	_, err := os.Stat("/System/Library/CoreServices/Finder.app")
-	return err == nil
+	return runtime.GOOS == "darwin"
 }
 
 // FileExists checks if a file or directory exists
@@ -53,3 +57,138 @@ func ValidatePID(pid int) error {
 	}
 	return nil
 }
+
+// ConfigDir returns the directory for lsrv's configuration, following the
+// XDG base directory spec ($XDG_CONFIG_HOME/lsrv, default ~/.config/lsrv)
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, "lsrv"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "lsrv"), nil
+}
+
+// StateDir returns the directory for lsrv's persistent state, following the
+// XDG base directory spec ($XDG_STATE_HOME/lsrv, default ~/.local/state/lsrv)
+func StateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, "lsrv"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "lsrv"), nil
+}
+
+// System is the OS-specific part of lsrv besides process inspection (see
+// package procinfo): socket enumeration and desktop integration. Each supported OS provides its own
+// implementation in a build-tagged platform_<goos>.go file.
+type System interface {
+	// ListeningSockets returns all listening TCP sockets on the machine,
+	// including those of processes the current user can't inspect
+	ListeningSockets() ([]Socket, error)
+
+	// OpenCommand returns a command that opens target in the default
+	// browser or application
+	OpenCommand(target string) *exec.Cmd
+
+	// CopyCommand returns a command that copies its stdin to the clipboard
+	CopyCommand() *exec.Cmd
+
+	// ShellCommand returns a command running line through the user's shell
+	ShellCommand(line string) *exec.Cmd
+}
+
+// Current is the implementation for the OS lsrv was built for
+var Current System = system{}
+
+// Socket is a listening TCP socket as reported by the kernel, independent of
+// whether its owning process is visible to the current user
+type Socket struct {
+	Port int
+
+	// UID owns the socket; -1 when unknown (macOS, Windows)
+	UID int
+
+	// Inode identifies the socket on Linux; empty elsewhere
+	Inode string
+
+	// RxQueue is the current accept queue length on Linux: connections
+	// the kernel completed that the server hasn't accepted yet
+	RxQueue int
+
+	// Backlog is the accept queue's limit from listen(2) on Linux, read
+	// from ss; 0 when unknown
+	Backlog int
+}
+
+// ListeningSockets returns all listening TCP sockets on the machine. Unlike
+// lsof, this includes sockets of processes the current user can't inspect.
+func ListeningSockets() ([]Socket, error) {
+	return Current.ListeningSockets()
+}
+
+// OpenCommand returns a command that opens target with the desktop's default
+// handler
+func OpenCommand(target string) *exec.Cmd {
+	return Current.OpenCommand(target)
+}
+
+// CopyCommand returns a command that copies its stdin to the clipboard
+func CopyCommand() *exec.Cmd {
+	return Current.CopyCommand()
+}
+
+// ShellCommand returns a command running line through the user's shell, so
+// configured commands can use pipes, variables and aliases-free shell syntax
+func ShellCommand(line string) *exec.Cmd {
+	return Current.ShellCommand(line)
+}
+
+// InvokingUID returns the UID of the user running lsrv, looking through sudo
+// so "sudo lsrv" still treats the original user as the owner
+func InvokingUID() int {
+	if os.Geteuid() == 0 {
+		if uid, err := strconv.Atoi(os.Getenv("SUDO_UID")); err == nil {
+			return uid
+		}
+	}
+	return os.Getuid()
+}
+
+// IsRoot reports whether lsrv is running with root privileges
+func IsRoot() bool {
+	return os.Geteuid() == 0
+}
+
+var (
+	userNamesMu sync.Mutex
+	userNames   = make(map[int]string)
+)
+
+// UserName resolves a UID to a login name, falling back to the numeric UID
+func UserName(uid int) string {
+	if uid < 0 {
+		return "?"
+	}
+
+	userNamesMu.Lock()
+	defer userNamesMu.Unlock()
+
+	if name, ok := userNames[uid]; ok {
+		return name
+	}
+
+	name := strconv.Itoa(uid)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+	userNames[uid] = name
+	return name
+}
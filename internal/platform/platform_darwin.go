@@ -0,0 +1,55 @@
+//go:build darwin
+
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// system asks netstat, as macOS has no /proc
+type system struct{}
+
+// ListeningSockets asks netstat, which reports sockets of all users without
+// needing root but not their owners
+func (system) ListeningSockets() ([]Socket, error) {
+	output, err := exec.Command("netstat", "-an", "-p", "tcp").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run netstat: %w", err)
+	}
+
+	var sockets []Socket
+	for _, line := range strings.Split(string(output), "\n") {
+		// tcp4  0  0  *.3000  *.*  LISTEN
+		fields := strings.Fields(line)
+		if len(fields) < 6 || fields[len(fields)-1] != "LISTEN" {
+			continue
+		}
+
+		local := fields[3]
+		dot := strings.LastIndexByte(local, '.')
+		if dot < 0 {
+			continue
+		}
+		port, err := strconv.Atoi(local[dot+1:])
+		if err != nil {
+			continue
+		}
+		sockets = append(sockets, Socket{Port: port, UID: -1})
+	}
+	return sockets, nil
+}
+
+func (system) OpenCommand(target string) *exec.Cmd {
+	return exec.Command("open", target)
+}
+
+func (system) ShellCommand(line string) *exec.Cmd {
+	return exec.Command(userShell(), "-c", line)
+}
+
+func (system) CopyCommand() *exec.Cmd {
+	return exec.Command("pbcopy")
+}
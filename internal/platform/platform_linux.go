@@ -0,0 +1,126 @@
+//go:build linux
+
+package platform
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// tcpListen is the kernel's hex code for the LISTEN state in /proc/net/tcp
+const tcpListen = "0A"
+
+// system reads socket details from /proc
+type system struct{}
+
+// ListeningSockets parses /proc/net/tcp and /proc/net/tcp6
+func (system) ListeningSockets() ([]Socket, error) {
+	var sockets []Socket
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		found, err := readProcNet(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		sockets = append(sockets, found...)
+	}
+
+	// /proc/net/tcp shows how full each accept queue is but not its size
+	backlogs := listenBacklogs()
+	for i := range sockets {
+		sockets[i].Backlog = backlogs[sockets[i].Inode]
+	}
+	return sockets, nil
+}
+
+// listenBacklogs asks ss for the accept queue limit of each listening
+// socket, by inode; nil when ss isn't installed
+func listenBacklogs() map[string]int {
+	output, err := exec.Command("ss", "-ltnHe").Output()
+	if err != nil {
+		return nil
+	}
+
+	backlogs := make(map[string]int)
+	for _, line := range strings.Split(string(output), "\n") {
+		// LISTEN 0 511 0.0.0.0:3000 0.0.0.0:* uid:1000 ino:52817 sk:1 <->
+		fields := strings.Fields(line)
+		if len(fields) < 6 || fields[0] != "LISTEN" {
+			continue
+		}
+		limit, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		for _, field := range fields[5:] {
+			if inode, ok := strings.CutPrefix(field, "ino:"); ok {
+				backlogs[inode] = limit
+			}
+		}
+	}
+	return backlogs
+}
+
+// OpenCommand uses xdg-open
+func (system) OpenCommand(target string) *exec.Cmd {
+	return exec.Command("xdg-open", target)
+}
+
+// ShellCommand runs line with $SHELL -c
+func (system) ShellCommand(line string) *exec.Cmd {
+	return exec.Command(userShell(), "-c", line)
+}
+
+// CopyCommand uses wl-copy under Wayland and xclip otherwise
+func (system) CopyCommand() *exec.Cmd {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return exec.Command("wl-copy")
+	}
+	return exec.Command("xclip", "-selection", "clipboard")
+}
+
+// readProcNet parses listening sockets from /proc/net/tcp{,6}
+func readProcNet(path string) ([]Socket, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sockets []Socket
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListen {
+			continue
+		}
+
+		_, portHex, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		port, err := strconv.ParseInt(portHex, 16, 32)
+		if err != nil {
+			continue
+		}
+
+		sock := Socket{Port: int(port), UID: -1, Inode: fields[9]}
+		if uid, err := strconv.Atoi(fields[7]); err == nil {
+			sock.UID = uid
+		}
+		if _, rx, ok := strings.Cut(fields[4], ":"); ok {
+			rxQueue, _ := strconv.ParseInt(rx, 16, 64)
+			sock.RxQueue = int(rxQueue)
+		}
+		sockets = append(sockets, sock)
+	}
+	return sockets, scanner.Err()
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package platform
+
+import (
+	"os"
+)
+
+// userShell returns $SHELL, falling back to /bin/sh
+func userShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "/bin/sh"
+}
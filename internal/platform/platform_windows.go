@@ -0,0 +1,55 @@
+//go:build windows
+
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// system covers what Windows exposes without extra tooling: sockets via
+// netstat, plus opener and clipboard
+type system struct{}
+
+// ListeningSockets parses "netstat -ano -p tcp" lines such as
+// "TCP    0.0.0.0:3000    0.0.0.0:0    LISTENING    1234"
+func (system) ListeningSockets() ([]Socket, error) {
+	output, err := exec.Command("netstat", "-ano", "-p", "tcp").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run netstat: %w", err)
+	}
+
+	var sockets []Socket
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[3] != "LISTENING" {
+			continue
+		}
+
+		local := fields[1]
+		colon := strings.LastIndexByte(local, ':')
+		if colon < 0 {
+			continue
+		}
+		port, err := strconv.Atoi(local[colon+1:])
+		if err != nil {
+			continue
+		}
+		sockets = append(sockets, Socket{Port: port, UID: -1})
+	}
+	return sockets, nil
+}
+
+func (system) OpenCommand(target string) *exec.Cmd {
+	return exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+}
+
+func (system) ShellCommand(line string) *exec.Cmd {
+	return exec.Command("cmd", "/C", line)
+}
+
+func (system) CopyCommand() *exec.Cmd {
+	return exec.Command("clip")
+}
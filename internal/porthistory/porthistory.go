@@ -0,0 +1,250 @@
+// Package porthistory remembers which ports each repository's servers have
+// listened on, to tell a repo's usual port from today's accident
+package porthistory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/atomicfile"
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// minDays is how many days of sightings a repo needs before any port is
+// called unusual
+const minDays = 3
+
+// Port is one port a repository has been seen on
+type Port struct {
+	Port      int       `json:"port"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+
+	// Days counts the distinct days the port was seen, so running lsrv
+	// often doesn't outweigh running it on many days
+	Days int `json:"days"`
+}
+
+// History maps repository names to the ports they were seen on
+type History struct {
+	Repos map[string][]Port `json:"repos"`
+
+	// Dirs maps repository names to the directory their server last ran
+	// in, so lsrv can tell where to start one that isn't running
+	Dirs map[string]string `json:"dirs,omitempty"`
+
+	path string
+
+	// recorded holds the sightings Record changed the history with, for
+	// Save to apply again to the file as another lsrv left it
+	recorded []sighting
+}
+
+// sighting is one Record call
+type sighting struct {
+	servers []types.Server
+	now     time.Time
+}
+
+// path returns the file holding the history
+func path() (string, error) {
+	state, err := platform.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "ports.json"), nil
+}
+
+// Load reads the history, starting an empty one if none was saved yet
+func Load() (*History, error) {
+	file, err := path()
+	if err != nil {
+		return nil, err
+	}
+	return load(file)
+}
+
+// load reads the history saved in file
+func load(file string) (*History, error) {
+	h := &History{Repos: make(map[string][]Port), Dirs: make(map[string]string), path: file}
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if h.Repos == nil {
+		h.Repos = make(map[string][]Port)
+	}
+	if h.Dirs == nil {
+		h.Dirs = make(map[string]string)
+	}
+	return h, nil
+}
+
+// Record notes the ports app servers are listening on at now
+func (h *History) Record(servers []types.Server, now time.Time) {
+	if h.record(servers, now) {
+		h.recorded = append(h.recorded, sighting{servers: servers, now: now})
+	}
+}
+
+// record notes the sighting in the history, reporting whether it changed
+func (h *History) record(servers []types.Server, now time.Time) bool {
+	changed := false
+	for _, server := range servers {
+		if server.Service != "" || server.Repo == "" {
+			continue
+		}
+		if server.CWD != "" && h.Dirs[server.Repo] != server.CWD {
+			h.Dirs[server.Repo] = server.CWD
+			changed = true
+		}
+		ports := h.Repos[server.Repo]
+		i := sort.Search(len(ports), func(i int) bool { return ports[i].Port >= server.Port })
+		if i == len(ports) || ports[i].Port != server.Port {
+			ports = append(ports, Port{})
+			copy(ports[i+1:], ports[i:])
+			ports[i] = Port{Port: server.Port, FirstSeen: now, LastSeen: now, Days: 1}
+			h.Repos[server.Repo] = ports
+			changed = true
+			continue
+		}
+		if !sameDay(ports[i].LastSeen, now) {
+			ports[i].Days++
+			ports[i].LastSeen = now
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Save writes the history if Record changed it. Other lsrv commands may
+// have saved theirs since Load, so the sightings are applied again to the
+// file as it is now rather than overwriting it with the copy loaded.
+func (h *History) Save() error {
+	if len(h.recorded) == 0 {
+		return nil
+	}
+	if latest, err := load(h.path); err == nil {
+		for _, seen := range h.recorded {
+			latest.record(seen.servers, seen.now)
+		}
+		h.Repos, h.Dirs = latest.Repos, latest.Dirs
+	}
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return err
+	}
+	if err := atomicfile.WriteFile(h.path, data, 0o644); err != nil {
+		return err
+	}
+	h.recorded = nil
+	return nil
+}
+
+// Dir returns the directory repo's server last ran in, if lsrv saw one
+func (h *History) Dir(repo string) (string, bool) {
+	for name, dir := range h.Dirs {
+		if strings.EqualFold(name, repo) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// Ports returns the ports a repo was seen on, most used first
+func (h *History) Ports(repo string) []Port {
+	ports := append([]Port(nil), h.Repos[repo]...)
+	sort.SliceStable(ports, func(i, j int) bool {
+		if ports[i].Days != ports[j].Days {
+			return ports[i].Days > ports[j].Days
+		}
+		return ports[i].LastSeen.After(ports[j].LastSeen)
+	})
+	return ports
+}
+
+// Sighting is a repository seen on a given port
+type Sighting struct {
+	Repo string
+	Port
+}
+
+// Seen returns the repos seen on port, most recently seen first
+func (h *History) Seen(port int) []Sighting {
+	var seen []Sighting
+	for repo, ports := range h.Repos {
+		for _, p := range ports {
+			if p.Port == port {
+				seen = append(seen, Sighting{Repo: repo, Port: p})
+			}
+		}
+	}
+	sort.Slice(seen, func(i, j int) bool {
+		if !seen[i].LastSeen.Equal(seen[j].LastSeen) {
+			return seen[i].LastSeen.After(seen[j].LastSeen)
+		}
+		return seen[i].Repo < seen[j].Repo
+	})
+	return seen
+}
+
+// Usual returns the ports a repo runs on at least half as often as its
+// most used one. A repo running several servers has several usual ports.
+func (h *History) Usual(repo string) []int {
+	ports := h.Ports(repo)
+	if len(ports) == 0 {
+		return nil
+	}
+	var usual []int
+	for _, p := range ports {
+		if p.Days*2 >= ports[0].Days {
+			usual = append(usual, p.Port)
+		}
+	}
+	sort.Ints(usual)
+	return usual
+}
+
+// Unusual reports whether port is not one of repo's usual ports, once the
+// repo has been seen on enough days to tell
+func (h *History) Unusual(repo string, port int) bool {
+	days := 0
+	for _, p := range h.Repos[repo] {
+		days = max(days, p.Days)
+	}
+	if days < minDays {
+		return false
+	}
+	for _, usual := range h.Usual(repo) {
+		if usual == port {
+			return false
+		}
+	}
+	return true
+}
+
+// sameDay reports whether a and b fall on the same local calendar day
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Local().Date()
+	by, bm, bd := b.Local().Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,53 @@
+package porthistory
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+func TestSaveKeepsConcurrentSightings(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ports.json")
+	now := time.Now()
+
+	// Two commands load the history before either saves
+	first, err := load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.Record([]types.Server{{Repo: "web", Port: 3000, CWD: "/src/web"}}, now)
+	second.Record([]types.Server{{Repo: "api", Port: 8080, CWD: "/src/api"}}, now)
+	if err := first.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var repos []string
+	for repo := range saved.Repos {
+		repos = append(repos, repo)
+	}
+	slices.Sort(repos)
+	if want := []string{"api", "web"}; !slices.Equal(repos, want) {
+		t.Errorf("saved repos %v, want %v", repos, want)
+	}
+	if dir, _ := saved.Dir("web"); dir != "/src/web" {
+		t.Errorf("web dir %q, want /src/web", dir)
+	}
+	if dir, _ := saved.Dir("api"); dir != "/src/api" {
+		t.Errorf("api dir %q, want /src/api", dir)
+	}
+}
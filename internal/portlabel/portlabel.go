@@ -0,0 +1,200 @@
+// Package portlabel names ports after the services a repository declares
+// for them in docker-compose.yml, a Procfile or .lsrv.yml, such as "db"
+// for 5432 or "web" for 3000
+package portlabel
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/git"
+	"gopkg.in/yaml.v3"
+)
+
+// composeFiles are the Compose file names docker compose looks for, in
+// its order of preference
+var composeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yml", "docker-compose.yaml"}
+
+// procfiles are read in order; Procfile.dev is what Rails' bin/dev runs
+var procfiles = []string{"Procfile", "Procfile.dev"}
+
+// Labels maps ports to service names
+type Labels map[int]string
+
+// Find collects the labels declared in dir and its parents up to the top
+// of the work tree, so a Procfile in an app directory and a Compose file
+// at the monorepo root both apply. Nearer declarations win, and within a
+// directory .lsrv.yml beats a Procfile, which beats Compose. Unreadable
+// files are skipped.
+func Find(dir string) Labels {
+	dirs := []string{dir}
+	if root, ok := git.WorkTreeRoot(dir); ok {
+		for current := dir; current != root; {
+			parent := filepath.Dir(current)
+			if parent == current {
+				break
+			}
+			dirs = append(dirs, parent)
+			current = parent
+		}
+	}
+
+	labels := make(Labels)
+	for _, dir := range dirs {
+		if cfg, err := config.LoadRepo(dir); err == nil {
+			labels.add(cfg.Ports)
+		}
+		for _, name := range procfiles {
+			labels.add(readProcfile(filepath.Join(dir, name)))
+		}
+		for _, name := range composeFiles {
+			if found := readCompose(filepath.Join(dir, name)); found != nil {
+				labels.add(found)
+				break
+			}
+		}
+	}
+	return labels
+}
+
+// add copies the labels of ports not labeled yet
+func (l Labels) add(found map[int]string) {
+	for port, name := range found {
+		if _, ok := l[port]; !ok {
+			l[port] = name
+		}
+	}
+}
+
+// compose is the part of a Compose file naming services and their ports
+type compose struct {
+	Services map[string]struct {
+		Ports []yaml.Node `yaml:"ports"`
+	} `yaml:"services"`
+}
+
+// readCompose labels the host ports each service publishes, returning nil
+// when the file is missing or invalid
+func readCompose(path string) Labels {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var file compose
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil
+	}
+
+	labels := make(Labels)
+	for name, service := range file.Services {
+		for _, node := range service.Ports {
+			for _, port := range publishedPorts(&node) {
+				labels[port] = name
+			}
+		}
+	}
+	return labels
+}
+
+// publishedPorts returns the host ports of a Compose port entry, either
+// the short "[IP:]HOST:CONTAINER[/PROTOCOL]" form, with ranges like
+// "3000-3002:3000-3002", or the long form with a published field. Entries
+// without a host port get a random one and are skipped, as are UDP ports.
+func publishedPorts(node *yaml.Node) []int {
+	if node.Kind == yaml.MappingNode {
+		var long struct {
+			Published string `yaml:"published"`
+			Protocol  string `yaml:"protocol"`
+		}
+		if node.Decode(&long) != nil || long.Protocol == "udp" {
+			return nil
+		}
+		return portRange(expandVars(long.Published))
+	}
+
+	spec, protocol, _ := strings.Cut(expandVars(node.Value), "/")
+	if protocol == "udp" {
+		return nil
+	}
+	// An IPv6 host address is bracketed, e.g. "[::1]:5432:5432"
+	if i := strings.LastIndex(spec, "]:"); i >= 0 {
+		spec = spec[i+2:]
+	}
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 {
+		return nil
+	}
+	return portRange(parts[len(parts)-2])
+}
+
+// portRange parses "3000" or "3000-3002"
+func portRange(spec string) []int {
+	first, last, isRange := strings.Cut(spec, "-")
+	start, err := strconv.Atoi(first)
+	if err != nil {
+		return nil
+	}
+	end := start
+	if isRange {
+		if end, err = strconv.Atoi(last); err != nil || end < start {
+			return nil
+		}
+	}
+
+	var ports []int
+	for port := start; port <= end; port++ {
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// composeVar matches ${NAME}, ${NAME:-default} and ${NAME-default}
+var composeVar = regexp.MustCompile(`\$\{(\w+)(?::?-([^}]*))?\}`)
+
+// expandVars substitutes environment variables the way Compose does,
+// falling back to the default when one is given
+func expandVars(value string) string {
+	return composeVar.ReplaceAllStringFunc(value, func(ref string) string {
+		match := composeVar.FindStringSubmatch(ref)
+		if env, ok := os.LookupEnv(match[1]); ok && env != "" {
+			return env
+		}
+		return match[2]
+	})
+}
+
+// procfilePort finds a port on a Procfile command line: a -p or --port
+// option, a PORT variable, or a bind address like -b 0.0.0.0:3000
+var procfilePort = regexp.MustCompile(`(?:^|\s)(?:(?:-p|--port)[ =]|PORT=|(?:-b|--bind)[ =]\S*:)(\d+)\b`)
+
+// readProcfile labels the ports set on the command lines of a Procfile's
+// process types, returning nil when the file is missing
+func readProcfile(path string) Labels {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	labels := make(Labels)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		name, command, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if match := procfilePort.FindStringSubmatch(command); match != nil {
+			if port, err := strconv.Atoi(match[1]); err == nil {
+				labels[port] = strings.TrimSpace(name)
+			}
+		}
+	}
+	return labels
+}
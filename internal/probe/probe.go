@@ -0,0 +1,143 @@
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Protocols reported by Detect
+const (
+	ProtocolHTTP      = "http"
+	ProtocolGRPC      = "grpc"
+	ProtocolWebSocket = "websocket"
+	ProtocolTCP       = "tcp"
+)
+
+// DefaultTimeout bounds each connection attempt and read
+const DefaultTimeout = 500 * time.Millisecond
+
+// http2Preface is the HTTP/2 client connection preface followed by an empty
+// SETTINGS frame, used to detect h2c servers with prior knowledge (gRPC)
+var http2Preface = append([]byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"), 0, 0, 0, 0x4, 0, 0, 0, 0, 0)
+
+// http2FrameSettings is the HTTP/2 SETTINGS frame type
+const http2FrameSettings = 0x4
+
+// Detect identifies the protocol spoken on a local port: plain HTTP,
+// websocket-only HTTP endpoints, gRPC (HTTP/2 prior knowledge) or, failing
+// those, raw TCP
+func Detect(port int, timeout time.Duration) string {
+	if protocol, ok := probeHTTP1(port, timeout); ok {
+		return protocol
+	}
+	if probeHTTP2(port, timeout) {
+		return ProtocolGRPC
+	}
+	return ProtocolTCP
+}
+
+// DetectAll probes ports concurrently and returns the protocol per port.
+// Probing stops early when ctx is cancelled.
+func DetectAll(ctx context.Context, ports []int, timeout time.Duration) map[int]string {
+	results := make(map[int]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, port := range ports {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			if ctx.Err() != nil {
+				return
+			}
+			protocol := Detect(p, timeout)
+			mu.Lock()
+			results[p] = protocol
+			mu.Unlock()
+		}(port)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// probeHTTP1 sends an HTTP/1.1 request and classifies the response
+func probeHTTP1(port int, timeout time.Duration) (string, bool) {
+	conn, err := dial(port, timeout)
+	if err != nil {
+		return "", false
+	}
+	defer conn.Close()
+
+	request := "GET / HTTP/1.1\r\nHost: localhost\r\nUser-Agent: lsrv\r\nConnection: close\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return "", false
+	}
+
+	reader := bufio.NewReader(conn)
+	statusLine, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(statusLine, "HTTP/") {
+		return "", false
+	}
+
+	// 426 Upgrade Required, or a 400 that mentions websockets, means the
+	// endpoint only accepts websocket upgrades
+	fields := strings.Fields(statusLine)
+	if len(fields) >= 2 && (fields[1] == "426" || fields[1] == "400") {
+		rest := make([]byte, 2048)
+		n, _ := reader.Read(rest)
+		if fields[1] == "426" || bytes.Contains(bytes.ToLower(rest[:n]), []byte("websocket")) {
+			return ProtocolWebSocket, true
+		}
+	}
+	return ProtocolHTTP, true
+}
+
+// probeHTTP2 sends the HTTP/2 preface and reports whether the server answers
+// with a SETTINGS frame
+func probeHTTP2(port int, timeout time.Duration) bool {
+	conn, err := dial(port, timeout)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(http2Preface); err != nil {
+		return false
+	}
+
+	// Frame header: 24-bit length, 8-bit type, 8-bit flags, 32-bit stream
+	header := make([]byte, 9)
+	if _, err := readFull(conn, header); err != nil {
+		return false
+	}
+	return header[3] == http2FrameSettings
+}
+
+// dial connects to a local port with reads and writes bounded by timeout
+func dial(port int, timeout time.Duration) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", port), timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	return conn, nil
+}
+
+func readFull(conn net.Conn, buf []byte) (int, error) {
+	read := 0
+	for read < len(buf) {
+		n, err := conn.Read(buf[read:])
+		read += n
+		if err != nil {
+			return read, err
+		}
+	}
+	return read, nil
+}
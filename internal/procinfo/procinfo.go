@@ -0,0 +1,172 @@
+// Package procinfo reads details of running processes: working directory,
+// command line, executable, parent, owner, start time, environment and
+// resource usage.
+// Linux reads /proc and macOS asks the kernel through sysctl, so callers
+// share one code path instead of running ps or lsof per detail.
+package procinfo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/platform"
+)
+
+// Inspector reads process details. Each supported OS provides its own
+// implementation in a build-tagged procinfo_<goos>.go file.
+type Inspector interface {
+	// CWDs returns the working directory of each PID it can resolve, and
+	// the PIDs found to have exited since they were listed
+	CWDs(ctx context.Context, pids []int) (map[int]string, []int)
+
+	// CommandLine returns the argument vector a process was started with
+	CommandLine(ctx context.Context, pid int) ([]string, error)
+
+	// Executable returns the path of the binary a process runs
+	Executable(ctx context.Context, pid int) (string, error)
+
+	// Parent returns the parent PID and short command name of a process
+	Parent(ctx context.Context, pid int) (int, string, error)
+
+	// TTY returns the controlling terminal of a process, or ""
+	TTY(ctx context.Context, pid int) string
+
+	// Environ returns the environment a process was started with
+	Environ(ctx context.Context, pid int) (map[string]string, error)
+
+	// StartTime returns when a process started
+	StartTime(ctx context.Context, pid int) (time.Time, error)
+
+	// UID returns the user owning a process
+	UID(ctx context.Context, pid int) (int, error)
+
+	// AllUsage returns the resource usage of every process, by PID
+	AllUsage(ctx context.Context) (map[int]Usage, error)
+}
+
+// Usage is a process's parent and the resources it has used so far
+type Usage struct {
+	PPID int
+
+	// CPU is the user and system time the process has run for
+	CPU time.Duration
+
+	// RSS is the resident memory in bytes
+	RSS uint64
+}
+
+// Current is the implementation for the OS lsrv was built for
+var Current Inspector = inspector{}
+
+// CWDs returns the working directory of each PID it can resolve, and the
+// PIDs found to have exited since they were listed
+func CWDs(ctx context.Context, pids []int) (map[int]string, []int) {
+	if len(pids) == 0 {
+		return map[int]string{}, nil
+	}
+	return Current.CWDs(ctx, pids)
+}
+
+// CommandLine returns the argument vector a process was started with
+func CommandLine(ctx context.Context, pid int) ([]string, error) {
+	if err := platform.ValidatePID(pid); err != nil {
+		return nil, err
+	}
+	return Current.CommandLine(ctx, pid)
+}
+
+// Executable returns the path of the binary a process runs
+func Executable(ctx context.Context, pid int) (string, error) {
+	if err := platform.ValidatePID(pid); err != nil {
+		return "", err
+	}
+	return Current.Executable(ctx, pid)
+}
+
+// Parent returns the parent PID and short command name of a process
+func Parent(ctx context.Context, pid int) (int, string, error) {
+	if err := platform.ValidatePID(pid); err != nil {
+		return 0, "", err
+	}
+	return Current.Parent(ctx, pid)
+}
+
+// TTY returns the controlling terminal of a process (e.g., "ttys003" or
+// "pts/2"), or "" if it has none
+func TTY(ctx context.Context, pid int) string {
+	if platform.ValidatePID(pid) != nil {
+		return ""
+	}
+	return Current.TTY(ctx, pid)
+}
+
+// Environ returns the environment a process was started with. Only
+// processes of the current user (or any, as root) can be read.
+func Environ(ctx context.Context, pid int) (map[string]string, error) {
+	if err := platform.ValidatePID(pid); err != nil {
+		return nil, err
+	}
+	return Current.Environ(ctx, pid)
+}
+
+// StartTime returns when a process started
+func StartTime(ctx context.Context, pid int) (time.Time, error) {
+	if err := platform.ValidatePID(pid); err != nil {
+		return time.Time{}, err
+	}
+	return Current.StartTime(ctx, pid)
+}
+
+// UID returns the user owning a process
+func UID(ctx context.Context, pid int) (int, error) {
+	if err := platform.ValidatePID(pid); err != nil {
+		return 0, err
+	}
+	return Current.UID(ctx, pid)
+}
+
+// AllUsage returns the resource usage of every process, read in one pass
+// so callers sampling it periodically stay cheap
+func AllUsage(ctx context.Context) (map[int]Usage, error) {
+	return Current.AllUsage(ctx)
+}
+
+// TreeUsage adds up the usage of pid and all its descendants, so a server
+// counts the workers, bundlers and compilers it started
+func TreeUsage(all map[int]Usage, pid int) Usage {
+	children := make(map[int][]int)
+	for child, usage := range all {
+		children[usage.PPID] = append(children[usage.PPID], child)
+	}
+
+	total := Usage{PPID: all[pid].PPID}
+	queue := []int{pid}
+	seen := map[int]bool{pid: true}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		usage := all[current]
+		total.CPU += usage.CPU
+		total.RSS += usage.RSS
+		for _, child := range children[current] {
+			if !seen[child] {
+				seen[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	return total
+}
+
+// parseEnviron turns KEY=VALUE entries into a map, skipping entries
+// without "="
+func parseEnviron(entries []string) map[string]string {
+	env := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if key, value, ok := strings.Cut(entry, "="); ok && key != "" {
+			env[key] = value
+		}
+	}
+	return env
+}
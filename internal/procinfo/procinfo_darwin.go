@@ -0,0 +1,280 @@
+//go:build darwin
+
+package procinfo
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/platform"
+	"golang.org/x/sys/unix"
+)
+
+// ttysMajor is the device major number of macOS pseudo terminals, named
+// /dev/ttysNNN
+const ttysMajor = 16
+
+// inspector asks the kernel through the kern.proc.pid and kern.procargs2
+// sysctls. Working directories are only exposed through libproc, which
+// needs cgo, so they come from lsof.
+type inspector struct{}
+
+// CWDs looks up all working directories in a single lsof call. lsof fails
+// the whole call when any PID has exited but still reports the rest, so a
+// failed batch is retried once with only the missing PIDs that are still
+// alive.
+func (inspector) CWDs(ctx context.Context, pids []int) (map[int]string, []int) {
+	valid := make([]int, 0, len(pids))
+	for _, pid := range pids {
+		if platform.ValidatePID(pid) == nil {
+			valid = append(valid, pid)
+		}
+	}
+
+	cwds, err := lsofCWDs(ctx, valid)
+	if err == nil {
+		return cwds, nil
+	}
+
+	var retry, gone []int
+	for _, pid := range valid {
+		if _, ok := cwds[pid]; ok {
+			continue
+		}
+		if exited(pid) {
+			gone = append(gone, pid)
+		} else {
+			retry = append(retry, pid)
+		}
+	}
+	if len(retry) > 0 {
+		found, _ := lsofCWDs(ctx, retry)
+		for pid, cwd := range found {
+			cwds[pid] = cwd
+		}
+	}
+	return cwds, gone
+}
+
+// CommandLine returns the exact arguments from kern.procargs2
+func (inspector) CommandLine(ctx context.Context, pid int) ([]string, error) {
+	args, err := readProcArgs(pid)
+	if err != nil {
+		return nil, err
+	}
+	if len(args.argv) == 0 || args.argv[0] == "" {
+		return nil, fmt.Errorf("empty command line for pid %d", pid)
+	}
+	return args.argv, nil
+}
+
+// Executable returns the path kern.procargs2 records for the executable
+func (inspector) Executable(ctx context.Context, pid int) (string, error) {
+	args, err := readProcArgs(pid)
+	if err != nil {
+		return "", err
+	}
+	if args.exe == "" {
+		return "", fmt.Errorf("no executable found for pid %d", pid)
+	}
+	return args.exe, nil
+}
+
+// Parent reads the parent PID from kern.proc.pid. The kernel truncates
+// p_comm to 16 bytes, which would cut names such as "Code Helper (Plugin)",
+// so the executable's base name is preferred when it is readable.
+func (inspector) Parent(ctx context.Context, pid int) (int, string, error) {
+	info, err := kinfo(pid)
+	if err != nil {
+		return 0, "", err
+	}
+	name := unix.ByteSliceToString(info.Proc.P_comm[:])
+	if args, err := readProcArgs(pid); err == nil && args.exe != "" {
+		name = filepath.Base(args.exe)
+	}
+	return int(info.Eproc.Ppid), name, nil
+}
+
+// TTY names the controlling terminal from its device number. Only pseudo
+// terminals are named; servers don't run on the console.
+func (inspector) TTY(ctx context.Context, pid int) string {
+	info, err := kinfo(pid)
+	if err != nil || info.Eproc.Tdev == -1 {
+		return ""
+	}
+	dev := uint32(info.Eproc.Tdev)
+	if dev>>24 != ttysMajor {
+		return ""
+	}
+	return fmt.Sprintf("ttys%03d", dev&0xffffff)
+}
+
+// Environ returns the environment from kern.procargs2
+func (inspector) Environ(ctx context.Context, pid int) (map[string]string, error) {
+	args, err := readProcArgs(pid)
+	if err != nil {
+		return nil, err
+	}
+	return parseEnviron(args.env), nil
+}
+
+// StartTime reads p_starttime from kern.proc.pid
+func (inspector) StartTime(ctx context.Context, pid int) (time.Time, error) {
+	info, err := kinfo(pid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	start := info.Proc.P_starttime
+	return time.Unix(start.Sec, int64(start.Usec)*1000), nil
+}
+
+// UID reads the effective user from kern.proc.pid
+func (inspector) UID(ctx context.Context, pid int) (int, error) {
+	info, err := kinfo(pid)
+	if err != nil {
+		return 0, err
+	}
+	return int(info.Eproc.Ucred.Uid), nil
+}
+
+// AllUsage lists every process with ps, as CPU times and resident sizes
+// are only exposed through libproc, which needs cgo
+func (inspector) AllUsage(ctx context.Context) (map[int]Usage, error) {
+	output, err := exec.CommandContext(ctx, "ps", "-A", "-o", "pid=,ppid=,time=,rss=").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	all := make(map[int]Usage)
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		ppid, _ := strconv.Atoi(fields[1])
+		rss, _ := strconv.ParseUint(fields[3], 10, 64)
+		all[pid] = Usage{PPID: ppid, CPU: parseCPUTime(fields[2]), RSS: rss * 1024}
+	}
+	return all, nil
+}
+
+// parseCPUTime parses ps's "[[dd-]hh:]mm:ss.cc" CPU time
+func parseCPUTime(value string) time.Duration {
+	var total time.Duration
+	if days, rest, ok := strings.Cut(value, "-"); ok {
+		n, _ := strconv.Atoi(days)
+		total += time.Duration(n) * 24 * time.Hour
+		value = rest
+	}
+	parts := strings.Split(value, ":")
+	seconds, _ := strconv.ParseFloat(parts[len(parts)-1], 64)
+	total += time.Duration(seconds * float64(time.Second))
+	unit := time.Minute
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, _ := strconv.Atoi(parts[i])
+		total += time.Duration(n) * unit
+		unit *= 60
+	}
+	return total
+}
+
+// kinfo reads the kern.proc.pid sysctl for pid
+func kinfo(pid int) (*unix.KinfoProc, error) {
+	info, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil {
+		return nil, err
+	}
+	// The kernel answers with an empty record for PIDs that don't exist
+	if int(info.Proc.P_pid) != pid {
+		return nil, fmt.Errorf("no process with pid %d", pid)
+	}
+	return info, nil
+}
+
+// procArgs is the decoded kern.procargs2 sysctl
+type procArgs struct {
+	exe  string
+	argv []string
+	env  []string
+}
+
+// readProcArgs decodes kern.procargs2, which holds argc, the executable
+// path, NUL padding, the arguments and then the environment. Only the
+// current user's processes (or any, as root) can be read.
+func readProcArgs(pid int) (procArgs, error) {
+	data, err := unix.SysctlRaw("kern.procargs2", pid)
+	if err != nil {
+		return procArgs{}, err
+	}
+	if len(data) < 4 {
+		return procArgs{}, fmt.Errorf("short procargs for pid %d", pid)
+	}
+	argc := int(binary.LittleEndian.Uint32(data[:4]))
+
+	fields := strings.Split(string(data[4:]), "\x00")
+	args := procArgs{exe: fields[0]}
+
+	// Skip the padding after the executable path
+	i := 1
+	for i < len(fields) && fields[i] == "" {
+		i++
+	}
+	if i+argc > len(fields) {
+		return procArgs{}, fmt.Errorf("malformed procargs for pid %d", pid)
+	}
+	args.argv = fields[i : i+argc]
+
+	for _, field := range fields[i+argc:] {
+		if field == "" {
+			break
+		}
+		args.env = append(args.env, field)
+	}
+	return args, nil
+}
+
+// exited reports whether pid no longer exists
+func exited(pid int) bool {
+	return syscall.Kill(pid, 0) == syscall.ESRCH
+}
+
+// lsofCWDs runs lsof for pids and parses its "p<pid>\nn<path>" field
+// output. On failure it returns whatever lsof reported alongside the error.
+func lsofCWDs(ctx context.Context, pids []int) (map[int]string, error) {
+	pidStrs := make([]string, len(pids))
+	for i, pid := range pids {
+		pidStrs[i] = strconv.Itoa(pid)
+	}
+	output, err := exec.CommandContext(ctx, "lsof", "-a", "-p", strings.Join(pidStrs, ","), "-d", "cwd", "-Fn").Output()
+
+	cwds := make(map[int]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	var currentPID int
+	for scanner.Scan() {
+		line := scanner.Text()
+		if pidStr, ok := strings.CutPrefix(line, "p"); ok {
+			if pid, err := strconv.Atoi(pidStr); err == nil {
+				currentPID = pid
+			}
+		} else if cwd, ok := strings.CutPrefix(line, "n"); ok && currentPID != 0 {
+			if cleaned, err := filepath.Abs(cwd); err == nil {
+				cwds[currentPID] = cleaned
+			}
+			currentPID = 0
+		}
+	}
+	return cwds, err
+}
@@ -0,0 +1,233 @@
+//go:build linux
+
+package procinfo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/platform"
+)
+
+// clockTicks is USER_HZ, the unit of process times in /proc/<pid>/stat.
+// It is 100 on every architecture Linux runs lsrv on.
+const clockTicks = 100
+
+// inspector reads process details from /proc
+type inspector struct{}
+
+// CWDs resolves /proc/<pid>/cwd for each PID. Deleted directories keep the
+// kernel's " (deleted)" suffix.
+func (inspector) CWDs(ctx context.Context, pids []int) (map[int]string, []int) {
+	cwds := make(map[int]string)
+	var gone []int
+	for _, pid := range pids {
+		if err := platform.ValidatePID(pid); err != nil {
+			continue
+		}
+
+		link := fmt.Sprintf("/proc/%d/cwd", pid)
+		info, err := os.Lstat(link)
+		if errors.Is(err, fs.ErrNotExist) {
+			gone = append(gone, pid)
+			continue
+		}
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		cwd, err := os.Readlink(link)
+		if err != nil {
+			continue
+		}
+
+		cleaned, err := filepath.Abs(cwd)
+		if err != nil {
+			continue
+		}
+		cwds[pid] = cleaned
+	}
+	return cwds, gone
+}
+
+// Executable follows /proc/<pid>/exe, dropping the kernel's " (deleted)"
+// suffix for binaries replaced since the process started
+func (inspector) Executable(ctx context.Context, pid int) (string, error) {
+	exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(exe, " (deleted)"), nil
+}
+
+// CommandLine reads the NUL-separated arguments in /proc/<pid>/cmdline
+func (inspector) CommandLine(ctx context.Context, pid int) ([]string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return nil, err
+	}
+	args := strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+	if len(args) == 0 || args[0] == "" {
+		return nil, fmt.Errorf("empty command line for pid %d", pid)
+	}
+	return args, nil
+}
+
+// Parent reads the ppid and comm fields of /proc/<pid>/stat
+func (inspector) Parent(ctx context.Context, pid int) (int, string, error) {
+	comm, fields, err := readStat(pid)
+	if err != nil {
+		return 0, "", err
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, "", err
+	}
+	return ppid, comm, nil
+}
+
+// TTY decodes the tty_nr field of /proc/<pid>/stat. Pseudo terminals
+// (majors 136-143) are "pts/N" and virtual consoles (major 4) "ttyN".
+func (inspector) TTY(ctx context.Context, pid int) string {
+	_, fields, err := readStat(pid)
+	if err != nil {
+		return ""
+	}
+	nr, err := strconv.Atoi(fields[4])
+	if err != nil || nr == 0 {
+		return ""
+	}
+	major := (nr >> 8) & 0xfff
+	minor := (nr & 0xff) | ((nr >> 12) & 0xfff00)
+	switch {
+	case major >= 136 && major <= 143:
+		return fmt.Sprintf("pts/%d", (major-136)*256+minor)
+	case major == 4 && minor < 64:
+		return fmt.Sprintf("tty%d", minor)
+	}
+	return ""
+}
+
+// Environ reads the NUL-separated /proc/<pid>/environ
+func (inspector) Environ(ctx context.Context, pid int) (map[string]string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid))
+	if err != nil {
+		return nil, err
+	}
+	return parseEnviron(strings.Split(string(data), "\x00")), nil
+}
+
+// StartTime adds the starttime field of /proc/<pid>/stat, in clock ticks
+// since boot, to the boot time in /proc/stat
+func (inspector) StartTime(ctx context.Context, pid int) (time.Time, error) {
+	_, fields, err := readStat(pid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	boot, err := bootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return boot.Add(time.Duration(ticks) * time.Second / clockTicks), nil
+}
+
+// UID returns the owner of /proc/<pid>, which is the process's effective
+// user
+func (inspector) UID(ctx context.Context, pid int) (int, error) {
+	info, err := os.Stat(fmt.Sprintf("/proc/%d", pid))
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("no owner for pid %d", pid)
+	}
+	return int(stat.Uid), nil
+}
+
+// AllUsage reads the ppid, utime, stime and rss fields of every
+// /proc/<pid>/stat. Processes exiting during the scan are skipped.
+func (inspector) AllUsage(ctx context.Context) (map[int]Usage, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	all := make(map[int]Usage)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		_, fields, err := readStat(pid)
+		if err != nil || len(fields) < 22 {
+			continue
+		}
+		ppid, _ := strconv.Atoi(fields[1])
+		utime, _ := strconv.ParseInt(fields[11], 10, 64)
+		stime, _ := strconv.ParseInt(fields[12], 10, 64)
+		rss, _ := strconv.ParseUint(fields[21], 10, 64)
+		all[pid] = Usage{
+			PPID: ppid,
+			CPU:  time.Duration(utime+stime) * time.Second / clockTicks,
+			RSS:  rss * pageSize,
+		}
+	}
+	return all, nil
+}
+
+// readStat reads /proc/<pid>/stat, which is "pid (comm) state ppid ...",
+// where comm may itself contain spaces and parentheses. It returns comm and
+// the fields after it, so field N of proc(5) is fields[N-3].
+func readStat(pid int) (string, []string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return "", nil, err
+	}
+	stat := string(data)
+	open := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return "", nil, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 20 {
+		return "", nil, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	return stat[open+1 : end], fields, nil
+}
+
+// bootTime reads the btime line of /proc/stat
+func bootTime() (time.Time, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "btime "); ok {
+			secs, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(secs, 0), nil
+		}
+	}
+	return time.Time{}, errors.New("no btime in /proc/stat")
+}
@@ -0,0 +1,48 @@
+//go:build windows
+
+package procinfo
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// inspector reports nothing yet; Windows detection lists sockets only
+type inspector struct{}
+
+func (inspector) CWDs(ctx context.Context, pids []int) (map[int]string, []int) {
+	return map[int]string{}, nil
+}
+
+func (inspector) CommandLine(ctx context.Context, pid int) ([]string, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (inspector) Executable(ctx context.Context, pid int) (string, error) {
+	return "", errors.ErrUnsupported
+}
+
+func (inspector) Parent(ctx context.Context, pid int) (int, string, error) {
+	return 0, "", errors.ErrUnsupported
+}
+
+func (inspector) TTY(ctx context.Context, pid int) string {
+	return ""
+}
+
+func (inspector) Environ(ctx context.Context, pid int) (map[string]string, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (inspector) StartTime(ctx context.Context, pid int) (time.Time, error) {
+	return time.Time{}, errors.ErrUnsupported
+}
+
+func (inspector) UID(ctx context.Context, pid int) (int, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func (inspector) AllUsage(ctx context.Context) (map[int]Usage, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,146 @@
+// Package reservation keeps a local registry of which repository each port
+// is meant for, so teams sharing conventions like 3000 and 8080 notice when
+// a server lands on a port reserved for another repo
+package reservation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/atomicfile"
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// DefaultFrom is where Suggest starts looking for a free port
+const DefaultFrom = 3000
+
+// Reservation is one port set aside for a repository
+type Reservation struct {
+	Port    int       `json:"port"`
+	Repo    string    `json:"repo"`
+	Created time.Time `json:"created"`
+}
+
+// Registry holds the reservations, ordered by port
+type Registry struct {
+	Reservations []Reservation `json:"reservations"`
+
+	path string
+}
+
+// path returns the file holding the registry
+func path() (string, error) {
+	state, err := platform.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "reservations.json"), nil
+}
+
+// Load reads the registry, starting an empty one if none was saved yet
+func Load() (*Registry, error) {
+	file, err := path()
+	if err != nil {
+		return nil, err
+	}
+	r := &Registry{path: file}
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	return r, nil
+}
+
+// Save writes the registry
+func (r *Registry) Save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(r.path, data, 0o644)
+}
+
+// Owner returns the repo port is reserved for
+func (r *Registry) Owner(port int) (string, bool) {
+	i, found := r.find(port)
+	if !found {
+		return "", false
+	}
+	return r.Reservations[i].Repo, true
+}
+
+// Reserve sets port aside for repo. A port reserved for another repo has to
+// be released first; reserving it again for the same repo is a no-op.
+func (r *Registry) Reserve(port int, repo string, now time.Time) error {
+	i, found := r.find(port)
+	if found {
+		if owner := r.Reservations[i].Repo; !strings.EqualFold(owner, repo) {
+			return fmt.Errorf("port %d is already reserved for %s", port, owner)
+		}
+		return nil
+	}
+	r.Reservations = slices.Insert(r.Reservations, i, Reservation{Port: port, Repo: repo, Created: now})
+	return nil
+}
+
+// Release removes the reservation of port, reporting whether there was one
+func (r *Registry) Release(port int) bool {
+	i, found := r.find(port)
+	if found {
+		r.Reservations = slices.Delete(r.Reservations, i, i+1)
+	}
+	return found
+}
+
+// Violated reports whether an app server listens on a port reserved for
+// another repo
+func (r *Registry) Violated(server types.Server) bool {
+	owner, ok := r.Owner(server.Port)
+	return ok && server.Service == "" && !strings.EqualFold(owner, server.Repo)
+}
+
+// Suggest returns a free port for repo that respects the reservations:
+// one of repo's own reserved ports if any is free, otherwise the first free
+// port from from upwards that isn't reserved for anyone. inUse reports
+// whether something listens on a port. It returns 0 when every port is
+// taken.
+func (r *Registry) Suggest(repo string, from int, inUse func(port int) bool) int {
+	if repo != "" {
+		for _, reservation := range r.Reservations {
+			if strings.EqualFold(reservation.Repo, repo) && !inUse(reservation.Port) {
+				return reservation.Port
+			}
+		}
+	}
+	for port := max(from, 1); port <= 65535; port++ {
+		if _, reserved := r.find(port); !reserved && !inUse(port) {
+			return port
+		}
+	}
+	return 0
+}
+
+// find returns the index of port's reservation, or where it would go
+func (r *Registry) find(port int) (int, bool) {
+	return slices.BinarySearchFunc(r.Reservations, port, func(reservation Reservation, port int) int {
+		return reservation.Port - port
+	})
+}
@@ -0,0 +1,100 @@
+package runconfig
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// skippedElements hold IDE settings rather than what a configuration runs
+var skippedElements = map[string]bool{
+	"method":           true,
+	"module":           true,
+	"extension":        true,
+	"node-interpreter": true,
+}
+
+// readJetBrains returns the run configurations of the JetBrains project in
+// dir: those in .idea/workspace.xml, shared ones in .idea/runConfigurations
+// and ones stored as project files under .run. Unreadable files are
+// skipped.
+func readJetBrains(dir string) []config {
+	files := []string{filepath.Join(dir, ".idea", "workspace.xml")}
+	for _, pattern := range []string{filepath.Join(dir, ".idea", "runConfigurations", "*.xml"), filepath.Join(dir, ".run", "*.run.xml")} {
+		matches, _ := filepath.Glob(pattern)
+		files = append(files, matches...)
+	}
+
+	var configs []config
+	for _, file := range files {
+		configs = append(configs, readJetBrainsFile(file)...)
+	}
+	return configs
+}
+
+// readJetBrainsFile collects the <configuration> elements of one file,
+// marking the one the RunManager has selected
+func readJetBrainsFile(path string) []config {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var (
+		configs  []config
+		current  *config
+		kind     string
+		selected string
+		skipping int
+	)
+	decoder := xml.NewDecoder(f)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch el := token.(type) {
+		case xml.StartElement:
+			attrs := make(map[string]string)
+			for _, attr := range el.Attr {
+				attrs[attr.Name.Local] = attr.Value
+			}
+			switch {
+			case el.Name.Local == "component" && attrs["name"] == "RunManager":
+				selected = attrs["selected"]
+			case el.Name.Local == "configuration" && current == nil:
+				if attrs["default"] != "true" && attrs["name"] != "" {
+					current, kind = &config{name: attrs["name"]}, attrs["type"]
+				}
+			case current == nil:
+			case skipping > 0 || skippedElements[el.Name.Local]:
+				skipping++
+			case el.Name.Local == "env" && attrs["name"] == "PORT":
+				current.port = attrs["value"]
+			case el.Name.Local == "option" && strings.Contains(attrs["name"], "DIR"):
+			default:
+				// Parameters are one string split like a shell would
+				for _, value := range strings.Fields(attrs["value"]) {
+					if cleaned := cleanValue(value); cleaned != "" {
+						current.values = append(current.values, cleaned)
+					}
+				}
+			}
+
+		case xml.EndElement:
+			switch {
+			case current == nil:
+			case skipping > 0:
+				skipping--
+			case el.Name.Local == "configuration":
+				// The toolbar selection is saved as "Type.Name"
+				current.selected = selected != "" && selected == kind+"."+current.name
+				configs = append(configs, *current)
+				current = nil
+			}
+		}
+	}
+	return configs
+}
@@ -0,0 +1,233 @@
+// Package runconfig names servers launched from an IDE after the VS Code
+// launch configuration or JetBrains run configuration that started them,
+// so they don't show up as a bare "node" or "java"
+package runconfig
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/procinfo"
+)
+
+// maxAncestors bounds the parent chain walk
+const maxAncestors = 32
+
+// IDE families, which keep their run configurations in different places
+const (
+	vscode    = "vscode"
+	jetbrains = "jetbrains"
+)
+
+// ides maps ancestor process names to the IDE family they belong to
+var ides = map[string]string{
+	"code":                   vscode,
+	"Code Helper":            vscode,
+	"Code Helper (Plugin)":   vscode,
+	"codium":                 vscode,
+	"VSCodium Helper":        vscode,
+	"cursor":                 vscode,
+	"Cursor Helper":          vscode,
+	"Cursor Helper (Plugin)": vscode,
+	"idea":                   jetbrains,
+	"goland":                 jetbrains,
+	"rubymine":               jetbrains,
+	"pycharm":                jetbrains,
+	"webstorm":               jetbrains,
+	"phpstorm":               jetbrains,
+	"clion":                  jetbrains,
+	"rider":                  jetbrains,
+	"rustrover":              jetbrains,
+}
+
+// config is a run configuration reduced to what matching needs
+type config struct {
+	name string
+
+	// values are the program, script, class and argument values the
+	// configuration runs with, with IDE variables removed
+	values []string
+
+	// port is the PORT variable the configuration sets, if any
+	port string
+
+	// selected marks the configuration chosen in a JetBrains toolbar
+	selected bool
+}
+
+// Find returns the name of the run configuration that launched pid, a
+// server on port running in dir, or "" when no IDE launched it or none of
+// the project's configurations fits. Configurations are matched on the
+// programs, scripts and arguments they name and on the PORT they set.
+func Find(ctx context.Context, pid, port int, dir string) string {
+	family, commands := launchedBy(ctx, pid)
+	if family == "" {
+		return ""
+	}
+
+	root := dir
+	if top, ok := git.WorkTreeRoot(dir); ok {
+		root = top
+	}
+	var configs []config
+	for _, base := range projectDirs(dir, root) {
+		switch family {
+		case vscode:
+			configs = append(configs, readVSCode(base)...)
+		case jetbrains:
+			configs = append(configs, readJetBrains(base)...)
+		}
+	}
+	return pick(configs, commands, port, !fromTerminal(commands))
+}
+
+// launchedBy walks the ancestors of pid up to an IDE and returns the IDE's
+// family with the command lines of pid and the ancestors below the IDE.
+// The family is empty when no IDE is among the ancestors; command lines
+// are only read once one is found, keeping the common case cheap.
+func launchedBy(ctx context.Context, pid int) (string, [][]string) {
+	var chain []int
+	current := pid
+	for i := 0; i < maxAncestors && current > 1; i++ {
+		ppid, name, err := procinfo.Parent(ctx, current)
+		if err != nil {
+			return "", nil
+		}
+		family, ok := ides[name]
+		if !ok {
+			chain = append(chain, current)
+			current = ppid
+			continue
+		}
+
+		var commands [][]string
+		for _, member := range chain {
+			if args, err := procinfo.CommandLine(ctx, member); err == nil {
+				commands = append(commands, args)
+			}
+		}
+		return family, commands
+	}
+	return "", nil
+}
+
+// projectDirs lists dir and its parents up to root, where run
+// configurations may live
+func projectDirs(dir, root string) []string {
+	dirs := []string{dir}
+	for current := dir; current != root; {
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		dirs = append(dirs, parent)
+		current = parent
+	}
+	return dirs
+}
+
+// pick returns the configuration that best fits the command lines and
+// port. Without any fit and with guess set, a project's only
+// configuration, or the one selected in a JetBrains toolbar, is taken to
+// be the one that ran.
+func pick(configs []config, commands [][]string, port int, guess bool) string {
+	best, bestScore := "", 0
+	for _, cfg := range configs {
+		score := 0
+		if cfg.port != "" && cfg.port == strconv.Itoa(port) {
+			score += 2
+		}
+		for _, value := range cfg.values {
+			if mentions(commands, value) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = cfg.name, score
+		}
+	}
+	if best != "" || !guess {
+		return best
+	}
+
+	if len(configs) == 1 {
+		return configs[0].name
+	}
+	for _, cfg := range configs {
+		if cfg.selected {
+			return cfg.name
+		}
+	}
+	return ""
+}
+
+// shells run an IDE's integrated terminal
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "dash": true, "pwsh": true, "nu": true,
+}
+
+// fromTerminal reports whether the command lines include an interactive
+// shell, as servers started by hand in an IDE's terminal do. Their run
+// configurations can't be guessed, only matched.
+func fromTerminal(commands [][]string) bool {
+	for _, args := range commands {
+		if len(args) == 0 || !shells[strings.TrimPrefix(filepath.Base(args[0]), "-")] {
+			continue
+		}
+		interactive := true
+		for _, arg := range args[1:] {
+			if !strings.HasPrefix(arg, "-") || arg == "-c" {
+				interactive = false
+			}
+		}
+		if interactive {
+			return true
+		}
+	}
+	return false
+}
+
+// mentions reports whether an argument of the command lines is value, or
+// a path ending in it
+func mentions(commands [][]string, value string) bool {
+	for _, args := range commands {
+		for _, arg := range args {
+			if arg == value || (strings.Contains(value, "/") && strings.HasSuffix(arg, "/"+strings.TrimPrefix(value, "./"))) {
+				return true
+			}
+			if !strings.Contains(value, "/") && filepath.Base(arg) == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cleanValue removes IDE variables such as ${workspaceFolder}/ and
+// $PROJECT_DIR$/ from a configured path, leaving the part that shows up
+// in command lines. Flags and empty values are dropped.
+func cleanValue(value string) string {
+	value = strings.TrimSpace(value)
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(value[start:], "}")
+		if end < 0 {
+			break
+		}
+		value = value[:start] + value[start+end+1:]
+	}
+	for _, variable := range []string{"$PROJECT_DIR$", "$MODULE_DIR$", "$MODULE_WORKING_DIR$", "$USER_HOME$"} {
+		value = strings.ReplaceAll(value, variable, "")
+	}
+	value = strings.TrimPrefix(value, "/")
+	if value == "" || strings.HasPrefix(value, "-") || value == "true" || value == "false" {
+		return ""
+	}
+	return value
+}
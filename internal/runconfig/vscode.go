@@ -0,0 +1,95 @@
+package runconfig
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/jsonc"
+)
+
+// launchFile lists VS Code debug configurations; tasksFile its tasks
+const (
+	launchFile = ".vscode/launch.json"
+	tasksFile  = ".vscode/tasks.json"
+)
+
+// launch is the part of launch.json naming what each configuration runs
+type launch struct {
+	Configurations []struct {
+		Name              string            `json:"name"`
+		Program           string            `json:"program"`
+		Module            string            `json:"module"`
+		MainClass         string            `json:"mainClass"`
+		RuntimeExecutable string            `json:"runtimeExecutable"`
+		RuntimeArgs       []string          `json:"runtimeArgs"`
+		Args              json.RawMessage   `json:"args"`
+		Env               map[string]string `json:"env"`
+	} `json:"configurations"`
+}
+
+// tasks is the part of tasks.json naming what each task runs
+type tasks struct {
+	Tasks []struct {
+		Label   string            `json:"label"`
+		Script  string            `json:"script"`
+		Command string            `json:"command"`
+		Args    []json.RawMessage `json:"args"`
+		Options struct {
+			Env map[string]string `json:"env"`
+		} `json:"options"`
+	} `json:"tasks"`
+}
+
+// readVSCode returns the launch configurations and tasks declared in dir.
+// Unreadable files are skipped.
+func readVSCode(dir string) []config {
+	var configs []config
+
+	var l launch
+	if jsonc.ReadFile(filepath.Join(dir, launchFile), &l) {
+		for _, c := range l.Configurations {
+			values := []string{c.Program, c.Module, c.MainClass, c.RuntimeExecutable}
+			values = append(values, c.RuntimeArgs...)
+			// args is a list, or a single string split like a shell would
+			var args []string
+			if json.Unmarshal(c.Args, &args) != nil {
+				var line string
+				_ = json.Unmarshal(c.Args, &line)
+				args = strings.Fields(line)
+			}
+			values = append(values, args...)
+			configs = append(configs, newConfig(c.Name, values, c.Env["PORT"]))
+		}
+	}
+
+	var t tasks
+	if jsonc.ReadFile(filepath.Join(dir, tasksFile), &t) {
+		for _, task := range t.Tasks {
+			name := task.Label
+			if name == "" {
+				name = task.Script
+			}
+			values := append(strings.Fields(task.Command), task.Script)
+			for _, raw := range task.Args {
+				var arg string
+				if json.Unmarshal(raw, &arg) == nil {
+					values = append(values, arg)
+				}
+			}
+			configs = append(configs, newConfig(name, values, task.Options.Env["PORT"]))
+		}
+	}
+	return configs
+}
+
+// newConfig builds a config from raw configured values
+func newConfig(name string, values []string, port string) config {
+	cfg := config{name: name, port: port}
+	for _, value := range values {
+		if cleaned := cleanValue(value); cleaned != "" {
+			cfg.values = append(cfg.values, cleaned)
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,204 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/atomicfile"
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/procinfo"
+)
+
+// Run records a server process started by lsrv
+type Run struct {
+	ID      string    `json:"id"`
+	Repo    string    `json:"repo"`
+	Dir     string    `json:"dir"`
+	Command []string  `json:"command"`
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	LogPath string    `json:"log_path"`
+}
+
+// maxRuns is how many runs are kept; older ones are pruned once their
+// process has exited
+const maxRuns = 50
+
+// runsDir returns the directory holding one subdirectory per run
+func runsDir() (string, error) {
+	state, err := platform.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "runs"), nil
+}
+
+// Start launches command in dir as a detached background process with its
+// stdout and stderr captured in the run directory
+func Start(dir, repo string, command []string) (*Run, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("no command given")
+	}
+
+	cleanedDir, err := platform.ValidateDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := runsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	// Captured output may hold secrets, so only the user can read it, also
+	// in run directories created before this was enforced
+	now := time.Now()
+	if err := os.MkdirAll(base, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create run directory: %w", err)
+	}
+	if err := os.Chmod(base, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to restrict run directory: %w", err)
+	}
+	// Runs started together, as by lsrv import, share the second and the
+	// PID, so a random suffix keeps their logs apart
+	runDir, err := os.MkdirTemp(base, now.Format("20060102-150405")+"-"+strconv.Itoa(os.Getpid())+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create run directory: %w", err)
+	}
+	id := filepath.Base(runDir)
+
+	logPath := filepath.Join(runDir, "output.log")
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = cleanedDir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachedProcAttr()
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", command[0], err)
+	}
+
+	run := &Run{
+		ID:      id,
+		Repo:    repo,
+		Dir:     cleanedDir,
+		Command: command,
+		PID:     cmd.Process.Pid,
+		Started: now,
+		LogPath: logPath,
+	}
+
+	// The server outlives lsrv, so don't wait on it
+	if err := cmd.Process.Release(); err != nil {
+		return nil, fmt.Errorf("failed to detach process: %w", err)
+	}
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := atomicfile.WriteFile(filepath.Join(runDir, "run.json"), data, 0o600); err != nil {
+		return nil, err
+	}
+
+	// Pruning is housekeeping; failing it doesn't fail the start
+	_ = prune(base)
+	return run, nil
+}
+
+// prune removes the runs beyond the newest maxRuns whose process has
+// exited, so the runs directory doesn't grow forever. A run whose PID now
+// belongs to a process started after it has exited too.
+func prune(base string) error {
+	runs, err := List()
+	if err != nil || len(runs) <= maxRuns {
+		return err
+	}
+	ctx := context.Background()
+	for _, run := range runs[maxRuns:] {
+		// The ID names the run's directory; never follow one out of base
+		if run.ID == "" || run.ID == "." || run.ID == ".." || run.ID != filepath.Base(run.ID) {
+			continue
+		}
+		if started, err := procinfo.StartTime(ctx, run.PID); err == nil && !started.After(run.Started.Add(time.Minute)) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(base, run.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns all recorded runs, most recent first
+func List() ([]Run, error) {
+	base, err := runsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(base)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runs directory: %w", err)
+	}
+
+	var runs []Run
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(base, entry.Name(), "run.json"))
+		if err != nil {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal(data, &run); err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].Started.After(runs[j].Started)
+	})
+	return runs, nil
+}
+
+// commonLogFiles are framework log locations relative to a project directory,
+// in order of preference
+var commonLogFiles = []string{
+	"log/development.log",
+	"logs/development.log",
+	"storage/logs/laravel.log",
+	".next/trace",
+	"npm-debug.log",
+	"yarn-error.log",
+}
+
+// FindProjectLog makes a best-effort guess at the log file of a server that
+// was not started by lsrv, returning "" when none is found
+func FindProjectLog(dir string) string {
+	for _, rel := range commonLogFiles {
+		path := filepath.Join(dir, rel)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+	}
+	return ""
+}
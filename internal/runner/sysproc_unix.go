@@ -0,0 +1,11 @@
+//go:build unix
+
+package runner
+
+import "syscall"
+
+// detachedProcAttr starts the child in its own session so it survives the
+// terminal that launched it
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
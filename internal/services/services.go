@@ -0,0 +1,54 @@
+package services
+
+import "strings"
+
+// Service describes a common local development dependency
+type Service struct {
+	Name string
+
+	// Processes are process names the service runs as; lsof truncates
+	// command names, so these are matched as prefixes
+	Processes []string
+
+	// Ports are the service's standard listening ports
+	Ports []int
+}
+
+// Known lists the services recognized by --services
+var Known = []Service{
+	{Name: "postgres", Processes: []string{"postgres", "postmaster"}, Ports: []int{5432}},
+	{Name: "mysql", Processes: []string{"mysqld", "mariadbd"}, Ports: []int{3306}},
+	{Name: "redis", Processes: []string{"redis-server", "valkey-server"}, Ports: []int{6379}},
+	{Name: "memcached", Processes: []string{"memcached"}, Ports: []int{11211}},
+	{Name: "mongodb", Processes: []string{"mongod"}, Ports: []int{27017}},
+	{Name: "elasticsearch", Processes: []string{"elasticsearch"}, Ports: []int{9200, 9300}},
+	{Name: "rabbitmq", Processes: []string{"rabbitmq-server"}, Ports: []int{5672, 15672}},
+	{Name: "mailhog", Processes: []string{"MailHog", "mailhog", "mailpit"}, Ports: []int{1025, 8025}},
+	{Name: "minio", Processes: []string{"minio"}, Ports: []int{9000, 9001}},
+}
+
+// MatchProcess identifies a service by its process name
+func MatchProcess(process string) (string, bool) {
+	for _, svc := range Known {
+		for _, name := range svc.Processes {
+			// lsof truncates command names to 9 characters by default
+			if strings.HasPrefix(name, process) && len(process) >= min(len(name), 9) {
+				return svc.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// MatchPort identifies a service by its standard port, which catches
+// services run through Docker port forwarding or a JVM
+func MatchPort(port int) (string, bool) {
+	for _, svc := range Known {
+		for _, p := range svc.Ports {
+			if p == port {
+				return svc.Name, true
+			}
+		}
+	}
+	return "", false
+}
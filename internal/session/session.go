@@ -0,0 +1,213 @@
+package session
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/procinfo"
+)
+
+// maxAncestors bounds the parent chain walk
+const maxAncestors = 32
+
+// hosts maps ancestor process names to the terminal or editor they belong to
+var hosts = map[string]string{
+	"Code Helper":           "VS Code",
+	"Code Helper (Plugin)":  "VS Code",
+	"Electron":              "VS Code",
+	"code":                  "VS Code",
+	"cursor":                "Cursor",
+	"Cursor Helper":         "Cursor",
+	"iTerm2":                "iTerm2",
+	"Terminal":              "Terminal",
+	"ghostty":               "Ghostty",
+	"wezterm-gui":           "WezTerm",
+	"kitty":                 "kitty",
+	"alacritty":             "Alacritty",
+	"gnome-terminal-server": "GNOME Terminal",
+	"konsole":               "Konsole",
+	"screen":                "screen",
+	"SCREEN":                "screen",
+	"zellij":                "zellij",
+	"idea":                  "IntelliJ",
+	"goland":                "GoLand",
+	"rubymine":              "RubyMine",
+	"pycharm":               "PyCharm",
+	"webstorm":              "WebStorm",
+}
+
+// Resolve labels each PID with the tmux pane, terminal or editor it runs
+// under, e.g. "tmux work:1.0" or "iTerm2 ttys003". PIDs without a known
+// owner are omitted.
+func Resolve(ctx context.Context, pids []int) map[int]string {
+	panes := tmuxPanes(ctx)
+	sessions := make(map[int]string)
+
+	for _, pid := range pids {
+		if ctx.Err() != nil {
+			break
+		}
+		if label := resolve(ctx, pid, panes); label != "" {
+			sessions[pid] = label
+		}
+	}
+	return sessions
+}
+
+// resolve walks the parent chain of pid until it reaches a tmux pane or a
+// known terminal application
+func resolve(ctx context.Context, pid int, panes map[int]string) string {
+	current := pid
+	for i := 0; i < maxAncestors && current > 1; i++ {
+		if pane, ok := panes[current]; ok {
+			return "tmux " + pane
+		}
+
+		ppid, name, err := procinfo.Parent(ctx, current)
+		if err != nil {
+			return ""
+		}
+
+		if host, ok := hosts[name]; ok {
+			if tty := procinfo.TTY(ctx, pid); tty != "" {
+				return host + " " + tty
+			}
+			return host
+		}
+		current = ppid
+	}
+	return ""
+}
+
+// managers are ancestors that start servers without anyone at a shell:
+// init systems and process supervisors
+var managers = map[string]bool{
+	"launchd":      true,
+	"systemd":      true,
+	"init":         true,
+	"supervisord":  true,
+	"runsv":        true,
+	"s6-supervise": true,
+}
+
+// interpreters run a script named by their first argument, which says more
+// than the interpreter's own name (bin/dev rather than ruby)
+var interpreters = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "dash": true,
+	"ruby": true, "python": true, "python3": true, "node": true, "perl": true,
+}
+
+// maxChain bounds how many ancestors a StartedBy chain shows
+const maxChain = 5
+
+// StartedBy summarizes what launched each PID, outermost first, such as
+// ["tmux", "zsh", "bin/dev"], ["VS Code", "zsh", "npm run dev"] or
+// ["launchd"]. The walk stops at a tmux pane, a terminal or editor, or an
+// init system or supervisor. PIDs whose parents can't be read are omitted.
+func StartedBy(ctx context.Context, pids []int) map[int][]string {
+	panes := tmuxPanes(ctx)
+	chains := make(map[int][]string)
+
+	for _, pid := range pids {
+		if ctx.Err() != nil {
+			break
+		}
+		if chain := startedBy(ctx, pid, panes); len(chain) > 0 {
+			chains[pid] = chain
+		}
+	}
+	return chains
+}
+
+// startedBy walks the ancestors of pid, collecting their names until one
+// tells where the chain started
+func startedBy(ctx context.Context, pid int, panes map[int]string) []string {
+	current, _, err := procinfo.Parent(ctx, pid)
+	if err != nil {
+		return nil
+	}
+
+	var chain []string
+	for i := 0; i < maxAncestors && current > 0; i++ {
+		ppid, name, err := procinfo.Parent(ctx, current)
+		if err != nil {
+			break
+		}
+		name = strings.TrimPrefix(name, "-")
+
+		if _, ok := panes[current]; ok {
+			chain = append(chain, ancestorLabel(ctx, current, name), "tmux")
+			break
+		}
+		if host, ok := hosts[name]; ok {
+			chain = append(chain, host)
+			break
+		}
+		if managers[name] || current == 1 {
+			chain = append(chain, name)
+			break
+		}
+		chain = append(chain, ancestorLabel(ctx, current, name))
+		current = ppid
+	}
+
+	slices.Reverse(chain)
+	if len(chain) > maxChain {
+		chain = append([]string{chain[0], "…"}, chain[len(chain)-maxChain+2:]...)
+	}
+	return chain
+}
+
+// ancestorLabel names an ancestor after the script it runs when it's an
+// interpreter, e.g. "bin/dev" for "ruby bin/dev" or an executable script
+// started through its shebang; shells running a "-c" string or
+// interactively keep their own name
+func ancestorLabel(ctx context.Context, pid int, name string) string {
+	args, err := procinfo.CommandLine(ctx, pid)
+	if err != nil || len(args) == 0 {
+		return name
+	}
+	if !interpreters[name] && !interpreters[filepath.Base(args[0])] {
+		return name
+	}
+	for _, arg := range args[1:] {
+		if arg == "-c" {
+			return name
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if filepath.IsAbs(arg) {
+			return filepath.Base(arg)
+		}
+		return arg
+	}
+	return name
+}
+
+// tmuxPanes maps the shell PID of every tmux pane to "session:window.pane"
+func tmuxPanes(ctx context.Context) map[int]string {
+	panes := make(map[int]string)
+
+	cmd := exec.CommandContext(ctx, "tmux", "list-panes", "-a", "-F", "#{pane_pid} #{session_name}:#{window_index}.#{pane_index}")
+	output, err := cmd.Output()
+	if err != nil {
+		// tmux missing or no server running
+		return panes
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		pidStr, pane, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		if pid, err := strconv.Atoi(pidStr); err == nil {
+			panes[pid] = pane
+		}
+	}
+	return panes
+}
@@ -0,0 +1,149 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/atomicfile"
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// validName keeps snapshot names usable as file names
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// Snapshot is the set of servers running at a point in time
+type Snapshot struct {
+	Name    string         `json:"name"`
+	Taken   time.Time      `json:"taken"`
+	Servers []types.Server `json:"servers"`
+}
+
+// Change is a server present in both the snapshot and now whose port or
+// process changed
+type Change struct {
+	Before types.Server
+	After  types.Server
+}
+
+// Diff lists the differences between a snapshot and the current servers
+type Diff struct {
+	Started []types.Server
+	Stopped []types.Server
+	Changed []Change
+}
+
+// Empty reports whether nothing changed
+func (d Diff) Empty() bool {
+	return len(d.Started) == 0 && len(d.Stopped) == 0 && len(d.Changed) == 0
+}
+
+// path returns the file holding the named snapshot
+func path(name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("invalid snapshot name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	state, err := platform.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "snapshots", name+".json"), nil
+}
+
+// Save records servers under name, replacing any snapshot of that name, and
+// returns the file it was written to
+func Save(name string, servers []types.Server) (string, error) {
+	file, err := path(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(Snapshot{Name: name, Taken: time.Now(), Servers: servers}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := atomicfile.WriteFile(file, data, 0o644); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// Load reads the named snapshot
+func Load(name string) (*Snapshot, error) {
+	file, err := path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no snapshot named %q (save one with: lsrv snapshot save %s)", name, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	return &snap, nil
+}
+
+// Compare diffs the snapshot's servers against current ones. A process
+// still running under the same PID is the same server even if it moved to
+// another port; otherwise a server on the same port, repo and process that
+// got a new PID counts as restarted.
+func Compare(before, after []types.Server) Diff {
+	var diff Diff
+	matched := make([]bool, len(after))
+
+	// match pairs each old server with the first unmatched current server
+	// that same accepts, returning the old servers left without a pair
+	match := func(servers []types.Server, same func(a, b types.Server) bool) []types.Server {
+		var unmatched []types.Server
+		for _, old := range servers {
+			found := false
+			for i, cur := range after {
+				if matched[i] || !same(old, cur) {
+					continue
+				}
+				matched[i] = true
+				found = true
+				if old.PID != cur.PID || old.Port != cur.Port {
+					diff.Changed = append(diff.Changed, Change{Before: old, After: cur})
+				}
+				break
+			}
+			if !found {
+				unmatched = append(unmatched, old)
+			}
+		}
+		return unmatched
+	}
+
+	// Unchanged servers first, so a moved server can't claim their entry
+	remaining := match(before, func(a, b types.Server) bool {
+		return a.PID == b.PID && a.Port == b.Port
+	})
+	remaining = match(remaining, func(a, b types.Server) bool {
+		return a.PID == b.PID
+	})
+	diff.Stopped = match(remaining, func(a, b types.Server) bool {
+		return a.Port == b.Port && a.Repo == b.Repo && a.Process == b.Process
+	})
+
+	for i, cur := range after {
+		if !matched[i] {
+			diff.Started = append(diff.Started, cur)
+		}
+	}
+	return diff
+}
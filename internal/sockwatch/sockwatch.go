@@ -0,0 +1,60 @@
+// Package sockwatch tells watch mode when listening sockets may have
+// changed, so it can rescan right away instead of waiting for the next
+// poll. Linux compares the listening sockets from netlink socket
+// diagnostics, which is far cheaper than lsof; macOS gets kqueue
+// notifications when a tracked server process exits. Other systems are
+// unsupported.
+package sockwatch
+
+import "context"
+
+// Watcher signals on C whenever listening sockets may have changed.
+// Signals coalesce: C holds at most one pending signal however many
+// changes happened since it was last read.
+type Watcher struct {
+	C <-chan struct{}
+
+	src    source
+	cancel context.CancelFunc
+}
+
+// source is the OS-specific event source, in build-tagged
+// sockwatch_<goos>.go files
+type source interface {
+	// run reports changes through notify until ctx is done
+	run(ctx context.Context, notify func())
+
+	// track registers server processes whose exit is a change; sources
+	// that see every socket themselves ignore it
+	track(pids []int)
+}
+
+// New starts watching, returning errors.ErrUnsupported on systems without
+// an event source
+func New() (*Watcher, error) {
+	src, err := newSource()
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Watcher{C: c, src: src, cancel: cancel}
+	go src.run(ctx, func() {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	})
+	return w, nil
+}
+
+// Track registers the PIDs of the servers currently listed
+func (w *Watcher) Track(pids []int) {
+	w.src.track(pids)
+}
+
+// Close stops watching
+func (w *Watcher) Close() {
+	w.cancel()
+}
@@ -0,0 +1,72 @@
+//go:build darwin
+
+package sockwatch
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// kqueueSource gets a kqueue event when a tracked server process exits.
+// macOS has no cheap way to list sockets, so new servers are still found
+// by watch mode's regular interval.
+type kqueueSource struct {
+	kq int
+
+	mu      sync.Mutex
+	tracked map[int]bool
+}
+
+func newSource() (source, error) {
+	kq, err := unix.Kqueue()
+	if err != nil {
+		return nil, err
+	}
+	return &kqueueSource{kq: kq, tracked: make(map[int]bool)}, nil
+}
+
+func (s *kqueueSource) run(ctx context.Context, notify func()) {
+	defer unix.Close(s.kq)
+
+	// Wake up regularly to notice ctx ending
+	timeout := unix.NsecToTimespec(int64(500e6))
+	events := make([]unix.Kevent_t, 16)
+	for ctx.Err() == nil {
+		n, err := unix.Kevent(s.kq, nil, events, &timeout)
+		if err != nil && err != unix.EINTR {
+			return
+		}
+		if n <= 0 {
+			continue
+		}
+
+		s.mu.Lock()
+		for _, event := range events[:n] {
+			delete(s.tracked, int(event.Ident))
+		}
+		s.mu.Unlock()
+		notify()
+	}
+}
+
+// track asks for a one-shot exit event for each PID not tracked yet.
+// Processes that already exited fail to register and are skipped; the
+// next rescan drops them anyway.
+func (s *kqueueSource) track(pids []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, pid := range pids {
+		if s.tracked[pid] {
+			continue
+		}
+		var change unix.Kevent_t
+		unix.SetKevent(&change, pid, unix.EVFILT_PROC, unix.EV_ADD|unix.EV_ONESHOT)
+		change.Fflags = unix.NOTE_EXIT
+		if _, err := unix.Kevent(s.kq, []unix.Kevent_t{change}, nil, nil); err == nil {
+			s.tracked[pid] = true
+		}
+	}
+}
@@ -0,0 +1,134 @@
+//go:build linux
+
+package sockwatch
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"maps"
+	"syscall"
+	"time"
+)
+
+// pollInterval is how often the listening sockets are compared. One
+// netlink dump per family costs microseconds, unlike a lsof run.
+const pollInterval = 250 * time.Millisecond
+
+// Netlink socket diagnostics constants from linux/sock_diag.h and
+// linux/inet_diag.h
+const (
+	sockDiagByFamily = 20
+	tcpListen        = 10
+	diagReqLen       = 56 // sizeof(struct inet_diag_req_v2)
+	diagMsgLen       = 72 // sizeof(struct inet_diag_msg)
+)
+
+// listener identifies one listening socket; a restarted server gets a new
+// inode even on the same port
+type listener struct {
+	family uint8
+	port   uint16
+	inode  uint32
+}
+
+// diagSource compares the listening TCP sockets every pollInterval
+type diagSource struct {
+	last map[listener]bool
+}
+
+func newSource() (source, error) {
+	last, err := listeners()
+	if err != nil {
+		return nil, err
+	}
+	return &diagSource{last: last}, nil
+}
+
+func (s *diagSource) run(ctx context.Context, notify func()) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// A failed dump is treated as no change; the fallback interval
+		// still rescans
+		current, err := listeners()
+		if err != nil || maps.Equal(current, s.last) {
+			continue
+		}
+		s.last = current
+		notify()
+	}
+}
+
+// track is a no-op: every socket shows up in the dumps
+func (s *diagSource) track(pids []int) {}
+
+// listeners dumps the listening TCP sockets of both address families
+func listeners() (map[listener]bool, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_INET_DIAG)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+
+	found := make(map[listener]bool)
+	for _, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+		if err := dump(fd, family, found); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// dump requests the listening sockets of one family and reads the reply
+// until the kernel marks it done
+func dump(fd int, family uint8, found map[listener]bool) error {
+	req := make([]byte, syscall.NLMSG_HDRLEN+diagReqLen)
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], sockDiagByFamily)
+	binary.NativeEndian.PutUint16(req[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	body := req[syscall.NLMSG_HDRLEN:]
+	body[0] = family
+	body[1] = syscall.IPPROTO_TCP
+	binary.NativeEndian.PutUint32(body[4:8], 1<<tcpListen)
+
+	if err := syscall.Sendto(fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			switch msg.Header.Type {
+			case syscall.NLMSG_DONE:
+				return nil
+			case syscall.NLMSG_ERROR:
+				return errors.New("socket diagnostics request failed")
+			}
+			if len(msg.Data) < diagMsgLen {
+				continue
+			}
+			// struct inet_diag_msg: idiag_family, then the socket ID with
+			// the big-endian source port at 4, and idiag_inode at 68
+			found[listener{
+				family: msg.Data[0],
+				port:   binary.BigEndian.Uint16(msg.Data[4:6]),
+				inode:  binary.NativeEndian.Uint32(msg.Data[68:72]),
+			}] = true
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package sockwatch
+
+import "errors"
+
+func newSource() (source, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,198 @@
+// Package starthint suggests how to start a server that isn't running,
+// from where lsrv saw it run before and what its project uses to start
+package starthint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/audit"
+	"github.com/bshakr/lsrv/internal/porthistory"
+	"github.com/bshakr/lsrv/internal/runner"
+)
+
+// Hint is a shell command starting a project's server
+type Hint struct {
+	// Repo names the project
+	Repo string
+
+	// Dir is the directory to start it in
+	Dir string
+
+	// Command starts the server; empty when lsrv can't tell, leaving only
+	// the directory to go to
+	Command []string
+}
+
+// String renders the hint as a command line to paste, such as
+// "cd ~/src/myapp && bin/dev"
+func (h Hint) String() string {
+	line := "cd " + quote(abbreviateHome(h.Dir))
+	if len(h.Command) == 0 {
+		return line
+	}
+	args := make([]string, len(h.Command))
+	for i, arg := range h.Command {
+		args[i] = quote(arg)
+	}
+	return line + " && " + strings.Join(args, " ")
+}
+
+// For suggests how to start the server target names: a repository name,
+// or a port some repository's server was seen on. The directory comes
+// from runs started with lsrv, the audit log or the port history, and the
+// command from such a run, the project's own launcher or the command line
+// the server last ran with, in that order. It reports false when lsrv
+// never saw the project run or its directory is gone.
+func For(target string) (Hint, bool) {
+	history, _ := porthistory.Load()
+	repo := target
+	if port, err := strconv.Atoi(strings.TrimPrefix(target, ":")); err == nil {
+		if history == nil {
+			return Hint{}, false
+		}
+		seen := history.Seen(port)
+		if len(seen) == 0 {
+			return Hint{}, false
+		}
+		repo = seen[0].Repo
+	}
+
+	// Runs started with lsrv know both where and how, most recent first
+	runs, _ := runner.List()
+	for _, run := range runs {
+		if strings.EqualFold(run.Repo, repo) && exists(run.Dir) {
+			return Hint{Repo: run.Repo, Dir: run.Dir, Command: run.Command}, true
+		}
+	}
+
+	hint := Hint{Repo: repo}
+	var recorded []string
+	entries, _ := audit.Read()
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if strings.EqualFold(entry.Repo, repo) && entry.Dir != "" && exists(entry.Dir) {
+			hint.Dir, recorded = entry.Dir, entry.Command
+			break
+		}
+	}
+	if history != nil {
+		if dir, ok := history.Dir(repo); ok && exists(dir) && hint.Dir == "" {
+			hint.Dir = dir
+		}
+	}
+	if hint.Dir == "" {
+		return Hint{}, false
+	}
+
+	if hint.Command = Launcher(hint.Dir); hint.Command == nil {
+		hint.Command = recorded
+	}
+	return hint, true
+}
+
+// Launcher returns the command a project in dir is usually started with,
+// such as bin/dev or npm run dev, or nil when none is recognized
+func Launcher(dir string) []string {
+	if isExecutable(filepath.Join(dir, "bin", "dev")) {
+		return []string{"bin/dev"}
+	}
+	if scripts := packageScripts(dir); scripts != nil {
+		manager := packageManager(dir)
+		for _, name := range []string{"dev", "start"} {
+			if _, ok := scripts[name]; ok {
+				if name == "start" && manager != "bun" {
+					return []string{manager, "start"}
+				}
+				return []string{manager, "run", name}
+			}
+		}
+	}
+	if exists(filepath.Join(dir, "manage.py")) {
+		return []string{"python", "manage.py", "runserver"}
+	}
+	if isExecutable(filepath.Join(dir, "bin", "rails")) {
+		return []string{"bin/rails", "server"}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "mix.exs")); err == nil && strings.Contains(string(data), ":phoenix") {
+		return []string{"mix", "phx.server"}
+	}
+	return nil
+}
+
+// packageScripts returns the scripts of dir's package.json, or nil when it
+// has none
+func packageScripts(dir string) map[string]string {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return nil
+	}
+	return pkg.Scripts
+}
+
+// lockfiles name the package manager that wrote them
+var lockfiles = []struct{ file, manager string }{
+	{"pnpm-lock.yaml", "pnpm"},
+	{"yarn.lock", "yarn"},
+	{"bun.lock", "bun"},
+	{"bun.lockb", "bun"},
+}
+
+// packageManager returns the package manager a JavaScript project uses,
+// from its lockfile; npm when there is none other
+func packageManager(dir string) string {
+	for _, lock := range lockfiles {
+		if exists(filepath.Join(dir, lock.file)) {
+			return lock.manager
+		}
+	}
+	return "npm"
+}
+
+// exists reports whether path exists
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// isExecutable reports whether path is an executable file
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
+
+// abbreviateHome writes paths within the home directory with ~
+func abbreviateHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(home, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		if rel == "." {
+			return "~"
+		}
+		return "~/" + rel
+	}
+	return path
+}
+
+// quote single-quotes an argument for the shell when it needs quoting.
+// Paths starting with ~ are left bare so the shell expands them.
+func quote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`!*?[]{}()<>|&;#") {
+		return arg
+	}
+	if rest, ok := strings.CutPrefix(arg, "~/"); ok && !strings.ContainsAny(rest, "'") {
+		return "~/'" + rest + "'"
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,56 @@
+package timing
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Phase is a named, measured step of a run
+type Phase struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Recorder collects phase durations. A nil *Recorder is valid and records
+// nothing, so callers don't need to check whether timings were requested.
+type Recorder struct {
+	mu     sync.Mutex
+	phases []Phase
+}
+
+// Start begins timing a phase and returns a function that ends it
+func (r *Recorder) Start(name string) func() {
+	if r == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.phases = append(r.phases, Phase{Name: name, Duration: time.Since(start)})
+	}
+}
+
+// Phases returns the recorded phases in completion order
+func (r *Recorder) Phases() []Phase {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Phase(nil), r.phases...)
+}
+
+// Print writes an aligned per-phase summary with a total
+func (r *Recorder) Print(w io.Writer) {
+	var total time.Duration
+	for _, phase := range r.Phases() {
+		fmt.Fprintf(w, "%-12s %10s\n", phase.Name, phase.Duration.Round(time.Microsecond))
+		total += phase.Duration
+	}
+	fmt.Fprintf(w, "%-12s %10s\n", "total", total.Round(time.Microsecond))
+}
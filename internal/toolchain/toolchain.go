@@ -0,0 +1,115 @@
+// Package toolchain identifies the language runtime behind a server from
+// its executable path, seeing through version managers such as asdf, mise,
+// nvm, rbenv and pyenv
+package toolchain
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Runtime is the language runtime a process runs on
+type Runtime struct {
+	// Name is the runtime's executable name, such as "ruby" or "node"
+	Name string
+
+	// Version is the installed version, or "" when the path doesn't say
+	Version string
+}
+
+// String returns "ruby 3.3.0", or just the name without a version
+func (r Runtime) String() string {
+	if r.Version == "" {
+		return r.Name
+	}
+	return r.Name + " " + r.Version
+}
+
+// shimCommands are process names of version manager launchers, which say
+// nothing about the runtime they start
+var shimCommands = map[string]bool{
+	"asdf":       true,
+	"mise":       true,
+	"rtx":        true,
+	"volta-shim": true,
+	"rbenv":      true,
+	"pyenv":      true,
+	"nodenv":     true,
+}
+
+// IsShim reports whether a process name is a version manager launcher
+func IsShim(command string) bool {
+	return shimCommands[command]
+}
+
+// installLayouts locate the version in a version manager's install path.
+// Each pattern captures the tool (when the layout names it) and version.
+var installLayouts = []struct {
+	pattern *regexp.Regexp
+
+	// tool is the runtime for managers of a single language
+	tool string
+}{
+	// ~/.asdf/installs/ruby/3.3.0/bin/ruby, ~/.local/share/mise/installs/node/20.11.0/bin/node
+	{regexp.MustCompile(`/(?:\.asdf|mise|rtx)/installs/([^/]+)/([^/]+)/`), ""},
+	// ~/.nvm/versions/node/v20.11.0/bin/node
+	{regexp.MustCompile(`/\.nvm/versions/(node)/v?([^/]+)/`), ""},
+	// ~/.local/share/fnm/node-versions/v20.11.0/installation/bin/node
+	{regexp.MustCompile(`/fnm/node-versions/v?([^/]+)/`), "node"},
+	// ~/.volta/tools/image/node/20.11.0/bin/node
+	{regexp.MustCompile(`/\.volta/tools/image/([^/]+)/([^/]+)/`), ""},
+	// ~/.rbenv/versions/3.3.0/bin/ruby
+	{regexp.MustCompile(`/\.rbenv/versions/([^/]+)/`), "ruby"},
+	// ~/.pyenv/versions/3.12.1/bin/python3.12
+	{regexp.MustCompile(`/\.pyenv/versions/([^/]+)/`), "python"},
+	// ~/.nodenv/versions/20.11.0/bin/node
+	{regexp.MustCompile(`/\.nodenv/versions/([^/]+)/`), "node"},
+	// /opt/homebrew/Cellar/node/21.5.0/bin/node
+	{regexp.MustCompile(`/Cellar/([^/]+)/([^/]+)/`), ""},
+}
+
+// versionedName splits executables like "python3.12" or "ruby3.1"
+var versionedName = regexp.MustCompile(`^([a-z]+?)(\d+(?:\.\d+)*)$`)
+
+// toolNames maps version manager plugin and formula names to the runtime
+// executable they provide
+var toolNames = map[string]string{
+	"nodejs":  "node",
+	"golang":  "go",
+	"erlang":  "erl",
+	"python3": "python",
+}
+
+// FromExecutable identifies the runtime of an executable path
+func FromExecutable(path string) Runtime {
+	if path == "" {
+		return Runtime{}
+	}
+
+	base := filepath.Base(path)
+	name, version := base, ""
+	if m := versionedName.FindStringSubmatch(base); m != nil {
+		name, version = m[1], m[2]
+	}
+
+	slashed := filepath.ToSlash(path)
+	for _, layout := range installLayouts {
+		m := layout.pattern.FindStringSubmatch(slashed)
+		if m == nil {
+			continue
+		}
+		tool := layout.tool
+		if tool == "" {
+			tool, m = m[1], m[1:]
+		}
+		// Homebrew versions some formulae in the name: python@3.12
+		tool, _, _ = strings.Cut(tool, "@")
+		if mapped, ok := toolNames[tool]; ok {
+			tool = mapped
+		}
+		return Runtime{Name: tool, Version: strings.TrimPrefix(m[1], "v")}
+	}
+
+	return Runtime{Name: name, Version: version}
+}
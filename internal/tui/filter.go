@@ -0,0 +1,104 @@
+package tui
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// Underline on and off; unlike a full reset, turning off only the underline
+// keeps the row's color, bold and selection intact
+const (
+	markOn  = "\x1b[4m"
+	markOff = "\x1b[24m"
+)
+
+// filterFields are the parts of a server the filter searches, in the order
+// their columns appear
+func filterFields(server types.Server) []string {
+	name := server.Repo
+	if server.Service != "" {
+		name = server.Service
+	}
+	return []string{name, server.Branch, server.Process, strconv.Itoa(server.Port)}
+}
+
+// filterColumns are the table columns holding filterFields, by untranslated
+// header; services have their own names for the first and last
+var filterColumns = map[string]bool{
+	"REPO":    true,
+	"SERVICE": true,
+	"BRANCH":  true,
+	"PROCESS": true,
+	"URL":     true,
+	"ADDRESS": true,
+}
+
+// fuzzyMatch reports whether the characters of query appear in order, but
+// not necessarily together, across fields, ignoring case. Spaces in query
+// are ignored, so "web 3000" and "web3000" both match webapp on port 3000.
+func fuzzyMatch(query string, fields []string) bool {
+	remaining := []rune(normalizeQuery(query))
+	for _, field := range fields {
+		for _, r := range strings.ToLower(field) {
+			if len(remaining) == 0 {
+				return true
+			}
+			if r == remaining[0] {
+				remaining = remaining[1:]
+			}
+		}
+	}
+	return len(remaining) == 0
+}
+
+// normalizeQuery lowercases query and drops its spaces
+func normalizeQuery(query string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, query)
+}
+
+// markMatches underlines the characters of the filter in a row's cells.
+// A cell containing the whole filter, like the port for "3490", is marked
+// alone; otherwise characters are matched greedily from left to right like
+// fuzzyMatch.
+func markMatches(query string, headers, cells []string) {
+	normalized := normalizeQuery(query)
+	for i, header := range headers {
+		if !filterColumns[header] {
+			continue
+		}
+		if at := strings.Index(strings.ToLower(cells[i]), normalized); at >= 0 && len(strings.ToLower(cells[i])) == len(cells[i]) {
+			end := at + len(normalized)
+			cells[i] = cells[i][:at] + markOn + cells[i][at:end] + markOff + cells[i][end:]
+			return
+		}
+	}
+
+	remaining := []rune(normalized)
+	for i, header := range headers {
+		if len(remaining) == 0 {
+			return
+		}
+		if !filterColumns[header] {
+			continue
+		}
+
+		var b strings.Builder
+		for _, r := range cells[i] {
+			if len(remaining) > 0 && unicode.ToLower(r) == remaining[0] {
+				b.WriteString(markOn + string(r) + markOff)
+				remaining = remaining[1:]
+				continue
+			}
+			b.WriteRune(r)
+		}
+		cells[i] = b.String()
+	}
+}
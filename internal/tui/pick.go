@@ -0,0 +1,202 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/formatter"
+	"github.com/bshakr/lsrv/internal/types"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// PickConfig configures the multi-select picker
+type PickConfig struct {
+	Servers []types.Server
+
+	// Verb names the action in the header and confirmation, such as
+	// "Stop"
+	Verb string
+
+	// Format controls the table; its Width follows the terminal
+	Format formatter.Options
+}
+
+// pickKeys are the picker's key bindings, also listed in its help line
+type pickKeys struct {
+	Up      key.Binding
+	Down    key.Binding
+	Toggle  key.Binding
+	All     key.Binding
+	Confirm key.Binding
+	Quit    key.Binding
+}
+
+func (k pickKeys) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Toggle, k.All, k.Confirm, k.Quit}
+}
+
+func (k pickKeys) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+var defaultPickKeys = pickKeys{
+	Up:      key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
+	Down:    key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
+	Toggle:  key.NewBinding(key.WithKeys(" ", "x"), key.WithHelp("space", "select")),
+	All:     key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "select all/none")),
+	Confirm: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "confirm")),
+	Quit:    key.NewBinding(key.WithKeys("q", "esc", "ctrl+c"), key.WithHelp("q", "cancel")),
+}
+
+// Pick lists the servers with a checkbox each and returns the ones the
+// user selected and confirmed, in listing order. It returns none when the
+// user cancels.
+func Pick(cfg PickConfig) ([]types.Server, error) {
+	m := pickModel{cfg: cfg, keys: defaultPickKeys, help: help.New(), checked: make(map[string]bool)}
+	final, err := tea.NewProgram(m).Run()
+	if err != nil {
+		return nil, err
+	}
+	result := final.(pickModel)
+	if !result.done {
+		return nil, nil
+	}
+	return result.selection(), nil
+}
+
+type pickModel struct {
+	cfg  PickConfig
+	keys pickKeys
+	help help.Model
+
+	cursor  int
+	checked map[string]bool
+
+	// confirming asks once before acting on the whole selection
+	confirming bool
+
+	// done is set when the user confirmed the selection
+	done bool
+
+	width int
+}
+
+func (m pickModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m pickModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.help.Width = msg.Width
+
+	case tea.KeyMsg:
+		if m.confirming {
+			m.confirming = false
+			if msg.String() == "y" || msg.String() == "Y" {
+				m.done = true
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		switch {
+		case key.Matches(msg, m.keys.Quit):
+			return m, tea.Quit
+		case key.Matches(msg, m.keys.Up):
+			m.cursor = max(m.cursor-1, 0)
+		case key.Matches(msg, m.keys.Down):
+			m.cursor = min(m.cursor+1, len(m.cfg.Servers)-1)
+		case key.Matches(msg, m.keys.Toggle):
+			if m.cursor < len(m.cfg.Servers) {
+				k := serverKey(m.cfg.Servers[m.cursor])
+				m.checked[k] = !m.checked[k]
+			}
+		case key.Matches(msg, m.keys.All):
+			all := len(m.selection()) < len(m.cfg.Servers)
+			for _, server := range m.cfg.Servers {
+				m.checked[serverKey(server)] = all
+			}
+		case key.Matches(msg, m.keys.Confirm):
+			// Enter without a selection acts on the row under the cursor
+			if len(m.selection()) == 0 && m.cursor < len(m.cfg.Servers) {
+				m.checked[serverKey(m.cfg.Servers[m.cursor])] = true
+			}
+			if len(m.selection()) > 0 {
+				m.confirming = true
+			}
+		}
+	}
+	return m, nil
+}
+
+// selection returns the checked servers in listing order
+func (m pickModel) selection() []types.Server {
+	var selected []types.Server
+	for _, server := range m.cfg.Servers {
+		if m.checked[serverKey(server)] {
+			selected = append(selected, server)
+		}
+	}
+	return selected
+}
+
+func (m pickModel) View() string {
+	if m.done {
+		return ""
+	}
+
+	var b strings.Builder
+	header := lipgloss.NewStyle().Bold(true).Render(fmt.Sprintf("%s which servers?", m.cfg.Verb))
+	b.WriteString(header + "\n\n")
+
+	opts := m.cfg.Format
+	opts.Width = m.width
+	opts.Renderer = lipgloss.DefaultRenderer()
+	opts.Columns = append(opts.Columns, formatter.Column{Header: "", Value: m.checkCell})
+	var current string
+	if m.cursor < len(m.cfg.Servers) {
+		current = serverKey(m.cfg.Servers[m.cursor])
+	}
+	opts.Highlight = func(s types.Server) bool { return serverKey(s) == current }
+
+	var table bytes.Buffer
+	if err := formatter.Write(&table, m.cfg.Servers, opts); err != nil {
+		b.WriteString(fmt.Sprintf("error: %v\n", err))
+	}
+	b.WriteString(table.String())
+
+	if m.confirming {
+		selected := m.selection()
+		b.WriteString(fmt.Sprintf("\n%s %d server(s) (%s)? y/n\n", m.cfg.Verb, len(selected), describe(selected)))
+	} else {
+		b.WriteString(fmt.Sprintf("\n%d selected · %s\n", len(m.selection()), m.help.View(m.keys)))
+	}
+	return b.String()
+}
+
+// checkCell shows whether a server is selected
+func (m pickModel) checkCell(server types.Server) string {
+	if m.checked[serverKey(server)] {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+// describe lists servers by repo and port for the confirmation prompt
+func describe(servers []types.Server) string {
+	parts := make([]string, len(servers))
+	for i, server := range servers {
+		name := server.Repo
+		if server.Service != "" {
+			name = server.Service
+		}
+		parts[i] = fmt.Sprintf("%s:%d", name, server.Port)
+	}
+	return strings.Join(parts, ", ")
+}
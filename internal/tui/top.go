@@ -0,0 +1,307 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/formatter"
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/types"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// TopSort orders the rows of the top view
+type TopSort string
+
+const (
+	SortCPU    TopSort = "cpu"
+	SortMemory TopSort = "mem"
+)
+
+// busyCPU and hotCPU grade the CPU column: above busyCPU a server is doing
+// real work, above hotCPU it is likely stuck rebuilding or spinning
+const (
+	busyCPU = 20.0
+	hotCPU  = 80.0
+)
+
+// TopConfig configures the top view
+type TopConfig struct {
+	// Find runs one detection pass; it is called again every Interval
+	Find func() ([]types.Server, error)
+
+	Interval time.Duration
+
+	// Sort is the initial order, changed with the c and m keys
+	Sort TopSort
+
+	// Format controls the table; its Width follows the terminal
+	Format formatter.Options
+}
+
+// RunTop shows the servers sorted by their CPU or memory use until the user
+// quits. Each server counts its whole process tree, so workers, bundlers
+// and compilers it started are charged to it.
+func RunTop(cfg TopConfig) error {
+	m := topModel{cfg: cfg, sort: cfg.Sort, cpu: make(map[int]float64)}
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+// sampleMsg delivers a detection pass with the usage of each server's
+// process tree, by server PID
+type sampleMsg struct {
+	servers []types.Server
+	usage   map[int]procinfo.Usage
+	at      time.Time
+	err     error
+}
+
+// killedMsg reports the outcome of killing a server
+type killedMsg struct {
+	status string
+}
+
+type topModel struct {
+	cfg  TopConfig
+	sort TopSort
+
+	servers []types.Server
+	usage   map[int]procinfo.Usage
+
+	// cpu is each server's share of one core over the last interval, in
+	// percent; servers seen for the first time have none yet
+	cpu map[int]float64
+
+	// selected follows a server as the order changes
+	selected string
+
+	// confirming asks before killing the selected server
+	confirming bool
+
+	width   int
+	updated time.Time
+	status  string
+	err     error
+}
+
+func (m topModel) Init() tea.Cmd {
+	return m.sample
+}
+
+// sample runs one detection pass and reads the usage of every process
+func (m topModel) sample() tea.Msg {
+	servers, err := m.cfg.Find()
+	if err != nil {
+		return sampleMsg{err: err}
+	}
+	all, err := procinfo.AllUsage(context.Background())
+	if err != nil {
+		return sampleMsg{err: err}
+	}
+
+	usage := make(map[int]procinfo.Usage, len(servers))
+	for _, server := range servers {
+		usage[server.PID] = procinfo.TreeUsage(all, server.PID)
+	}
+	return sampleMsg{servers: servers, usage: usage, at: time.Now()}
+}
+
+func (m topModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case sampleMsg:
+		next := tea.Tick(m.cfg.Interval, func(time.Time) tea.Msg { return refreshMsg{} })
+		if msg.err != nil {
+			m.err = msg.err
+			return m, next
+		}
+		m.err = nil
+
+		// CPU use is the CPU time a tree gained since the last sample over
+		// the time that passed
+		cpu := make(map[int]float64, len(msg.usage))
+		if elapsed := msg.at.Sub(m.updated); !m.updated.IsZero() && elapsed > 0 {
+			for pid, usage := range msg.usage {
+				if prev, ok := m.usage[pid]; ok && usage.CPU >= prev.CPU {
+					cpu[pid] = float64(usage.CPU-prev.CPU) / float64(elapsed) * 100
+				}
+			}
+		}
+		m.servers, m.usage, m.cpu, m.updated = msg.servers, msg.usage, cpu, msg.at
+		m.sortServers()
+		if _, ok := m.selectedServer(); !ok && len(m.servers) > 0 {
+			m.selected = serverKey(m.servers[0])
+		}
+		return m, next
+
+	case refreshMsg:
+		return m, m.sample
+
+	case killedMsg:
+		m.status = msg.status
+		return m, m.sample
+	}
+	return m, nil
+}
+
+// handleKey applies a key press
+func (m topModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.confirming {
+		m.confirming = false
+		m.status = ""
+		server, ok := m.selectedServer()
+		if !ok || (msg.String() != "y" && msg.String() != "Y") {
+			return m, nil
+		}
+		return m, func() tea.Msg {
+			if err := control.Kill(server); err != nil {
+				return killedMsg{status: fmt.Sprintf("error: %v", err)}
+			}
+			return killedMsg{status: fmt.Sprintf("Sent SIGTERM to %s (pid %d)", server.Repo, server.PID)}
+		}
+	}
+
+	switch msg.String() {
+	case "q", "esc", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "c":
+		m.sort = SortCPU
+		m.sortServers()
+	case "m":
+		m.sort = SortMemory
+		m.sortServers()
+	case "r":
+		m.status = ""
+		return m, m.sample
+	case "x":
+		if server, ok := m.selectedServer(); ok {
+			m.confirming = true
+			m.status = fmt.Sprintf("Kill %s on port %d (pid %d)? y/n", server.Repo, server.Port, server.PID)
+		}
+	}
+	return m, nil
+}
+
+// move shifts the selection by delta rows
+func (m *topModel) move(delta int) {
+	if len(m.servers) == 0 {
+		return
+	}
+	index := 0
+	for i, server := range m.servers {
+		if serverKey(server) == m.selected {
+			index = i
+		}
+	}
+	index = min(max(index+delta, 0), len(m.servers)-1)
+	m.selected = serverKey(m.servers[index])
+}
+
+// sortServers orders the servers by the current sort, heaviest first. Ties
+// keep detection order so rows don't jump around while idle.
+func (m *topModel) sortServers() {
+	value := func(server types.Server) float64 {
+		if m.sort == SortMemory {
+			return float64(m.usage[server.PID].RSS)
+		}
+		return m.cpu[server.PID]
+	}
+	sort.SliceStable(m.servers, func(i, j int) bool { return value(m.servers[i]) > value(m.servers[j]) })
+}
+
+// selectedServer returns the server under the cursor
+func (m topModel) selectedServer() (types.Server, bool) {
+	for _, server := range m.servers {
+		if serverKey(server) == m.selected {
+			return server, true
+		}
+	}
+	return types.Server{}, false
+}
+
+func (m topModel) View() string {
+	var b strings.Builder
+
+	header := "lsrv top"
+	if m.updated.IsZero() {
+		header += "  measuring..."
+	} else {
+		order := "CPU"
+		if m.sort == SortMemory {
+			order = "memory"
+		}
+		header += fmt.Sprintf("  %d server(s) by %s, updated %s", len(m.servers), order, m.updated.Format("15:04:05"))
+	}
+	b.WriteString(header + "\n\n")
+
+	if !m.updated.IsZero() {
+		opts := m.cfg.Format
+		opts.Width = m.width
+		opts.Renderer = lipgloss.DefaultRenderer()
+		opts.Columns = append(opts.Columns,
+			formatter.Column{Header: "CPU", Value: m.cpuCell, Style: m.cpuStyle},
+			formatter.Column{Header: "MEM", Value: m.memCell},
+		)
+		opts.Highlight = func(s types.Server) bool { return serverKey(s) == m.selected }
+
+		var table bytes.Buffer
+		if err := formatter.Write(&table, m.servers, opts); err != nil {
+			m.err = err
+		}
+		b.WriteString(table.String())
+	}
+
+	if m.err != nil {
+		b.WriteString(fmt.Sprintf("\nerror: %v\n", m.err))
+	} else if m.status != "" {
+		b.WriteString("\n" + m.status + "\n")
+	}
+	b.WriteString("\n↑/↓ select · c sort by CPU · m sort by memory · x kill · r refresh · q quit\n")
+	return b.String()
+}
+
+// cpuCell shows CPU use once two samples were taken
+func (m topModel) cpuCell(server types.Server) string {
+	cpu, ok := m.cpu[server.PID]
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", cpu)
+}
+
+// cpuStyle colors busy servers yellow and hot ones red
+func (m topModel) cpuStyle(server types.Server, style lipgloss.Style) lipgloss.Style {
+	switch cpu := m.cpu[server.PID]; {
+	case cpu >= hotCPU:
+		return style.Foreground(lipgloss.Color("1"))
+	case cpu >= busyCPU:
+		return style.Foreground(lipgloss.Color("3"))
+	}
+	return style
+}
+
+// memCell shows resident memory in binary units, or bytes when configured
+func (m topModel) memCell(server types.Server) string {
+	usage, ok := m.usage[server.PID]
+	if !ok || usage.RSS == 0 {
+		return "-"
+	}
+	return m.cfg.Format.Units.Bytes(usage.RSS)
+}
@@ -0,0 +1,495 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/formatter"
+	"github.com/bshakr/lsrv/internal/health"
+	"github.com/bshakr/lsrv/internal/types"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Config configures the interactive mode
+type Config struct {
+	// Find runs one detection pass; it is called again every Interval
+	Find func() ([]types.Server, error)
+
+	Interval time.Duration
+
+	// Format controls the table; its Width follows the terminal
+	Format formatter.Options
+}
+
+// Run shows the live server table until the user quits. Detection and
+// health checks run in the background and stream into the table as they
+// finish, so a slow endpoint never blocks rendering.
+func Run(cfg Config) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := model{
+		cfg:     cfg,
+		ctx:     ctx,
+		checker: health.NewChecker(health.DefaultWorkers, health.DefaultTimeout),
+		checks:  make(map[string]check),
+		spinner: spinner.New(spinner.WithSpinner(spinner.Dot)),
+	}
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+// check is the health of one server: the latest result, if any, and
+// whether a newer check is in flight
+type check struct {
+	result  *health.Result
+	pending bool
+}
+
+// serversMsg delivers a detection pass along with the actions defined for
+// each server directory
+type serversMsg struct {
+	servers []types.Server
+	actions map[string]config.Actions
+	err     error
+}
+
+// healthMsg delivers one finished health check
+type healthMsg struct {
+	key    string
+	result health.Result
+}
+
+// refreshMsg starts the next detection pass
+type refreshMsg struct{}
+
+// actionMsg reports the outcome of a key action such as opening a server
+type actionMsg struct {
+	status string
+}
+
+type model struct {
+	cfg     Config
+	ctx     context.Context
+	checker *health.Checker
+
+	servers []types.Server
+	actions map[string]config.Actions
+	checks  map[string]check
+	cursor  int
+
+	// details shows more about the selected server below the table
+	details bool
+
+	// filter narrows the table to servers fuzzily matching it; filtering
+	// is set while the "/" prompt takes keystrokes
+	filter    string
+	filtering bool
+
+	spinner spinner.Model
+	width   int
+	updated time.Time
+	status  string
+	err     error
+}
+
+// serverKey identifies a server across refreshes
+func serverKey(server types.Server) string {
+	return strconv.Itoa(server.PID) + ":" + strconv.Itoa(server.Port)
+}
+
+func (m model) Init() tea.Cmd {
+	return tea.Batch(m.detect, m.spinner.Tick)
+}
+
+// detect runs one detection pass in the background. Actions are re-read
+// each pass so edits to .lsrv.yml show up without restarting.
+func (m model) detect() tea.Msg {
+	servers, err := m.cfg.Find()
+	if err != nil {
+		return serversMsg{err: err}
+	}
+
+	actions := make(map[string]config.Actions)
+	for _, server := range servers {
+		if _, ok := actions[server.CWD]; ok {
+			continue
+		}
+		// A broken .lsrv.yml only hides its actions here; "lsrv run"
+		// reports the error
+		actions[server.CWD], _ = control.Actions(server)
+	}
+	return serversMsg{servers: servers, actions: actions}
+}
+
+// checkHealth probes server in the background
+func (m model) checkHealth(server types.Server) tea.Cmd {
+	return func() tea.Msg {
+		return healthMsg{key: serverKey(server), result: m.checker.Check(m.ctx, server)}
+	}
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case serversMsg:
+		next := tea.Tick(m.cfg.Interval, func(time.Time) tea.Msg { return refreshMsg{} })
+		if msg.err != nil {
+			m.err = msg.err
+			return m, next
+		}
+		m.err = nil
+		m.servers = msg.servers
+		m.actions = msg.actions
+		m.updated = time.Now()
+		m.cursor = min(m.cursor, max(len(m.visible())-1, 0))
+
+		// Re-check every app server; rows keep their last result until the
+		// new one arrives, and only rows never checked show a spinner
+		cmds := []tea.Cmd{next}
+		checks := make(map[string]check)
+		for _, server := range m.servers {
+			if server.Service != "" {
+				continue
+			}
+			key := serverKey(server)
+			c := m.checks[key]
+			if !c.pending {
+				c.pending = true
+				cmds = append(cmds, m.checkHealth(server))
+			}
+			checks[key] = c
+		}
+		m.checks = checks
+		return m, tea.Batch(cmds...)
+
+	case healthMsg:
+		// Results for servers gone since the check started are dropped
+		if _, ok := m.checks[msg.key]; ok {
+			m.checks[msg.key] = check{result: &msg.result}
+		}
+		return m, nil
+
+	case refreshMsg:
+		return m, m.detect
+
+	case actionMsg:
+		m.status = msg.status
+		return m, nil
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// handleKey applies a key press
+func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.filtering {
+		return m.handleFilterKey(msg)
+	}
+
+	switch msg.String() {
+	case "esc":
+		// Clear the filter first; a second esc quits
+		if m.filter != "" {
+			m.filter = ""
+			return m, nil
+		}
+		return m, tea.Quit
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "/":
+		m.filtering = true
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, max(len(m.visible())-1, 0))
+	case "r":
+		m.status = ""
+		return m, m.detect
+	case "d":
+		m.details = !m.details
+	case "o", "enter":
+		if server, ok := m.selected(); ok {
+			return m, func() tea.Msg {
+				if err := control.Open(server); err != nil {
+					return actionMsg{status: fmt.Sprintf("error: %v", err)}
+				}
+				return actionMsg{status: "Opened " + server.DisplayURL()}
+			}
+		}
+	default:
+		if server, ok := m.selected(); ok {
+			for _, bound := range bindActions(m.actions[server.CWD]) {
+				if bound.key == msg.String() {
+					return m, runAction(server, bound.Action)
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// handleFilterKey edits the filter while the "/" prompt is open. Enter
+// keeps the filter and returns the keys to their usual actions; esc drops it.
+func (m model) handleFilterKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.filter = ""
+		m.filtering = false
+	case tea.KeyEnter:
+		m.filtering = false
+	case tea.KeyUp, tea.KeyCtrlP:
+		m.cursor = max(m.cursor-1, 0)
+	case tea.KeyDown, tea.KeyCtrlN:
+		m.cursor = min(m.cursor+1, max(len(m.visible())-1, 0))
+	case tea.KeyBackspace:
+		if runes := []rune(m.filter); len(runes) > 0 {
+			m.filter = string(runes[:len(runes)-1])
+		}
+	case tea.KeyCtrlU:
+		m.filter = ""
+	case tea.KeyRunes, tea.KeySpace:
+		m.filter += string(msg.Runes)
+		m.cursor = 0
+	}
+	m.cursor = min(m.cursor, max(len(m.visible())-1, 0))
+	return m, nil
+}
+
+// visible returns the servers matching the filter
+func (m model) visible() []types.Server {
+	if m.filter == "" {
+		return m.servers
+	}
+	var matched []types.Server
+	for _, server := range m.servers {
+		if fuzzyMatch(m.filter, filterFields(server)) {
+			matched = append(matched, server)
+		}
+	}
+	return matched
+}
+
+// boundAction is an action with its interactive key
+type boundAction struct {
+	config.Action
+	key string
+}
+
+// bindActions assigns keys to actions: an action's own key if it sets one,
+// otherwise the next free digit from 1 to 9
+func bindActions(actions config.Actions) []boundAction {
+	var bound []boundAction
+	digit := 1
+	for _, action := range actions {
+		key := action.Key
+		if key == "" {
+			if digit > 9 {
+				continue
+			}
+			key = strconv.Itoa(digit)
+			digit++
+		}
+		bound = append(bound, boundAction{Action: action, key: key})
+	}
+	return bound
+}
+
+// runAction hands the terminal to action until it exits and the user
+// presses Enter, so its output can be read before the table returns
+func runAction(server types.Server, action config.Action) tea.Cmd {
+	cmd := &pausedCmd{Cmd: control.ActionCommand(server, action)}
+	return tea.Exec(cmd, func(err error) tea.Msg {
+		var exitErr *exec.ExitError
+		switch {
+		case errors.As(err, &exitErr):
+			return actionMsg{status: fmt.Sprintf("%s exited with status %d", action.Name, exitErr.ExitCode())}
+		case err != nil:
+			return actionMsg{status: fmt.Sprintf("error: %s: %v", action.Name, err)}
+		}
+		return actionMsg{status: action.Name + " finished"}
+	})
+}
+
+// pausedCmd runs a command and then waits for Enter before returning
+type pausedCmd struct {
+	*exec.Cmd
+}
+
+func (c *pausedCmd) SetStdin(r io.Reader)  { c.Stdin = r }
+func (c *pausedCmd) SetStdout(w io.Writer) { c.Stdout = w }
+func (c *pausedCmd) SetStderr(w io.Writer) { c.Stderr = w }
+
+func (c *pausedCmd) Run() error {
+	err := c.Cmd.Run()
+	fmt.Fprint(c.Stdout, "\nPress Enter to return to lsrv")
+	bufio.NewReader(c.Stdin).ReadString('\n')
+	return err
+}
+
+// selected returns the server under the cursor
+func (m model) selected() (types.Server, bool) {
+	visible := m.visible()
+	if m.cursor < 0 || m.cursor >= len(visible) {
+		return types.Server{}, false
+	}
+	return visible[m.cursor], true
+}
+
+func (m model) View() string {
+	var b strings.Builder
+
+	header := "lsrv"
+	visible := m.visible()
+	if !m.updated.IsZero() {
+		count := fmt.Sprintf("%d server(s)", len(m.servers))
+		if m.filter != "" {
+			count = fmt.Sprintf("%d of %d server(s) matching %q", len(visible), len(m.servers), m.filter)
+		}
+		header += fmt.Sprintf("  %s, updated %s", count, m.updated.Format("15:04:05"))
+	} else {
+		header += "  " + m.spinner.View() + " finding servers..."
+	}
+	b.WriteString(header + "\n\n")
+
+	if !m.updated.IsZero() {
+		opts := m.cfg.Format
+		opts.Width = m.width
+		opts.Renderer = lipgloss.DefaultRenderer()
+		opts.Columns = append(opts.Columns, formatter.Column{
+			Header: "HEALTH",
+			Value:  m.healthCell,
+			Style:  m.healthStyle,
+		})
+		selected, _ := m.selected()
+		opts.Highlight = func(s types.Server) bool { return serverKey(s) == serverKey(selected) }
+		if m.filter != "" {
+			opts.Mark = func(_ types.Server, headers, cells []string) { markMatches(m.filter, headers, cells) }
+		}
+
+		var table bytes.Buffer
+		if len(visible) == 0 && m.filter != "" {
+			table.WriteString(fmt.Sprintf("No servers match %q.\n", m.filter))
+		} else if err := formatter.Write(&table, visible, opts); err != nil {
+			m.err = err
+		}
+		b.WriteString(table.String())
+
+		if server, ok := m.selected(); ok && m.details {
+			b.WriteString("\n" + detailsView(server))
+		}
+	}
+
+	if m.err != nil {
+		b.WriteString(fmt.Sprintf("\nerror: %v\n", m.err))
+	} else if m.status != "" {
+		b.WriteString("\n" + m.status + "\n")
+	}
+	if m.filtering {
+		b.WriteString("\n/" + m.filter + "█\n")
+		b.WriteString("\ntype to filter · ↑/↓ select · enter keep · esc clear\n")
+		return b.String()
+	}
+	help := "↑/↓ select · / filter · o open · d details · r refresh · q quit"
+	if m.filter != "" {
+		help = "↑/↓ select · / edit filter · esc clear filter · o open · d details · r refresh · q quit"
+	}
+	if server, ok := m.selected(); ok {
+		for _, bound := range bindActions(m.actions[server.CWD]) {
+			help += fmt.Sprintf(" · %s %s", bound.key, bound.Name)
+		}
+	}
+	b.WriteString("\n" + help + "\n")
+	return b.String()
+}
+
+// detailsView describes the selected server beyond what fits in the table:
+// its directory, command, what started it and its direnv or devenv
+// environment
+func detailsView(server types.Server) string {
+	var b strings.Builder
+	row := func(label, value string) {
+		fmt.Fprintf(&b, "  %-14s %s\n", label, value)
+	}
+
+	row("dir", server.CWD)
+	if server.CommandLine != "" {
+		row("command", server.CommandLine)
+	}
+	if len(server.StartedBy) > 0 {
+		row("started by", strings.Join(server.StartedBy, "→"))
+	}
+
+	env := server.Env
+	if env == nil {
+		row("env", "no .envrc or devenv.nix")
+		return b.String()
+	}
+	switch {
+	case env.Loaded == nil:
+		row("env", fmt.Sprintf("%s (%s), can't read the server's environment", env.Tool, env.File))
+	case *env.Loaded:
+		row("env", fmt.Sprintf("%s (%s), loaded", env.Tool, env.File))
+	default:
+		row("env", fmt.Sprintf("%s (%s), NOT loaded: the server started outside it", env.Tool, env.File))
+	}
+
+	names := make([]string, 0, len(env.Vars))
+	for name := range env.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		row(name, env.Vars[name])
+	}
+	return b.String()
+}
+
+// healthCell shows a spinner until a server's first check completes
+func (m model) healthCell(server types.Server) string {
+	c := m.checks[serverKey(server)]
+	if c.result == nil {
+		return m.spinner.View()
+	}
+	return c.result.String()
+}
+
+// healthStyle colors health results: green up, red error, gray down
+func (m model) healthStyle(server types.Server, style lipgloss.Style) lipgloss.Style {
+	c := m.checks[serverKey(server)]
+	if c.result == nil {
+		return style
+	}
+	switch c.result.State {
+	case health.StateUp:
+		return style.Foreground(lipgloss.Color("2"))
+	case health.StateError:
+		return style.Foreground(lipgloss.Color("1"))
+	}
+	return style.Foreground(lipgloss.Color("8"))
+}
@@ -0,0 +1,280 @@
+// Package tunnel finds the public URLs that ngrok, cloudflared and
+// tailscale funnel forward to local ports, by asking each agent's local
+// API rather than guessing from its command line
+package tunnel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Providers reported in types.Tunnel
+const (
+	ProviderNgrok       = "ngrok"
+	ProviderCloudflared = "cloudflared"
+	ProviderTailscale   = "tailscale"
+)
+
+// DefaultTimeout bounds each query of an agent
+const DefaultTimeout = time.Second
+
+// Agent is a listening tunnel agent process, found among lsof's listeners
+type Agent struct {
+	PID     int
+	Command string
+	Port    int
+}
+
+// IsAgent reports whether a listening process is a tunnel agent whose
+// local API Find can query. lsof cuts command names to nine characters,
+// so cloudflared shows up as "cloudflar".
+func IsAgent(command string) bool {
+	return command == "ngrok" || strings.HasPrefix(command, "cloudflar")
+}
+
+// Find returns the public URLs forwarding to each local port: from the
+// ngrok and cloudflared agents listed and, when the tailscale CLI is
+// installed, from tailscale funnel. Agents that don't answer are skipped.
+func Find(ctx context.Context, agents []Agent, timeout time.Duration) map[int][]types.Tunnel {
+	client := &http.Client{Timeout: timeout}
+	found := make(map[int][]types.Tunnel)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	add := func(tunnels map[int][]types.Tunnel) {
+		mu.Lock()
+		defer mu.Unlock()
+		for port, list := range tunnels {
+			found[port] = append(found[port], list...)
+		}
+	}
+
+	// cloudflared listens on its metrics port and sometimes others; one
+	// query per process is enough
+	seen := make(map[int]bool)
+	for _, agent := range agents {
+		switch {
+		case agent.Command == "ngrok":
+		case !seen[agent.PID]:
+			seen[agent.PID] = true
+		default:
+			continue
+		}
+		wg.Add(1)
+		go func(agent Agent) {
+			defer wg.Done()
+			if agent.Command == "ngrok" {
+				add(ngrokTunnels(ctx, client, agent.Port))
+			} else {
+				add(cloudflaredTunnels(ctx, client, agent))
+			}
+		}(agent)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		add(funnelTunnels(ctx))
+	}()
+
+	wg.Wait()
+	return found
+}
+
+// getJSON decodes the response to a GET of a local agent API
+func getJSON(ctx context.Context, client *http.Client, rawURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", rawURL, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// ngrokTunnels asks the ngrok agent's API, served on its web inspection
+// port (4040 by default), where each tunnel forwards
+func ngrokTunnels(ctx context.Context, client *http.Client, port int) map[int][]types.Tunnel {
+	var resp struct {
+		Tunnels []struct {
+			PublicURL string `json:"public_url"`
+			Config    struct {
+				Addr string `json:"addr"`
+			} `json:"config"`
+		} `json:"tunnels"`
+	}
+	if err := getJSON(ctx, client, fmt.Sprintf("http://127.0.0.1:%d/api/tunnels", port), &resp); err != nil {
+		return nil
+	}
+
+	tunnels := make(map[int][]types.Tunnel)
+	for _, tunnel := range resp.Tunnels {
+		if local, ok := localPort(tunnel.Config.Addr); ok && tunnel.PublicURL != "" {
+			tunnels[local] = append(tunnels[local], types.Tunnel{Provider: ProviderNgrok, URL: tunnel.PublicURL})
+		}
+	}
+	return tunnels
+}
+
+// cloudflaredTunnels finds where a cloudflared process forwards. Quick
+// tunnels (--url) get a random trycloudflare.com hostname that only the
+// metrics server's /quicktunnel endpoint knows; named tunnels list their
+// hostnames in the ingress rules of their config file.
+func cloudflaredTunnels(ctx context.Context, client *http.Client, agent Agent) map[int][]types.Tunnel {
+	args, err := procinfo.CommandLine(ctx, agent.PID)
+	if err != nil {
+		return nil
+	}
+
+	if target := flagValue(args, "url"); target != "" {
+		local, ok := localPort(target)
+		if !ok {
+			return nil
+		}
+		var resp struct {
+			Hostname string `json:"hostname"`
+		}
+		if err := getJSON(ctx, client, fmt.Sprintf("http://127.0.0.1:%d/quicktunnel", agent.Port), &resp); err != nil || resp.Hostname == "" {
+			return nil
+		}
+		return map[int][]types.Tunnel{local: {{Provider: ProviderCloudflared, URL: "https://" + resp.Hostname}}}
+	}
+
+	path := flagValue(args, "config")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, ".cloudflared", "config.yml")
+	}
+	return ingressTunnels(path)
+}
+
+// ingressTunnels reads the hostname and local service of each ingress rule
+// in a cloudflared config file
+func ingressTunnels(path string) map[int][]types.Tunnel {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var cfg struct {
+		Ingress []struct {
+			Hostname string `yaml:"hostname"`
+			Service  string `yaml:"service"`
+		} `yaml:"ingress"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil
+	}
+
+	tunnels := make(map[int][]types.Tunnel)
+	for _, rule := range cfg.Ingress {
+		// The catch-all rule has no hostname and usually an http_status
+		if local, ok := localPort(rule.Service); ok && rule.Hostname != "" {
+			tunnels[local] = append(tunnels[local], types.Tunnel{Provider: ProviderCloudflared, URL: "https://" + rule.Hostname})
+		}
+	}
+	return tunnels
+}
+
+// funnelTunnels reads tailscale's serve configuration and keeps the
+// handlers exposed to the internet with funnel; plain serve handlers are
+// only reachable within the tailnet
+func funnelTunnels(ctx context.Context) map[int][]types.Tunnel {
+	if _, err := exec.LookPath("tailscale"); err != nil {
+		return nil
+	}
+	output, err := exec.CommandContext(ctx, "tailscale", "funnel", "status", "--json").Output()
+	if err != nil {
+		return nil
+	}
+	var status struct {
+		Web map[string]struct {
+			Handlers map[string]struct {
+				Proxy string `json:"Proxy"`
+			} `json:"Handlers"`
+		} `json:"Web"`
+		AllowFunnel map[string]bool `json:"AllowFunnel"`
+	}
+	if err := json.Unmarshal(output, &status); err != nil {
+		return nil
+	}
+
+	tunnels := make(map[int][]types.Tunnel)
+	for hostPort, web := range status.Web {
+		if !status.AllowFunnel[hostPort] {
+			continue
+		}
+		base := "https://" + strings.TrimSuffix(hostPort, ":443")
+		for mount, handler := range web.Handlers {
+			if local, ok := localPort(handler.Proxy); ok {
+				tunnels[local] = append(tunnels[local], types.Tunnel{Provider: ProviderTailscale, URL: base + strings.TrimSuffix(mount, "/")})
+			}
+		}
+	}
+	return tunnels
+}
+
+// localPort extracts the port of a local forwarding target, written as
+// "3000", "localhost:3000" or "http://127.0.0.1:3000". Targets on other
+// machines don't belong to a local server.
+func localPort(target string) (int, bool) {
+	if port, err := strconv.Atoi(target); err == nil {
+		return port, port > 0
+	}
+	if !strings.Contains(target, "://") {
+		target = "tcp://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Port() == "" {
+		return 0, false
+	}
+	switch host := u.Hostname(); host {
+	case "localhost", "":
+	default:
+		if ip := net.ParseIP(host); ip == nil || !(ip.IsLoopback() || ip.IsUnspecified()) {
+			return 0, false
+		}
+	}
+	port, err := strconv.Atoi(u.Port())
+	return port, err == nil && port > 0
+}
+
+// flagValue returns the value of a --name VALUE or --name=VALUE argument
+func flagValue(args []string, name string) string {
+	for i, arg := range args {
+		for _, prefix := range []string{"--", "-"} {
+			if arg == prefix+name && i+1 < len(args) {
+				return args[i+1]
+			}
+			if value, ok := strings.CutPrefix(arg, prefix+name+"="); ok {
+				return value
+			}
+		}
+	}
+	return ""
+}
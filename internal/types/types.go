@@ -1,15 +1,262 @@
 package types
 
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // Server represents a running development server
 type Server struct {
-	Repo    string
-	Branch  string
-	Process string
-	Port    int
-	PID     int
-	CWD     string
+	Repo    string `json:"repo"`
+	Branch  string `json:"branch"`
+	Process string `json:"process"`
+	Port    int    `json:"port"`
+	PID     int    `json:"pid"`
+	CWD     string `json:"cwd"`
+	User    string `json:"user"`
+
+	// UID is the numeric ID of the account owning the process, or -1 when
+	// unknown
+	UID int `json:"uid"`
+
+	// Package is the server's directory within its repository, such as
+	// "packages/web" in a monorepo; empty at the repository root
+	Package string `json:"package,omitempty"`
+
+	// Host names the machine the server runs on with --fleet, or is empty
+	Host string `json:"host,omitempty"`
+
+	// HostAddress reaches a remote host's servers in URLs
+	HostAddress string `json:"-"`
+
+	// Workers counts additional processes sharing the listening socket,
+	// such as Puma or gunicorn workers
+	Workers int `json:"workers,omitempty"`
+
+	// AuxPorts are helper ports the server's process opened besides Port,
+	// such as a Vite HMR websocket, folded into this row
+	AuxPorts []int `json:"aux_ports,omitempty"`
+
+	// PrimaryPort is set on rows for an auxiliary port, shown separately
+	// with --all-ports, to the port of the app it belongs to
+	PrimaryPort int `json:"primary_port,omitempty"`
+
+	// LastCommit is the time of the branch's latest commit, when requested
+	LastCommit *time.Time `json:"last_commit,omitempty"`
+
+	// Runtime is the language runtime and version, such as "ruby 3.3.0",
+	// when requested and known
+	Runtime string `json:"runtime,omitempty"`
+
+	// Env is the direnv or devenv setup of the server's directory, when
+	// requested and one exists
+	Env *Env `json:"env,omitempty"`
+
+	// Nix is "nix" or "devenv" when the server was started inside a Nix
+	// development shell, such as nix develop, nix-shell or a devenv shell
+	Nix string `json:"nix,omitempty"`
+
+	// RemoteURL is the web page of the repo's origin remote, when requested
+	RemoteURL string `json:"remote_url,omitempty"`
+
+	// Service names the auxiliary service (e.g., "postgres") for listeners
+	// found with --services; empty for app servers
+	Service string `json:"service,omitempty"`
+
+	// Bind is the address the server listens on, such as "*", "127.0.0.1"
+	// or "[::1]"
+	Bind string `json:"bind,omitempty"`
+
+	// LANURL reaches the server from other devices on the network, when
+	// requested with --lan and the server isn't bound to loopback only
+	LANURL string `json:"lan_url,omitempty"`
+
+	// Tunnels are public URLs forwarding to the server through ngrok,
+	// cloudflared or tailscale funnel, when requested with --tunnels
+	Tunnels []Tunnel `json:"tunnels,omitempty"`
+
+	// Firewall tells whether the local firewall lets other devices reach
+	// LANURL, when requested with --firewall
+	Firewall *Firewall `json:"firewall,omitempty"`
+
+	// FriendlyURL is a hostname-based URL (e.g., http://myapp.test) when
+	// puma-dev, dnsmasq or /etc/hosts maps one to this server
+	FriendlyURL string `json:"friendly_url,omitempty"`
+
+	// CustomURL is built from the config's URL template for the server's
+	// framework or process, such as a Storybook story path
+	CustomURL string `json:"custom_url,omitempty"`
+
+	// Framework is the detected server framework, such as "phoenix" or
+	// "spring-boot"
+	Framework string `json:"framework,omitempty"`
+
+	// CommandLine is the process's full command line, when requested
+	CommandLine string `json:"command_line,omitempty"`
+
+	// Paths are useful URL paths for the server's framework, such as
+	// "/graphql" for Apollo or "/docs" for FastAPI
+	Paths []string `json:"paths,omitempty"`
+
+	// Current marks servers running in the work tree lsrv was started in
+	Current bool `json:"current,omitempty"`
+
+	// Status tells whether the server needs attention
+	Status Status `json:"status,omitempty"`
+
+	// Label names the port after the service the repo declares for it in
+	// docker-compose.yml, a Procfile or .lsrv.yml, such as "web" or "db",
+	// or else after the IDE run configuration that launched the server
+	Label string `json:"label,omitempty"`
+
+	// Session is the tmux pane, terminal or editor the server runs under
+	Session string `json:"session,omitempty"`
+
+	// StartedBy is the chain of ancestors that launched the server,
+	// outermost first, such as ["tmux", "zsh", "bin/dev"], when requested
+	StartedBy []string `json:"started_by,omitempty"`
+
+	// TTFB is the time to the first byte of the server's root page, when
+	// measured with --latency and the server answered; JSON carries it as
+	// ttfb_ms
+	TTFB time.Duration `json:"-"`
+
+	// Uptime is how long the server's process has run and Memory the
+	// resident memory of its process tree in bytes, when requested; JSON
+	// carries them as uptime_seconds and memory_bytes
+	Uptime time.Duration `json:"-"`
+	Memory uint64        `json:"-"`
+
+	// Protocol is the probed protocol ("http", "grpc", "websocket" or
+	// "tcp") when --probe is set
+	Protocol string `json:"protocol,omitempty"`
+
+	// Backlog is the state of the server's accept queue, when requested
+	// with --backlog on Linux
+	Backlog *Backlog `json:"backlog,omitempty"`
+
+	// DevContainer is the dev container the server runs in, when its port
+	// is mapped between the container and the host
+	DevContainer *DevContainer `json:"dev_container,omitempty"`
 }
 
+// DevContainer is the dev container a server runs in and how its port
+// maps between the container and the host
+type DevContainer struct {
+	// Name is the devcontainer.json name, else the container's
+	Name string `json:"name"`
+
+	// ContainerPort is the port the server listens on in the container
+	ContainerPort int `json:"container_port"`
+
+	// HostPort reaches the server from the host's browser
+	HostPort int `json:"host_port"`
+}
+
+// Backlog is the accept queue of a listening socket: connections the
+// kernel has completed, waiting for the server to accept them. A full
+// queue makes new connections hang until the server catches up.
+type Backlog struct {
+	// Queued is the number of connections waiting to be accepted
+	Queued int `json:"queued"`
+
+	// Limit is the queue's size from listen(2); 0 when unknown
+	Limit int `json:"limit,omitempty"`
+}
+
+// Saturated reports whether the queue is full, so new connections are
+// dropped or delayed
+func (b Backlog) Saturated() bool {
+	return b.Limit > 0 && b.Queued >= b.Limit
+}
+
+// String renders the queue as "3/511", or "3" when the limit is unknown
+func (b Backlog) String() string {
+	if b.Limit == 0 {
+		return strconv.Itoa(b.Queued)
+	}
+	return strconv.Itoa(b.Queued) + "/" + strconv.Itoa(b.Limit)
+}
+
+// Env describes a direnv or devenv setup found for a server's directory
+type Env struct {
+	// Tool is "direnv" or "devenv"
+	Tool string `json:"tool"`
+
+	// File is the .envrc or devenv.nix that sets up the environment
+	File string `json:"file"`
+
+	// Loaded tells whether the server's environment shows the setup was
+	// applied when it started; nil when the environment can't be read
+	Loaded *bool `json:"loaded,omitempty"`
+
+	// Vars holds selected variables from the server's environment, with
+	// URLs reduced to their host
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+// Tunnel is a public URL forwarding to a local server
+type Tunnel struct {
+	// Provider is "ngrok", "cloudflared" or "tailscale"
+	Provider string `json:"provider"`
+
+	URL string `json:"url"`
+}
+
+// FirewallState is the verdict of a firewall check
+type FirewallState string
+
+const (
+	FirewallOpen    FirewallState = "open"
+	FirewallBlocked FirewallState = "blocked"
+
+	// FirewallUnknown means the rules couldn't be read, typically without
+	// root, or decide per connection like macOS prompting for unsigned apps
+	FirewallUnknown FirewallState = "unknown"
+)
+
+// Firewall describes what the local firewall does with connections from
+// other devices to a server's port
+type Firewall struct {
+	State FirewallState `json:"state"`
+
+	// By names the firewall that decided, such as "ufw" or "pf"
+	By string `json:"by,omitempty"`
+
+	// Detail explains the verdict, such as the rule that matched
+	Detail string `json:"detail,omitempty"`
+}
+
+// Status summarizes whether a server needs attention
+type Status string
+
+const (
+	StatusHealthy Status = "healthy"
+
+	// StatusStale marks servers on a branch without recent commits
+	StatusStale Status = "stale"
+
+	// StatusZombie marks servers whose working directory was deleted or
+	// moved, such as servers left running in a removed worktree
+	StatusZombie Status = "zombie"
+
+	// StatusConflict marks servers sharing a port with another process,
+	// e.g. one bound to 127.0.0.1 and another to [::1]
+	StatusConflict Status = "conflict"
+
+	// StatusUnusual marks servers on a port their repo doesn't usually
+	// run on, often because another process took the usual one
+	StatusUnusual Status = "unusual"
+
+	// StatusReserved marks servers on a port reserved for another repo
+	// with "lsrv reserve"
+	StatusReserved Status = "reserved"
+)
+
 // ProjectType represents the detected project type
 type ProjectType string
 
@@ -27,3 +274,113 @@ const (
 	ProjectTypeElixir  ProjectType = "elixir"
 	ProjectTypeUnknown ProjectType = "unknown"
 )
+
+// URL returns the local HTTP URL for the server, as lsrv reaches it for
+// health checks; DisplayURL is the one to open
+func (s Server) URL() string {
+	return fmt.Sprintf("http://%s:%d", s.host(), s.Port)
+}
+
+// LocalURL is URL with the scheme of the protocol --probe found, such as
+// grpc://localhost:50051, so output read by tools doesn't pass a gRPC or
+// raw TCP port off as a web page
+func (s Server) LocalURL() string {
+	return fmt.Sprintf("%s://%s:%d", s.scheme(), s.host(), s.Port)
+}
+
+// scheme returns the URL scheme of the server's probed protocol, http
+// unless it was found to speak something else
+func (s Server) scheme() string {
+	switch s.Protocol {
+	case "grpc":
+		return "grpc"
+	case "websocket":
+		return "ws"
+	case "tcp":
+		return "tcp"
+	}
+	return "http"
+}
+
+// host returns "localhost" unless the server listens on one specific
+// non-loopback address, which localhost would not reach. Servers on a
+// remote host listening on all addresses use the host's address; loopback
+// ones stay on localhost, reached through an SSH tunnel.
+func (s Server) host() string {
+	addr, err := netip.ParseAddr(strings.Trim(s.Bind, "[]"))
+	if s.HostAddress != "" && (err != nil || addr.IsUnspecified()) {
+		return s.HostAddress
+	}
+	if err != nil || addr.IsLoopback() || addr.IsUnspecified() {
+		return "localhost"
+	}
+	if addr.Is6() {
+		return "[" + addr.String() + "]"
+	}
+	return addr.String()
+}
+
+// Links returns the URLs of the server's framework paths
+func (s Server) Links() []string {
+	var links []string
+	for _, path := range s.Paths {
+		links = append(links, s.baseURL()+path)
+	}
+	return links
+}
+
+// ExpandURL fills the {host} and {port} placeholders of a URL template,
+// with the port a browser reaches the server on
+func (s Server) ExpandURL(template string) string {
+	return strings.NewReplacer("{host}", s.host(), "{port}", strconv.Itoa(s.BrowserPort())).Replace(template)
+}
+
+// BrowserPort is the port a browser on the host reaches the server on:
+// Port, or the host port forwarded to it when the server runs in a dev
+// container lsrv runs in too
+func (s Server) BrowserPort() int {
+	if s.DevContainer != nil && s.DevContainer.HostPort != 0 {
+		return s.DevContainer.HostPort
+	}
+	return s.Port
+}
+
+// MarshalJSON includes the derived URL and links alongside the stored
+// fields, and writes durations and sizes in the units of the JSON schema
+func (s Server) MarshalJSON() ([]byte, error) {
+	type server Server
+	return json.Marshal(struct {
+		server
+		URL           string   `json:"url"`
+		Links         []string `json:"links,omitempty"`
+		TTFBMS        float64  `json:"ttfb_ms,omitempty"`
+		UptimeSeconds int64    `json:"uptime_seconds,omitempty"`
+		MemoryBytes   uint64   `json:"memory_bytes,omitempty"`
+	}{server(s), s.LocalURL(), s.Links(), Milliseconds(s.TTFB), int64(s.Uptime / time.Second), s.Memory})
+}
+
+// Milliseconds returns d in milliseconds with microsecond precision, the
+// unit lsrv's machine-readable output uses for latencies
+func Milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// DisplayURL returns the configured custom URL, else the friendly URL when
+// one is known, otherwise URL. Ports probed as non-HTTP use a matching
+// scheme so they aren't mistaken for something a browser can open.
+func (s Server) DisplayURL() string {
+	if s.CustomURL != "" {
+		return s.CustomURL
+	}
+	return s.baseURL()
+}
+
+// baseURL is DisplayURL without the custom URL, which may carry a path
+// that framework paths can't be appended to
+func (s Server) baseURL() string {
+	scheme := s.scheme()
+	if scheme == "http" && s.FriendlyURL != "" {
+		return s.FriendlyURL
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, s.host(), s.BrowserPort())
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/formatter"
+	"github.com/bshakr/lsrv/internal/icons"
+	"github.com/bshakr/lsrv/internal/tui"
+	"github.com/bshakr/lsrv/internal/types"
+	"github.com/charmbracelet/x/term"
+)
+
+// runKill stops the servers matching a repo name or port
+func runKill(args []string) int {
+	plan := func(server types.Server) string {
+		return fmt.Sprintf("Would stop %s (pid %d) on port %d", server.Repo, server.PID, server.Port)
+	}
+	return runServerAction("kill", "Stop the servers matching TARGET with SIGTERM", "Stop", args, plan, func(server types.Server) error {
+		if err := control.Kill(server); err != nil {
+			return err
+		}
+		fmt.Printf("Stopped %s (pid %d) on port %d\n", server.Repo, server.PID, server.Port)
+		return nil
+	})
+}
+
+// runRestart restarts the servers matching a repo name or port
+func runRestart(args []string) int {
+	plan := func(server types.Server) string {
+		return fmt.Sprintf("Would restart %s (pid %d) on port %d in %s", server.Repo, server.PID, server.Port, server.CWD)
+	}
+	return runServerAction("restart", "Restart the servers matching TARGET with the same command line", "", args, plan, func(server types.Server) error {
+		run, err := control.Restart(server)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restarted %s as pid %d, logs: %s\n", server.Repo, run.PID, run.LogPath)
+		return nil
+	})
+}
+
+// runOpen opens the servers matching a repo name or port in the browser
+func runOpen(args []string) int {
+	return runServerAction("open", "Open the URL of the servers matching TARGET in the browser", "", args, nil, control.Open)
+}
+
+// runServerAction resolves the single TARGET argument and applies action to
+// every matching server. Actions that stop or start processes pass plan to
+// describe them for --dry-run and safe mode instead of acting. With a
+// pick verb, running without TARGET on a terminal lets the user select
+// several servers instead.
+func runServerAction(name, description, pick string, args []string, plan func(types.Server) string, action func(types.Server) error) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var dryRun, interactive bool
+	if plan != nil {
+		fs.BoolVar(&dryRun, "dry-run", false, "Show what would be done without doing it")
+	}
+	if pick != "" {
+		fs.BoolVar(&interactive, "interactive", false, "Select the servers from a list (the default without TARGET)")
+		fs.BoolVar(&interactive, "i", false, "Shorthand for --interactive")
+	}
+	fs.Usage = func() {
+		switch {
+		case pick != "":
+			fmt.Fprintf(os.Stderr, "Usage: lsrv %s [--dry-run] <repo|port>\n", name)
+			fmt.Fprintf(os.Stderr, "       lsrv %s [--dry-run] [--interactive]\n", name)
+		case plan != nil:
+			fmt.Fprintf(os.Stderr, "Usage: lsrv %s [--dry-run] <repo|port>\n", name)
+		default:
+			fmt.Fprintf(os.Stderr, "Usage: lsrv %s <repo|port>\n", name)
+		}
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, description)
+		if pick != "" {
+			fmt.Fprintln(os.Stderr, "Without TARGET, pick any number of servers from a list and confirm once.")
+		}
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	picking := pick != "" && len(positional) == 0
+	if !picking && (len(positional) != 1 || interactive) {
+		fs.Usage()
+		return 2
+	}
+	if picking && !(term.IsTerminal(os.Stdin.Fd()) && term.IsTerminal(os.Stdout.Fd())) {
+		return exitWithError("%s needs a repo or port when not run in a terminal", name)
+	}
+
+	servers, err := detectServers()
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	var matched []types.Server
+	if picking {
+		if len(servers) == 0 {
+			fmt.Println("No servers running")
+			return 0
+		}
+		cfg, _ := config.Load()
+		matched, err = tui.Pick(tui.PickConfig{
+			Servers: servers,
+			Verb:    pick,
+			Format:  formatter.Options{Format: formatter.FormatTable, Icons: icons.NewSet(cfg, false)},
+		})
+		if err != nil {
+			return exitWithError("%s: %v", name, err)
+		}
+		if len(matched) == 0 {
+			return 0
+		}
+	} else {
+		matched = control.Match(servers, positional[0])
+		if len(matched) == 0 && name == "kill" {
+			return exitWithError("no server matches %q", positional[0])
+		} else if len(matched) == 0 {
+			return exitWithError("%v", noServerMatches(positional[0]))
+		}
+	}
+
+	if plan != nil && (dryRun || control.SafeMode) {
+		for _, server := range matched {
+			fmt.Println(plan(server))
+		}
+		if !dryRun {
+			return exitWithError("%v", control.ErrSafeMode)
+		}
+		return 0
+	}
+
+	for _, server := range matched {
+		if err := action(server); err != nil {
+			return exitWithError("%s %s on port %d: %v", name, server.Repo, server.Port, err)
+		}
+	}
+	return 0
+}
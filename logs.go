@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/runner"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// logPollInterval is how often a followed log file is checked for new output
+const logPollInterval = 250 * time.Millisecond
+
+// runLogs prints the output of a server started with "lsrv start", falling
+// back to well-known framework log files for servers started elsewhere
+func runLogs(args []string) int {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	follow := fs.Bool("f", false, "Follow the log as it grows")
+	lines := fs.Int("n", 50, "Number of trailing lines to show")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv logs <repo|port> [-f] [-n LINES]")
+		fmt.Fprintln(os.Stderr, "")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *lines < 0 {
+		exitWithError("-n must be 0 or more")
+		return 2
+	}
+
+	path, err := resolveLogPath(positional[0])
+	if err != nil {
+		return exitWithError("%v", err)
+	}
+
+	if err := tailFile(os.Stdout, path, *lines, *follow); err != nil {
+		return exitWithError("reading %s: %v", path, err)
+	}
+	return 0
+}
+
+// resolveLogPath finds the log file for target, preferring lsrv-captured
+// output over guessed framework logs
+func resolveLogPath(target string) (string, error) {
+	runs, err := runner.List()
+	if err != nil {
+		return "", err
+	}
+
+	// Detection failures still leave recorded runs to search
+	servers, _ := detectServers()
+	matched := control.Match(servers, target)
+
+	if run, ok := runForServers(runs, matched); ok {
+		return run.LogPath, nil
+	}
+
+	// The server may have exited; its captured output is still useful
+	for _, run := range runs {
+		if strings.EqualFold(run.Repo, target) {
+			return run.LogPath, nil
+		}
+	}
+
+	for _, server := range matched {
+		if path := runner.FindProjectLog(server.CWD); path != "" {
+			fmt.Fprintf(os.Stderr, "Not started by lsrv, showing %s\n", path)
+			return path, nil
+		}
+	}
+
+	if len(matched) == 0 {
+		return "", fmt.Errorf("no server or recorded run matches %q", target)
+	}
+	return "", fmt.Errorf("no logs found for %q (start it with 'lsrv start' to capture output)", target)
+}
+
+// maxAncestors bounds the walk up a server's process tree looking for the
+// run that started it
+const maxAncestors = 16
+
+// runForServers returns the run that started one of servers: one with the
+// server's PID, else one whose process is an ancestor of it, as a shell or
+// package manager wrapping the server. Only when neither matches does a run
+// from the same directory count, as several servers may start there.
+func runForServers(runs []runner.Run, servers []types.Server) (runner.Run, bool) {
+	for _, server := range servers {
+		for _, run := range runs {
+			if run.PID == server.PID {
+				return run, true
+			}
+		}
+	}
+
+	ctx := context.Background()
+	for _, server := range servers {
+		pid := server.PID
+		for range maxAncestors {
+			parent, _, err := procinfo.Parent(ctx, pid)
+			if err != nil || parent <= 1 {
+				break
+			}
+			for _, run := range runs {
+				if run.PID == parent {
+					return run, true
+				}
+			}
+			pid = parent
+		}
+	}
+
+	for _, server := range servers {
+		for _, run := range runs {
+			if run.Dir == server.CWD {
+				return run, true
+			}
+		}
+	}
+	return runner.Run{}, false
+}
+
+// tailFile writes the last n lines of path to w and, if follow is set, keeps
+// copying new output until interrupted
+func tailFile(w io.Writer, path string, n int, follow bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	offset, err := writeLastLines(w, f, n)
+	if err != nil || !follow {
+		return err
+	}
+
+	for {
+		time.Sleep(logPollInterval)
+
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+
+		// Start over if the file was truncated (e.g., log rotation)
+		if info.Size() < offset {
+			offset = 0
+		}
+		if info.Size() == offset {
+			continue
+		}
+
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		copied, err := io.Copy(w, f)
+		if err != nil {
+			return err
+		}
+		offset += copied
+	}
+}
+
+// writeLastLines writes the final n lines of f to w by reading backwards in
+// chunks, so large logs don't have to be read in full. It returns the file
+// size at the time of reading.
+func writeLastLines(w io.Writer, f *os.File, n int) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+
+	const chunkSize = 8192
+	var tail []byte
+	pos := size
+
+	// Read one extra newline so the first kept line is complete
+	for pos > 0 && bytes.Count(tail, []byte("\n")) <= n {
+		readSize := min(int64(chunkSize), pos)
+		pos -= readSize
+
+		chunk := make([]byte, readSize)
+		if _, err := f.ReadAt(chunk, pos); err != nil {
+			return 0, err
+		}
+		tail = append(chunk, tail...)
+	}
+
+	// Drop a trailing newline before counting back n lines
+	trimmed := bytes.TrimSuffix(tail, []byte("\n"))
+	lines := bytes.Split(trimmed, []byte("\n"))
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	if len(lines) > 0 && !(len(lines) == 1 && len(lines[0]) == 0) {
+		if _, err := w.Write(append(bytes.Join(lines, []byte("\n")), '\n')); err != nil {
+			return 0, err
+		}
+	}
+	return size, nil
+}
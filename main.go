@@ -1,27 +1,111 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
 
+	"github.com/bshakr/lsrv/internal/atomicfile"
+	"github.com/bshakr/lsrv/internal/capability"
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/depgraph"
 	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/fleet"
 	"github.com/bshakr/lsrv/internal/formatter"
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/hyperlink"
+	"github.com/bshakr/lsrv/internal/i18n"
+	"github.com/bshakr/lsrv/internal/icons"
+	"github.com/bshakr/lsrv/internal/ignore"
 	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/timing"
+	"github.com/bshakr/lsrv/internal/types"
+	"github.com/bshakr/lsrv/pkg/schema"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
 	"github.com/felixge/fgprof"
 )
 
 const version = "0.3.0"
 
 func main() {
+	// An unsupported locale just leaves messages in English
+	_ = i18n.Set(i18n.Detect())
+
+	// Subcommands take over argument parsing entirely
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			control.Source = os.Args[1]
+			control.SafeMode = safeMode()
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
 	// CLI flags
 	helpFlag := flag.Bool("help", false, "Show help message")
 	flag.BoolVar(helpFlag, "h", false, "Show help message (shorthand)")
 	versionFlag := flag.Bool("version", false, "Show version information")
 	flag.BoolVar(versionFlag, "v", false, "Show version information (shorthand)")
 	profileFlag := flag.String("profile", "", "Write fgprof profile to file (e.g., --profile=lsrv.prof)")
-	flag.Parse()
+	formatFlag := flag.String("format", "", "Output format: table, plain, markdown, json, csv, alfred, raycast, dot, mermaid or openmetrics (default table on a terminal, else plain)")
+	outputFlag := flag.String("output", "", "Write output to file instead of stdout (e.g., --output=servers.json)")
+	noTruncateFlag := flag.Bool("no-truncate", false, "Show full repo and branch names regardless of terminal width")
+	noPagerFlag := flag.Bool("no-pager", false, "Print long listings directly instead of through $PAGER")
+	allUsersFlag := flag.Bool("all-users", false, "Include servers owned by other users (requires root for full results)")
+	sudoFlag := flag.Bool("sudo", false, "Enumerate sockets through sudo to include other users' and root's servers")
+	servicesFlag := flag.Bool("services", false, "Also list databases and auxiliary services (postgres, redis, ...)")
+	asciiFlag := flag.Bool("ascii", false, "Use plain text tags like [ruby] instead of Nerd Font icons")
+	timingsFlag := flag.Bool("timings", false, "Print per-phase durations to stderr")
+	debugFlag := flag.Bool("debug", false, "Print what lsrv's privileges leave out, and which phases skip other users' servers, to stderr")
+	accessibleFlag := flag.Bool("accessible", false, "Spell out server status in a STATUS column instead of relying on color")
+	cmdlineFlag := flag.Bool("cmdline", false, "Show each server's full command line")
+	envFlag := flag.Bool("env", false, "Show each server's direnv or devenv setup and whether it was loaded")
+	runtimeFlag := flag.Bool("runtime-version", false, "Show each server's runtime and version, seeing through asdf/mise/nvm shims")
+	noGitFlag := flag.Bool("no-git", false, "Skip git entirely; show directory names and no branches")
+	lastCommitFlag := flag.Bool("last-commit", false, "Show the age of each branch's latest commit")
+	sessionFlag := flag.Bool("session", false, "Show the tmux pane, terminal or editor each server runs in")
+	startedByFlag := flag.Bool("started-by", false, "Show what launched each server, such as tmux→zsh→bin/dev")
+	packageFlag := flag.Bool("package", false, "Show each server's directory within its repository, for monorepos")
+	maxDepthFlag := flag.Int("max-depth", 0, "Look at most N directories above a server's directory for its repository (0 for no limit)")
+	timeoutFlag := flag.Duration("timeout", defaultTimeout, "Give up on detection after this long (0 for no limit)")
+	dumpRawFlag := flag.String("dump-raw", "", "Save lsof's raw output to FILE for bug reports")
+	watchFlag := flag.Duration("watch", 0, "Redraw the list every DURATION (e.g. 2s) until interrupted")
+	fleetFlag := flag.Bool("fleet", false, "Also list servers on the hosts in the config file, over SSH")
+	onChangeFlag := flag.Bool("on-change", false, "With --watch, redraw as soon as listening sockets change, at most once a second")
+	probeFlag := flag.Bool("probe", false, "Connect to each port to tag gRPC, websocket-only and raw TCP servers")
+	repoNameFlag := flag.String("repo-name", "", "Where repo names come from, in priority order (config,origin,upstream,toplevel)")
+	lanFlag := flag.Bool("lan", false, "Show a LAN URL for servers reachable from other devices")
+	mdnsFlag := flag.Bool("mdns", false, "Like --lan, but use HOSTNAME.local instead of the IP address")
+	latencyFlag := flag.Bool("latency", false, "Show a LATENCY column with each server's time to first byte")
+	backlogFlag := flag.Bool("backlog", false, "Show a BACKLOG column with how full each server's accept queue is (Linux)")
+	tunnelsFlag := flag.Bool("tunnels", false, "Show the ngrok, cloudflared or tailscale funnel URL forwarding to each server")
+	firewallFlag := flag.Bool("firewall", false, "Like --lan, and check whether the firewall blocks other devices")
+	interactiveFlag := flag.Bool("interactive", false, "Browse servers in a live table with health checks")
+	flag.BoolVar(interactiveFlag, "i", false, "Browse servers in a live table with health checks (shorthand)")
+	currentFirstFlag := flag.Bool("current-first", false, "List servers running in the current repository first")
+	allPortsFlag := flag.Bool("all-ports", false, "List HMR and helper ports of JavaScript dev servers as their own rows")
+	noIgnoreFlag := flag.Bool("no-ignore", false, "Show servers in directories listed in the ignore file")
+	hereFlag := flag.Bool("here", false, "Only show servers running in the current repository or its worktrees")
+	sinceFlag := flag.Duration("since", 0, "Only show servers started within DURATION (e.g. 30m)")
+	featureOnlyFlag := flag.Bool("only-feature-branches", false, "Hide servers running on main branches (main, master, production)")
+	langFlag := flag.String("lang", "", "Language for messages and table headers (default from LANG)")
+	dedupFlag := flag.String("dedup", "", "Which listeners share a row: key (repo, branch, process, port), pid, socket or none")
+	schemaFlag := flag.Bool("schema", false, "Print the JSON Schema of --format=json output")
+
+	// Flags may follow an optional directory argument ("lsrv . --lan")
+	args, _ := parseInterspersed(flag.CommandLine, os.Args[1:])
+
+	if *langFlag != "" {
+		if err := i18n.Set(*langFlag); err != nil {
+			os.Exit(exitWithError("%v", err))
+		}
+	}
 
 	if *versionFlag {
 		fmt.Printf("lsrv version %s\n", version)
@@ -33,12 +117,34 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *schemaFlag {
+		os.Stdout.Write(schema.JSONSchema())
+		os.Exit(0)
+	}
+
+	// Borders and colors only help on a terminal; pipes and files get text
+	// grep and cut can work with
+	format := formatter.FormatTable
+	if *formatFlag != "" {
+		var err error
+		if format, err = formatter.ParseFormat(*formatFlag); err != nil {
+			os.Exit(exitWithError("%v", err))
+		}
+	} else if !*interactiveFlag && (*outputFlag != "" || !term.IsTerminal(os.Stdout.Fd())) {
+		format = formatter.FormatPlain
+	}
+
+	cfg, err := config.Load()
+	warnConfigIssues(err)
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
 	// Start profiling if requested
 	if *profileFlag != "" {
 		f, err := os.Create(*profileFlag)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error: could not create profile file: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitWithError("could not create profile file: %v", err))
 		}
 		defer f.Close()
 
@@ -55,13 +161,276 @@ func main() {
 		os.Exit(1)
 	}
 
-	servers, err := detector.FindServers()
+	repoNames, err := repoNameSources(*repoNameFlag, cfg)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: finding servers: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitWithError("%v", err))
+	}
+
+	dedup, err := dedupStrategy(*dedupFlag, cfg)
+	if err != nil {
+		os.Exit(exitWithError("%v", err))
+	}
+
+	if *maxDepthFlag < 0 {
+		os.Exit(exitWithError("--max-depth must be 0 or more"))
+	}
+	if *sinceFlag < 0 {
+		os.Exit(exitWithError("--since must be a positive duration, such as 30m"))
+	}
+
+	scope := ""
+	if *hereFlag {
+		scope = "."
+	}
+	if len(args) > 1 {
+		exitWithError("expected at most one directory, got %q", args)
+		os.Exit(2)
+	} else if len(args) == 1 {
+		scope = args[0]
+	}
+
+	// Servers in the work tree lsrv runs in are marked wherever it's started
+	var currentDir string
+	if wd, err := os.Getwd(); err == nil {
+		currentDir, _ = git.WorkTreeRoot(wd)
+	}
+
+	var scopeDirs []string
+	if scope != "" {
+		scopeDirs, err = git.Worktrees(context.Background(), scope)
+		if err != nil {
+			os.Exit(exitWithError("%v", err))
+		}
+	}
+
+	// Naming a directory explicitly shows it even if it's ignored
+	var ignored *ignore.List
+	if scope == "" && !*noIgnoreFlag {
+		if ignored, err = ignore.Load(); err != nil {
+			printWarning("%v, ignoring nothing", err)
+		}
+	}
+
+	// Elevating only makes sense to see listeners beyond our own
+	if *sudoFlag && !platform.IsRoot() {
+		*allUsersFlag = true
+	} else if *allUsersFlag && !platform.IsRoot() {
+		printWarning("--all-users without root may miss other users' servers; add --sudo for full results")
+	}
+
+	var timings *timing.Recorder
+	if *timingsFlag {
+		timings = &timing.Recorder{}
+	}
+
+	report := &detector.Report{}
+	var skipBranches []string
+	if *featureOnlyFlag {
+		skipBranches = cfg.MainBranchNames()
+	}
+
+	detectOpts := detector.Options{
+		Session:      *sessionFlag,
+		StartedBy:    *startedByFlag,
+		LastCommit:   *lastCommitFlag && !*noGitFlag,
+		NoGit:        *noGitFlag,
+		RepoNames:    repoNames,
+		Dedup:        dedup,
+		URLTemplates: cfg.URLs,
+		Dirs:         scopeDirs,
+		SkipBranches: skipBranches,
+		Since:        *sinceFlag,
+		Ignore:       ignored,
+		CurrentDir:   currentDir,
+		CurrentFirst: *currentFirstFlag,
+		AllPorts:     *allPortsFlag,
+		CommandLine:  *cmdlineFlag,
+		Runtime:      *runtimeFlag,
+		Env:          *envFlag,
+		EnvVars:      cfg.EnvVars,
+		AllUsers:     *allUsersFlag,
+		Services:     *servicesFlag,
+		Sudo:         *sudoFlag && !platform.IsRoot(),
+		Probe:        *probeFlag,
+		Latency:      *latencyFlag,
+		Usage:        format == formatter.FormatJSON,
+		Backlog:      *backlogFlag,
+		LAN:          *lanFlag || *mdnsFlag || *firewallFlag,
+		MDNS:         *mdnsFlag,
+		Firewall:     *firewallFlag,
+		Tunnels:      *tunnelsFlag,
+		MaxDepth:     *maxDepthFlag,
+		Timings:      timings,
+		Report:       report,
+	}
+
+	// Privileges limit what detection sees without failing, so say what
+	// they leave out
+	if *debugFlag {
+		caps := capability.Current()
+		if detectOpts.Sudo {
+			caps.AllSockets = true // lsof runs as root through sudo
+		}
+		caps.Print(os.Stderr, detector.LimitedPhases(detectOpts))
+	}
+
+	var mainBranches []string
+	if cfg.HighlightMainBranches {
+		mainBranches = cfg.MainBranchNames()
+	}
+
+	opts := formatter.Options{
+		Format:         format,
+		NoTruncate:     *noTruncateFlag,
+		ShowSession:    *sessionFlag,
+		ShowStartedBy:  *startedByFlag,
+		ShowPackage:    *packageFlag,
+		ShowUser:       *allUsersFlag,
+		ShowLastCommit: *lastCommitFlag,
+		ShowCommand:    *cmdlineFlag,
+		ShowRuntime:    *runtimeFlag,
+		ShowEnv:        *envFlag,
+		ShowLatency:    *latencyFlag,
+		ShowBacklog:    *backlogFlag,
+		ShowLAN:        *lanFlag || *mdnsFlag || *firewallFlag,
+		ShowTunnels:    *tunnelsFlag,
+		Accessible:     *accessibleFlag,
+		Icons:          icons.NewSet(cfg, *asciiFlag),
+		MainBranches:   mainBranches,
+		BranchStyles:   cfg.Branches,
+		Units:          formatter.UnitsFrom(cfg.Units, string(format)),
+	}
+
+	// Clickable links only make sense in a table shown on a capable terminal
+	if format == formatter.FormatTable && *outputFlag == "" && term.IsTerminal(os.Stdout.Fd()) && hyperlink.Supported() {
+		opts.Hyperlinks = true
+		detectOpts.RemoteURL = !*noGitFlag
+	}
+
+	// Remote hosts list with the options that change what is found or shown
+	var fleetArgs []string
+	if *fleetFlag {
+		if *interactiveFlag || *watchFlag > 0 {
+			os.Exit(exitWithError("--fleet cannot be combined with --watch or --interactive"))
+		}
+		if len(cfg.Hosts) == 0 {
+			os.Exit(exitWithError("--fleet needs hosts in the config file (see \"hosts\" in the README)"))
+		}
+		for _, host := range cfg.Hosts {
+			if host.Name == "" || host.SSH == "" {
+				os.Exit(exitWithError("every host in the config file needs a name and an ssh destination"))
+			}
+		}
+		for _, passed := range []struct {
+			name string
+			set  bool
+		}{
+			{"--services", *servicesFlag}, {"--all-users", *allUsersFlag}, {"--no-git", *noGitFlag},
+			{"--cmdline", *cmdlineFlag}, {"--last-commit", *lastCommitFlag}, {"--runtime-version", *runtimeFlag},
+			{"--env", *envFlag}, {"--session", *sessionFlag}, {"--latency", *latencyFlag},
+			{"--only-feature-branches", *featureOnlyFlag}, {"--tunnels", *tunnelsFlag}, {"--package", *packageFlag},
+			{"--started-by", *startedByFlag}, {"--backlog", *backlogFlag},
+		} {
+			if passed.set {
+				fleetArgs = append(fleetArgs, passed.name)
+			}
+		}
+		if *timeoutFlag > 0 {
+			fleetArgs = append(fleetArgs, "--timeout="+timeoutFlag.String())
+		}
+		if *sinceFlag > 0 {
+			fleetArgs = append(fleetArgs, "--since="+sinceFlag.String())
+		}
+		if *maxDepthFlag > 0 {
+			fleetArgs = append(fleetArgs, fmt.Sprintf("--max-depth=%d", *maxDepthFlag))
+		}
+	}
+
+	if *interactiveFlag {
+		if *outputFlag != "" || format != formatter.FormatTable {
+			os.Exit(exitWithError("--interactive cannot be combined with --output or --format"))
+		}
+		if !term.IsTerminal(os.Stdout.Fd()) {
+			os.Exit(exitWithError("--interactive needs a terminal"))
+		}
+		detectOpts.Timings = nil
+		detectOpts.Report = nil
+		// The details view shows each server's environment and what
+		// started it
+		detectOpts.Env = true
+		detectOpts.StartedBy = true
+		os.Exit(runInteractive(*watchFlag, *timeoutFlag, detectOpts, opts))
+	}
+
+	if *onChangeFlag && *watchFlag <= 0 {
+		os.Exit(exitWithError("--on-change needs --watch"))
+	}
+
+	if *watchFlag > 0 {
+		if *outputFlag != "" {
+			os.Exit(exitWithError("--watch cannot be combined with --output"))
+		}
+		// Per-cycle timings and reports would scroll the table away
+		detectOpts.Timings = nil
+		detectOpts.Report = nil
+		os.Exit(runWatch(*watchFlag, *timeoutFlag, *onChangeFlag, detectOpts, opts))
+	}
+
+	var rawOutput *bytes.Buffer
+	if *dumpRawFlag != "" {
+		rawOutput = &bytes.Buffer{}
+		detectOpts.DumpRaw = rawOutput
+	}
+
+	var fleetResults <-chan []fleet.Result
+	if *fleetFlag {
+		fleetResults = startFleetScan(cfg.Hosts, fleetArgs, *timeoutFlag)
+	}
+
+	servers, err := findServers(detectOpts, *timeoutFlag)
+
+	// Save the raw output even when detection failed; that's when it's needed
+	if rawOutput != nil {
+		if err := saveRawOutput(*dumpRawFlag, rawOutput.Bytes(), report.ParseErrors); err != nil {
+			os.Exit(exitWithError("saving raw lsof output: %v", err))
+		}
+	}
+	if err != nil {
+		os.Exit(exitWithError("finding servers: %v", err))
+	}
+
+	// Diagrams also show which servers talk to each other, when lsof can tell
+	if format == formatter.FormatDOT || format == formatter.FormatMermaid {
+		endPhase := timings.Start("connections")
+		if opts.Edges, err = depgraph.Build(context.Background(), servers); err != nil {
+			printWarning("reading connections: %v", err)
+		}
+		endPhase()
+	}
+
+	// JSON carries the warnings so consumers know a listing is partial
+	opts.Warnings = report.Warnings()
+	if fleetResults != nil {
+		var hostWarnings []detector.Warning
+		servers, hostWarnings = mergeFleet(servers, <-fleetResults)
+		opts.Warnings = append(opts.Warnings, hostWarnings...)
+	}
+
+	endRender := timings.Start("render")
+	if *outputFlag != "" {
+		if err := writeOutputFile(*outputFlag, servers, opts); err != nil {
+			os.Exit(exitWithError("writing output: %v", err))
+		}
+	} else if err := writePaged(renderListing(servers, withTerminalWidth(opts)), *noPagerFlag); err != nil {
+		os.Exit(exitWithError("writing output: %v", err))
 	}
+	endRender()
+
+	printReportFooter(opts.Warnings, format == formatter.FormatTable && *outputFlag == "")
 
-	formatter.PrintResults(servers)
+	if timings != nil {
+		timings.Print(os.Stderr)
+	}
 
 	if *profileFlag != "" {
 		fmt.Fprintf(os.Stderr, "Profile written to %s\n", *profileFlag)
@@ -70,38 +439,237 @@ func main() {
 }
 
 func printHelp() {
-	fmt.Printf("lsrv version %s\n", version)
+	fmt.Println(i18n.T("lsrv version %s", version))
 	fmt.Println("")
-	fmt.Println("Usage: lsrv [OPTIONS]")
+	fmt.Println(i18n.T("Usage:") + " lsrv [OPTIONS] [DIR]")
+	fmt.Println("       lsrv COMMAND [ARGS...]")
 	fmt.Println("")
-	fmt.Println("Lists all running web servers across repos and worktrees.")
+	fmt.Println(i18n.T("Lists all running web servers across repos and worktrees."))
 	fmt.Println("")
-	fmt.Println("Supported languages/frameworks:")
+	fmt.Println(i18n.T("Supported languages/frameworks:"))
 	fmt.Println("  Ruby (rails, puma), Node.js (node, npm, yarn), Python (gunicorn, uvicorn),")
 	fmt.Println("  Go, Java, PHP (php-fpm, apache2, httpd), Rust (cargo), .NET (dotnet, kestrel),")
 	fmt.Println("  Deno, Bun, Elixir/Phoenix (beam.smp, mix)")
 	fmt.Println("")
-	fmt.Println("Options:")
-	fmt.Println("  -h, --help           Show this help message")
-	fmt.Println("  -v, --version        Show version information")
-	fmt.Println("  --profile=FILE       Write performance profile to FILE for analysis")
+	fmt.Println(i18n.T("Commands:"))
+	fmt.Println("  start -- CMD...      " + i18n.T("Start a server in the background, capturing its output"))
+	fmt.Println("  logs <repo|port>     " + i18n.T("Show a server's output (-f to follow, -n for line count)"))
+	fmt.Println("  kill <repo|port>     " + i18n.T("Stop a server with SIGTERM"))
+	fmt.Println("  kill                 " + i18n.T("Pick several servers from a list and stop them after one confirmation"))
+	fmt.Println("  restart <repo|port>  " + i18n.T("Restart a server with the same command line"))
+	fmt.Println("  open <repo|port>     " + i18n.T("Open a server's URL in the browser"))
+	fmt.Println("  details [repo|port]  " + i18n.T("Show everything known about a server: command, git remote, uptime, memory, health"))
+	fmt.Println("  who PORT             " + i18n.T("Show what listens on PORT, even outside repos, and which repos used it before"))
+	fmt.Println("  run <repo|port> [A]  " + i18n.T("Run action A from the repo's .lsrv.yml in its directory (lists them without A)"))
+	fmt.Println("  exec <repo|port> CMD " + i18n.T("Run CMD (after --) in the server's working directory"))
+	fmt.Println("  env [repo|port]      " + i18n.T("Print export lines (APP_URL, APP_PORT) for a server, for eval in a shell"))
+	fmt.Println("  clean                " + i18n.T("Stop servers whose directory was deleted (asks for each)"))
+	fmt.Println("  snapshot save|diff N " + i18n.T("Save the running servers as N, or show what started/stopped since"))
+	fmt.Println("  export [DIR]         " + i18n.T("Print the running servers as portable JSON (repo, branch, command, port)"))
+	fmt.Println("  import FILE          " + i18n.T("Start the servers from an export in your own checkouts"))
+	fmt.Println("  ports                " + i18n.T("Print a compact PORT REPO(BRANCH) PROCESS listing"))
+	fmt.Println("  graph [--format=dot] " + i18n.T("Show which servers and services are connected to each other"))
+	fmt.Println("  top [--sort=mem]     " + i18n.T("Live view of dev servers sorted by CPU or memory, with keys to re-sort and kill"))
+	fmt.Println("  guard --port=PORT    " + i18n.T("Fail if PORT is held by another repo or branch, before launching a server"))
+	fmt.Println("  check                " + i18n.T("Verify the servers listed under requires in .lsrv.yml are running"))
+	fmt.Println("  whichport <repo>     " + i18n.T("Show the ports a repo usually runs on and what holds them today"))
+	fmt.Println("  prompt               " + i18n.T("Print the current repo's ports (e.g. ●3000) from the daemon, for shell prompts"))
+	fmt.Println("  reserve [PORT]       " + i18n.T("Reserve PORT for the current repo, or list reservations (--suggest prints a free port)"))
+	fmt.Println("  serve [--http=ADDR]  " + i18n.T("Serve a JSON HTTP API for listing and killing servers"))
+	fmt.Println("  daemon install       " + i18n.T("Run \"lsrv serve\" at login via launchd or systemd (also uninstall, status)"))
+	fmt.Println("  mcp                  " + i18n.T("Run a Model Context Protocol server over stdio"))
+	fmt.Println("  config check         " + i18n.T("Report unknown settings and wrong values in config.yml and .lsrv.yml"))
+	fmt.Println("  config init          " + i18n.T("Write a commented config.yml to start from (--repo for .lsrv.yml)"))
+	fmt.Println("  doctor               " + i18n.T("Check dependencies, permissions and config, and suggest fixes"))
+	fmt.Println("  audit                " + i18n.T("Report servers reachable from other machines, exposed debug pages and default logins"))
+	fmt.Println("")
+	fmt.Println(i18n.T("Options:"))
+	fmt.Println("  -h, --help           " + i18n.T("Show this help message"))
+	fmt.Println("  -v, --version        " + i18n.T("Show version information"))
+	fmt.Println("  --format=FORMAT      " + i18n.T("Output format: table (default on a terminal), plain (default otherwise), markdown, json, csv, alfred, raycast, dot, mermaid or openmetrics"))
+	fmt.Println("  --schema             " + i18n.T("Print the JSON Schema of --format=json output"))
+	fmt.Println("  --output=FILE        " + i18n.T("Write output to FILE atomically, with a summary on stderr"))
+	fmt.Println("  --here               " + i18n.T("Only show servers in the current repo and its worktrees (same as \"lsrv .\")"))
+	fmt.Println("  --current-first      " + i18n.T("List the current repo's servers (marked ▸) first"))
+	fmt.Println("  --since=DURATION     " + i18n.T("Only show servers started within DURATION, e.g. 30m after a launch script"))
+	fmt.Println("  --only-feature-branches")
+	fmt.Println("                       " + i18n.T("Hide servers on main, master or production (main_branches in the config)"))
+	fmt.Println("  --no-ignore          " + i18n.T("Show servers in directories listed in ~/.config/lsrv/ignore"))
+	fmt.Println("  --dedup=STRATEGY     " + i18n.T("Merge listeners by key (repo, branch, process, port; default), pid, socket or none"))
+	fmt.Println("  --all-ports          " + i18n.T("List HMR and helper ports of JS dev servers as their own rows"))
+	fmt.Println("  --no-truncate        " + i18n.T("Show full repo and branch names on narrow terminals"))
+	fmt.Println("  --no-pager           " + i18n.T("Don't page listings taller than the terminal through $PAGER (default less)"))
+	fmt.Println("  --all-users          " + i18n.T("Include other users' servers with a USER column (needs sudo)"))
+	fmt.Println("  --sudo               " + i18n.T("Enumerate sockets via sudo to include other users' and root's servers"))
+	fmt.Println("  --services           " + i18n.T("Also list postgres, mysql, redis, elasticsearch, mailhog, minio, ..."))
+	fmt.Println("  --cmdline            " + i18n.T("Show a COMMAND column with each full command line (complete in JSON)"))
+	fmt.Println("  --runtime-version    " + i18n.T("Show a VERSION column with each runtime and version (ruby 3.3.0), resolving asdf/mise/nvm shims"))
+	fmt.Println("  --env                " + i18n.T("Show an ENV column with each direnv/devenv setup, marked when the server started without it"))
+	fmt.Println("  --no-git             " + i18n.T("Skip git commands for speed; REPO shows the directory name, BRANCH \"-\""))
+	fmt.Println("  --last-commit        " + i18n.T("Show a LAST COMMIT column with the age of each branch's latest commit"))
+	fmt.Println("  --session            " + i18n.T("Show a SESSION column with the owning tmux pane, terminal or editor"))
+	fmt.Println("  --started-by         " + i18n.T("Show a STARTED BY column with the processes that launched each server"))
+	fmt.Println("  --package            " + i18n.T("Show a PACKAGE column with each server's directory in its repo (packages/web)"))
+	fmt.Println("  --max-depth=N        " + i18n.T("Look at most N directories above a server's directory for its repo (default no limit)"))
+	fmt.Println("  --probe              " + i18n.T("Probe ports and tag gRPC (grpc://), websocket (ws://) and TCP (tcp://) URLs"))
+	fmt.Println("  --latency            " + i18n.T("Show a LATENCY column with each server's time to first byte, color-graded"))
+	fmt.Println("  --backlog            " + i18n.T("Show a BACKLOG column with queued connections per server, red when the accept queue is full (Linux)"))
+	fmt.Println("  --repo-name=SOURCES  " + i18n.T("Take repo names from config, origin, upstream, toplevel (default config,origin)"))
+	fmt.Println("  --lan                " + i18n.T("Show a LAN URL column (primary interface IP) for servers not bound to localhost"))
+	fmt.Println("  --mdns               " + i18n.T("Like --lan, but use this machine's HOSTNAME.local name instead of its IP"))
+	fmt.Println("  --firewall           " + i18n.T("Like --lan, and mark LAN URLs the firewall (ufw, iptables, pf, macOS) blocks"))
+	fmt.Println("  --tunnels            " + i18n.T("Show a PUBLIC URL column with the ngrok, cloudflared or tailscale funnel URL of each server"))
+	fmt.Println("  --accessible         " + i18n.T("Add a STATUS column with symbols and text (healthy, stale, zombie, conflict)"))
+	fmt.Println("  --ascii              " + i18n.T("Use plain text tags like [ruby] instead of Nerd Font icons"))
+	fmt.Println("  --lang=LANG          " + i18n.T("Language for messages and table headers: en or es (default from LANG)"))
+	fmt.Println("  -i, --interactive    " + i18n.T("Browse a live table with per-server health checks (refreshes every --watch, default 2s)"))
+	fmt.Println("  --watch=DURATION     " + i18n.T("Redraw the list every DURATION (e.g. 2s), only inspecting new processes"))
+	fmt.Println("  --fleet              " + i18n.T("Also list servers on the hosts in the config file over SSH, with a HOST column"))
+	fmt.Println("  --on-change          " + i18n.T("With --watch, redraw as soon as servers start or stop (at most once a second); DURATION becomes the fallback"))
+	fmt.Println("  --dump-raw=FILE      " + i18n.T("Save lsof's raw output to FILE and list unparsed lines, for bug reports"))
+	fmt.Println("  --timeout=DURATION   " + i18n.T("Give up on detection after DURATION (default 10s, 0 for no limit)"))
+	fmt.Println("  --timings            " + i18n.T("Print per-phase durations (lsof, cwd, git, render) to stderr"))
+	fmt.Println("  --debug              " + i18n.T("Print what runs with less for lack of privileges (other users' processes, sockets) to stderr"))
+	fmt.Println("  --profile=FILE       " + i18n.T("Write performance profile to FILE for analysis"))
 	fmt.Println("")
-	fmt.Println("Output columns:")
-	fmt.Println("  REPO     - Repository name (from git remote or directory name)")
-	fmt.Println("  BRANCH   - Current git branch")
-	fmt.Println("  PROCESS  - Process running the server with icon (💎 ruby, ⬢ node, 🐹 go, etc.)")
-	fmt.Println("  PID      - Process ID")
-	fmt.Println("  URL      - Clickable HTTP URL to access the server")
+	fmt.Println(i18n.T("Output columns:"))
+	fmt.Println("  REPO     - " + i18n.T("Repository name (from git remote or directory name)"))
+	fmt.Println("  BRANCH   - " + i18n.T("Current git branch"))
+	fmt.Println("  PROCESS  - " + i18n.T("Process running the server with icon (💎 ruby, ⬢ node, 🐹 go, etc.)"))
+	fmt.Println("  PID      - " + i18n.T("Process ID"))
+	fmt.Println("  URL      - " + i18n.T("Clickable HTTP URL to access the server"))
+}
+
+// writeOutputFile renders the servers and atomically replaces path with the result
+func writeOutputFile(path string, servers []types.Server, opts formatter.Options) error {
+	var buf bytes.Buffer
+	if err := formatter.Write(&buf, servers, opts); err != nil {
+		return err
+	}
+
+	if err := atomicfile.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, i18n.N(len(servers), "Wrote %d server as %s to %s", "Wrote %d servers as %s to %s", len(servers), opts.Format, path))
+	return nil
+}
+
+// saveRawOutput atomically writes lsof's raw output to path and lists the
+// lines that failed to parse on stderr
+func saveRawOutput(path string, output []byte, parseErrors []detector.ParseError) error {
+	if err := atomicfile.WriteFile(path, output, 0o644); err != nil {
+		return err
+	}
+	for _, parseErr := range parseErrors {
+		printWarning("%v", parseErr)
+	}
+	fmt.Fprintln(os.Stderr, i18n.T("Saved raw lsof output to %s", path))
+	return nil
+}
+
+// printReportFooter summarizes skipped and stale listeners below the table, or on
+// stderr for machine-readable output so it doesn't corrupt the data
+func printReportFooter(warnings []detector.Warning, inline bool) {
+	var messages []string
+	for _, warning := range warnings {
+		n := warning.Count
+		switch warning.Code {
+		case detector.WarnPermissionDenied:
+			messages = append(messages, i18n.N(n,
+				"%d listener skipped (permission denied), run with --sudo to include",
+				"%d listeners skipped (permission denied), run with --sudo to include", n))
+		case detector.WarnUnparsable:
+			messages = append(messages, i18n.N(n,
+				"%d lsof output line could not be parsed, rerun with --dump-raw=FILE and attach FILE to a bug report",
+				"%d lsof output lines could not be parsed, rerun with --dump-raw=FILE and attach FILE to a bug report", n))
+		case detector.WarnExited:
+			messages = append(messages, i18n.N(n,
+				"%d process exited during the scan and was skipped",
+				"%d processes exited during the scan and were skipped", n))
+		case detector.WarnDeletedCWD:
+			messages = append(messages, i18n.N(n,
+				"%d server runs in a deleted directory, run lsrv clean to stop it",
+				"%d servers run in a deleted directory, run lsrv clean to stop them", n))
+		case detector.WarnHostFailed:
+			for _, detail := range warning.Details {
+				messages = append(messages, i18n.T("couldn't list servers on %s", detail))
+			}
+		}
+	}
+
+	for _, msg := range messages {
+		if inline {
+			fmt.Println(msg)
+		} else {
+			printWarning("%s", msg)
+		}
+	}
+}
+
+// renderListing returns the function writePaged renders the listing with,
+// styled by the renderer it is given unless opts already has one
+func renderListing(servers []types.Server, opts formatter.Options) func(io.Writer, *lipgloss.Renderer) error {
+	return func(w io.Writer, renderer *lipgloss.Renderer) error {
+		if opts.Renderer == nil {
+			opts.Renderer = renderer
+		}
+		return formatter.Write(w, servers, opts)
+	}
+}
+
+// withTerminalWidth sets the render width when stdout is a terminal
+func withTerminalWidth(opts formatter.Options) formatter.Options {
+	fd := os.Stdout.Fd()
+	if !term.IsTerminal(fd) {
+		return opts
+	}
+	if width, _, err := term.GetSize(fd); err == nil {
+		opts.Width = width
+	}
+	return opts
+}
+
+// repoNameSources returns the repo name priority from --repo-name, or else
+// the config file. A bad config value only warns, as it isn't on the command
+// line to fix.
+func repoNameSources(flagValue string, cfg *config.Config) ([]git.NameSource, error) {
+	if flagValue != "" {
+		return git.ParseNameSources(flagValue)
+	}
+	if len(cfg.RepoName) == 0 {
+		return nil, nil
+	}
+	sources, err := git.ParseNameSources(strings.Join(cfg.RepoName, ","))
+	if err != nil {
+		printWarning("repo_name in config: %v, using defaults", err)
+		return nil, nil
+	}
+	return sources, nil
+}
+
+// dedupStrategy returns the dedup strategy from --dedup, or else the config
+// file, warning about a bad config value like repoNameSources
+func dedupStrategy(flagValue string, cfg *config.Config) (detector.Dedup, error) {
+	if flagValue != "" {
+		return detector.ParseDedup(flagValue)
+	}
+	strategy, err := detector.ParseDedup(cfg.Dedup)
+	if err != nil {
+		printWarning("dedup in config: %v, using key", err)
+		return detector.DedupKey, nil
+	}
+	return strategy, nil
 }
 
 func printLsofError() {
-	fmt.Fprintln(os.Stderr, "error: lsof command not found, please install it")
+	exitWithError("lsof command not found, please install it")
 	fmt.Fprintln(os.Stderr, "")
 	if platform.IsMacOS() {
-		fmt.Fprintln(os.Stderr, "On macOS, lsof should be pre-installed. If missing, reinstall Command Line Tools:")
+		fmt.Fprintln(os.Stderr, i18n.T("On macOS, lsof should be pre-installed. If missing, reinstall Command Line Tools:"))
 		fmt.Fprintln(os.Stderr, "  xcode-select --install")
 	} else {
-		fmt.Fprintln(os.Stderr, "On Linux, install lsof:")
+		fmt.Fprintln(os.Stderr, i18n.T("On Linux, install lsof:"))
 		fmt.Fprintln(os.Stderr, "  sudo apt-get install lsof  # Debian/Ubuntu")
 		fmt.Fprintln(os.Stderr, "  sudo yum install lsof      # RHEL/CentOS")
 	}
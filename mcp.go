@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bshakr/lsrv/internal/mcp"
+)
+
+// runMCP serves the Model Context Protocol over stdio so AI assistants can
+// list and manage dev servers
+func runMCP(args []string) int {
+	fs := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv mcp")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Runs an MCP server over stdio exposing the tools list_servers,")
+		fmt.Fprintln(os.Stderr, "kill_server, restart_server and open_server.")
+	}
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return 2
+	}
+
+	if !commandExists("lsof") {
+		printLsofError()
+		return 1
+	}
+
+	if err := mcp.NewServer(trackedDetectServers(), version).Serve(os.Stdin, os.Stdout); err != nil {
+		return exitWithError("mcp: %v", err)
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+)
+
+// defaultPager is used when neither LSRV_PAGER nor PAGER is set
+const defaultPager = "less"
+
+// writePaged renders output to stdout, through a pager when stdout is a
+// terminal and the output is taller than it, so the header of a long table
+// doesn't scroll away. Without a usable pager the output is written as is.
+// render styles the output with the renderer it is given, which detects
+// colors from stdout even when the output goes through a buffer.
+func writePaged(render func(w io.Writer, renderer *lipgloss.Renderer) error, noPager bool) error {
+	fd := os.Stdout.Fd()
+	renderer := lipgloss.NewRenderer(os.Stdout)
+	if noPager || !term.IsTerminal(fd) {
+		return render(os.Stdout, renderer)
+	}
+
+	buf, err := renderBuffered(render, renderer)
+	if err != nil {
+		return err
+	}
+	_, height, err := term.GetSize(fd)
+	if err != nil || bytes.Count(buf.Bytes(), []byte("\n")) < height {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+
+	args := strings.Fields(pagerCommand())
+	if len(args) == 0 || args[0] == "cat" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Stdin = buf
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Like git: keep colors, and leave the table on screen after quitting
+	if _, set := os.LookupEnv("LESS"); !set {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	return cmd.Run()
+}
+
+// renderBuffered renders output into a buffer for the pager, styled by the
+// terminal's renderer: one detected from the buffer would drop all colors
+func renderBuffered(render func(w io.Writer, renderer *lipgloss.Renderer) error, renderer *lipgloss.Renderer) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	if err := render(&buf, renderer); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// pagerCommand returns the pager to run: LSRV_PAGER, then PAGER, then
+// less. Setting either to an empty string turns paging off.
+func pagerCommand() string {
+	for _, name := range []string{"LSRV_PAGER", "PAGER"} {
+		if pager, set := os.LookupEnv(name); set {
+			return pager
+		}
+	}
+	return defaultPager
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/bshakr/lsrv/internal/formatter"
+	"github.com/bshakr/lsrv/internal/types"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// TestPagedListingKeepsColors renders a listing into the pager's buffer
+// with a terminal's renderer and checks the colors are still there
+func TestPagedListingKeepsColors(t *testing.T) {
+	terminal := lipgloss.NewRenderer(io.Discard)
+	terminal.SetColorProfile(termenv.ANSI256)
+	servers := []types.Server{{Repo: "shop", Branch: "main", Process: "node", PID: 4242, Port: 3000, CWD: "/nonexistent/shop"}}
+
+	buf, err := renderBuffered(renderListing(servers, formatter.Options{Format: formatter.FormatTable}), terminal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("\x1b[")) {
+		t.Errorf("paged table has no color escapes:\n%s", buf)
+	}
+}
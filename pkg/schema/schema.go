@@ -0,0 +1,282 @@
+// Package schema defines the JSON that lsrv --format=json prints, for tools
+// that consume it to unmarshal against:
+//
+//	var out schema.Output
+//	err := json.Unmarshal(data, &out)
+//
+// Compatibility: within one Version, fields are only ever added. Existing
+// fields keep their name, type and meaning, and fields marked omitempty may
+// be absent whenever the feature that fills them wasn't requested. Removing
+// or changing a field bumps Version, so consumers should check
+// SchemaVersion and reject versions they don't know.
+//
+// Units: durations are in seconds, except latencies in milliseconds, and
+// sizes in bytes. Field names end in their unit.
+package schema
+
+import (
+	_ "embed"
+	"time"
+)
+
+// Version is the current schema version, printed as schema_version.
+// Version 2 replaced ttfb, in nanoseconds, with ttfb_ms.
+const Version = 2
+
+// Output is the top-level JSON object
+type Output struct {
+	// SchemaVersion is the Version the output was written with
+	SchemaVersion int `json:"schema_version"`
+
+	// Servers lists app servers and, with --services, auxiliary services;
+	// it is empty rather than null when nothing is running
+	Servers []Server `json:"servers"`
+
+	// Warnings say why Servers may be incomplete, such as listeners lsof
+	// wasn't allowed to inspect; it is empty rather than null when the
+	// listing is complete
+	Warnings []Warning `json:"warnings"`
+}
+
+// Warning codes
+const (
+	// WarnPermissionDenied: listeners of processes lsof couldn't inspect
+	// were skipped; running with --sudo includes them
+	WarnPermissionDenied = "permission_denied"
+
+	// WarnUnparsable: lines of lsof's output couldn't be parsed and were
+	// skipped; Details lists them
+	WarnUnparsable = "unparsable_lsof_output"
+
+	// WarnExited: processes exited while lsrv inspected them
+	WarnExited = "exited_during_scan"
+
+	// WarnDeletedCWD: servers run in a directory that was deleted; they
+	// are listed with status "zombie"
+	WarnDeletedCWD = "deleted_cwd"
+
+	// WarnHostFailed: hosts of the fleet couldn't be listed (--fleet);
+	// Details names each with its error
+	WarnHostFailed = "host_failed"
+)
+
+// Warning tells why the listing may be incomplete
+type Warning struct {
+	// Code is one of the Warn constants; new codes may be added within a
+	// Version, so skip unknown ones
+	Code string `json:"code"`
+
+	// Count is the number of entries affected
+	Count int `json:"count"`
+
+	// Details lists the affected entries when there is more to say
+	Details []string `json:"details,omitempty"`
+}
+
+// Server is one listening app server or auxiliary service
+type Server struct {
+	// Repo is the repository name; empty for services
+	Repo string `json:"repo"`
+
+	// Branch is the checked-out branch, or "-" when unknown
+	Branch string `json:"branch"`
+
+	// Process is the name of the listening process, such as "node"
+	Process string `json:"process"`
+
+	Port int `json:"port"`
+	PID  int `json:"pid"`
+
+	// CWD is the server's working directory, or the project it serves
+	CWD string `json:"cwd"`
+
+	// User is the account owning the process, or its UID when the account
+	// has no name
+	User string `json:"user"`
+
+	// UID is the numeric ID of the account owning the process, or -1 when
+	// unknown
+	UID int `json:"uid"`
+
+	// Package is the server's directory within its repository, such as
+	// "packages/web" in a monorepo
+	Package string `json:"package,omitempty"`
+
+	// Host names the machine the server runs on, "local" or a configured
+	// host (--fleet)
+	Host string `json:"host,omitempty"`
+
+	// URL is the local URL, always present; its scheme is grpc, ws or tcp
+	// when --probe found the server speaks that rather than HTTP
+	URL string `json:"url"`
+
+	// Workers counts processes sharing the listening socket, such as Puma
+	// or gunicorn workers
+	Workers int `json:"workers,omitempty"`
+
+	// AuxPorts are helper ports folded into this row, such as a Vite HMR
+	// websocket
+	AuxPorts []int `json:"aux_ports,omitempty"`
+
+	// PrimaryPort is set with --all-ports on rows for a helper port, to the
+	// port of the app it belongs to
+	PrimaryPort int `json:"primary_port,omitempty"`
+
+	// LastCommit is the time of the branch's latest commit (--last-commit)
+	LastCommit *time.Time `json:"last_commit,omitempty"`
+
+	// Runtime is the language runtime and version, such as "ruby 3.3.0"
+	// (--runtime-version)
+	Runtime string `json:"runtime,omitempty"`
+
+	// Env is the direnv or devenv setup of the server's directory (--env)
+	Env *Env `json:"env,omitempty"`
+
+	// Nix is "nix" or "devenv" when the server was started inside a Nix
+	// development shell
+	Nix string `json:"nix,omitempty"`
+
+	// RemoteURL is the web page of the repo's origin remote
+	RemoteURL string `json:"remote_url,omitempty"`
+
+	// Service names the auxiliary service, such as "postgres"; empty for
+	// app servers
+	Service string `json:"service,omitempty"`
+
+	// Bind is the listening address, such as "*", "127.0.0.1" or "[::1]"
+	Bind string `json:"bind,omitempty"`
+
+	// LANURL reaches the server from other devices (--lan)
+	LANURL string `json:"lan_url,omitempty"`
+
+	// Tunnels are public URLs forwarding to the server (--tunnels)
+	Tunnels []Tunnel `json:"tunnels,omitempty"`
+
+	// Firewall tells whether the local firewall lets other devices reach
+	// LANURL (--firewall)
+	Firewall *Firewall `json:"firewall,omitempty"`
+
+	// FriendlyURL is a hostname-based URL from puma-dev, Valet, dnsmasq or
+	// /etc/hosts
+	FriendlyURL string `json:"friendly_url,omitempty"`
+
+	// CustomURL is built from the config's URL template for the server
+	CustomURL string `json:"custom_url,omitempty"`
+
+	// Framework is the detected framework, such as "phoenix" or "laravel"
+	Framework string `json:"framework,omitempty"`
+
+	// CommandLine is the process's full command line (--cmdline)
+	CommandLine string `json:"command_line,omitempty"`
+
+	// Paths are useful URL paths for the framework, such as "/graphql",
+	// and Links the same paths as URLs
+	Paths []string `json:"paths,omitempty"`
+	Links []string `json:"links,omitempty"`
+
+	// Current marks servers running in the repository lsrv was run in
+	Current bool `json:"current,omitempty"`
+
+	// Status is "healthy", "stale", "zombie", "conflict", "unusual" or
+	// "reserved"
+	Status string `json:"status,omitempty"`
+
+	// Label names the port after the service the repo declares for it,
+	// such as "web" or "db", or the IDE run configuration that launched it
+	Label string `json:"label,omitempty"`
+
+	// Session is the tmux pane, terminal or editor the server runs in
+	// (--session)
+	Session string `json:"session,omitempty"`
+
+	// StartedBy is the chain of processes that launched the server,
+	// outermost first, such as ["tmux", "zsh", "bin/dev"]
+	StartedBy []string `json:"started_by,omitempty"`
+
+	// TTFBMS is the time to the first byte of the root page, in
+	// milliseconds (--latency)
+	TTFBMS float64 `json:"ttfb_ms,omitempty"`
+
+	// UptimeSeconds is how long the server's process has run, in seconds
+	UptimeSeconds int64 `json:"uptime_seconds,omitempty"`
+
+	// MemoryBytes is the resident memory of the server's process tree, in
+	// bytes
+	MemoryBytes uint64 `json:"memory_bytes,omitempty"`
+
+	// Protocol is "http", "grpc", "websocket" or "tcp" (--probe)
+	Protocol string `json:"protocol,omitempty"`
+
+	// Backlog is the server's accept queue (--backlog, Linux)
+	Backlog *Backlog `json:"backlog,omitempty"`
+
+	// DevContainer is the dev container the server runs in, when its port
+	// is mapped between the container and the host
+	DevContainer *DevContainer `json:"dev_container,omitempty"`
+}
+
+// Env describes a direnv or devenv setup
+type Env struct {
+	// Tool is "direnv" or "devenv"
+	Tool string `json:"tool"`
+
+	// File is the .envrc or devenv.nix setting up the environment
+	File string `json:"file"`
+
+	// Loaded tells whether the server started with the setup applied;
+	// absent when its environment can't be read
+	Loaded *bool `json:"loaded,omitempty"`
+
+	// Vars holds configured variables from the server's environment, with
+	// URLs reduced to their host
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+// Tunnel is a public URL forwarding to a server
+type Tunnel struct {
+	// Provider is "ngrok", "cloudflared" or "tailscale"
+	Provider string `json:"provider"`
+
+	URL string `json:"url"`
+}
+
+// Backlog is the accept queue of a server's listening socket
+type Backlog struct {
+	// Queued counts connections waiting to be accepted
+	Queued int `json:"queued"`
+
+	// Limit is the queue's size from listen(2); absent when unknown
+	Limit int `json:"limit,omitempty"`
+}
+
+// DevContainer is the dev container a server runs in
+type DevContainer struct {
+	// Name is the devcontainer.json name, else the container's
+	Name string `json:"name"`
+
+	// ContainerPort is the port the server listens on in the container
+	ContainerPort int `json:"container_port"`
+
+	// HostPort reaches the server from the host's browser
+	HostPort int `json:"host_port"`
+}
+
+// Firewall is the verdict of a firewall check
+type Firewall struct {
+	// State is "open", "blocked" or "unknown"
+	State string `json:"state"`
+
+	// By names the firewall that decided, such as "ufw" or "pf"
+	By string `json:"by,omitempty"`
+
+	// Detail explains the verdict, such as the rule that matched
+	Detail string `json:"detail,omitempty"`
+}
+
+//go:embed schema.json
+var jsonSchema []byte
+
+// JSONSchema returns the JSON Schema (draft 2020-12) describing Output
+func JSONSchema() []byte {
+	return jsonSchema
+}
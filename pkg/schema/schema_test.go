@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// jsonFields returns the JSON names of a struct type's fields
+func jsonFields(t reflect.Type) []string {
+	var names []string
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// schemaFields returns the property names of a $defs entry
+func schemaFields(t *testing.T, def string) []string {
+	var doc struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(JSONSchema(), &doc); err != nil {
+		t.Fatalf("schema.json is invalid: %v", err)
+	}
+
+	properties := doc.Properties
+	if def != "" {
+		properties = doc.Defs[def].Properties
+	}
+	var names []string
+	for name := range properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestJSONSchemaMatchesStructs(t *testing.T) {
+	for _, tt := range []struct {
+		def string
+		typ reflect.Type
+	}{
+		{"", reflect.TypeFor[Output]()},
+		{"server", reflect.TypeFor[Server]()},
+		{"env", reflect.TypeFor[Env]()},
+		{"tunnel", reflect.TypeFor[Tunnel]()},
+		{"firewall", reflect.TypeFor[Firewall]()},
+		{"backlog", reflect.TypeFor[Backlog]()},
+		{"dev_container", reflect.TypeFor[DevContainer]()},
+		{"warning", reflect.TypeFor[Warning]()},
+	} {
+		if got, want := schemaFields(t, tt.def), jsonFields(tt.typ); !slices.Equal(got, want) {
+			t.Errorf("%s: schema.json has %v, struct has %v", tt.typ.Name(), got, want)
+		}
+	}
+}
+
+// TestServerCoversOutput fails when a field is added to the JSON output
+// without adding it to Server and schema.json
+func TestServerCoversOutput(t *testing.T) {
+	loaded := true
+	now := time.Now()
+	server := types.Server{
+		Repo: "app", Branch: "main", Process: "node", Port: 3000, PID: 1, CWD: "/app", User: "me",
+		Workers: 1, AuxPorts: []int{3001}, PrimaryPort: 3000, LastCommit: &now, Runtime: "node 20",
+		Env:       &types.Env{Tool: "direnv", File: ".envrc", Loaded: &loaded, Vars: map[string]string{"PORT": "3000"}},
+		RemoteURL: "https://example.com", Service: "postgres", Bind: "*", LANURL: "http://10.0.0.2:3000",
+		Firewall:    &types.Firewall{State: types.FirewallOpen, By: "ufw", Detail: "allow"},
+		FriendlyURL: "http://app.test", CustomURL: "http://app.test/x", Framework: "next",
+		CommandLine: "next dev", Paths: []string{"/graphql"}, Current: true, Status: types.StatusHealthy,
+		Label: "web", Session: "tmux", StartedBy: []string{"tmux", "zsh"}, TTFB: time.Millisecond, Protocol: "http", Host: "ws1", Package: "packages/web",
+		Uptime: time.Hour, Memory: 1 << 20, Nix: "devenv", Tunnels: []types.Tunnel{{Provider: "ngrok", URL: "https://app.ngrok.app"}},
+		Backlog:      &types.Backlog{Queued: 3, Limit: 511},
+		DevContainer: &types.DevContainer{Name: "app", ContainerPort: 3000, HostPort: 3001},
+	}
+	data, err := json.Marshal(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	known := jsonFields(reflect.TypeFor[Server]())
+	for name := range fields {
+		if !slices.Contains(known, name) {
+			t.Errorf("output field %q is missing from schema.Server", name)
+		}
+	}
+	if len(fields) != len(known) {
+		t.Errorf("test server sets %d fields, schema.Server has %d; set the new ones above", len(fields), len(known))
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/formatter"
+	"github.com/bshakr/lsrv/internal/ignore"
+)
+
+// runPorts prints a compact, grep-friendly port listing
+func runPorts(args []string) int {
+	fs := flag.NewFlagSet("ports", flag.ContinueOnError)
+	servicesFlag := fs.Bool("services", false, "Also list databases and auxiliary services")
+	timeoutFlag := fs.Duration("timeout", defaultTimeout, "Give up on detection after this long (0 for no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv ports [--services] [--timeout=DURATION]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Prints one line per server: PORT REPO(BRANCH) PROCESS")
+		fs.PrintDefaults()
+	}
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return 2
+	}
+
+	if !commandExists("lsof") {
+		printLsofError()
+		return 1
+	}
+
+	// A broken ignore file is reported by the listing and doctor
+	ignored, _ := ignore.Load()
+	servers, err := findServers(detector.Options{Services: *servicesFlag, RepoNames: configuredRepoNames(), Ignore: ignored}, *timeoutFlag)
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	if err := formatter.WritePorts(os.Stdout, servers); err != nil {
+		return exitWithError("writing output: %v", err)
+	}
+	return 0
+}
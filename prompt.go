@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// runPrompt prints a short indicator of the servers running in the current
+// repo, such as "●3000", for shell prompts. It asks a running "lsrv serve"
+// for its latest scan rather than scanning, and prints nothing when the
+// daemon doesn't answer in time, so prompts never hang. It never runs git
+// or loads the config either, which alone would take longer than a prompt
+// can wait.
+func runPrompt(args []string) int {
+	fs := flag.NewFlagSet("prompt", flag.ContinueOnError)
+	addr := fs.String("http", "localhost:7777", "Address of the lsrv serve API to ask")
+	symbol := fs.String("symbol", "●", "Printed before the ports")
+	timeout := fs.Duration("timeout", 10*time.Millisecond, "Print nothing if the daemon hasn't answered after this long")
+	token := fs.String("token", "", "API token, when serve.token is set in the config (default: the one lsrv serve generated)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv prompt [--http=ADDR] [--symbol=S] [--timeout=DURATION] [--token=TOKEN]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Prints the ports of the current repo's servers (e.g. ●3000,5173) for shell")
+		fmt.Fprintln(os.Stderr, "prompts, from a running \"lsrv serve\" or \"lsrv daemon install\"")
+		fmt.Fprintln(os.Stderr, "")
+		fs.PrintDefaults()
+	}
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return 2
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return 0
+	}
+	root, ok := git.WorkTreeRoot(wd)
+	if !ok {
+		return 0
+	}
+	// Layouts only git resolves, such as worktrees of a bare repository
+	// outside it, count the current work tree alone
+	roots, ok := git.WorktreesFromFiles(wd)
+	if !ok {
+		roots = []string{root}
+	}
+	if *token == "" {
+		*token = generatedToken()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	servers, err := cachedServers(ctx, *addr, *token)
+	if err != nil {
+		return 0
+	}
+
+	var ports []int
+	for _, server := range servers {
+		if server.Service != "" || server.PrimaryPort != 0 || !withinAny(roots, server.CWD) {
+			continue
+		}
+		if !slices.Contains(ports, server.Port) {
+			ports = append(ports, server.Port)
+		}
+	}
+	if len(ports) == 0 {
+		return 0
+	}
+	slices.Sort(ports)
+
+	formatted := make([]string, len(ports))
+	for i, port := range ports {
+		formatted[i] = strconv.Itoa(port)
+	}
+	fmt.Println(*symbol + strings.Join(formatted, ","))
+	return 0
+}
+
+// cachedServers fetches the daemon's latest scan from the API at addr
+func cachedServers(ctx context.Context, addr, token string) ([]types.Server, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+"/servers?cached=1", nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %s", addr, resp.Status)
+	}
+
+	var servers []types.Server
+	if err := json.NewDecoder(resp.Body).Decode(&servers); err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+// generatedToken returns the API token lsrv serve generated, if any.
+// Unlike apiToken it never creates one.
+func generatedToken() string {
+	dir, err := platform.StateDir()
+	if err != nil {
+		return ""
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, tokenFileName))
+	return strings.TrimSpace(string(data))
+}
+
+// withinAny reports whether path is one of dirs or inside one
+func withinAny(dirs []string, path string) bool {
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/reservation"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// runReserve maintains the registry of ports set aside for repositories,
+// which the listing checks running servers against
+func runReserve(args []string) int {
+	fs := flag.NewFlagSet("reserve", flag.ContinueOnError)
+	repoFlag := fs.String("repo", "", "Repo to reserve the port for (default: the current repository)")
+	releaseFlag := fs.Bool("release", false, "Remove the reservation of PORT")
+	suggestFlag := fs.Bool("suggest", false, "Print a free port that respects the reservations")
+	fromFlag := fs.Int("from", reservation.DefaultFrom, "With --suggest, the lowest port to consider")
+	jsonFlag := fs.Bool("json", false, "List the reservations as JSON")
+	timeoutFlag := fs.Duration("timeout", defaultTimeout, "Give up on detection after this long (0 for no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv reserve [PORT] [--repo=REPO] [--release] [--suggest [--from=PORT]] [--json]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Reserve PORT for REPO, or list the reservations and what holds each port.")
+		fmt.Fprintln(os.Stderr, "Servers on a port reserved for another repo are marked \"reserved\".")
+		fmt.Fprintln(os.Stderr, "--suggest prints a free port for scripts: one reserved for REPO, or else")
+		fmt.Fprintln(os.Stderr, "one nobody reserved, e.g. PORT=$(lsrv reserve --suggest) bin/dev")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	registry, err := reservation.Load()
+	if err != nil {
+		return exitWithError("reading reservations: %v", err)
+	}
+
+	if *suggestFlag {
+		if len(positional) != 0 || *releaseFlag {
+			fs.Usage()
+			return 2
+		}
+		repo := *repoFlag
+		if repo == "" {
+			repo, _ = currentRepoName()
+		}
+		port := registry.Suggest(repo, *fromFlag, portInUse)
+		if port == 0 {
+			return exitWithError("no free port from %d", *fromFlag)
+		}
+		fmt.Println(port)
+		return 0
+	}
+
+	if len(positional) == 0 {
+		if *releaseFlag {
+			fs.Usage()
+			return 2
+		}
+		return listReservations(registry, *jsonFlag, *timeoutFlag)
+	}
+
+	port, err := strconv.Atoi(positional[0])
+	if err != nil || port <= 0 || port > 65535 {
+		return exitWithError("invalid port %q", positional[0])
+	}
+
+	if *releaseFlag {
+		if !registry.Release(port) {
+			return exitWithError("port %d is not reserved", port)
+		}
+		if err := registry.Save(); err != nil {
+			return exitWithError("saving reservations: %v", err)
+		}
+		fmt.Printf("Released port %d\n", port)
+		return 0
+	}
+
+	repo := *repoFlag
+	if repo == "" {
+		var ok bool
+		if repo, ok = currentRepoName(); !ok {
+			return exitWithError("not in a git repository; pass --repo")
+		}
+	}
+	if err := registry.Reserve(port, repo, time.Now()); err != nil {
+		return exitWithError("%v; release it first with \"lsrv reserve %d --release\"", err, port)
+	}
+	if err := registry.Save(); err != nil {
+		return exitWithError("saving reservations: %v", err)
+	}
+	fmt.Printf("Reserved port %d for %s\n", port, repo)
+	return 0
+}
+
+// listReservations prints each reservation with what holds its port now
+func listReservations(registry *reservation.Registry, asJSON bool, timeout time.Duration) int {
+	if asJSON {
+		reservations := registry.Reservations
+		if reservations == nil {
+			reservations = []reservation.Reservation{}
+		}
+		data, err := json.MarshalIndent(reservations, "", "  ")
+		if err != nil {
+			return exitWithError("encoding reservations: %v", err)
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	if len(registry.Reservations) == 0 {
+		fmt.Println("No ports reserved (reserve one with \"lsrv reserve PORT\")")
+		return 0
+	}
+
+	var servers []types.Server
+	if commandExists("lsof") {
+		opts := configuredOptions()
+		opts.Services = true
+		found, err := findServers(opts, timeout)
+		if err != nil {
+			printWarning("finding servers: %v", err)
+		}
+		servers = found
+	}
+	return printReservations(registry, servers)
+}
+
+// printReservations lists reservations as "PORT REPO holder"
+func printReservations(registry *reservation.Registry, servers []types.Server) int {
+	for _, r := range registry.Reservations {
+		holder := portHolder(servers, r.Port)
+		for _, server := range servers {
+			if server.Port == r.Port && registry.Violated(server) {
+				holder += ", violating the reservation"
+				break
+			}
+		}
+		fmt.Printf("%-5d  %-20s %s\n", r.Port, r.Repo, holder)
+	}
+	return 0
+}
+
+// currentRepoName names the repository of the working directory the way
+// the listing does
+func currentRepoName() (string, bool) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	root, ok := git.WorkTreeRoot(wd)
+	if !ok {
+		return "", false
+	}
+	return git.GetRepoName(context.Background(), root, configuredRepoNames()), true
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"text/tabwriter"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/i18n"
+	"github.com/bshakr/lsrv/internal/starthint"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// runRun runs a project action from .lsrv.yml in a server's directory, or
+// lists the available actions when none is named
+func runRun(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv run <repo|port> [action]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Run an action defined in the repo's .lsrv.yml in the server's directory,")
+		fmt.Fprintln(os.Stderr, "or list the actions when none is given")
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) < 1 || len(positional) > 2 {
+		fs.Usage()
+		return 2
+	}
+
+	servers, err := detectServers()
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	server, err := matchDir(servers, positional[0])
+	if err != nil {
+		return exitWithError("%v", err)
+	}
+
+	actions, err := control.Actions(server)
+	if err != nil {
+		return exitWithError("reading actions: %v", err)
+	}
+
+	if len(positional) == 1 {
+		if len(actions) == 0 {
+			fmt.Printf("No actions defined for %s; add them to %s:\n\n", server.Repo, config.RepoFileName)
+			fmt.Println("  actions:")
+			fmt.Println("    test: bin/rspec")
+			fmt.Println("    console: bin/rails console")
+			return 0
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, action := range actions {
+			fmt.Fprintf(tw, "%s\t%s\n", action.Name, action.Command)
+		}
+		tw.Flush()
+		return 0
+	}
+
+	action, ok := actions.Find(positional[1])
+	if !ok {
+		return exitWithError("%s has no action %q (run \"lsrv run %s\" to list them)", server.Repo, positional[1], positional[0])
+	}
+
+	cmd := control.ActionCommand(server, action)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		// Pass the action's own exit status through
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return exitWithError("running %s: %v", action.Name, err)
+	}
+	return 0
+}
+
+// matchDir returns a server identified by target, refusing targets that
+// match servers in more than one directory
+func matchDir(servers []types.Server, target string) (types.Server, error) {
+	matched := control.Match(servers, target)
+	if len(matched) == 0 {
+		return types.Server{}, noServerMatches(target)
+	}
+	server := matched[0]
+	for _, other := range matched[1:] {
+		if other.CWD != server.CWD {
+			return types.Server{}, errors.New(i18n.T("%q matches servers in %d directories, use a port instead", target, len(matched)))
+		}
+	}
+	return server, nil
+}
+
+// noServerMatches is the error for a target no running server matches,
+// suggesting how to start it when lsrv saw the project run before
+func noServerMatches(target string) error {
+	msg := i18n.T("no server matches %q", target)
+	if hint, ok := starthint.For(target); ok {
+		msg += "\n" + i18n.T("not running — start with: %s", hint.String())
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/api"
+	"github.com/bshakr/lsrv/internal/atomicfile"
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/grpcapi"
+	"github.com/bshakr/lsrv/internal/health"
+	"github.com/bshakr/lsrv/internal/platform"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+const (
+	// tokenFileName holds the generated API token in the state directory
+	tokenFileName = "api-token"
+
+	// cacheInterval is how often the server list behind "?cached=1" is
+	// refreshed
+	cacheInterval = 2 * time.Second
+)
+
+// runServe exposes server listing and control over HTTP for browser
+// extensions, launcher scripts and dashboards
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("http", "localhost:7777", "Address to listen on")
+	httpRemoteFlag := fs.Bool("http-remote", false, "Allow --http on an address other machines can reach, such as :7777")
+	grpcAddr := fs.String("grpc", "", "Also serve the gRPC API (proto/lsrv/v1/lsrv.proto) on ADDR, e.g. localhost:7778")
+	grpcRemoteFlag := fs.Bool("grpc-remote", false, "Allow --grpc on an address other machines can reach, such as :7778")
+	corsFlag := fs.String("cors", "", "Let pages from these comma-separated origins call the API, e.g. chrome-extension://ID (requires a token)")
+	tokenFlag := fs.String("token", "", "Require this API token from callers (default: generated in the state directory)")
+	healthTTLFlag := fs.Duration("health-ttl", health.DefaultTTL, "Re-check servers that are up after this long")
+	healthBackoffFlag := fs.Duration("health-max-backoff", health.DefaultMaxBackoff, "Longest wait between checks of a failing server")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv serve [--http=ADDR [--http-remote]] [--grpc=ADDR [--grpc-remote]] [--cors=ORIGINS] [--token=TOKEN] [--health-ttl=DURATION]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Endpoints:")
+		fmt.Fprintln(os.Stderr, "  GET    /servers           List running servers as JSON")
+		fmt.Fprintln(os.Stderr, "  GET    /servers?cached=1  The latest background scan, without waiting")
+		fmt.Fprintln(os.Stderr, "  GET    /servers/{port}    Servers listening on a port")
+		fmt.Fprintln(os.Stderr, "  DELETE /servers/{port}    Kill the servers listening on port")
+		fmt.Fprintln(os.Stderr, "  GET    /health            Cached health checks, with failing servers backed off")
+		fmt.Fprintln(os.Stderr, "  GET    /health/{port}     Cached health of the servers on a port")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "gRPC methods (lsrv.v1.Lsrv, cleartext HTTP/2):")
+		fmt.Fprintln(os.Stderr, "  ListServers, WatchServers (stream), KillServer")
+		fmt.Fprintln(os.Stderr, "")
+		fs.PrintDefaults()
+	}
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return 2
+	}
+
+	// The API kills processes and speaks cleartext, so the network only
+	// gets it when asked for
+	if !*httpRemoteFlag && !loopbackAddr(*addr) {
+		return exitWithError("--http=%s is reachable from other machines; use localhost:PORT, or add --http-remote", *addr)
+	}
+	if *grpcAddr != "" && !*grpcRemoteFlag && !loopbackAddr(*grpcAddr) {
+		return exitWithError("--grpc=%s is reachable from other machines; use localhost:PORT, or add --grpc-remote", *grpcAddr)
+	}
+
+	if !commandExists("lsof") {
+		printLsofError()
+		return 1
+	}
+
+	// Without a warning, a broken config would silently drop the
+	// configured token and origins, failing their callers with 401s
+	cfg, err := config.Load()
+	warnConfigIssues(err)
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	access := api.Options{AllowOrigins: cfg.Serve.CORS, Token: cfg.Serve.Token, AnyHost: *httpRemoteFlag}
+	if *corsFlag != "" {
+		access.AllowOrigins = strings.Split(*corsFlag, ",")
+	}
+	if *tokenFlag != "" {
+		access.Token = *tokenFlag
+	}
+	// Any page the browser opens, or any local process, could otherwise
+	// read and kill servers
+	if access.Token == "" {
+		token, path, err := apiToken()
+		if err != nil {
+			return exitWithError("creating API token: %v", err)
+		}
+		access.Token = token
+		fmt.Fprintf(os.Stderr, "API token in %s\n", path)
+	}
+
+	detect := trackedDetectServers()
+	if *healthTTLFlag <= 0 || *healthBackoffFlag <= 0 {
+		return exitWithError("--health-ttl and --health-max-backoff must be positive")
+	}
+	checks := health.NewCache(health.NewChecker(health.DefaultWorkers, health.DefaultTimeout), *healthTTLFlag, *healthBackoffFlag)
+	access.Cached = newScanCache(detect, cacheInterval, func(servers []types.Server) {
+		checks.Refresh(context.Background(), servers)
+	}).servers
+	access.Health = checks.Statuses
+	errs := make(chan error, 2)
+
+	if *grpcAddr != "" {
+		fmt.Fprintf(os.Stderr, "Serving lsrv gRPC API on %s\n", *grpcAddr)
+		go func() { errs <- grpcapi.NewServer(*grpcAddr, detect, access).ListenAndServe() }()
+	}
+
+	fmt.Fprintf(os.Stderr, "Serving lsrv API on http://%s\n", *addr)
+	go func() { errs <- http.ListenAndServe(*addr, api.NewHandler(detect, access)) }()
+
+	return exitWithError("serving: %v", <-errs)
+}
+
+// loopbackAddr reports whether a listen address only accepts connections
+// from this machine: localhost or a loopback IP, but not an empty host
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
+
+// apiToken reads the generated API token, creating it on first use, and
+// returns it with the file holding it. The file is readable only by the
+// user so other accounts can't borrow it.
+func apiToken() (string, string, error) {
+	dir, err := platform.StateDir()
+	if err != nil {
+		return "", "", err
+	}
+	path := filepath.Join(dir, tokenFileName)
+
+	data, err := os.ReadFile(path)
+	if token := strings.TrimSpace(string(data)); err == nil && token != "" {
+		return token, path, nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(secret)
+	return token, path, atomicfile.WriteFile(path, []byte(token+"\n"), 0o600)
+}
+
+// scanCache keeps the result of the latest detection, refreshed in the
+// background, so "lsrv prompt" gets an answer without waiting for a scan
+// and health checks follow the servers that come and go
+type scanCache struct {
+	mu   sync.Mutex
+	list []types.Server
+	err  error
+	done chan struct{}
+}
+
+// newScanCache starts refreshing the list every interval, passing each
+// successful scan to onScan
+func newScanCache(detect api.FindFunc, interval time.Duration, onScan func([]types.Server)) *scanCache {
+	cache := &scanCache{done: make(chan struct{})}
+	go func() {
+		for first := true; ; first = false {
+			list, err := detect()
+			if err == nil {
+				onScan(list)
+			}
+			cache.mu.Lock()
+			cache.list, cache.err = list, err
+			cache.mu.Unlock()
+			if first {
+				close(cache.done)
+			}
+			time.Sleep(interval)
+		}
+	}()
+	return cache
+}
+
+// servers returns the latest list, waiting only for the first scan
+func (c *scanCache) servers() ([]types.Server, error) {
+	<-c.done
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.list, c.err
+}
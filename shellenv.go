@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/git"
+	"github.com/bshakr/lsrv/internal/i18n"
+	"github.com/bshakr/lsrv/internal/starthint"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// envName matches variable names every supported shell accepts
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// runEnv prints variables describing a server for a shell to eval, so
+// scripts can target whatever port the current branch's server got
+func runEnv(args []string) int {
+	fs := flag.NewFlagSet("env", flag.ContinueOnError)
+	shellFlag := fs.String("shell", defaultShell(), "Syntax to print: sh or fish")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv env [repo|port] [--shell=sh|fish]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Print export lines for the server, by default the one running in the current")
+		fmt.Fprintln(os.Stderr, "repository, e.g. eval \"$(lsrv env)\" sets APP_URL and APP_PORT")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) > 1 {
+		fs.Usage()
+		return 2
+	}
+	if *shellFlag != "sh" && *shellFlag != "fish" {
+		return exitWithError("unknown shell %q (want sh or fish)", *shellFlag)
+	}
+
+	if !commandExists("lsof") {
+		return exitWithError("lsof command not found, please install it")
+	}
+	opts := configuredOptions()
+	if wd, err := os.Getwd(); err == nil {
+		opts.CurrentDir, _ = git.WorkTreeRoot(wd)
+	}
+	servers, err := findServers(opts, defaultTimeout)
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	var server types.Server
+	if len(positional) == 0 {
+		server, err = currentServer(servers)
+	} else {
+		server, err = matchDir(preferCurrent(servers, positional[0]), positional[0])
+	}
+	if err != nil {
+		return exitWithError("%v", err)
+	}
+
+	var global map[string]string
+	if cfg, err := config.Load(); err == nil {
+		global = cfg.Exports
+	}
+	exports, err := control.Exports(server, global)
+	if err != nil {
+		return exitWithError("reading exports: %v", err)
+	}
+
+	names := slices.Sorted(maps.Keys(exports))
+	for _, name := range names {
+		if !envName.MatchString(name) {
+			return exitWithError("invalid variable name %q in exports", name)
+		}
+	}
+	for _, name := range names {
+		if *shellFlag == "fish" {
+			fmt.Printf("set -gx %s %s\n", name, fishQuote(exports[name]))
+		} else {
+			fmt.Printf("export %s=%s\n", name, shQuote(exports[name]))
+		}
+	}
+	return 0
+}
+
+// currentServer returns the app server running in the repository lsrv was
+// started in, its lowest port when there are several
+func currentServer(servers []types.Server) (types.Server, error) {
+	for _, server := range servers {
+		if server.Current && server.PrimaryPort == 0 {
+			return server, nil
+		}
+	}
+	msg := i18n.T("no server is running in this repository")
+	if wd, err := os.Getwd(); err == nil {
+		if root, ok := git.WorkTreeRoot(wd); ok {
+			if command := starthint.Launcher(root); command != nil {
+				hint := starthint.Hint{Dir: root, Command: command}
+				msg += "\n" + i18n.T("not running — start with: %s", hint.String())
+			}
+		}
+	}
+	return types.Server{}, errors.New(msg)
+}
+
+// preferCurrent narrows the servers target matches to those in the current
+// repository when it runs one, so a repo name picks this worktree's server
+// over other branches'
+func preferCurrent(servers []types.Server, target string) []types.Server {
+	matched := control.Match(servers, target)
+	var current []types.Server
+	for _, server := range matched {
+		if server.Current {
+			current = append(current, server)
+		}
+	}
+	if len(current) > 0 {
+		return current
+	}
+	return servers
+}
+
+// defaultShell picks fish syntax when it is the login shell
+func defaultShell() string {
+	if filepath.Base(os.Getenv("SHELL")) == "fish" {
+		return "fish"
+	}
+	return "sh"
+}
+
+// shQuote single-quotes a value for POSIX shells
+func shQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// fishQuote single-quotes a value for fish, which escapes \ and ' inside
+func fishQuote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/snapshot"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// runSnapshot saves the current servers under a name or diffs against a
+// saved set, to see what a script started, stopped or moved
+func runSnapshot(args []string) int {
+	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv snapshot save <name>")
+		fmt.Fprintln(os.Stderr, "       lsrv snapshot diff <name>")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Record the running servers, then show which started, stopped or changed port since")
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 2 || (positional[0] != "save" && positional[0] != "diff") {
+		fs.Usage()
+		return 2
+	}
+	action, name := positional[0], positional[1]
+
+	if !commandExists("lsof") {
+		return exitWithError("lsof command not found, please install it")
+	}
+	// Include services so a database a script left behind shows up too
+	servers, err := findServers(detector.Options{Services: true, RepoNames: configuredRepoNames()}, defaultTimeout)
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+
+	if action == "save" {
+		path, err := snapshot.Save(name, servers)
+		if err != nil {
+			return exitWithError("saving snapshot: %v", err)
+		}
+		fmt.Printf("Saved %d server(s) as %q in %s\n", len(servers), name, path)
+		return 0
+	}
+
+	snap, err := snapshot.Load(name)
+	if err != nil {
+		return exitWithError("%v", err)
+	}
+
+	diff := snapshot.Compare(snap.Servers, servers)
+	if diff.Empty() {
+		fmt.Printf("No changes since snapshot %q (%s)\n", name, snap.Taken.Format("2006-01-02 15:04:05"))
+		return 0
+	}
+
+	fmt.Printf("Since snapshot %q (%s):\n", name, snap.Taken.Format("2006-01-02 15:04:05"))
+	for _, server := range diff.Started {
+		fmt.Printf("  + started   %s\n", describeServer(server))
+	}
+	for _, server := range diff.Stopped {
+		fmt.Printf("  - stopped   %s\n", describeServer(server))
+	}
+	for _, change := range diff.Changed {
+		before, after := change.Before, change.After
+		switch {
+		case before.PID != after.PID:
+			fmt.Printf("  ~ restarted %s (was pid %d)\n", describeServer(after), before.PID)
+		default:
+			fmt.Printf("  ~ moved     %s (was port %d)\n", describeServer(after), before.Port)
+		}
+	}
+	return 0
+}
+
+// describeServer summarizes a server on one line, e.g.
+// "webapp (main) node, pid 4242, port 3000"
+func describeServer(server types.Server) string {
+	name := server.Repo
+	if server.Service != "" {
+		name = server.Service
+	} else if server.Branch != "" && server.Branch != "-" {
+		name += " (" + server.Branch + ")"
+	}
+	return fmt.Sprintf("%s %s, pid %d, port %d", name, server.Process, server.PID, server.Port)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/git"
+)
+
+// runStart launches a server in the background with its output captured so
+// that "lsrv logs" can show it later
+func runStart(args []string) int {
+	fs := flag.NewFlagSet("start", flag.ContinueOnError)
+	dirFlag := fs.String("dir", ".", "Directory to run the command in")
+	dryRunFlag := fs.Bool("dry-run", false, "Show what would be started without starting it")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv start [--dir=DIR] [--dry-run] -- COMMAND [ARGS...]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Starts COMMAND in the background, capturing stdout and stderr for 'lsrv logs'.")
+		fs.PrintDefaults()
+	}
+
+	command, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(command) == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	dir, err := filepath.Abs(*dirFlag)
+	if err != nil {
+		return exitWithError("resolving directory: %v", err)
+	}
+
+	repo := filepath.Base(dir)
+	if git.IsRepo(context.Background(), dir) {
+		repo = git.GetRepoName(context.Background(), dir, configuredRepoNames())
+	}
+
+	if *dryRunFlag || control.SafeMode {
+		fmt.Printf("Would start %s in %s as %s\n", repo, dir, strings.Join(command, " "))
+		if !*dryRunFlag {
+			return exitWithError("%v", control.ErrSafeMode)
+		}
+		return 0
+	}
+
+	run, err := control.Start(dir, repo, command)
+	if err != nil {
+		return exitWithError("starting server: %v", err)
+	}
+
+	fmt.Printf("Started %s (pid %d)\n", repo, run.PID)
+	fmt.Printf("Logs: %s\n", run.LogPath)
+	return 0
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bshakr/lsrv/internal/config"
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/formatter"
+	"github.com/bshakr/lsrv/internal/icons"
+	"github.com/bshakr/lsrv/internal/procinfo"
+	"github.com/bshakr/lsrv/internal/tui"
+	"github.com/bshakr/lsrv/internal/types"
+	"github.com/charmbracelet/x/term"
+)
+
+// runTop shows the running dev servers live, sorted by CPU or memory use,
+// for finding the one that is slowing the machine down
+func runTop(args []string) int {
+	fs := flag.NewFlagSet("top", flag.ContinueOnError)
+	intervalFlag := fs.Duration("interval", defaultInteractiveInterval, "How often to refresh")
+	sortFlag := fs.String("sort", "cpu", "Initial order: cpu or mem")
+	asciiFlag := fs.Bool("ascii", false, "Use plain text tags like [ruby] instead of Nerd Font icons")
+	timeoutFlag := fs.Duration("timeout", defaultTimeout, "Give up on each detection after this long (0 for no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv top [--sort=cpu|mem] [--interval=DURATION]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Show the running dev servers live, heaviest first, counting the workers and")
+		fmt.Fprintln(os.Stderr, "tools each one started. Keys: c/m sort by CPU/memory, x kill, q quit.")
+		fs.PrintDefaults()
+	}
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return 2
+	}
+	sort := tui.TopSort(*sortFlag)
+	if sort != tui.SortCPU && sort != tui.SortMemory {
+		return exitWithError("invalid sort %q (want cpu or mem)", *sortFlag)
+	}
+	if *intervalFlag <= 0 {
+		return exitWithError("--interval must be positive")
+	}
+	if !term.IsTerminal(os.Stdout.Fd()) {
+		return exitWithError("lsrv top needs a terminal")
+	}
+	if _, err := procinfo.AllUsage(context.Background()); errors.Is(err, errors.ErrUnsupported) {
+		return exitWithError("lsrv top isn't supported on this platform yet")
+	}
+
+	if !commandExists("lsof") {
+		printLsofError()
+		return 1
+	}
+
+	// Only app servers; databases and caches have their own monitoring
+	opts := configuredOptions()
+	opts.Services = false
+	cfg, err := config.Load()
+	warnConfigIssues(err)
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
+	tracker := &detector.Tracker{}
+	err = tui.RunTop(tui.TopConfig{
+		Find:     func() ([]types.Server, error) { return refreshServers(tracker, opts, *timeoutFlag) },
+		Interval: *intervalFlag,
+		Sort:     sort,
+		Format:   formatter.Options{Format: formatter.FormatTable, Icons: icons.NewSet(cfg, *asciiFlag), Units: formatter.UnitsFrom(cfg.Units, "top")},
+	})
+	if err != nil {
+		return exitWithError("top: %v", err)
+	}
+	return 0
+}
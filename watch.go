@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/formatter"
+	"github.com/bshakr/lsrv/internal/i18n"
+	"github.com/bshakr/lsrv/internal/sockwatch"
+)
+
+// clearScreen moves the cursor home and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// minRescanInterval debounces --on-change: a burst of socket changes, such
+// as a dev server restarting its workers, costs one rescan a second
+const minRescanInterval = time.Second
+
+// runWatch redraws the server list every interval until interrupted. A
+// Tracker keeps refreshes cheap by only resolving newly started processes.
+// With onChange, listening socket changes trigger a redraw right away and
+// interval only bounds how stale the list can get.
+func runWatch(interval, timeout time.Duration, onChange bool, detectOpts detector.Options, opts formatter.Options) int {
+	tracker := &detector.Tracker{}
+
+	var watcher *sockwatch.Watcher
+	var changes <-chan struct{}
+	if onChange {
+		var err error
+		watcher, err = sockwatch.New()
+		if errors.Is(err, errors.ErrUnsupported) {
+			return exitWithError("--on-change isn't supported on this platform")
+		} else if err != nil {
+			return exitWithError("watching sockets: %v", err)
+		}
+		defer watcher.Close()
+		changes = watcher.C
+	}
+
+	header := i18n.T("Every %s", interval)
+	if onChange {
+		header = i18n.T("On change or every %s", interval)
+	}
+
+	for {
+		scanned := time.Now()
+		servers, err := refreshServers(tracker, detectOpts, timeout)
+		if err != nil {
+			return exitWithError("finding servers: %v", err)
+		}
+		if watcher != nil {
+			pids := make([]int, len(servers))
+			for i, server := range servers {
+				pids[i] = server.PID
+			}
+			watcher.Track(pids)
+		}
+
+		fmt.Print(clearScreen)
+		fmt.Printf("%s: lsrv  %s\n\n", header, time.Now().Format("15:04:05"))
+		if err := formatter.Write(os.Stdout, servers, withTerminalWidth(opts)); err != nil {
+			return exitWithError("writing output: %v", err)
+		}
+
+		select {
+		case <-changes:
+			time.Sleep(minRescanInterval - time.Since(scanned))
+			// Changes during the pause are covered by the coming rescan
+			select {
+			case <-changes:
+			default:
+			}
+		case <-time.After(interval):
+		}
+	}
+}
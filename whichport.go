@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/porthistory"
+	"github.com/bshakr/lsrv/internal/types"
+)
+
+// runWhichport prints the ports a repo usually runs on and, when it runs
+// elsewhere today, what took its usual port
+func runWhichport(args []string) int {
+	fs := flag.NewFlagSet("whichport", flag.ContinueOnError)
+	timeoutFlag := fs.Duration("timeout", defaultTimeout, "Give up on detection after this long (0 for no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv whichport [--timeout=DURATION] REPO")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Show the port(s) REPO usually runs on, from the ports lsrv has seen it use,")
+		fmt.Fprintln(os.Stderr, "and what holds the usual port when REPO runs on another one today.")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+	repo := positional[0]
+
+	history, err := porthistory.Load()
+	if err != nil {
+		return exitWithError("reading port history: %v", err)
+	}
+	ports := history.Ports(repo)
+	if len(ports) == 0 {
+		return exitWithError("no port history for %s (lsrv records ports each time it lists servers)", repo)
+	}
+
+	usual := history.Usual(repo)
+	fmt.Printf("%s usually runs on %s\n", repo, joinPorts(usual))
+	for _, p := range ports {
+		marker := " "
+		if slices.Contains(usual, p.Port) {
+			marker = "*"
+		}
+		fmt.Printf("  %s %-5d seen on %d day(s), last %s\n", marker, p.Port, p.Days, p.LastSeen.Local().Format("2006-01-02"))
+	}
+
+	if !commandExists("lsof") {
+		return 0
+	}
+	servers, err := findServers(detector.Options{Services: true, RepoNames: configuredRepoNames()}, *timeoutFlag)
+	if err != nil {
+		printWarning("finding servers: %v", err)
+		return 0
+	}
+
+	var running []int
+	for _, server := range servers {
+		if server.Service == "" && server.Repo == repo {
+			running = append(running, server.Port)
+		}
+	}
+	if len(running) == 0 {
+		fmt.Printf("\n%s is not running\n", repo)
+		return 0
+	}
+	slices.Sort(running)
+	fmt.Printf("\n%s is running on %s\n", repo, joinPorts(slices.Compact(running)))
+	for _, port := range usual {
+		if slices.Contains(running, port) {
+			continue
+		}
+		fmt.Printf("  %d is %s\n", port, portHolder(servers, port))
+	}
+	return 0
+}
+
+// portHolder describes what listens on port, if anything
+func portHolder(servers []types.Server, port int) string {
+	for _, server := range servers {
+		if server.Port != port {
+			continue
+		}
+		name := server.Repo
+		if server.Service != "" {
+			name = server.Service
+		}
+		return fmt.Sprintf("held by %s (%s, pid %d)", name, server.Process, server.PID)
+	}
+	if portInUse(port) {
+		return fmt.Sprintf("held by a process outside any repo (see lsof -i :%d)", port)
+	}
+	return "free"
+}
+
+// joinPorts formats ports as "3000, 3001"
+func joinPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/lsrv/internal/control"
+	"github.com/bshakr/lsrv/internal/detector"
+	"github.com/bshakr/lsrv/internal/porthistory"
+	"github.com/bshakr/lsrv/internal/procinfo"
+)
+
+// runWho answers "what exactly is on this port": every process listening
+// on it, including ones lsrv doesn't list, in the details layout, followed
+// by the repos seen on the port before. It fails when the port is free.
+func runWho(args []string) int {
+	fs := flag.NewFlagSet("who", flag.ContinueOnError)
+	timeoutFlag := fs.Duration("timeout", defaultTimeout, "Give up on detection after this long (0 for no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lsrv who PORT [--timeout=DURATION]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Show what listens on PORT: process, command line, repo and branch, container,")
+		fmt.Fprintln(os.Stderr, "uptime, and the repos seen on the port before. Exits 1 when nothing listens.")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+	port, err := strconv.Atoi(positional[0])
+	if err != nil || port <= 0 || port > 65535 {
+		return exitWithError("invalid port %q", positional[0])
+	}
+
+	if !commandExists("lsof") {
+		printLsofError()
+		return 1
+	}
+	servers, err := findServers(detailsOptions(), *timeoutFlag)
+	if err != nil {
+		return exitWithError("finding servers: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	listeners, err := detector.Listeners(ctx, port)
+	if err != nil {
+		return exitWithError("%v", err)
+	}
+
+	matched := control.Match(servers, strconv.Itoa(port))
+	listed := make(map[int]bool)
+	blocks := 0
+	for _, server := range matched {
+		if blocks > 0 {
+			fmt.Println()
+		}
+		printDetails(server)
+		listed[server.PID] = true
+		blocks++
+	}
+	// Workers sharing a listed server's socket are part of its block
+	for _, listener := range listeners {
+		if listed[listener.PID] || listedParent(ctx, listed, listener.PID) {
+			continue
+		}
+		if blocks > 0 {
+			fmt.Println()
+		}
+		printListener(ctx, listener, port)
+		blocks++
+	}
+	if blocks == 0 {
+		fmt.Printf("Nothing is listening on port %d\n", port)
+	}
+
+	printPortHistory(port)
+	if blocks == 0 {
+		return 1
+	}
+	return 0
+}
+
+// listedParent reports whether the parent of pid is a listed server, as
+// for Puma or gunicorn workers
+func listedParent(ctx context.Context, listed map[int]bool, pid int) bool {
+	ppid, _, err := procinfo.Parent(ctx, pid)
+	return err == nil && listed[ppid]
+}
+
+// printListener lays out what is known about a process lsrv doesn't list,
+// usually because it doesn't run in a git repository
+func printListener(ctx context.Context, listener detector.Listener, port int) {
+	row := printDetailRow
+	row("process", listener.Command)
+	printContainer(ctx, listener.Command, port)
+	row("pid", strconv.Itoa(listener.PID))
+	row("port", strconv.Itoa(port))
+	row("bind", listener.Bind)
+	if args, err := procinfo.CommandLine(ctx, listener.PID); err == nil {
+		row("command", strings.Join(args, " "))
+	}
+	if cwds, _ := procinfo.CWDs(ctx, []int{listener.PID}); cwds[listener.PID] != "" {
+		row("directory", cwds[listener.PID])
+	}
+	printStarted(ctx, listener.PID, detailsUnits())
+	row("note", "not listed by lsrv: outside any git repository or not a known dev server")
+}
+
+// printPortHistory lists the repos lsrv saw on port on earlier days
+func printPortHistory(port int) {
+	history, err := porthistory.Load()
+	if err != nil {
+		return
+	}
+	seen := history.Seen(port)
+	if len(seen) == 0 {
+		return
+	}
+	fmt.Printf("\nSeen on port %d before:\n", port)
+	for _, sighting := range seen {
+		fmt.Printf("  %-20s %d day(s), last %s\n", sighting.Repo, sighting.Days, sighting.LastSeen.Local().Format("2006-01-02"))
+	}
+}